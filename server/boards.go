@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+// StoreBoardKey is the key used to store the bookmark board of a channel in
+// the plugin KV store
+const StoreBoardKey = "board"
+
+// getBoard returns the bookmark board of a channel
+func (b *Board) getBoard() (*Board, error) {
+	// if a channel does not have a board, bb will be nil
+	bb, appErr := b.api.KVGet(getBoardKey(b.channelID))
+	if appErr != nil {
+		return nil, errors.Wrapf(appErr, "Unable to get bookmark board for channel %s", b.channelID)
+	}
+
+	if bb == nil {
+		return b, nil
+	}
+
+	jsonErr := json.Unmarshal(bb, b)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	if b.Entries == nil {
+		b.Entries = make(map[string]*CollectionEntry)
+	}
+
+	return b, nil
+}
+
+func getBoardKey(channelID string) string {
+	return fmt.Sprintf("%s_%s", StoreBoardKey, channelID)
+}
+
+// canCurateBoard returns whether a user can change the board of a channel.
+// Depending on the configuration this is channel admins or all members
+func (p *Plugin) canCurateBoard(userID, channelID string) bool {
+	if p.getConfiguration().getChannelBoardCuration() == boardCurationMembers {
+		return p.API.HasPermissionToChannel(userID, channelID, model.PERMISSION_READ_CHANNEL)
+	}
+	return p.API.HasPermissionToChannel(userID, channelID, model.PERMISSION_MANAGE_CHANNEL_ROLES)
+}
+
+// getBoardText returns the bookmarks of a board as markdown.  Only posts of
+// the channel can be on its board, so the text is the same for every member
+func (p *Plugin) getBoardText(board *Board) (string, error) {
+	text := "#### Channel Bookmarks\n"
+	if len(board.Entries) == 0 {
+		return text + "There are no bookmarks on this channel board\n", nil
+	}
+
+	for _, entry := range sortEntries(board.Entries) {
+		nextText, err := p.getBmarkTextOneLine(&Bookmark{PostID: entry.PostID, Title: entry.Title}, nil)
+		if err != nil {
+			return "", err
+		}
+		text += strings.TrimSuffix(nextText, "\n") + fmt.Sprintf(" - added by %s\n", p.getUsernameText(entry.AddedBy))
+	}
+	return text, nil
+}
+
+// pinBoardPost creates the pinned board post, or updates it if the board
+// already has one
+func (p *Plugin) pinBoardPost(board *Board) error {
+	text, err := p.getBoardText(board)
+	if err != nil {
+		return err
+	}
+
+	if board.PostID != "" {
+		if post, appErr := p.API.GetPost(board.PostID); appErr == nil && post.DeleteAt == 0 {
+			post.Message = text
+			post.IsPinned = true
+			if _, appErr = p.API.UpdatePost(post); appErr != nil {
+				return appErr
+			}
+			return nil
+		}
+	}
+
+	// the board post does not exist yet or was deleted by a user
+	post, appErr := p.API.CreatePost(&model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: board.channelID,
+		Message:   text,
+		IsPinned:  true,
+	})
+	if appErr != nil {
+		return appErr
+	}
+	board.PostID = post.Id
+	return board.storeBoard()
+}
+
+// updateBoardPost updates the pinned board post after the board changed.
+// Boards without a board post are left alone
+func (p *Plugin) updateBoardPost(board *Board) error {
+	if board.PostID == "" {
+		return nil
+	}
+	return p.pinBoardPost(board)
+}
+
+// unpinBoardPost deletes the pinned board post
+func (p *Plugin) unpinBoardPost(board *Board) error {
+	if board.PostID == "" {
+		return nil
+	}
+	if _, appErr := p.API.GetPost(board.PostID); appErr == nil {
+		if appErr = p.API.DeletePost(board.PostID); appErr != nil {
+			return appErr
+		}
+	}
+	board.PostID = ""
+	return board.storeBoard()
+}
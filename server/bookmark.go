@@ -2,11 +2,27 @@ package main
 
 // Bookmark contains information about an individual bookmark
 type Bookmark struct {
-	PostID     string   `json:"postid"`              // PostID is the ID for the bookmarked post and doubles as the Bookmark ID
+	PostID     string   `json:"postid"`              // PostID is the ID for the bookmarked post and doubles as the Bookmark ID. Other kinds use IDs like url:<hash>
+	Kind       string   `json:"kind,omitempty"`      // What is bookmarked: post, thread, channel, url or file
+	Target     string   `json:"target,omitempty"`    // The bookmarked post ID, thread root ID, channel ID, URL or file ID
 	Title      string   `json:"title,omitempty"`     // Title given to the bookmark
 	CreateAt   int64    `json:"create_at"`           // The original creation time of the bookmark
 	ModifiedAt int64    `json:"update_at"`           // The original creation time of the bookmark
 	LabelIDs   []string `json:"label_ids,omitempty"` // Array of labels added to the bookmark
+	DeleteAt   int64    `json:"delete_at,omitempty"` // The time the bookmark was moved to the trash
+	Status     string   `json:"status,omitempty"`    // Reading status: unread, in-progress, done or archived
+	Priority   string   `json:"priority,omitempty"`  // Priority: high, normal or low
+	Position   int64    `json:"position,omitempty"`  // Position in the order chosen by the user, starting at 1
+	Due        string   `json:"due,omitempty"`       // Due date as YYYY-MM-DD in the users timezone
+
+	ThreadReplyCount int64 `json:"thread_reply_count,omitempty"` // The number of thread replies when the user last saw the thread
+	ThreadSeenAt     int64 `json:"thread_seen_at,omitempty"`     // The time the user last saw the thread
+	ThreadNotify     bool  `json:"thread_notify,omitempty"`      // Whether the bot notifies the user of new thread replies
+	ThreadNotifiedAt int64 `json:"thread_notified_at,omitempty"` // The time the bot last notified the user of new thread replies
+
+	Snapshot *BookmarkSnapshot `json:"snapshot,omitempty"` // Copy of the bookmarked post taken when the bookmark was added
+
+	History []*BookmarkEvent `json:"history,omitempty"` // Changes made to the bookmark, oldest first
 }
 
 func (bm *Bookmark) hasUserTitle() bool {
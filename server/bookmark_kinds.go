@@ -0,0 +1,228 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	bookmarkKindPost    = "post"
+	bookmarkKindThread  = "thread"
+	bookmarkKindChannel = "channel"
+	bookmarkKindURL     = "url"
+	bookmarkKindFile    = "file"
+
+	// migrationBookmarkKindsKey marks that the kinds of bookmarks stored
+	// before kinds existed have been set
+	migrationBookmarkKindsKey = "migration_bookmark_kinds"
+)
+
+// getBookmarkID returns the ID of a bookmark of a kind.  Post bookmarks keep
+// using the post ID so existing bookmarks keep their IDs.  URLs are hashed to
+// keep IDs short
+func getBookmarkID(kind, target string) string {
+	switch kind {
+	case bookmarkKindPost:
+		return target
+	case bookmarkKindURL:
+		sum := sha256.Sum256([]byte(target))
+		return kind + ":" + hex.EncodeToString(sum[:])[:16]
+	}
+	return kind + ":" + target
+}
+
+// newBookmark returns a bookmark of a kind for a target
+func newBookmark(kind, target string) *Bookmark {
+	return &Bookmark{
+		PostID: getBookmarkID(kind, target),
+		Kind:   kind,
+		Target: target,
+	}
+}
+
+// getKind returns what is bookmarked.  Bookmarks stored before kinds existed
+// are posts
+func (bm *Bookmark) getKind() string {
+	if bm.Kind == "" {
+		return bookmarkKindPost
+	}
+	return bm.Kind
+}
+
+// getTarget returns the post ID, thread root ID, channel ID, URL or file ID
+// of the bookmark
+func (bm *Bookmark) getTarget() string {
+	if bm.Target == "" {
+		return bm.PostID
+	}
+	return bm.Target
+}
+
+// isPost returns whether the bookmark is of a post, either on its own or as
+// the root of a thread
+func (bm *Bookmark) isPost() bool {
+	kind := bm.getKind()
+	return kind == bookmarkKindPost || kind == bookmarkKindThread
+}
+
+// validateBookmarkURL checks that a URL can be bookmarked
+func validateBookmarkURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.New(fmt.Sprintf("URL `%s` is not valid. Please use a full http or https URL", rawURL))
+	}
+	return nil
+}
+
+// getBmarkGeneratedTitle returns the text displayed for bookmarks without a
+// user title: the post message, the channel name, the URL or the file name
+func (p *Plugin) getBmarkGeneratedTitle(bmark *Bookmark) (string, error) {
+	switch bmark.getKind() {
+	case bookmarkKindChannel:
+		channel, appErr := p.API.GetChannel(bmark.getTarget())
+		if appErr != nil {
+			return "", appErr
+		}
+		if channel.DisplayName != "" {
+			return channel.DisplayName, nil
+		}
+		return channel.Name, nil
+	case bookmarkKindURL:
+		return bmark.getTarget(), nil
+	case bookmarkKindFile:
+		info, appErr := p.API.GetFileInfo(bmark.getTarget())
+		if appErr != nil {
+			return "", appErr
+		}
+		return info.Name, nil
+	}
+
+	title, err := p.getTitleFromPost(bmark.getTarget())
+	if err != nil && bmark.Snapshot != nil {
+		// the post is gone, use the saved copy
+		snapshot := bmark.Snapshot
+		return generateTitle(snapshot.Message, nil, snapshot.FileNames, p.getConfiguration().getGeneratedTitleLength()), nil
+	}
+	return title, err
+}
+
+// getBmarkIconLink returns a markdown link to what is bookmarked with an icon
+// for its kind
+func (p *Plugin) getBmarkIconLink(bmark *Bookmark) string {
+	switch bmark.getKind() {
+	case bookmarkKindThread:
+		return fmt.Sprintf("[:speech_balloon:](%s)", p.getPermaLink(bmark.getTarget()))
+	case bookmarkKindChannel:
+		if link := p.getChannelLink(bmark.getTarget()); link != "" {
+			return fmt.Sprintf("[:hash:](%s)", link)
+		}
+		return ":hash:"
+	case bookmarkKindURL:
+		return fmt.Sprintf("[:globe_with_meridians:](%s)", bmark.getTarget())
+	case bookmarkKindFile:
+		return fmt.Sprintf("[:paperclip:](%s/api/v4/files/%s)", p.GetSiteURL(), bmark.getTarget())
+	}
+	return p.getIconLink(bmark.getTarget())
+}
+
+// getChannelLink returns a link to a team channel, or an empty string for
+// direct and group messages or channels that no longer exist
+func (p *Plugin) getChannelLink(channelID string) string {
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil || channel.TeamId == "" {
+		return ""
+	}
+	team, appErr := p.API.GetTeam(channel.TeamId)
+	if appErr != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s/channels/%s", p.GetSiteURL(), team.Name, channel.Name)
+}
+
+// canAccessBookmark returns whether a user can see what a bookmark points
+// to.  URLs are public, everything else depends on channel permissions
+func (p *Plugin) canAccessBookmark(userID string, bmark *Bookmark) bool {
+	switch bmark.getKind() {
+	case bookmarkKindChannel:
+		return p.API.HasPermissionToChannel(userID, bmark.getTarget(), model.PERMISSION_READ_CHANNEL)
+	case bookmarkKindURL:
+		return true
+	case bookmarkKindFile:
+		info, appErr := p.API.GetFileInfo(bmark.getTarget())
+		if appErr != nil || info.PostId == "" {
+			return false
+		}
+		post, appErr := p.API.GetPost(info.PostId)
+		return appErr == nil && p.canReadPost(userID, post)
+	}
+	post, appErr := p.API.GetPost(bmark.getTarget())
+	return appErr == nil && p.canReadPost(userID, post)
+}
+
+// setKinds sets the kind of bookmarks stored before kinds existed and
+// returns the number of changed bookmarks
+func setKinds(bmarks map[string]*Bookmark) int {
+	count := 0
+	for _, bmark := range bmarks {
+		if bmark.Kind == "" {
+			bmark.Kind = bookmarkKindPost
+			bmark.Target = bmark.PostID
+			count++
+		}
+	}
+	return count
+}
+
+// migrateBookmarkKinds sets the kind of all stored bookmarks, including
+// bookmarks in the trash.  The migration only runs once
+func (p *Plugin) migrateBookmarkKinds() error {
+	done, appErr := p.API.KVGet(migrationBookmarkKindsKey)
+	if appErr != nil {
+		return appErr
+	}
+	if done != nil {
+		return nil
+	}
+
+	bmarksUserIDs, err := p.listUserIDsWithPrefix(StoreBookmarksKey + "_")
+	if err != nil {
+		return err
+	}
+	trashUserIDs, err := p.listUserIDsWithPrefix(StoreTrashKey + "_")
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range bmarksUserIDs {
+		bmarks, err := NewBookmarksWithUser(p.API, userID).getBookmarks()
+		if err != nil || bmarks == nil {
+			continue
+		}
+		if setKinds(bmarks.ByID) != 0 {
+			if err = bmarks.storeBookmarks(); err != nil {
+				return err
+			}
+		}
+	}
+	for _, userID := range trashUserIDs {
+		trash, err := NewTrashWithUser(p.API, userID).getTrash()
+		if err != nil {
+			continue
+		}
+		if setKinds(trash.ByID) != 0 {
+			if err = trash.storeTrash(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if appErr = p.API.KVSet(migrationBookmarkKindsKey, []byte("done")); appErr != nil {
+		return appErr
+	}
+	return nil
+}
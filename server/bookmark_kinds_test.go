@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetBookmarkID(t *testing.T) {
+	assert.Equal(t, p1ID, getBookmarkID(bookmarkKindPost, p1ID))
+	assert.Equal(t, "thread:"+p1ID, getBookmarkID(bookmarkKindThread, p1ID))
+	assert.Equal(t, "channel:ChannelID", getBookmarkID(bookmarkKindChannel, "ChannelID"))
+
+	id := getBookmarkID(bookmarkKindURL, "https://example.com/docs")
+	assert.Len(t, id, len("url:")+16)
+	assert.Equal(t, id, getBookmarkID(bookmarkKindURL, "https://example.com/docs"))
+	assert.NotEqual(t, id, getBookmarkID(bookmarkKindURL, "https://example.com/other"))
+}
+
+func TestValidateBookmarkURL(t *testing.T) {
+	assert.Nil(t, validateBookmarkURL("https://example.com/docs?page=1"))
+	assert.Nil(t, validateBookmarkURL("http://example.com"))
+	assert.NotNil(t, validateBookmarkURL("example.com"))
+	assert.NotNil(t, validateBookmarkURL("ftp://example.com/file"))
+	assert.NotNil(t, validateBookmarkURL("https://"))
+}
+
+func TestGetKindDefaultsToPost(t *testing.T) {
+	bmark := &Bookmark{PostID: p1ID}
+	assert.Equal(t, bookmarkKindPost, bmark.getKind())
+	assert.Equal(t, p1ID, bmark.getTarget())
+	assert.True(t, bmark.isPost())
+	assert.False(t, newBookmark(bookmarkKindURL, "https://example.com").isPost())
+}
+
+func TestGetBmarkTextOneLineKinds(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+	siteURL := "https://myhost.com"
+	api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+	api.On("GetPost", p1ID).Return(&model.Post{Id: p1ID, Message: "root message"}, nil)
+	api.On("GetPostThread", p1ID).Return(getTestThread(p1ID, 2), nil)
+	api.On("GetChannel", "ChannelID").Return(&model.Channel{Id: "ChannelID", TeamId: teamID1, Name: "town-square", DisplayName: "Town Square"}, nil)
+	api.On("GetTeam", teamID1).Return(&model.Team{Id: teamID1, Name: "myteam"}, nil)
+	api.On("GetFileInfo", "FileID").Return(&model.FileInfo{Id: "FileID", Name: "report.pdf"}, nil)
+
+	tests := map[string]struct {
+		bmark    *Bookmark
+		expected string
+	}{
+		"post": {
+			bmark:    newBookmark(bookmarkKindPost, p1ID),
+			expected: "[:link:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx) **`TFP`** root message",
+		},
+		"thread": {
+			bmark:    newBookmark(bookmarkKindThread, p1ID),
+			expected: "[:speech_balloon:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx) **`TFP`** root message _(2 new replies)_",
+		},
+		"channel": {
+			bmark:    newBookmark(bookmarkKindChannel, "ChannelID"),
+			expected: "[:hash:](https://myhost.com/myteam/channels/town-square) Town Square",
+		},
+		"url with title": {
+			bmark:    &Bookmark{PostID: "url:1", Kind: bookmarkKindURL, Target: "https://example.com", Title: "Example"},
+			expected: "[:globe_with_meridians:](https://example.com) **_Example_**",
+		},
+		"file": {
+			bmark:    newBookmark(bookmarkKindFile, "FileID"),
+			expected: "[:paperclip:](https://myhost.com/api/v4/files/FileID) report.pdf",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			text, err := p.getBmarkTextOneLine(tt.bmark, nil)
+			require.Nil(t, err)
+			assert.Equal(t, tt.expected+"\n", text)
+		})
+	}
+}
+
+func TestMigrateBookmarkKinds(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+
+	jsonBmarks, err := json.Marshal(getExecuteCommandTestBookmarks())
+	require.Nil(t, err)
+	trash := NewTrashWithUser(nil, UserID)
+	trash.ByID["ID5xxxxxxxxxxxxxxxxxxxxxxx"] = &Bookmark{PostID: "ID5xxxxxxxxxxxxxxxxxxxxxxx"}
+	jsonTrash, err := json.Marshal(trash)
+	require.Nil(t, err)
+
+	api.On("KVGet", migrationBookmarkKindsKey).Return(nil, nil).Once()
+	api.On("KVList", 0, kvListPerPage).Return([]string{getBookmarksKey(UserID), getTrashKey(UserID), getLabelsKey(UserID)}, nil)
+	api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+	api.On("KVGet", getTrashKey(UserID)).Return(jsonTrash, nil)
+
+	store := mockBookmarksStore(api, UserID)
+	storedTrash := NewTrashWithUser(api, UserID)
+	api.On("KVSet", getTrashKey(UserID), mock.Anything).Run(func(args mock.Arguments) {
+		require.Nil(t, json.Unmarshal(args.Get(1).([]byte), storedTrash))
+	}).Return(nil).Once()
+	api.On("KVSet", migrationBookmarkKindsKey, []byte("done")).Return(nil).Once()
+
+	require.Nil(t, p.migrateBookmarkKinds())
+	stored := store.getBookmarks()
+	require.Len(t, stored.ByID, 4)
+	for id, bmark := range stored.ByID {
+		assert.Equal(t, bookmarkKindPost, bmark.Kind)
+		assert.Equal(t, id, bmark.Target)
+	}
+	assert.Equal(t, bookmarkKindPost, storedTrash.ByID["ID5xxxxxxxxxxxxxxxxxxxxxxx"].Kind)
+
+	// the migration only runs once
+	api.On("KVGet", migrationBookmarkKindsKey).Return([]byte("done"), nil).Once()
+	require.Nil(t, p.migrateBookmarkKinds())
+	// the users with bookmarks and with a trash are listed once
+	api.AssertNumberOfCalls(t, "KVList", 2)
+}
+
+func TestMigrateBookmarkKindsManyUsers(t *testing.T) {
+	store := newMemoryKVStore()
+	p := &Plugin{}
+	p.SetAPI(store)
+
+	// the bucket keys written while migrating sort before the bookmarks keys,
+	// the users on every page are migrated
+	var userIDs []string
+	for i := 0; i < 2*kvListPerPage; i++ {
+		userID := fmt.Sprintf("User%03d", i)
+		userIDs = append(userIDs, userID)
+		store.values[getBookmarksKey(userID)] = []byte(`{"ByID":{"` + p1ID + `":{"PostID":"` + p1ID + `"}}}`)
+	}
+
+	require.Nil(t, p.migrateBookmarkKinds())
+	assert.Equal(t, []byte("done"), store.values[migrationBookmarkKindsKey])
+	for _, userID := range userIDs {
+		bmarks, err := NewBookmarksWithUser(store, userID).getBookmarks()
+		require.Nil(t, err)
+		require.NotNil(t, bmarks.get(p1ID), userID)
+		assert.Equal(t, bookmarkKindPost, bmarks.get(p1ID).Kind, userID)
+	}
+}
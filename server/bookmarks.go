@@ -1,10 +1,9 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
-	"sort"
 
+	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/pkg/errors"
 )
 
@@ -15,100 +14,130 @@ const (
 
 // getBookmark returns a bookmark with the specified bookmarkID
 func (b *Bookmarks) getBookmark(bmarkID string) (*Bookmark, error) {
-	_, ok := b.exists(bmarkID)
+	bmark, ok := b.exists(bmarkID)
 	if !ok {
 		return nil, errors.New(fmt.Sprintf("Bookmark `%v` does not exist", bmarkID))
 	}
-
-	for _, bmark := range b.ByID {
-		if bmark.PostID == bmarkID {
-			return bmark, nil
-		}
-	}
-
-	return nil, nil
+	return bmark, nil
 }
 
 // addBookmark stores the bookmark in a map,
 func (b *Bookmarks) addBookmark(bmark *Bookmark) error {
-	// user doesn't have any bookmarks add first bookmark and return
-	if len(b.ByID) == 0 {
-		if err := b.add(bmark); err != nil {
-			return err
+	return b.addBookmarks([]*Bookmark{bmark})
+}
+
+// addBookmarks adds or updates several bookmarks and stores them once
+func (b *Bookmarks) addBookmarks(bmarks []*Bookmark) error {
+	var ids []string
+	for _, bmark := range bmarks {
+		ids = append(ids, bmark.PostID)
+	}
+
+	// bookmarks may have been removed to the trash before
+	trash, err := NewTrashWithUser(b.api, b.userID).getTrash()
+	if err != nil {
+		return err
+	}
+	trashedByID := make(map[string]*Bookmark)
+	for _, id := range ids {
+		if bmark := trash.get(id); bmark != nil {
+			trashedByID[id] = bmark
 		}
-		return nil
 	}
 
-	// bookmark already exists, update ModifiedAt and save
-	_, ok := b.exists(bmark.PostID)
-	if ok {
-		b.updateTimes(bmark.PostID)
-		b.updateLabels(bmark)
-		if err := b.add(bmark); err != nil {
-			return err
+	for _, bmark := range bmarks {
+		// bookmarks are posts unless the caller sets a kind
+		if bmark.Kind == "" {
+			bmark.Kind = bookmarkKindPost
+			bmark.Target = bmark.PostID
+		}
+
+		// history is only recorded by the store, never provided by the caller
+		bmark.History = nil
+		bmark.ModifiedAt = model.GetMillis()
+
+		// bookmark already exists, keep its creation time and history and
+		// record the changes
+		bmarkOrig, ok := b.exists(bmark.PostID)
+		if !ok && trashedByID[bmark.PostID] != nil {
+			bmarkOrig = trashedByID[bmark.PostID]
+			bmarkOrig.recordEvent(&BookmarkEvent{Type: eventRestored})
 		}
-		return nil
+		if bmarkOrig != nil {
+			// the status, priority and due date are kept unless the caller changes
+			// them
+			if bmark.Status == "" {
+				bmark.Status = bmarkOrig.Status
+			}
+			if bmark.Priority == "" {
+				bmark.Priority = bmarkOrig.Priority
+			}
+			if bmark.Due == "" {
+				bmark.Due = bmarkOrig.Due
+			}
+			if bmark.Snapshot == nil {
+				bmark.Snapshot = bmarkOrig.Snapshot
+			}
+			bmark.Position = bmarkOrig.Position
+			bmark.CreateAt = bmarkOrig.CreateAt
+			bmark.History = bmarkOrig.History
+			bmark.recordChanges(bmarkOrig)
+			b.ByID[bmark.PostID] = bmark
+			continue
+		}
+
+		// bookmark doesn't exist. Add it
+		bmark.CreateAt = bmark.ModifiedAt
+		bmark.recordEvent(&BookmarkEvent{
+			Type:     eventCreated,
+			Title:    bmark.getTitle(),
+			LabelIDs: bmark.getLabelIDs(),
+		})
+		b.ByID[bmark.PostID] = bmark
 	}
 
-	// bookmark doesn't exist. Add it
-	if err := b.add(bmark); err != nil {
-		return err
+	if err = b.storeBookmarks(); err != nil {
+		return errors.Wrap(err, "failed to add bookmark")
+	}
+
+	// bookmarks are only taken out of the trash once they are stored
+	if len(trashedByID) != 0 {
+		if _, err = trash.removeBookmarks(ids); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-// BookmarksFromJSON returns unmarshalled bookmark or initialized bookmarks if
-// bytes are empty
-func (b *Bookmarks) BookmarksFromJSON(bytes []byte) (*Bookmarks, error) {
-	bmarks := NewBookmarksWithUser(b.api, b.userID)
-	if len(bytes) != 0 {
-		jsonErr := json.Unmarshal(bytes, &bmarks)
-		if jsonErr != nil {
-			return nil, jsonErr
-		}
+// getBookmarks returns all of a users bookmarks.  If the user has no
+// bookmarks, return nil bookmarks
+func (b *Bookmarks) getBookmarks() (*Bookmarks, error) {
+	bmarks, err := b.getBookmarksIndex()
+	if err != nil || bmarks == nil {
+		return bmarks, err
+	}
+	if err = bmarks.loadAllBookmarks(); err != nil {
+		return nil, err
 	}
 	return bmarks, nil
 }
 
-// getBookmarks returns a users bookmarks.  If the user has no bookmarks,
-// return nil bookmarks
-func (b *Bookmarks) getBookmarks() (*Bookmarks, error) {
+// getBookmarksIndex returns a users bookmarks without loading them.
+// The bucket of a bookmark is loaded when the bookmark is first used by get
+// or exists, so ByID only holds the bookmarks of the buckets used so far
+func (b *Bookmarks) getBookmarksIndex() (*Bookmarks, error) {
 	// if a user not not have bookmarks, bb will be nil
 	bb, appErr := b.api.KVGet(getBookmarksKey(b.userID))
 	if appErr != nil {
 		return nil, errors.Wrapf(appErr, "Unable to get bookmarks for user %s", b.userID)
 	}
 
-	return b.BookmarksFromJSON(bb)
+	return b.loadBookmarksIndex(bb)
 }
 
 // ByPostCreateAt returns an array of bookmarks sorted by post.CreateAt times
 func (b *Bookmarks) ByPostCreateAt() ([]*Bookmark, error) {
-	// build temp map
-	tempMap := make(map[int64]string)
-	for _, bmark := range b.ByID {
-		post, appErr := b.api.GetPost(bmark.PostID)
-		if appErr != nil {
-			return nil, appErr
-		}
-		tempMap[post.CreateAt] = bmark.PostID
-	}
-
-	// sort post.CreateAt (keys)
-	keys := make([]int, 0, len(tempMap))
-	for k := range tempMap {
-		keys = append(keys, int(k))
-	}
-	sort.Ints(keys)
-
-	// reconstruct the bookmarks in a sorted array
-	var bookmarks []*Bookmark
-	for _, k := range keys {
-		bmark := b.ByID[tempMap[int64(k)]]
-		bookmarks = append(bookmarks, bmark)
-	}
-
-	return bookmarks, nil
+	return b.sortBookmarks(bookmarkSortTime)
 }
 
 func (b *Bookmarks) getBookmarksWithLabelID(labelID string) (*Bookmarks, error) {
@@ -118,9 +147,8 @@ func (b *Bookmarks) getBookmarksWithLabelID(labelID string) (*Bookmarks, error)
 		if bmark.hasLabels() {
 			for _, id := range bmark.getLabelIDs() {
 				if labelID == id {
-					if err := bmarksWithLabel.add(bmark); err != nil {
-						return nil, err
-					}
+					// Do not save the bookmarks to the store. only hold in data structure
+					bmarksWithLabel.ByID[bmark.PostID] = bmark
 				}
 			}
 		}
@@ -129,7 +157,7 @@ func (b *Bookmarks) getBookmarksWithLabelID(labelID string) (*Bookmarks, error)
 	return bmarksWithLabel, nil
 }
 
-// deleteBookmark deletes a bookmark from the store
+// deleteBookmark deletes a bookmark from the store and moves it to the trash
 func (b *Bookmarks) deleteBookmark(bmarkID string) (*Bookmark, error) {
 	var bmark *Bookmark
 
@@ -143,8 +171,23 @@ func (b *Bookmarks) deleteBookmark(bmarkID string) (*Bookmark, error) {
 		return nil, err
 	}
 
+	// the bookmark is in the trash before it is removed, so a failure never
+	// loses it
+	trash, err := NewTrashWithUser(b.api, b.userID).getTrash()
+	if err != nil {
+		return nil, err
+	}
+	bmark.recordEvent(&BookmarkEvent{Type: eventRemoved})
+	if err = trash.addBookmark(bmark); err != nil {
+		return nil, err
+	}
+
 	b.delete(bmarkID)
-	if err := b.storeBookmarks(); err != nil {
+	if err = b.storeBookmarks(); err != nil {
+		b.ByID[bmarkID] = bmark
+		if _, trashErr := trash.removeBookmarks([]string{bmarkID}); trashErr != nil {
+			b.api.LogWarn("Unable to take bookmark out of the trash", "error", trashErr.Error())
+		}
 		return nil, err
 	}
 
@@ -168,7 +211,7 @@ func (b *Bookmarks) deleteLabel(bmarkID string, labelID string) error {
 		newLabels = append(newLabels, ID)
 	}
 
-	bmark.addLabelIDs(newLabels)
+	bmark.updateLabelIDs(newLabels)
 
 	if err := b.add(bmark); err != nil {
 		return err
@@ -194,6 +237,31 @@ func (b *Bookmarks) getBmarkLabelNames(bmark *Bookmark) ([]string, error) {
 	}
 	return labelNames, nil
 }
+
+// updateBookmark changes a users bookmark with update, stores it and returns
+// the bookmark
+func (p *Plugin) updateBookmark(userID, bmarkID string, update func(*Bookmark)) (*Bookmark, error) {
+	bmarks, err := NewBookmarksWithUser(p.API, userID).getBookmarksIndex()
+	if err != nil {
+		return nil, err
+	}
+	if bmarks == nil {
+		return nil, errors.New("You do not have any saved bookmarks")
+	}
+
+	bmark, err := bmarks.getBookmark(bmarkID)
+	if err != nil {
+		return nil, err
+	}
+
+	update(bmark)
+	bmark.ModifiedAt = model.GetMillis()
+	if err = bmarks.add(bmark); err != nil {
+		return nil, err
+	}
+	return bmark, nil
+}
+
 func getBookmarksKey(userID string) string {
 	return fmt.Sprintf("%s_%s", StoreBookmarksKey, userID)
 }
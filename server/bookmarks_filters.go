@@ -1,15 +1,98 @@
 package main
 
 import (
+	"fmt"
 	"regexp"
+	"strings"
+	"time"
 
 	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/pkg/errors"
+)
+
+const (
+	queryAll          = "all"
+	queryLabelPrefix  = "label:"
+	queryTitlePrefix  = "title:"
+	queryStatusPrefix = "status:"
+	queryDuePrefix    = "due:"
 )
 
 type BookmarksFilters struct {
 	TitleText  string
 	LabelIDs   []string
 	LabelNames []string
+	Statuses   []string
+	Due        string    // overdue, today or this-week
+	Now        time.Time // The current time in the users timezone, used by the due filter
+}
+
+// parseBookmarksQuery returns the filters for a bookmarks query.  A query is
+// all, or a list of label:<labels>, status:<statuses>, due:<filter>, overdue
+// and title:<regexp> terms.  Other words are matched literally against
+// bookmark titles.  Only titles given by users are matched, not the titles
+// generated from posts, channels and files
+func parseBookmarksQuery(terms []string) (*BookmarksFilters, error) {
+	if len(terms) == 0 {
+		return nil, errors.New("Please specify a query, e.g. `all`, `label:<labels>` or `title:<text>`")
+	}
+
+	filters := &BookmarksFilters{}
+	if len(terms) == 1 && terms[0] == queryAll {
+		return filters, nil
+	}
+
+	var words []string
+	for _, term := range terms {
+		switch {
+		case strings.HasPrefix(term, queryLabelPrefix):
+			names := strings.TrimPrefix(term, queryLabelPrefix)
+			if names == "" {
+				return nil, errors.New("Please specify label names after `label:`")
+			}
+			for _, name := range strings.Split(names, ",") {
+				if name != "" {
+					filters.LabelNames = append(filters.LabelNames, name)
+				}
+			}
+		case strings.HasPrefix(term, queryStatusPrefix):
+			names := strings.TrimPrefix(term, queryStatusPrefix)
+			if names == "" {
+				return nil, errors.New("Please specify statuses after `status:`")
+			}
+			statuses, err := parseStatuses(strings.Split(names, ","))
+			if err != nil {
+				return nil, err
+			}
+			filters.Statuses = append(filters.Statuses, statuses...)
+		case term == dueFilterOverdue || strings.HasPrefix(term, queryDuePrefix):
+			if filters.Due != "" {
+				return nil, errors.New("Only one `due:` term is allowed in a query")
+			}
+			filters.Due = strings.TrimPrefix(term, queryDuePrefix)
+			if err := validateDueFilter(filters.Due); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(term, queryTitlePrefix):
+			if filters.TitleText != "" {
+				return nil, errors.New("Only one `title:` term is allowed in a query")
+			}
+			filters.TitleText = strings.TrimPrefix(term, queryTitlePrefix)
+			if _, err := regexp.Compile(filters.TitleText); err != nil {
+				return nil, errors.New(fmt.Sprintf("Invalid title expression `%s`", filters.TitleText))
+			}
+		default:
+			words = append(words, term)
+		}
+	}
+
+	if len(words) != 0 {
+		if filters.TitleText != "" {
+			return nil, errors.New("Use either a `title:` term or plain words in a query, not both")
+		}
+		filters.TitleText = regexp.QuoteMeta(strings.Join(words, " "))
+	}
+	return filters, nil
 }
 
 // applyFilters will apply the available filters to an object of bookmarks
@@ -20,6 +103,8 @@ func (b *Bookmarks) applyFilters(filters *BookmarksFilters) (*Bookmarks, error)
 		filteredBmark := bmark.withLabelIDs(filters.LabelIDs)
 		filteredBmark = filteredBmark.withLabelNames(filters.LabelNames, b.api, b.userID)
 		filteredBmark = filteredBmark.withTitleText(filters.TitleText)
+		filteredBmark = filteredBmark.withStatuses(filters.Statuses)
+		filteredBmark = filteredBmark.withDue(filters.Due, filters.Now)
 
 		if filteredBmark != nil {
 			// Do not save the bookmarks to the store. only hold in data structure
@@ -63,21 +148,30 @@ func (bm *Bookmark) withLabelNames(names []string, api plugin.API, userID string
 	// 	return p.responsef(args, err.Error())
 	// }
 
-	// iter through bmark label ids
+	// a requested label matches the label and all labels nested under it
+	matchIDs := make(map[string]bool)
+	for _, name := range names {
+		id, err := labels.getIDFromName(name)
+		if err != nil {
+			continue
+		}
+		for _, descendantID := range labels.getDescendantIDs(id) {
+			matchIDs[descendantID] = true
+		}
+	}
+
+	// return bookmark if has a requested label
 	for _, labelID := range bm.getLabelIDs() {
-		// iter through requested label names
-		for _, name := range names {
-			// return bookmark if has requested label name
-			n, _ := labels.getNameFromID(labelID)
-			if n == name {
-				return bm
-			}
+		if matchIDs[labelID] {
+			return bm
 		}
 	}
 	return nil
 }
 
-// withTitleText returns a bookmark with given title text or nil
+// withTitleText returns a bookmark with given title text or nil.  Titles
+// generated from posts, channels and files are not matched, they would be
+// looked up for every bookmark
 func (bm *Bookmark) withTitleText(text string) *Bookmark {
 	// return bookmark if empty text is empty or bmark is nil
 	if text == "" || bm == nil {
@@ -93,3 +187,45 @@ func (bm *Bookmark) withTitleText(text string) *Bookmark {
 
 	return nil
 }
+
+// withStatuses returns a bookmark with one of the given statuses or nil
+func (bm *Bookmark) withStatuses(statuses []string) *Bookmark {
+	// return bookmark if no statuses requested or bmark is nil
+	if len(statuses) == 0 || bm == nil {
+		return bm
+	}
+
+	if containsID(statuses, bm.getStatus()) {
+		return bm
+	}
+	return nil
+}
+
+// withDue returns a bookmark that is overdue, due today or due this week, or
+// nil
+func (bm *Bookmark) withDue(filter string, now time.Time) *Bookmark {
+	// return bookmark if no due filter requested or bmark is nil
+	if filter == "" || bm == nil {
+		return bm
+	}
+
+	if now.IsZero() {
+		now = time.Now().UTC()
+	}
+
+	switch filter {
+	case dueFilterOverdue:
+		if bm.isOverdue(now) {
+			return bm
+		}
+	case dueFilterToday:
+		if bm.Due == now.Format(dueDateFormat) {
+			return bm
+		}
+	case dueFilterThisWeek:
+		if bm.isDueThisWeek(now) {
+			return bm
+		}
+	}
+	return nil
+}
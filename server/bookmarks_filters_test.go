@@ -1,7 +1,9 @@
 package main
 
 import (
+	"encoding/json"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -10,6 +12,7 @@ import (
 
 func TestApplyFilters(t *testing.T) {
 	api := makeAPIMock()
+	api.On("KVGet", mock.Anything).Return(nil, nil)
 	api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
 	p := makePlugin(api)
 
@@ -159,3 +162,81 @@ func TestApplyFilters(t *testing.T) {
 		})
 	}
 }
+
+func TestWithLabelNamesNested(t *testing.T) {
+	api := makeAPIMock()
+	jsonLabels, err := json.Marshal(getExecuteCommandTestNestedLabels())
+	assert.Nil(t, err)
+	api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+
+	tests := map[string]struct {
+		labelIDs []string
+		names    []string
+		expected bool
+	}{
+		"no names requested":             {labelIDs: []string{"UUID6"}, names: nil, expected: true},
+		"exact nested label":             {labelIDs: []string{"UUID6"}, names: []string{"project/alpha/bugs"}, expected: true},
+		"parent matches child":           {labelIDs: []string{"UUID6"}, names: []string{"project/alpha"}, expected: true},
+		"ancestor matches grandchild":    {labelIDs: []string{"UUID7"}, names: []string{"project"}, expected: true},
+		"child does not match parent":    {labelIDs: []string{"UUID5"}, names: []string{"project/alpha/bugs"}, expected: false},
+		"sibling does not match":         {labelIDs: []string{"UUID8"}, names: []string{"project/alpha"}, expected: false},
+		"name prefix is not a parent":    {labelIDs: []string{"UUID1"}, names: []string{"label"}, expected: false},
+		"unknown name does not match":    {labelIDs: []string{"UUID6"}, names: []string{"doesnotexist"}, expected: false},
+		"one of several names matches":   {labelIDs: []string{"UUID8"}, names: []string{"label2", "project"}, expected: true},
+		"bookmark without labels":        {labelIDs: nil, names: []string{"project"}, expected: false},
+		"top-level label matches itself": {labelIDs: []string{"UUID2"}, names: []string{"label2"}, expected: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			bmark := &Bookmark{PostID: "postID1", LabelIDs: tt.labelIDs}
+			filtered := bmark.withLabelNames(tt.names, api, UserID)
+			assert.Equal(t, tt.expected, filtered != nil)
+		})
+	}
+}
+
+func TestParseBookmarksQuery(t *testing.T) {
+	tests := map[string]struct {
+		query       string
+		expected    *BookmarksFilters
+		expectedErr bool
+	}{
+		"empty query":            {query: "", expectedErr: true},
+		"all bookmarks":          {query: "all", expected: &BookmarksFilters{}},
+		"labels":                 {query: "label:label1,label2 label:label3", expected: &BookmarksFilters{LabelNames: []string{"label1", "label2", "label3"}}},
+		"empty label term":       {query: "label:", expectedErr: true},
+		"title expression":       {query: "title:^Title[12]", expected: &BookmarksFilters{TitleText: "^Title[12]"}},
+		"invalid title":          {query: "title:(", expectedErr: true},
+		"two title terms":        {query: "title:a title:b", expectedErr: true},
+		"plain words are quoted": {query: "Title2 - (draft)", expected: &BookmarksFilters{TitleText: `Title2 - \(draft\)`}},
+		"title term and words":   {query: "title:a words", expectedErr: true},
+		"labels and words":       {query: "label:label1 some words", expected: &BookmarksFilters{LabelNames: []string{"label1"}, TitleText: "some words"}},
+		"all with other terms":   {query: "all label:label1", expected: &BookmarksFilters{LabelNames: []string{"label1"}, TitleText: "all"}},
+		"statuses":               {query: "status:unread,in-progress", expected: &BookmarksFilters{Statuses: []string{statusUnread, statusInProgress}}},
+		"all statuses":           {query: "status:all", expected: &BookmarksFilters{Statuses: bookmarkStatuses}},
+		"invalid status":         {query: "status:later", expectedErr: true},
+		"overdue":                {query: "overdue label:label1", expected: &BookmarksFilters{Due: dueFilterOverdue, LabelNames: []string{"label1"}}},
+		"due this week":          {query: "due:this-week", expected: &BookmarksFilters{Due: dueFilterThisWeek}},
+		"invalid due filter":     {query: "due:later", expectedErr: true},
+		"two due terms":          {query: "overdue due:today", expectedErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			filters, err := parseBookmarksQuery(strings.Fields(tt.query))
+			if tt.expectedErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, filters)
+		})
+	}
+}
+
+func TestWithTitleTextOnlyMatchesUserTitles(t *testing.T) {
+	titled := &Bookmark{PostID: p1ID, Title: "Release notes"}
+	untitled := &Bookmark{PostID: p2ID}
+
+	assert.Equal(t, titled, titled.withTitleText("^Release"))
+	assert.Nil(t, untitled.withTitleText("Release"))
+}
@@ -9,7 +9,7 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
-//nolint
+// nolint
 func makePlugin(api *plugintest.API) *Plugin {
 	p := &Plugin{}
 	p.SetAPI(api)
@@ -18,6 +18,7 @@ func makePlugin(api *plugintest.API) *Plugin {
 
 func TestStoreBookmarks(t *testing.T) {
 	api := makeAPIMock()
+	api.On("KVGet", "bookmarks_userID1").Return(nil, nil)
 	api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
 	p := makePlugin(api)
 
@@ -25,8 +26,8 @@ func TestStoreBookmarks(t *testing.T) {
 	u1 := "userID1"
 	// u2 := "userID2"
 
-	b1 := &Bookmark{PostID: "ID1", Title: "Title1"}
-	b2 := &Bookmark{PostID: "ID2", Title: "Title2"}
+	b1 := &Bookmark{PostID: "ID1xxxxxxxxxxxxxxxxxxxxxxx", Title: "Title1"}
+	b2 := &Bookmark{PostID: "ID2xxxxxxxxxxxxxxxxxxxxxxx", Title: "Title2"}
 
 	// Add Bookmarks
 	bmarks := NewBookmarksWithUser(p.API, u1)
@@ -50,13 +51,13 @@ func TestAddBookmark(t *testing.T) {
 	p := makePlugin(api)
 
 	// create some test bookmarks
-	b1 := &Bookmark{PostID: "ID1", Title: "Title1"}
+	b1 := &Bookmark{PostID: "ID1xxxxxxxxxxxxxxxxxxxxxxx", Title: "Title1"}
 	b2 := &Bookmark{
-		PostID:   "ID2",
+		PostID:   "ID2xxxxxxxxxxxxxxxxxxxxxxx",
 		Title:    "Title2",
 		LabelIDs: []string{"UUID1", "UUID2"},
 	}
-	b3 := &Bookmark{PostID: "ID3", Title: "Title3"}
+	b3 := &Bookmark{PostID: "ID3xxxxxxxxxxxxxxxxxxxxxxx", Title: "Title3"}
 
 	// User 1 has no bookmarks
 	u1 := "userID1"
@@ -66,6 +67,7 @@ func TestAddBookmark(t *testing.T) {
 	// User 2 has 2 existing bookmarks
 	u2 := "userID2"
 	bmarksU2 := NewBookmarksWithUser(p.API, u2)
+	api.On("KVGet", getBookmarksKey(u2)).Return(nil, nil).Twice()
 	err := bmarksU2.add(b1)
 	assert.Nil(t, err)
 	err = bmarksU2.add(b2)
@@ -101,6 +103,7 @@ func TestAddBookmark(t *testing.T) {
 			key := getBookmarksKey(tt.userID)
 			api.On("KVSet", key, mock.Anything).Return(nil)
 			api.On("KVGet", key).Return(jsonBookmarks, nil)
+			api.On("KVGet", getTrashKey(tt.userID)).Return(nil, nil)
 
 			// store bmarks using API
 			// bmarks, err := p.addBookmark(tt.userID, b3)
@@ -114,12 +117,13 @@ func TestAddBookmark(t *testing.T) {
 func TestDeleteBookmark(t *testing.T) {
 	api := makeAPIMock()
 	api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+	api.On("KVDelete", mock.Anything).Return(nil)
 	p := makePlugin(api)
 
 	// create some test bookmarks
-	b1 := &Bookmark{PostID: "ID1", Title: "Title1"}
+	b1 := &Bookmark{PostID: "ID1xxxxxxxxxxxxxxxxxxxxxxx", Title: "Title1"}
 	b2 := &Bookmark{
-		PostID:   "ID2",
+		PostID:   "ID2xxxxxxxxxxxxxxxxxxxxxxx",
 		Title:    "Title2",
 		LabelIDs: []string{"UUID1", "UUID2"},
 	}
@@ -131,6 +135,7 @@ func TestDeleteBookmark(t *testing.T) {
 	// User 2 has 2 existing bookmarks
 	u2 := "userID2"
 	bmarksU2 := NewBookmarksWithUser(p.API, u2)
+	api.On("KVGet", getBookmarksKey(u2)).Return(nil, nil).Twice()
 	err := bmarksU2.add(b1)
 	assert.Nil(t, err)
 	err = bmarksU2.add(b2)
@@ -148,14 +153,14 @@ func TestDeleteBookmark(t *testing.T) {
 			userID:     u1,
 			bmarks:     bmarksU1,
 			wantErr:    true,
-			wantErrMsg: "Bookmark `ID2` does not exist",
+			wantErrMsg: "Bookmark `ID2xxxxxxxxxxxxxxxxxxxxxxx` does not exist",
 		},
 		{
 			name:       "u2 two previous bookmarks  delete one bookmark",
 			userID:     u2,
 			bmarks:     bmarksU2,
 			wantErr:    false,
-			wantErrMsg: "Bookmark `ID2` does not exist",
+			wantErrMsg: "Bookmark `ID2xxxxxxxxxxxxxxxxxxxxxxx` does not exist",
 		},
 	}
 	for _, tt := range tests {
@@ -166,6 +171,7 @@ func TestDeleteBookmark(t *testing.T) {
 			key := getBookmarksKey(tt.userID)
 			api.On("KVSet", key, mock.Anything).Return(nil)
 			api.On("KVGet", key).Return(jsonBookmarks, nil)
+			api.On("KVGet", getTrashKey(tt.userID)).Return(nil, nil)
 
 			// store bmarks using API
 			_, err = tt.bmarks.deleteBookmark(b2.PostID)
@@ -8,6 +8,12 @@ import (
 
 // PostBotDM posts a DM as the Bot user
 func (p *Plugin) PostBotDM(userID string, message string) error {
+	return p.postBotDMWithAttachments(userID, message, nil)
+}
+
+// postBotDMWithAttachments posts a DM with interactive attachments as the Bot
+// user
+func (p *Plugin) postBotDMWithAttachments(userID string, message string, attachments []*model.SlackAttachment) error {
 	channel, appError := p.API.GetDirectChannel(userID, p.BotUserID)
 	if appError != nil {
 		return appError
@@ -16,13 +22,19 @@ func (p *Plugin) PostBotDM(userID string, message string) error {
 		return fmt.Errorf("could not get direct channel for bot and user_id=%s", userID)
 	}
 
-	_, appError = p.API.CreatePost(&model.Post{
+	post := &model.Post{
 		UserId:    p.BotUserID,
 		ChannelId: channel.Id,
 		Message:   message,
-	})
+	}
+	if len(attachments) != 0 {
+		model.ParseSlackAttachment(post, attachments)
+	}
+	if _, appError = p.API.CreatePost(post); appError != nil {
+		return appError
+	}
 
-	return appError
+	return nil
 }
 
 func (p *Plugin) getBotID() string {
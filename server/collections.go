@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// StoreCollectionsKey is the key used to store the collections of a team
+	// in the plugin KV store
+	StoreCollectionsKey = "collections"
+
+	maxCollectionNameLength = 64
+)
+
+// collectionNameRegexp matches collection names, which are single command
+// arguments
+var collectionNameRegexp = regexp.MustCompile(`^[\p{L}\p{N}_.\-]+$`)
+
+// getCollections returns the collections shared in a team
+func (c *Collections) getCollections() (*Collections, error) {
+	// if a team does not have collections, bb will be nil
+	bb, appErr := c.api.KVGet(getCollectionsKey(c.teamID))
+	if appErr != nil {
+		return nil, errors.Wrapf(appErr, "Unable to get collections for team %s", c.teamID)
+	}
+
+	if bb == nil {
+		return c, nil
+	}
+
+	jsonErr := json.Unmarshal(bb, c)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+
+	return c, nil
+}
+
+func getCollectionsKey(teamID string) string {
+	return fmt.Sprintf("%s_%s", StoreCollectionsKey, teamID)
+}
+
+// getByName returns the collection with a name, ignoring case
+func (c *Collections) getByName(name string) *Collection {
+	for _, collection := range c.ByID {
+		if strings.EqualFold(collection.Name, name) {
+			return collection
+		}
+	}
+	return nil
+}
+
+// getForMember returns the collections a user is a member of, sorted by name
+func (c *Collections) getForMember(userID string) []*Collection {
+	var collections []*Collection
+	for _, collection := range c.ByID {
+		if collection.isMember(userID) {
+			collections = append(collections, collection)
+		}
+	}
+	sort.Slice(collections, func(i, j int) bool {
+		return strings.ToLower(collections[i].Name) < strings.ToLower(collections[j].Name)
+	})
+	return collections
+}
+
+// validateCollectionName checks that a collection name can be used as a
+// single command argument
+func validateCollectionName(name string) error {
+	if name == "" {
+		return errors.New("Collection name cannot be empty")
+	}
+	if utf8.RuneCountInString(name) > maxCollectionNameLength {
+		return errors.New(fmt.Sprintf("Collection name `%s` is too long. Collection names can have at most %v characters", name, maxCollectionNameLength))
+	}
+	if !collectionNameRegexp.MatchString(name) {
+		return errors.New(fmt.Sprintf("Collection name `%s` is not valid. Collection names can only contain letters, numbers, `-`, `_` and `.`", name))
+	}
+	return nil
+}
+
+// newCollection returns a collection owned by its creator
+func newCollection(teamID, name, creatorID string) *Collection {
+	return &Collection{
+		ID:        NewID(),
+		Name:      name,
+		TeamID:    teamID,
+		OwnerIDs:  []string{creatorID},
+		MemberIDs: []string{creatorID},
+		Entries:   make(map[string]*CollectionEntry),
+		CreateAt:  model.GetMillis(),
+		CreatorID: creatorID,
+	}
+}
+
+func (c *Collection) isMember(userID string) bool {
+	return containsID(c.MemberIDs, userID)
+}
+
+func (c *Collection) isOwner(userID string) bool {
+	return containsID(c.OwnerIDs, userID)
+}
+
+// addMember adds a user to the collection, optionally as an owner.  It
+// returns false if nothing changed
+func (c *Collection) addMember(userID string, owner bool) bool {
+	changed := false
+	if !c.isMember(userID) {
+		c.MemberIDs = append(c.MemberIDs, userID)
+		changed = true
+	}
+	if owner && !c.isOwner(userID) {
+		c.OwnerIDs = append(c.OwnerIDs, userID)
+		changed = true
+	}
+	return changed
+}
+
+// removeMember removes a user and their ownership from the collection
+func (c *Collection) removeMember(userID string) {
+	c.MemberIDs = subtractIDs(c.MemberIDs, []string{userID})
+	c.OwnerIDs = subtractIDs(c.OwnerIDs, []string{userID})
+}
+
+// addEntry adds a post to the collection.  It returns false if the post is
+// already in the collection
+func (c *Collection) addEntry(postID, title, userID string) bool {
+	if c.Entries == nil {
+		c.Entries = make(map[string]*CollectionEntry)
+	}
+	if _, ok := c.Entries[postID]; ok {
+		return false
+	}
+	c.Entries[postID] = &CollectionEntry{
+		PostID:   postID,
+		Title:    title,
+		AddedBy:  userID,
+		CreateAt: model.GetMillis(),
+	}
+	return true
+}
+
+// sortedEntries returns the entries of the collection, oldest first
+func (c *Collection) sortedEntries() []*CollectionEntry {
+	return sortEntries(c.Entries)
+}
+
+// sortEntries returns bookmark entries keyed by PostID, oldest first
+func sortEntries(byPostID map[string]*CollectionEntry) []*CollectionEntry {
+	var entries []*CollectionEntry
+	for _, entry := range byPostID {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].CreateAt != entries[j].CreateAt {
+			return entries[i].CreateAt < entries[j].CreateAt
+		}
+		return entries[i].PostID < entries[j].PostID
+	})
+	return entries
+}
+
+// canReadPost returns whether a user can see a post.  Collections are shared
+// with members who may not have access to every channel, so entries are
+// checked for each viewer
+func (p *Plugin) canReadPost(userID string, post *model.Post) bool {
+	return p.API.HasPermissionToChannel(userID, post.ChannelId, model.PERMISSION_READ_CHANNEL)
+}
+
+func containsID(ids []string, id string) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
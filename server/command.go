@@ -15,31 +15,123 @@ const (
 **/bookmarks add**
 * |/bookmarks add <post_id> <bookmark_title> --labels <label1,label2>| - add a bookmark by specifying a post_id (with optional title)
 * |/bookmarks add <permalink> <bookmark_title> --labels <label1,label2>| - add a bookmark by specifying the post permalink (with optional title)
+* |/bookmarks add <post_id> --thread| - add a bookmark of the thread of a post
+* |/bookmarks add <post_id> --thread --notify| - add a bookmark of the thread of a post and get notified of new replies
+* |/bookmarks add <~channel> <bookmark_title>| - add a bookmark of a channel
+* |/bookmarks add <post_id> <permalink> ... --labels <label1,label2>| - add bookmarks of several posts at once
+* |/bookmarks add --channel <~channel> --since <1d> --from <@user> --labels <label1,label2>| - add bookmarks of the posts of a channel (the current channel by default) posted since a time ago, optionally only by one user
+* |/bookmarks add-url <url> <bookmark_title> --labels <label1,label2>| - add a bookmark of an external URL
+* |/bookmarks add-file <file_id> <bookmark_title> --labels <label1,label2>| - add a bookmark of a file attached to a post
 `
 	labelCommandText = `
 **/bookmarks label**
 * |/bookmarks label <post_id> --labels <labels>| - add labels (comma-separated) to a bookmark
-* |/bookmarks label add <labels> | - create a new label
-* |/bookmarks label rename <old> <new>| - rename a label
+* |/bookmarks label add <labels> | - create a new label. Use |/| to nest labels, e.g. |project/alpha/bugs|
+* |/bookmarks label rename <old> <new>| - rename a label and the labels nested under it
+* |/bookmarks label move <label> <parent>| - move a label and the labels nested under it to a new parent label, or to the top level with |/|
+* |/bookmarks label apply <labels> --to <query>| - show how many bookmarks matching a query would get the labels
+* |/bookmarks label apply <labels> --to <query> --force| - add labels to all bookmarks matching a query
+* |/bookmarks label strip <labels> --from <query> --force| - remove labels from all bookmarks matching a query
+* |/bookmarks label merge <from> <into>| - merge one or more labels into another label, replacing them on all bookmarks
 * |/bookmarks label remove <labels> | - remove a label
 * |/bookmarks label remove <labels> --force | - forces removal of labels from bookmarks currently using the label as well as the label list
-* |/bookmarks label view | - list all labels
+* |/bookmarks label view | - list all labels with the number of bookmarks using them and when they were last used
+* |/bookmarks label view --sort <name|count|used>| - list all labels sorted by name, bookmark count or last used time
+* |/bookmarks label dedupe| - list labels that only differ by case or spaces
+* |/bookmarks label dedupe --force| - merge labels that only differ by case or spaces
+* |/bookmarks label prune| - list the labels not used by any bookmarks or labeling rules
+* |/bookmarks label prune --force| - remove the labels not used by any bookmarks or labeling rules
+* |/bookmarks label color <label> <color>| - set the color of a label to a hex color or a named color, or |none| to clear it
+* |/bookmarks label describe <label> <description>| - set the description of a label, or clear it when no description is given
 `
 	viewCommandText = `
 **/bookmarks view**
 * |/bookmarks view| - view all saved bookmarks
 * |/bookmarks view <post_id> OR <permalink>| - view detailed bookmark view
+* |/bookmarks view <post_id> --history| - view detailed bookmark view with the history of changes
+* |/bookmarks view --sort <time|created|priority|manual>| - view bookmarks sorted by post time, bookmark creation time, priority or the order set with |/bookmarks move|
+* |/bookmarks view --due <overdue|today|this-week>| - view the bookmarks that are overdue, due today or due this week
+* |/bookmarks view --status <status1,status2>| - view the bookmarks with statuses, or |all|. Done and archived bookmarks are hidden by default
 `
 	removeCommandText = `
 **/bookmarks remove**
 * |/bookmarks remove <post_id>| - remove bookmarks by post_id, or permalink
 * |/bookmarks remove <post_id1> <post_id2>| - remove multiple bookmarks by post_id, or permalink
+`
+	undoCommandText = `
+**/bookmarks undo**
+* |/bookmarks undo| - restore the bookmarks or label removed by the most recent remove command
+`
+	trashCommandText = `
+**/bookmarks trash**
+* |/bookmarks trash view| - view removed bookmarks
+* |/bookmarks trash restore <post_id>| - restore removed bookmarks by post_id, or permalink
+* |/bookmarks trash empty| - permanently delete all removed bookmarks
+`
+	ruleCommandText = `
+**/bookmarks rule**
+* |/bookmarks rule add <~channel|@user|#hashtag|keyword> --labels <labels>| - automatically add labels to new bookmarks of posts in a channel, by a user, with a hashtag or containing a keyword
+* |/bookmarks rule list| - list all labeling rules
+* |/bookmarks rule remove <~channel|@user|#hashtag|keyword>| - remove a labeling rule
+`
+	collectionCommandText = `
+**/bookmarks collection**
+* |/bookmarks collection create <name>| - create a collection of bookmarks shared with members of the team
+* |/bookmarks collection add <name> <post_id> <title>| - add a post to a collection by post_id, or permalink
+* |/bookmarks collection view <name>| - view the bookmarks of a collection, or list your collections
+* |/bookmarks collection share <name> @user --owner| - add a member to a collection. Owners can also share the collection
+* |/bookmarks collection leave <name>| - leave a collection. Collections are deleted when their last member leaves
+`
+	channelCommandText = `
+**/bookmarks channel**
+* |/bookmarks channel view| - view the bookmark board of the current channel
+* |/bookmarks channel add <post_id> <title>| - add a post of the channel to its bookmark board by post_id, or permalink
+* |/bookmarks channel remove <post_id>| - remove a post from the channel bookmark board
+* |/bookmarks channel pin| - post the board to the channel and pin it. The post is updated when the board changes
+* |/bookmarks channel unpin| - delete the pinned board post
+`
+	shareCommandText = `
+**/bookmarks share**
+* |/bookmarks share <post_id> @user| - send bookmarks to another user by post_id, or permalink. They can add them to their bookmarks
+* |/bookmarks share --filter-labels <label1,label2> @user| - send the bookmarks with labels to another user
+`
+	quotaCommandText = `
+**/bookmarks quota**
+* |/bookmarks quota| - view how many bookmarks and labels you have and the limits
+* |/bookmarks quota report| - system admins only, list the users near their limits
+`
+	statusCommandText = `
+**/bookmarks status**
+* |/bookmarks done <post_id>| - mark bookmarks as done by post_id, or permalink
+* |/bookmarks status| - view the number of bookmarks of each status
+* |/bookmarks status <post_id> <unread|in-progress|done|archived>| - set the status of a bookmark
+`
+	orderCommandText = `
+**/bookmarks priority and move**
+* |/bookmarks priority <post_id> <high|normal|low>| - set the priority of a bookmark
+* |/bookmarks move <post_id> --top| - move a bookmark to the top of the manual order
+* |/bookmarks move <post_id> --before <post_id>| - move a bookmark before another bookmark in the manual order
+`
+	dueCommandText = `
+**/bookmarks due**
+* |/bookmarks due <post_id> <YYYY-MM-DD|today|tomorrow|weekday>| - set the due date of a bookmark in your timezone. You get a message with the bookmarks due each day
+* |/bookmarks due <post_id> none| - remove the due date of a bookmark
 `
 	helpCommandText = `###### Bookmarks Slash Command Help` +
 		addCommandText +
 		labelCommandText +
 		viewCommandText +
-		removeCommandText
+		removeCommandText +
+		statusCommandText +
+		orderCommandText +
+		dueCommandText +
+		undoCommandText +
+		trashCommandText +
+		ruleCommandText +
+		collectionCommandText +
+		channelCommandText +
+		shareCommandText +
+		quotaCommandText
 )
 
 func getHelp(text string) string {
@@ -53,16 +145,252 @@ func getCommand() *model.Command {
 		Description:      "Manage Mattermost messages!",
 		AutoComplete:     true,
 		AutoCompleteHint: "[command]",
-		AutoCompleteDesc: "Available commands: add, view, remove, label help",
+		AutoCompleteDesc: "Available commands: add, add-url, add-file, view, remove, done, status, priority, move, due, label, undo, trash, rule, collection, channel, share, quota, help",
+		AutocompleteData: getAutocompleteData(),
+	}
+}
+
+// getAutocompleteData returns the autocomplete tree for all /bookmarks
+// sub-commands and flags
+func getAutocompleteData() *model.AutocompleteData {
+	bookmarks := model.NewAutocompleteData(commandTriggerBookmarks, "[command]", "Available commands: add, add-url, add-file, view, remove, done, status, priority, move, due, label, undo, trash, rule, collection, channel, share, quota, help")
+
+	add := model.NewAutocompleteData("add", "<post_id> <bookmark_title> --labels <label1,label2>", "Add a bookmark by post_id, permalink or ~channel")
+	add.AddTextArgument("post_id or permalink of the post to bookmark, or a ~channel", "<post_id>", "")
+	add.AddNamedDynamicListArgument(flagLabel, "Comma-separated list of labels", autocompleteLabelsURL, false)
+	add.AddNamedStaticListArgument(flagThread, "Bookmark the thread of the post", false, []model.AutocompleteListItem{
+		{Item: "true"},
+	})
+	add.AddNamedStaticListArgument(flagNotify, "Get notified of new replies to the thread", false, []model.AutocompleteListItem{
+		{Item: "true"},
+	})
+	add.AddNamedTextArgument(flagChannel, "Bookmark the posts of a channel", "<~channel>", "", false)
+	add.AddNamedTextArgument(flagSince, "Bookmark the posts posted since a time ago", "<30m|12h|1d|2w>", "", false)
+	add.AddNamedTextArgument(flagFrom, "Bookmark the posts of a user", "<@user>", "", false)
+	bookmarks.AddCommand(add)
+
+	addURL := model.NewAutocompleteData("add-url", "<url> <bookmark_title> --labels <label1,label2>", "Add a bookmark of an external URL")
+	addURL.AddTextArgument("URL to bookmark", "<url>", "")
+	addURL.AddNamedDynamicListArgument(flagLabel, "Comma-separated list of labels", autocompleteLabelsURL, false)
+	bookmarks.AddCommand(addURL)
+
+	addFile := model.NewAutocompleteData("add-file", "<file_id> <bookmark_title> --labels <label1,label2>", "Add a bookmark of a file attached to a post")
+	addFile.AddTextArgument("file_id of the file to bookmark", "<file_id>", "")
+	addFile.AddNamedDynamicListArgument(flagLabel, "Comma-separated list of labels", autocompleteLabelsURL, false)
+	bookmarks.AddCommand(addFile)
+
+	view := model.NewAutocompleteData("view", "<post_id> --filter-labels <label1,label2>", "View all bookmarks, or the details of a single bookmark")
+	view.AddDynamicListArgument("post_id of the bookmark to view", autocompleteBookmarksURL, false)
+	view.AddNamedDynamicListArgument(flagFilterLabels, "Only show bookmarks with these labels", autocompleteLabelsURL, false)
+	view.AddNamedStaticListArgument(flagHistory, "Show the history of changes to the bookmark", false, []model.AutocompleteListItem{
+		{Item: "true"},
+	})
+	view.AddNamedStaticListArgument(flagStatus, "Only show bookmarks with these statuses", false, getStatusAutocompleteItems(true))
+	view.AddNamedStaticListArgument(flagDue, "Only show bookmarks that are overdue, due today or due this week", false, []model.AutocompleteListItem{
+		{Item: dueFilterOverdue},
+		{Item: dueFilterToday},
+		{Item: dueFilterThisWeek},
+	})
+	view.AddNamedStaticListArgument(flagSort, "Sort bookmarks by post time, creation time, priority or manual order", false, []model.AutocompleteListItem{
+		{Item: bookmarkSortTime},
+		{Item: bookmarkSortCreated},
+		{Item: bookmarkSortPriority},
+		{Item: bookmarkSortManual},
+	})
+	bookmarks.AddCommand(view)
+
+	remove := model.NewAutocompleteData("remove", "<post_id1> <post_id2>", "Remove bookmarks by post_id or permalink")
+	remove.AddDynamicListArgument("post_id of the bookmark to remove", autocompleteBookmarksURL, true)
+	bookmarks.AddCommand(remove)
+
+	done := model.NewAutocompleteData("done", "<post_id1> <post_id2>", "Mark bookmarks as done by post_id or permalink")
+	done.AddDynamicListArgument("post_id of the bookmark to mark as done", autocompleteBookmarksURL, true)
+	bookmarks.AddCommand(done)
+
+	status := model.NewAutocompleteData("status", "<post_id> <status>", "View the number of bookmarks of each status, or set the status of a bookmark")
+	status.AddDynamicListArgument("post_id of the bookmark", autocompleteBookmarksURL, false)
+	status.AddStaticListArgument("Status of the bookmark", false, getStatusAutocompleteItems(false))
+	bookmarks.AddCommand(status)
+
+	priority := model.NewAutocompleteData("priority", "<post_id> <priority>", "Set the priority of a bookmark")
+	priority.AddDynamicListArgument("post_id of the bookmark", autocompleteBookmarksURL, true)
+	priority.AddStaticListArgument("Priority of the bookmark", true, []model.AutocompleteListItem{
+		{Item: priorityHigh},
+		{Item: priorityNormal},
+		{Item: priorityLow},
+	})
+	bookmarks.AddCommand(priority)
+
+	move := model.NewAutocompleteData("move", "<post_id> --top|--before <post_id>", "Move a bookmark in the manual order")
+	move.AddDynamicListArgument("post_id of the bookmark to move", autocompleteBookmarksURL, true)
+	move.AddNamedStaticListArgument(flagMoveTop, "Move the bookmark to the top", false, []model.AutocompleteListItem{
+		{Item: "true"},
+	})
+	move.AddNamedDynamicListArgument(flagMoveBefore, "Move the bookmark before this bookmark", autocompleteBookmarksURL, false)
+	bookmarks.AddCommand(move)
+
+	due := model.NewAutocompleteData("due", "<post_id> <date>", "Set or remove the due date of a bookmark")
+	due.AddDynamicListArgument("post_id of the bookmark", autocompleteBookmarksURL, true)
+	due.AddTextArgument("Due date as YYYY-MM-DD, today, tomorrow, a weekday or none", "<date>", "")
+	bookmarks.AddCommand(due)
+
+	bookmarks.AddCommand(getLabelAutocompleteData())
+
+	undo := model.NewAutocompleteData("undo", "", "Restore the bookmarks or label removed by the most recent remove command")
+	bookmarks.AddCommand(undo)
+
+	trash := model.NewAutocompleteData("trash", "[command]", "Available commands: view, restore, empty")
+	trash.AddCommand(model.NewAutocompleteData("view", "", "View removed bookmarks"))
+	restore := model.NewAutocompleteData("restore", "<post_id>", "Restore removed bookmarks by post_id or permalink")
+	restore.AddDynamicListArgument("post_id of the removed bookmark to restore", autocompleteTrashURL, true)
+	trash.AddCommand(restore)
+	trash.AddCommand(model.NewAutocompleteData("empty", "", "Permanently delete all removed bookmarks"))
+	bookmarks.AddCommand(trash)
+
+	rule := model.NewAutocompleteData("rule", "[command]", "Available commands: add, list, remove")
+	ruleAdd := model.NewAutocompleteData("add", "<~channel|@user|#hashtag|keyword> --labels <labels>", "Automatically label new bookmarks of matching posts")
+	ruleAdd.AddTextArgument("Channel, user, hashtag or keyword to match", "<~channel|@user|#hashtag|keyword>", "")
+	ruleAdd.AddNamedDynamicListArgument(flagLabel, "Comma-separated list of labels", autocompleteLabelsURL, true)
+	rule.AddCommand(ruleAdd)
+	rule.AddCommand(model.NewAutocompleteData("list", "", "List all labeling rules"))
+	ruleRemove := model.NewAutocompleteData("remove", "<~channel|@user|#hashtag|keyword>", "Remove a labeling rule")
+	ruleRemove.AddDynamicListArgument("Rule to remove", autocompleteRulesURL, true)
+	rule.AddCommand(ruleRemove)
+	bookmarks.AddCommand(rule)
+
+	collection := model.NewAutocompleteData("collection", "[command]", "Available commands: create, add, view, share, leave")
+	collection.AddCommand(model.NewAutocompleteData("create", "<name>", "Create a collection shared with members of the team"))
+	collection.AddCommand(model.NewAutocompleteData("add", "<name> <post_id> <title>", "Add a post to a collection by post_id or permalink"))
+	collection.AddCommand(model.NewAutocompleteData("view", "<name>", "View the bookmarks of a collection, or list your collections"))
+	collectionShare := model.NewAutocompleteData("share", "<name> @user --owner", "Add a member to a collection")
+	collectionShare.AddTextArgument("Collection to share", "<name>", "")
+	collectionShare.AddTextArgument("User to add, followed by --owner to also allow them to share the collection", "@user", "")
+	collection.AddCommand(collectionShare)
+	collection.AddCommand(model.NewAutocompleteData("leave", "<name>", "Leave a collection"))
+	bookmarks.AddCommand(collection)
+
+	channel := model.NewAutocompleteData("channel", "[command]", "Available commands: view, add, remove, pin, unpin")
+	channel.AddCommand(model.NewAutocompleteData("view", "", "View the bookmark board of the current channel"))
+	channel.AddCommand(model.NewAutocompleteData("add", "<post_id> <title>", "Add a post of the channel to its bookmark board"))
+	channel.AddCommand(model.NewAutocompleteData("remove", "<post_id>", "Remove a post from the channel bookmark board"))
+	channel.AddCommand(model.NewAutocompleteData("pin", "", "Post and pin the channel bookmark board"))
+	channel.AddCommand(model.NewAutocompleteData("unpin", "", "Delete the pinned channel bookmark board"))
+	bookmarks.AddCommand(channel)
+
+	share := model.NewAutocompleteData("share", "<post_id> --filter-labels <labels> @user", "Send bookmarks to another user")
+	share.AddDynamicListArgument("post_id of the bookmark to share", autocompleteBookmarksURL, false)
+	share.AddNamedDynamicListArgument(flagFilterLabels, "Share the bookmarks with these labels", autocompleteLabelsURL, false)
+	bookmarks.AddCommand(share)
+
+	quota := model.NewAutocompleteData("quota", "[command]", "View your bookmark and label limits")
+	quota.AddCommand(model.NewAutocompleteData("report", "", "List the users near their limits"))
+	bookmarks.AddCommand(quota)
+
+	help := model.NewAutocompleteData("help", "", "Display usage")
+	bookmarks.AddCommand(help)
+
+	return bookmarks
+}
+
+// getStatusAutocompleteItems returns the bookmark statuses as autocomplete
+// items, optionally including all
+func getStatusAutocompleteItems(withAll bool) []model.AutocompleteListItem {
+	var items []model.AutocompleteListItem
+	for _, status := range bookmarkStatuses {
+		items = append(items, model.AutocompleteListItem{Item: status})
+	}
+	if withAll {
+		items = append(items, model.AutocompleteListItem{Item: statusAll, HelpText: "Bookmarks of every status"})
+	}
+	return items
+}
+
+// getLabelAutocompleteData returns the autocomplete tree for the /bookmarks
+// label sub-commands
+func getLabelAutocompleteData() *model.AutocompleteData {
+	label := model.NewAutocompleteData("label", "[command]", "Available commands: add, apply, strip, rename, move, merge, dedupe, remove, prune, view, color, describe")
+
+	add := model.NewAutocompleteData("add", "<label>", "Create a new label")
+	add.AddTextArgument("Name of the new label", "<label>", "")
+	label.AddCommand(add)
+
+	apply := model.NewAutocompleteData("apply", "<labels> --to <query> --force", "Add labels to all bookmarks matching a query, e.g. all, label:<labels> or title:<text>")
+	apply.AddDynamicListArgument("Labels to add", autocompleteLabelsURL, true)
+	apply.AddTextArgument("Bookmarks to label, e.g. --to all, --to label:<labels> or --to title:<text>. Add --force to apply", "--to <query> --force", "")
+	label.AddCommand(apply)
+
+	strip := model.NewAutocompleteData("strip", "<labels> --from <query> --force", "Remove labels from all bookmarks matching a query, e.g. all, label:<labels> or title:<text>")
+	strip.AddDynamicListArgument("Labels to remove", autocompleteLabelsURL, true)
+	strip.AddTextArgument("Bookmarks to unlabel, e.g. --from all, --from label:<labels> or --from title:<text>. Add --force to apply", "--from <query> --force", "")
+	label.AddCommand(strip)
+
+	rename := model.NewAutocompleteData("rename", "<old> <new>", "Rename a label")
+	rename.AddDynamicListArgument("Label to rename", autocompleteLabelsURL, true)
+	rename.AddTextArgument("New name of the label", "<new>", "")
+	label.AddCommand(rename)
+
+	move := model.NewAutocompleteData("move", "<label> <parent>", "Move a label and the labels nested under it to a new parent label")
+	move.AddDynamicListArgument("Label to move", autocompleteLabelsURL, true)
+	move.AddDynamicListArgument("New parent label, or / for the top level", autocompleteLabelsURL, true)
+	label.AddCommand(move)
+
+	merge := model.NewAutocompleteData("merge", "<from> <into>", "Merge one or more labels into another label")
+	merge.AddDynamicListArgument("Labels to merge, followed by the label to merge them into", autocompleteLabelsURL, true)
+	label.AddCommand(merge)
+
+	remove := model.NewAutocompleteData("remove", "<label> --force", "Remove a label")
+	remove.AddDynamicListArgument("Label to remove", autocompleteLabelsURL, true)
+	remove.AddNamedStaticListArgument(flagForce, "Also remove the label from bookmarks currently using it", false, []model.AutocompleteListItem{
+		{Item: "true"},
+	})
+	label.AddCommand(remove)
+
+	dedupe := model.NewAutocompleteData("dedupe", "--force", "Merge labels that only differ by case or spaces")
+	dedupe.AddNamedStaticListArgument(flagForce, "Merge the labels instead of listing them", false, []model.AutocompleteListItem{
+		{Item: "true"},
+	})
+	label.AddCommand(dedupe)
+
+	prune := model.NewAutocompleteData("prune", "--force", "Remove the labels not used by any bookmarks or labeling rules")
+	prune.AddNamedStaticListArgument(flagForce, "Remove the labels instead of listing them", false, []model.AutocompleteListItem{
+		{Item: "true"},
+	})
+	label.AddCommand(prune)
+
+	view := model.NewAutocompleteData("view", "--sort <name|count|used>", "List all labels")
+	view.AddNamedStaticListArgument(flagSort, "Sort labels by name, bookmark count or last used time", false, []model.AutocompleteListItem{
+		{Item: labelSortName},
+		{Item: labelSortCount},
+		{Item: labelSortUsed},
+	})
+	label.AddCommand(view)
+
+	color := model.NewAutocompleteData("color", "<label> <color>", "Set the color of a label")
+	color.AddDynamicListArgument("Label to color", autocompleteLabelsURL, true)
+	colors := []model.AutocompleteListItem{{Item: labelColorNone, HelpText: "Clear the color"}}
+	for _, name := range getLabelColorNames() {
+		colors = append(colors, model.AutocompleteListItem{Item: name, HelpText: labelColorPalette[name]})
 	}
+	color.AddStaticListArgument("Hex color or named color", true, colors)
+	label.AddCommand(color)
+
+	describe := model.NewAutocompleteData("describe", "<label> <description>", "Set the description of a label")
+	describe.AddDynamicListArgument("Label to describe", autocompleteLabelsURL, true)
+	describe.AddTextArgument("Description of the label", "<description>", "")
+	label.AddCommand(describe)
+
+	return label
 }
 
-func (p *Plugin) postCommandResponse(args *model.CommandArgs, text string) {
+func (p *Plugin) postCommandResponse(args *model.CommandArgs, text string, attachments ...*model.SlackAttachment) {
 	post := &model.Post{
 		UserId:    p.getBotID(),
 		ChannelId: args.ChannelId,
 		Message:   text,
 	}
+	if len(attachments) != 0 {
+		model.ParseSlackAttachment(post, attachments)
+	}
 	_ = p.API.SendEphemeralPost(args.UserId, post)
 }
 
@@ -71,6 +399,13 @@ func (p *Plugin) responsef(commandArgs *model.CommandArgs, format string, args .
 	return &model.CommandResponse{}
 }
 
+// responseWithAttachments posts text with interactive attachments as the
+// command response
+func (p *Plugin) responseWithAttachments(args *model.CommandArgs, text string, attachments []*model.SlackAttachment) *model.CommandResponse {
+	p.postCommandResponse(args, text, attachments...)
+	return &model.CommandResponse{}
+}
+
 func (p *Plugin) executeCommandHelp(args *model.CommandArgs) *model.CommandResponse {
 	return p.responsef(args, getHelp(helpCommandText))
 }
@@ -88,12 +423,40 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 	switch action {
 	case "add":
 		return p.executeCommandAdd(args), nil
+	case "add-url":
+		return p.executeCommandAddURL(args), nil
+	case "add-file":
+		return p.executeCommandAddFile(args), nil
 	case "label":
 		return p.executeCommandLabel(args), nil
 	case "remove":
 		return p.executeCommandRemove(args), nil
 	case "view":
 		return p.executeCommandView(args), nil
+	case "done":
+		return p.executeCommandDone(args), nil
+	case "status":
+		return p.executeCommandStatus(args), nil
+	case "priority":
+		return p.executeCommandPriority(args), nil
+	case "move":
+		return p.executeCommandMove(args), nil
+	case "due":
+		return p.executeCommandDue(args), nil
+	case "undo":
+		return p.executeCommandUndo(args), nil
+	case "trash":
+		return p.executeCommandTrash(args), nil
+	case "rule":
+		return p.executeCommandRule(args), nil
+	case "collection":
+		return p.executeCommandCollection(args), nil
+	case "channel":
+		return p.executeCommandChannel(args), nil
+	case "share":
+		return p.executeCommandShare(args), nil
+	case "quota":
+		return p.executeCommandQuota(args), nil
 	case "help":
 		return p.executeCommandHelp(args), nil
 
@@ -1,23 +1,42 @@
 package main
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 )
 
 const (
-	flagLabel = "labels"
+	flagLabel  = "labels"
+	flagThread = "thread"
+	flagNotify = "notify"
+
+	flagChannel = "channel"
+	flagSince   = "since"
+	flagFrom    = "from"
 )
 
 type addBookmarkOptions struct {
 	labels []string
+	thread bool
+	notify bool
+
+	channel string
+	since   string
+	from    string
 }
 
 func getAddBookmarkFlagSet() *pflag.FlagSet {
 	flagSet := pflag.NewFlagSet("add labels to bookmarks", pflag.ContinueOnError)
 	flagSet.StringSlice(flagLabel, nil, "Add a label to a bookmark")
+	flagSet.Bool(flagThread, false, "Bookmark the thread of a post")
+	flagSet.Bool(flagNotify, false, "Get notified of new replies to a bookmarked thread")
+	flagSet.String(flagChannel, "", "Bookmark the posts of a channel")
+	flagSet.String(flagSince, "", "Bookmark the posts of a channel posted since a time ago, like 12h or 1d")
+	flagSet.String(flagFrom, "", "Bookmark the posts of a channel posted by a user")
 
 	return flagSet
 }
@@ -36,9 +55,43 @@ func parseAddBookmarkArgs(args []string) (addBookmarkOptions, error) {
 		return options, err
 	}
 
+	options.thread, err = addBookmarkFlagSet.GetBool(flagThread)
+	if err != nil {
+		return options, err
+	}
+
+	options.notify, err = addBookmarkFlagSet.GetBool(flagNotify)
+	if err != nil {
+		return options, err
+	}
+
+	options.channel, err = addBookmarkFlagSet.GetString(flagChannel)
+	if err != nil {
+		return options, err
+	}
+
+	options.since, err = addBookmarkFlagSet.GetString(flagSince)
+	if err != nil {
+		return options, err
+	}
+
+	options.from, err = addBookmarkFlagSet.GetString(flagFrom)
+	if err != nil {
+		return options, err
+	}
+
 	return options, nil
 }
 
+// checkNotify returns an error if notifications are asked for a bookmark that
+// is not a thread
+func checkNotify(notify, thread bool) error {
+	if notify && !thread {
+		return errors.New(fmt.Sprintf("`--%s` can only be used with `--%s`", flagNotify, flagThread))
+	}
+	return nil
+}
+
 // executeCommandAdd adds a bookmark to the store
 func (p *Plugin) executeCommandAdd(args *model.CommandArgs) *model.CommandResponse {
 	subCommand := strings.Fields(args.Command)
@@ -47,15 +100,75 @@ func (p *Plugin) executeCommandAdd(args *model.CommandArgs) *model.CommandRespon
 	if len(subCommand) < 1 {
 		return p.responsef(args, "Missing sub-command. You can try %v", getHelp(addCommandText))
 	}
-	postID := p.getPostIDFromLink(subCommand[0])
 
-	_, appErr := p.API.GetPost(postID)
+	options, err := parseAddBookmarkArgs(subCommand)
+	if err != nil {
+		return p.responsef(args, "Unable to parse options, %s", err)
+	}
+
+	// user bookmarks the posts of a channel
+	if options.channel != "" || options.since != "" || options.from != "" {
+		if err = checkNotify(options.notify, options.thread); err != nil {
+			return p.responsef(args, err.Error())
+		}
+		return p.executeCommandAddFromChannel(args, subCommand, options)
+	}
+
+	// user bookmarks several posts
+	if refs := getPostRefArguments(subCommand); len(refs) > 1 {
+		if p.getTitleFromArguments(subCommand[len(refs):]) != "" {
+			return p.responsef(args, "Titles can only be given when adding a single bookmark")
+		}
+		return p.addBookmarksBatch(args, refs, options)
+	}
+
+	// user bookmarks a channel
+	if strings.HasPrefix(subCommand[0], "~") {
+		if err = checkNotify(options.notify, options.thread); err != nil {
+			return p.responsef(args, err.Error())
+		}
+		name := strings.TrimPrefix(subCommand[0], "~")
+		channel, appErr := p.API.GetChannelByName(args.TeamId, name, false)
+		if appErr != nil || !p.API.HasPermissionToChannel(args.UserId, channel.Id, model.PERMISSION_READ_CHANNEL) {
+			return p.responsef(args, "Channel `%s` does not exist", subCommand[0])
+		}
+		bookmark := newBookmark(bookmarkKindChannel, channel.Id)
+		bookmark.setTitle(p.getTitleFromArguments(subCommand[1:]))
+		return p.saveBookmark(args, bookmark, nil, options.labels)
+	}
+
+	permalink, err := p.parsePostLink(subCommand[0])
+	if err != nil {
+		return p.responsef(args, "%s", err.Error())
+	}
+	// thread links bookmark the thread
+	options.thread = options.thread || permalink.Thread
+
+	if err = checkNotify(options.notify, options.thread); err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	postID := permalink.PostID
+
+	post, appErr := p.API.GetPost(postID)
 	if appErr != nil {
 		return p.responsef(args, "PostID `%s` is not a valid postID", postID)
 	}
 
-	var bookmark Bookmark
-	bookmark.PostID = postID
+	bookmark := newBookmark(bookmarkKindPost, postID)
+
+	// user bookmarks the thread of the post
+	if options.thread {
+		rootID := post.Id
+		if post.RootId != "" {
+			rootID = post.RootId
+		}
+		if post, appErr = p.API.GetPost(rootID); appErr != nil {
+			return p.responsef(args, "PostID `%s` is not a valid postID", rootID)
+		}
+		bookmark = newBookmark(bookmarkKindThread, rootID)
+		bookmark.ThreadNotify = options.notify
+	}
 
 	// user provides a title
 	if len(subCommand) >= 2 {
@@ -63,61 +176,160 @@ func (p *Plugin) executeCommandAdd(args *model.CommandArgs) *model.CommandRespon
 		bookmark.setTitle(title)
 	}
 
+	return p.saveBookmark(args, bookmark, post, options.labels)
+}
+
+// executeCommandAddURL adds a bookmark of an external URL
+func (p *Plugin) executeCommandAddURL(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+	subCommand = subCommand[2:]
+
+	if len(subCommand) < 1 {
+		return p.responsef(args, "Please specify a URL to bookmark%v", getHelp(addCommandText))
+	}
+	if err := validateBookmarkURL(subCommand[0]); err != nil {
+		return p.responsef(args, err.Error())
+	}
+
 	options, err := parseAddBookmarkArgs(subCommand)
 	if err != nil {
 		return p.responsef(args, "Unable to parse options, %s", err)
 	}
 
-	// var labels *Labels
-	var labelIDsForBookmark []string
+	bookmark := newBookmark(bookmarkKindURL, subCommand[0])
+	bookmark.setTitle(p.getTitleFromArguments(subCommand[1:]))
+	return p.saveBookmark(args, bookmark, nil, options.labels)
+}
 
-	// user going to add labels names
-	if len(options.labels) != 0 {
-		labels := NewLabelsWithUser(p.API, args.UserId)
-		labels, err = labels.getLabels()
-		if err != nil {
-			return p.responsef(args, "Unable to get labels for user, %s", err)
-		}
+// executeCommandAddFile adds a bookmark of a file attached to a post
+func (p *Plugin) executeCommandAddFile(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+	subCommand = subCommand[2:]
 
-		for _, name := range options.labels {
-			label := labels.getLabelByName(name)
-			// create new label in labels store and add ID to bookmark
-			if label == nil {
-				_, err = labels.addLabel(name)
-				if err != nil {
-					return p.responsef(args, "Unable to add new label for: %s, err=%s", name, err.Error())
-				}
-			}
-			var labelID string
-			labelID, err = labels.getIDFromName(name)
-			if err != nil {
-				return p.responsef(args, err.Error())
-			}
-			labelIDsForBookmark = append(labelIDsForBookmark, labelID)
-		}
-		bookmark.addLabelIDs(labelIDsForBookmark)
+	if len(subCommand) < 1 {
+		return p.responsef(args, "Please specify a file_id to bookmark%v", getHelp(addCommandText))
+	}
+
+	options, err := parseAddBookmarkArgs(subCommand)
+	if err != nil {
+		return p.responsef(args, "Unable to parse options, %s", err)
 	}
 
-	// get all bookmarks for user
+	bookmark := newBookmark(bookmarkKindFile, subCommand[0])
+	if !p.canAccessBookmark(args.UserId, bookmark) {
+		return p.responsef(args, "FileID `%s` is not a valid fileID", subCommand[0])
+	}
+	bookmark.setTitle(p.getTitleFromArguments(subCommand[1:]))
+	return p.saveBookmark(args, bookmark, nil, options.labels)
+}
+
+// saveBookmark adds labels to a bookmark, creating new labels, stores it and
+// responds with the bookmark.  Labeling rules are applied to new bookmarks of
+// posts
+func (p *Plugin) saveBookmark(args *model.CommandArgs, bookmark *Bookmark, post *model.Post, labelNames []string) *model.CommandResponse {
+	var err error
+
+	// bookmarks are loaded when used
 	b := NewBookmarksWithUser(p.API, args.UserId)
-	bmarks, err := b.getBookmarks()
+	bmarks, err := b.getBookmarksIndex()
 	if err != nil {
 		return p.responsef(args, "Unable to get bookmarks")
 	}
+	if err = p.checkBookmarksLimit(bmarks, []string{bookmark.PostID}); err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	// labels are loaded when the user adds labels
+	var labels *Labels
+	if len(labelNames) != 0 {
+		labels, err = NewLabelsWithUser(p.API, args.UserId).getLabels()
+		if err != nil {
+			return p.responsef(args, "Unable to get labels for user, %s", err)
+		}
+	}
 
-	err = bmarks.addBookmark(&bookmark)
+	labelNames, err = p.prepareBookmark(args.UserId, bmarks, bookmark, post, labels, labelNames)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	err = bmarks.addBookmark(bookmark)
 	if err != nil {
 		return p.responsef(args, "Unable to add bookmark")
 	}
 
-	text, err := p.getBmarkTextOneLine(&bookmark, options.labels)
+	text, err := p.getBmarkTextOneLine(bookmark, labelNames)
 	if err != nil {
 		return p.responsef(args, "Unable to get bookmarks list bookmark")
 	}
 
+	// the user only watches the thread once the bookmark is stored
+	if bookmark.getKind() == bookmarkKindThread {
+		if err = p.watchThread(args.UserId, bookmark); err != nil {
+			p.API.LogWarn("Unable to watch thread", "error", err.Error())
+			text += fmt.Sprintf("Unable to watch thread `%s`\n", bookmark.getTarget())
+		}
+	}
+
 	return p.responsef(args, "Added bookmark: %s", text)
 }
 
+// prepareBookmark checks the title of a bookmark before it is stored, adds
+// the labels named by the user, creating new labels, and counts the replies
+// of threads.  Labeling rules and snapshots only apply to new bookmarks.  It
+// returns the names of the labels added by the user and by rules
+func (p *Plugin) prepareBookmark(userID string, bmarks *Bookmarks, bookmark *Bookmark, post *model.Post, labels *Labels, labelNames []string) ([]string, error) {
+	if err := p.checkTitleLength(bookmark.getTitle()); err != nil {
+		return nil, err
+	}
+
+	if len(labelNames) != 0 {
+		if err := p.checkLabelsLimit(labels, labelNames); err != nil {
+			return nil, err
+		}
+		ids, err := getOrCreateLabelIDs(labels, labelNames)
+		if err != nil {
+			return nil, err
+		}
+		bookmark.addLabelIDs(ids)
+	}
+
+	if bookmark.getKind() == bookmarkKindThread {
+		if err := p.markThreadSeen(bookmark); err != nil {
+			return nil, errors.New(fmt.Sprintf("Unable to get the replies of thread `%s`", bookmark.getTarget()))
+		}
+	}
+
+	if _, ok := bmarks.exists(bookmark.PostID); ok {
+		return labelNames, nil
+	}
+	names := append(append([]string{}, labelNames...), p.applyLabelRulesOrWarn(userID, bookmark, post)...)
+	if post != nil {
+		bookmark.Snapshot = newPostSnapshot(p.API, post)
+	}
+	return names, nil
+}
+
+// getOrCreateLabelIDs returns the IDs of labels by name, creating the labels
+// that do not exist
+func getOrCreateLabelIDs(labels *Labels, names []string) ([]string, error) {
+	var ids []string
+	for _, name := range names {
+		// create new label in labels store
+		if labels.getLabelByName(name) == nil {
+			if _, err := labels.addLabel(name); err != nil {
+				return nil, errors.New(fmt.Sprintf("Unable to add new label for: %s, err=%s", name, err.Error()))
+			}
+		}
+		id, err := labels.getIDFromName(name)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
 func (p *Plugin) getTitleFromArguments(args []string) string {
 	for i, arg := range args {
 		// user also provided a --flag
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+// maxBatchBookmarks is the maximum number of posts bookmarked by one command
+const maxBatchBookmarks = 100
+
+// batchItem is a post to bookmark in a batch
+type batchItem struct {
+	ref  string      // The post ID or link given by the user
+	post *model.Post // The post, when it is already known
+}
+
+// getPostRefArguments returns the leading arguments that are post IDs or
+// links
+func getPostRefArguments(args []string) []string {
+	var refs []string
+	for _, arg := range args {
+		if !isLink(arg) && !model.IsValidId(arg) {
+			break
+		}
+		refs = append(refs, arg)
+	}
+	return refs
+}
+
+// parseSince parses a time ago like 30m, 12h, 1d or 2w
+func parseSince(text string) (time.Duration, error) {
+	units := map[byte]time.Duration{
+		'm': time.Minute,
+		'h': time.Hour,
+		'd': 24 * time.Hour,
+		'w': 7 * 24 * time.Hour,
+	}
+	if len(text) < 2 {
+		return 0, errors.New(fmt.Sprintf("`%s` is not a valid time ago. Use minutes, hours, days or weeks like `12h` or `1d`", text))
+	}
+	unit, ok := units[text[len(text)-1]]
+	count, err := strconv.Atoi(text[:len(text)-1])
+	if !ok || err != nil || count <= 0 {
+		return 0, errors.New(fmt.Sprintf("`%s` is not a valid time ago. Use minutes, hours, days or weeks like `12h` or `1d`", text))
+	}
+	return time.Duration(count) * unit, nil
+}
+
+// executeCommandAddFromChannel bookmarks the posts of a channel posted since
+// a time ago, optionally only the posts of one user
+func (p *Plugin) executeCommandAddFromChannel(args *model.CommandArgs, subCommand []string, options addBookmarkOptions) *model.CommandResponse {
+	if !strings.HasPrefix(subCommand[0], "--") {
+		return p.responsef(args, "Posts cannot be given with `--%s`, `--%s` or `--%s`", flagChannel, flagSince, flagFrom)
+	}
+	if options.since == "" {
+		return p.responsef(args, "Please specify how far back to bookmark posts, like `--%s 1d`", flagSince)
+	}
+	ago, err := parseSince(options.since)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	since := model.GetMillis() - int64(ago/time.Millisecond)
+
+	// posts of the current channel are bookmarked unless a channel is given
+	channelID := args.ChannelId
+	channelName := "this channel"
+	if options.channel != "" {
+		channelName = options.channel
+		channel, appErr := p.API.GetChannelByName(args.TeamId, strings.TrimPrefix(options.channel, "~"), false)
+		if appErr != nil {
+			return p.responsef(args, "Channel `%s` does not exist", options.channel)
+		}
+		channelID = channel.Id
+	}
+	if !p.API.HasPermissionToChannel(args.UserId, channelID, model.PERMISSION_READ_CHANNEL) {
+		return p.responsef(args, "Channel `%s` does not exist", channelName)
+	}
+
+	var fromID string
+	if options.from != "" {
+		user, appErr := p.API.GetUserByUsername(strings.TrimPrefix(options.from, "@"))
+		if appErr != nil {
+			return p.responsef(args, "User `%s` does not exist", options.from)
+		}
+		fromID = user.Id
+	}
+
+	list, appErr := p.API.GetPostsSince(channelID, since)
+	if appErr != nil {
+		return p.responsef(args, "Unable to get the posts of %s", channelName)
+	}
+
+	var posts []*model.Post
+	for _, post := range list.Posts {
+		// posts edited since are returned too
+		if post.CreateAt < since || post.DeleteAt != 0 || post.IsSystemMessage() {
+			continue
+		}
+		if fromID != "" && post.UserId != fromID {
+			continue
+		}
+		posts = append(posts, post)
+	}
+	if len(posts) == 0 {
+		return p.responsef(args, "No posts to bookmark in %s since %s", channelName, options.since)
+	}
+	if len(posts) > maxBatchBookmarks {
+		return p.responsef(args, "Found %v posts, more than the %v that can be bookmarked at once. Try a shorter `--%s` or `--%s`", len(posts), maxBatchBookmarks, flagSince, flagFrom)
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].CreateAt < posts[j].CreateAt
+	})
+
+	items := make([]batchItem, 0, len(posts))
+	for _, post := range posts {
+		items = append(items, batchItem{ref: post.Id, post: post})
+	}
+	return p.saveBookmarksBatch(args, items, options)
+}
+
+// addBookmarksBatch bookmarks posts given by post ID or link
+func (p *Plugin) addBookmarksBatch(args *model.CommandArgs, refs []string, options addBookmarkOptions) *model.CommandResponse {
+	if len(refs) > maxBatchBookmarks {
+		return p.responsef(args, "Only %v posts can be bookmarked at once", maxBatchBookmarks)
+	}
+	items := make([]batchItem, 0, len(refs))
+	for _, ref := range refs {
+		items = append(items, batchItem{ref: ref})
+	}
+	return p.saveBookmarksBatch(args, items, options)
+}
+
+// getBatchBookmark returns a new bookmark of a post in a batch and the
+// bookmarked post
+func (p *Plugin) getBatchBookmark(userID string, item batchItem, options addBookmarkOptions) (*Bookmark, *model.Post, error) {
+	post := item.post
+	thread := options.thread
+	if post == nil {
+		permalink, err := p.parsePostLink(item.ref)
+		if err != nil {
+			return nil, nil, err
+		}
+		thread = thread || permalink.Thread
+
+		var appErr *model.AppError
+		post, appErr = p.API.GetPost(permalink.PostID)
+		if appErr != nil || !p.canReadPost(userID, post) {
+			return nil, nil, errors.New(fmt.Sprintf("PostID `%s` is not a valid postID", permalink.PostID))
+		}
+	}
+
+	if err := checkNotify(options.notify, thread); err != nil {
+		return nil, nil, err
+	}
+	if !thread {
+		return newBookmark(bookmarkKindPost, post.Id), post, nil
+	}
+
+	// user bookmarks the thread of the post
+	if post.RootId != "" {
+		root, appErr := p.API.GetPost(post.RootId)
+		if appErr != nil {
+			return nil, nil, errors.New(fmt.Sprintf("PostID `%s` is not a valid postID", post.RootId))
+		}
+		post = root
+	}
+	bmark := newBookmark(bookmarkKindThread, post.Id)
+	bmark.ThreadNotify = options.notify
+	return bmark, post, nil
+}
+
+// saveBookmarksBatch bookmarks many posts with the same labels, storing the
+// bookmarks at once, and responds with the result for each post.  Posts that
+// are already bookmarked are skipped
+func (p *Plugin) saveBookmarksBatch(args *model.CommandArgs, items []batchItem, options addBookmarkOptions) *model.CommandResponse {
+	bmarks, err := NewBookmarksWithUser(p.API, args.UserId).getBookmarksIndex()
+	if err != nil {
+		return p.responsef(args, "Unable to get bookmarks")
+	}
+
+	var added []*Bookmark
+	var ids []string
+	var failedText string
+	posts := make(map[string]*model.Post)
+	for _, item := range items {
+		bmark, post, err := p.getBatchBookmark(args.UserId, item, options)
+		if err != nil {
+			failedText += fmt.Sprintf("* %s\n", err.Error())
+			continue
+		}
+		if _, ok := bmarks.exists(bmark.PostID); ok || posts[bmark.PostID] != nil {
+			failedText += fmt.Sprintf("* PostID `%s` is already bookmarked\n", bmark.getTarget())
+			continue
+		}
+		posts[bmark.PostID] = post
+		added = append(added, bmark)
+		ids = append(ids, bmark.PostID)
+	}
+	if err = p.checkBookmarksLimit(bmarks, ids); err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	labels, err := NewLabelsWithUser(p.API, args.UserId).getLabels()
+	if err != nil {
+		return p.responsef(args, "Unable to get labels for user, %s", err)
+	}
+	// new labels are created once for all posts
+	if len(added) != 0 {
+		if err = p.checkLabelsLimit(labels, options.labels); err != nil {
+			return p.responsef(args, err.Error())
+		}
+		if _, err = getOrCreateLabelIDs(labels, options.labels); err != nil {
+			return p.responsef(args, err.Error())
+		}
+	}
+
+	// each post is prepared like a single bookmark
+	var addedText string
+	prepared := make([]*Bookmark, 0, len(added))
+	for _, bmark := range added {
+		labelNames, err := p.prepareBookmark(args.UserId, bmarks, bmark, posts[bmark.PostID], labels, options.labels)
+		if err != nil {
+			failedText += fmt.Sprintf("* %s\n", err.Error())
+			continue
+		}
+		prepared = append(prepared, bmark)
+
+		text, err := p.getBmarkTextOneLine(bmark, labelNames)
+		if err != nil {
+			return p.responsef(args, "Unable to get bookmarks list bookmark")
+		}
+		addedText += text
+	}
+	added = prepared
+
+	if len(added) != 0 {
+		if err = bmarks.addBookmarks(added); err != nil {
+			return p.responsef(args, "Unable to add bookmarks")
+		}
+		for _, bmark := range added {
+			if bmark.getKind() != bookmarkKindThread {
+				continue
+			}
+			if err = p.watchThread(args.UserId, bmark); err != nil {
+				p.API.LogWarn("Unable to watch thread", "error", err.Error())
+			}
+		}
+	}
+
+	text := fmt.Sprintf("Added %v bookmarks:\n%s", len(added), addedText)
+	if failedText != "" {
+		text += fmt.Sprintf("\nUnable to add %v posts:\n%s", len(items)-len(added), failedText)
+	}
+	return p.responsef(args, "%s", text)
+}
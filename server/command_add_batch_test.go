@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	batchPostID1 = "b1tch1d1xxxxxxxxxxxxxxxxxa"
+	batchPostID2 = "b1tch1d2xxxxxxxxxxxxxxxxxa"
+	batchPostID3 = "b1tch1d3xxxxxxxxxxxxxxxxxa"
+)
+
+func TestParseSince(t *testing.T) {
+	tests := map[string]struct {
+		text     string
+		expected time.Duration
+		err      bool
+	}{
+		"minutes":  {text: "30m", expected: 30 * time.Minute},
+		"hours":    {text: "12h", expected: 12 * time.Hour},
+		"days":     {text: "1d", expected: 24 * time.Hour},
+		"weeks":    {text: "2w", expected: 14 * 24 * time.Hour},
+		"no count": {text: "d", err: true},
+		"no unit":  {text: "12", err: true},
+		"zero":     {text: "0d", err: true},
+		"negative": {text: "-1d", err: true},
+		"unknown":  {text: "1y", err: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			actual, err := parseSince(tt.text)
+			if tt.err {
+				assert.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestGetPostRefArguments(t *testing.T) {
+	link := "https://myhost.com/myteam/pl/" + batchPostID2
+	assert.Equal(t, []string{batchPostID1, link}, getPostRefArguments([]string{batchPostID1, link, "--labels", "label1"}))
+	assert.Equal(t, []string{batchPostID1}, getPostRefArguments([]string{batchPostID1, "my", "title"}))
+	assert.Nil(t, getPostRefArguments([]string{"~town-square"}))
+}
+
+func TestExecuteCommandAddBatch(t *testing.T) {
+	tests := map[string]struct {
+		command          string
+		expectedPrefix   string
+		expectedContains []string
+		expectedStored   []string
+	}{
+		"several posts with labels": {
+			command:          "/bookmarks add " + batchPostID1 + " https://myhost.com/myteam/pl/" + batchPostID2 + " --labels label1",
+			expectedPrefix:   "Added 2 bookmarks:",
+			expectedContains: []string{"`label1` first post", "`label1` second post"},
+			expectedStored:   []string{p1ID, p2ID, p3ID, p4ID, batchPostID1, batchPostID2},
+		},
+		"several posts with failures": {
+			command:        "/bookmarks add " + batchPostID1 + " " + batchPostID1 + " " + batchPostID3 + " https://chat.example.com/myteam/pl/" + batchPostID2,
+			expectedPrefix: "Added 1 bookmarks:",
+			expectedContains: []string{
+				"Unable to add 3 posts:",
+				"* PostID `" + batchPostID1 + "` is already bookmarked",
+				"* PostID `" + batchPostID3 + "` is not a valid postID",
+				"* Link `https://chat.example.com/myteam/pl/" + batchPostID2 + "` points to another server, `chat.example.com`",
+			},
+			expectedStored: []string{p1ID, p2ID, p3ID, p4ID, batchPostID1},
+		},
+		"threads of posts in the same thread": {
+			command:          "/bookmarks add " + batchPostID1 + " " + batchPostID2 + " --thread",
+			expectedPrefix:   "Added 1 bookmarks:",
+			expectedContains: []string{"[:speech_balloon:](https://myhost.com/_redirect/pl/" + batchPostID1 + ")", "* PostID `" + batchPostID1 + "` is already bookmarked"},
+			expectedStored:   []string{p1ID, p2ID, p3ID, p4ID, getBookmarkID(bookmarkKindThread, batchPostID1)},
+		},
+		"several posts with notify without thread": {
+			command:          "/bookmarks add " + batchPostID1 + " https://myhost.com/myteam/threads/" + batchPostID2 + " --notify",
+			expectedPrefix:   "Added 1 bookmarks:",
+			expectedContains: []string{"[:speech_balloon:](https://myhost.com/_redirect/pl/" + batchPostID1 + ")", "* `--notify` can only be used with `--thread`"},
+			expectedStored:   []string{p1ID, p2ID, p3ID, p4ID, getBookmarkID(bookmarkKindThread, batchPostID1)},
+		},
+		"several posts with a title": {
+			command:        "/bookmarks add " + batchPostID1 + " " + batchPostID2 + " my title",
+			expectedPrefix: "Titles can only be given when adding a single bookmark",
+		},
+		"posts of a channel from a user": {
+			command:          "/bookmarks add --channel ~town-square --since 1d --from @alice --labels label1",
+			expectedPrefix:   "Added 1 bookmarks:",
+			expectedContains: []string{"`label1` first post"},
+			expectedStored:   []string{p1ID, p2ID, p3ID, p4ID, batchPostID1},
+		},
+		"posts of the current channel": {
+			command:          "/bookmarks add --since 1d",
+			expectedPrefix:   "Added 2 bookmarks:",
+			expectedContains: []string{"first post", "second post"},
+			expectedStored:   []string{p1ID, p2ID, p3ID, p4ID, batchPostID1, batchPostID2},
+		},
+		"posts of a channel without since": {
+			command:        "/bookmarks add --channel ~town-square",
+			expectedPrefix: "Please specify how far back to bookmark posts, like `--since 1d`",
+		},
+		"posts of a channel with an invalid since": {
+			command:        "/bookmarks add --channel ~town-square --since yesterday",
+			expectedPrefix: "`yesterday` is not a valid time ago",
+		},
+		"posts of a channel and post ids": {
+			command:        "/bookmarks add " + batchPostID1 + " --since 1d",
+			expectedPrefix: "Posts cannot be given with `--channel`, `--since` or `--from`",
+		},
+		"posts of an unknown user": {
+			command:        "/bookmarks add --since 1d --from @nobody",
+			expectedPrefix: "User `@nobody` does not exist",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := makeAPIMock()
+			siteURL := "https://myhost.com"
+			api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+
+			now := model.GetMillis()
+			post1 := &model.Post{Id: batchPostID1, UserId: "AliceID", ChannelId: "ChannelID", Message: "first post", CreateAt: now - 2000}
+			post2 := &model.Post{Id: batchPostID2, UserId: "BobID", ChannelId: "ChannelID", Message: "second post", CreateAt: now - 1000, RootId: batchPostID1}
+			api.On("GetPost", batchPostID1).Return(post1, nil)
+			api.On("GetPost", batchPostID2).Return(post2, nil)
+			api.On("GetPost", batchPostID3).Return(nil, &model.AppError{Message: "not found"})
+			api.On("GetPostThread", batchPostID1).Return(getTestThread(batchPostID1, 1), nil)
+			api.On("KVGet", getThreadWatchersKey(batchPostID1)).Return(nil, nil)
+			api.On("KVSet", getThreadWatchersKey(batchPostID1), mock.Anything).Return(nil)
+
+			list := model.NewPostList()
+			list.AddPost(post1)
+			list.AddPost(post2)
+			list.AddPost(&model.Post{Id: "SystemID", ChannelId: "ChannelID", Type: model.POST_JOIN_CHANNEL, CreateAt: now})
+			list.AddPost(&model.Post{Id: "EditedID", ChannelId: "ChannelID", CreateAt: now - int64(48*time.Hour/time.Millisecond)})
+			api.On("GetPostsSince", "ChannelID", mock.AnythingOfType("int64")).Return(list, nil)
+
+			channel := &model.Channel{Id: "ChannelID", Name: "town-square", DisplayName: "Town Square"}
+			api.On("GetChannelByName", teamID1, "town-square", false).Return(channel, nil)
+			api.On("GetChannel", "ChannelID").Return(channel, nil)
+			api.On("HasPermissionToChannel", UserID, "ChannelID", model.PERMISSION_READ_CHANNEL).Return(true)
+			api.On("GetUserByUsername", "alice").Return(&model.User{Id: "AliceID", Username: "alice"}, nil)
+			api.On("GetUserByUsername", "nobody").Return(nil, &model.AppError{Message: "not found"})
+			api.On("GetUser", "AliceID").Return(&model.User{Id: "AliceID", Username: "alice"}, nil)
+			api.On("GetUser", "BobID").Return(&model.User{Id: "BobID", Username: "bob"}, nil)
+
+			jsonBmarks, err := json.Marshal(getExecuteCommandTestBookmarks())
+			require.Nil(t, err)
+			api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+			jsonLabels, err := json.Marshal(getExecuteCommandTestLabels())
+			require.Nil(t, err)
+			api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+			api.On("KVGet", getRulesKey(UserID)).Return(nil, nil)
+			api.On("KVGet", getTrashKey(UserID)).Return(nil, nil)
+			api.On("KVSet", getLabelsKey(UserID), mock.Anything).Return(nil)
+			api.On("KVSet", getTrashKey(UserID), mock.Anything).Return(nil)
+
+			store := mockBookmarksStore(api, UserID)
+
+			api.On("SendEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				actual := strings.TrimSpace(args.Get(1).(*model.Post).Message)
+				assert.True(t, strings.HasPrefix(actual, tt.expectedPrefix), "Expected returned message to start with: \n%s\nActual:\n%s", tt.expectedPrefix, actual)
+				for _, s := range tt.expectedContains {
+					assert.Contains(t, actual, s)
+				}
+			}).Once().Return(&model.Post{})
+
+			p := makePlugin(api)
+			args := &model.CommandArgs{Command: tt.command, UserId: UserID, TeamId: teamID1, ChannelId: "ChannelID"}
+			cmdResponse, appError := p.ExecuteCommand(&plugin.Context{}, args)
+			require.Nil(t, appError)
+			require.NotNil(t, cmdResponse)
+
+			var stored []string
+			for id := range store.getBookmarks().ByID {
+				stored = append(stored, id)
+			}
+			assert.ElementsMatch(t, tt.expectedStored, stored)
+
+			// the index is written once for all added bookmarks
+			if len(tt.expectedStored) != 0 {
+				assert.Equal(t, 1, store.headerWrites)
+			}
+		})
+	}
+}
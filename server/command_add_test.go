@@ -31,16 +31,22 @@ func TestExecuteCommandAdd(t *testing.T) {
 			expectedContains:  []string{"Missing sub-command", "bookmarks add"},
 		},
 		"PostID doesn't exist": {
-			commandArgs:       &model.CommandArgs{Command: fmt.Sprintf("/bookmarks add %v", PostIDDoesNotExist)},
+			commandArgs:       &model.CommandArgs{Command: fmt.Sprintf("/bookmarks add %v", PostIDDoesNotExistxxxxxxxx)},
 			bookmarks:         nil,
-			expectedMsgPrefix: strings.TrimSpace(fmt.Sprintf("PostID `%v` is not a valid postID", PostIDDoesNotExist)),
+			expectedMsgPrefix: strings.TrimSpace(fmt.Sprintf("PostID `%v` is not a valid postID", PostIDDoesNotExistxxxxxxxx)),
+			expectedContains:  nil,
+		},
+		"PostID is not valid": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks add notanid"},
+			bookmarks:         nil,
+			expectedMsgPrefix: "`notanid` is not a valid post ID or permalink",
 			expectedContains:  nil,
 		},
 		"Bookmark added  no title provided": {
 			commandArgs:       &model.CommandArgs{Command: fmt.Sprintf("/bookmarks add %v", p1ID)},
 			bookmarks:         getExecuteCommandTestBookmarks(),
 			labels:            getExecuteCommandTestLabels(),
-			expectedMsgPrefix: strings.TrimSpace(fmt.Sprintf("%sID1) **`TFP`** this is the post.Message", addPrefixMsg)),
+			expectedMsgPrefix: strings.TrimSpace(fmt.Sprintf("%sID1xxxxxxxxxxxxxxxxxxxxxxx) **`TFP`** this is the post.Message", addPrefixMsg)),
 			expectedContains:  nil,
 		},
 
@@ -48,14 +54,14 @@ func TestExecuteCommandAdd(t *testing.T) {
 		"Bookmark added  title provided no spaces": {
 			commandArgs:       &model.CommandArgs{Command: fmt.Sprintf("/bookmarks add %v %v", PostIDExists, "TitleProvidedByUser")},
 			bookmarks:         getExecuteCommandTestBookmarks(),
-			expectedMsgPrefix: strings.TrimSpace(fmt.Sprintf("%sID2)", addPrefixMsg)),
+			expectedMsgPrefix: strings.TrimSpace(fmt.Sprintf("%sID2xxxxxxxxxxxxxxxxxxxxxxx)", addPrefixMsg)),
 			expectedContains:  []string{"TitleProvidedByUser"},
 		},
 		"Bookmark added  title provided with spaces": {
 			commandArgs:       &model.CommandArgs{Command: fmt.Sprintf("/bookmarks add %v %v", PostIDExists, "Title Provided By User")},
 			bookmarks:         getExecuteCommandTestBookmarks(),
 			labels:            getExecuteCommandTestLabels(),
-			expectedMsgPrefix: strings.TrimSpace(fmt.Sprintf("%sID2)", addPrefixMsg)),
+			expectedMsgPrefix: strings.TrimSpace(fmt.Sprintf("%sID2xxxxxxxxxxxxxxxxxxxxxxx)", addPrefixMsg)),
 			expectedContains:  []string{"Title Provided By User"},
 		},
 
@@ -64,21 +70,21 @@ func TestExecuteCommandAdd(t *testing.T) {
 			commandArgs:       &model.CommandArgs{Command: fmt.Sprintf("/bookmarks add %v %v --labels %v", PostIDExists, "Title Provided By User", "label1,label2,label8")},
 			bookmarks:         getExecuteCommandTestBookmarks(),
 			labels:            getExecuteCommandTestLabels(),
-			expectedMsgPrefix: strings.TrimSpace(fmt.Sprintf("%sID2)", addPrefixMsg)),
+			expectedMsgPrefix: strings.TrimSpace(fmt.Sprintf("%sID2xxxxxxxxxxxxxxxxxxxxxxx)", addPrefixMsg)),
 			expectedContains:  []string{"label1", "label2", "label8", "Title Provided By User"},
 		},
 		"no flag optionBookmark added  title provided with spaces and labels": {
 			commandArgs:       &model.CommandArgs{Command: fmt.Sprintf("/bookmarks add %v %v --labels %v", PostIDExists, "Title Provided By User", "label1,label2")},
 			bookmarks:         getExecuteCommandTestBookmarks(),
 			labels:            getExecuteCommandTestLabels(),
-			expectedMsgPrefix: strings.TrimSpace(fmt.Sprintf("%sID2) `label1` `label2` **_Title Provided By User_**", addPrefixMsg)),
+			expectedMsgPrefix: strings.TrimSpace(fmt.Sprintf("%sID2xxxxxxxxxxxxxxxxxxxxxxx) `label1` `label2` **_Title Provided By User_**", addPrefixMsg)),
 			expectedContains:  []string{"label1", "label2", "Title Provided By User"},
 		},
 		"Bookmark added  title provided with labels": {
 			commandArgs:         &model.CommandArgs{Command: fmt.Sprintf("/bookmarks add %v %v --labels label1,label2", PostIDExists, "TitleProvidedByUser")},
 			bookmarks:           getExecuteCommandTestBookmarks(),
 			labels:              getExecuteCommandTestLabels(),
-			expectedMsgPrefix:   strings.TrimSpace(fmt.Sprintf("%sID2) ", addPrefixMsg)),
+			expectedMsgPrefix:   strings.TrimSpace(fmt.Sprintf("%sID2xxxxxxxxxxxxxxxxxxxxxxx) ", addPrefixMsg)),
 			expectedContains:    []string{"label1", "label2", "TitleProvidedByUser"},
 			expectedNotContains: []string{"--labels"},
 		},
@@ -100,7 +106,7 @@ func TestExecuteCommandAdd(t *testing.T) {
 			commandArgs:         &model.CommandArgs{Command: fmt.Sprintf("/bookmarks add %v --labels label1", p1ID)},
 			bookmarks:           getExecuteCommandTestBookmarks(),
 			labels:              getExecuteCommandTestLabels(),
-			expectedMsgPrefix:   strings.TrimSpace(fmt.Sprintf("%sID1", addPrefixMsg)),
+			expectedMsgPrefix:   strings.TrimSpace(fmt.Sprintf("%sID1xxxxxxxxxxxxxxxxxxxxxxx", addPrefixMsg)),
 			expectedNotContains: []string{"--labels"},
 			expectedContains:    nil,
 		},
@@ -108,7 +114,7 @@ func TestExecuteCommandAdd(t *testing.T) {
 			commandArgs:         &model.CommandArgs{Command: fmt.Sprintf("/bookmarks add %v --labels label1,label2", p1ID)},
 			bookmarks:           getExecuteCommandTestBookmarks(),
 			labels:              getExecuteCommandTestLabels(),
-			expectedMsgPrefix:   strings.TrimSpace(fmt.Sprintf("%sID1", addPrefixMsg)),
+			expectedMsgPrefix:   strings.TrimSpace(fmt.Sprintf("%sID1xxxxxxxxxxxxxxxxxxxxxxx", addPrefixMsg)),
 			expectedContains:    []string{"label1", "label2"},
 			expectedNotContains: []string{"--labels"},
 		},
@@ -116,7 +122,7 @@ func TestExecuteCommandAdd(t *testing.T) {
 			commandArgs:         &model.CommandArgs{Command: fmt.Sprintf("/bookmarks add %v --labels label1,label2,label8", p1ID)},
 			bookmarks:           getExecuteCommandTestBookmarks(),
 			labels:              getExecuteCommandTestLabels(),
-			expectedMsgPrefix:   strings.TrimSpace(fmt.Sprintf("%sID1", addPrefixMsg)),
+			expectedMsgPrefix:   strings.TrimSpace(fmt.Sprintf("%sID1xxxxxxxxxxxxxxxxxxxxxxx", addPrefixMsg)),
 			expectedContains:    []string{"label1", "label2", "label8"},
 			expectedNotContains: []string{"--labels"},
 		},
@@ -124,7 +130,7 @@ func TestExecuteCommandAdd(t *testing.T) {
 			commandArgs:         &model.CommandArgs{Command: fmt.Sprintf("/bookmarks add %v --labels label1,l8,l2,aa,cc,bb,xx", p1ID)},
 			bookmarks:           getExecuteCommandTestBookmarks(),
 			labels:              getExecuteCommandTestLabels(),
-			expectedMsgPrefix:   strings.TrimSpace(fmt.Sprintf("%sID1) **`TFP`** `aa` `bb` `cc` `l2` `l8` `label1` `xx`", addPrefixMsg)),
+			expectedMsgPrefix:   strings.TrimSpace(fmt.Sprintf("%sID1xxxxxxxxxxxxxxxxxxxxxxx) **`TFP`** `aa` `bb` `cc` `l2` `l8` `label1` `xx`", addPrefixMsg)),
 			expectedContains:    []string{"l1", "l2", "l8"},
 			expectedNotContains: []string{"--labels"},
 			// expectedContains: nil,
@@ -134,7 +140,7 @@ func TestExecuteCommandAdd(t *testing.T) {
 		api := makeAPIMock()
 		tt.commandArgs.UserId = UserID
 		siteURL := "https://myhost.com"
-		api.On("GetPost", PostIDDoesNotExist).Return(nil, &model.AppError{Message: "An Error Occurred"})
+		api.On("GetPost", PostIDDoesNotExistxxxxxxxx).Return(nil, &model.AppError{Message: "An Error Occurred"})
 		api.On("GetPost", p1ID).Return(&model.Post{Message: "this is the post.Message"}, nil)
 		api.On("GetPost", p2ID).Return(&model.Post{Message: "this is the post.Message"}, nil)
 		api.On("GetPost", p3ID).Return(&model.Post{Message: "this is the post.Message"}, nil)
@@ -150,6 +156,8 @@ func TestExecuteCommandAdd(t *testing.T) {
 
 		jsonLabels, err := json.Marshal(tt.labels)
 		api.On("KVGet", getLabelsKey(tt.commandArgs.UserId)).Return(jsonLabels, nil)
+		api.On("KVGet", getRulesKey(tt.commandArgs.UserId)).Return(nil, nil)
+		api.On("KVGet", getTrashKey(tt.commandArgs.UserId)).Return(nil, nil)
 
 		t.Run(name, func(t *testing.T) {
 			assert.Nil(t, err)
@@ -176,3 +184,167 @@ func TestExecuteCommandAdd(t *testing.T) {
 		})
 	}
 }
+
+func TestExecuteCommandAddAppliesRules(t *testing.T) {
+	tests := map[string]struct {
+		postID           string
+		expectedContains []string
+		expectedNot      []string
+	}{
+		"rule labels are added to new bookmarks": {
+			postID:           "ID9xxxxxxxxxxxxxxxxxxxxxxx",
+			expectedContains: []string{"`label1`", "`label2`"},
+		},
+		"rule labels are not added to existing bookmarks": {
+			postID:      p1ID,
+			expectedNot: []string{"`label1`"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := makeAPIMock()
+			siteURL := "https://myhost.com"
+			post := &model.Post{ChannelId: "ChannelID", Message: "#release notes"}
+			api.On("GetPost", tt.postID).Return(post, nil)
+			api.On("GetChannel", "ChannelID").Return(&model.Channel{Id: "ChannelID", Name: "town-square"}, nil)
+			api.On("GetTeam", mock.Anything).Return(&model.Team{Id: teamID1}, nil)
+			api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+			api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+
+			jsonBmarks, err := json.Marshal(getExecuteCommandTestBookmarks())
+			require.Nil(t, err)
+			api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+			jsonLabels, err := json.Marshal(getExecuteCommandTestLabels())
+			require.Nil(t, err)
+			api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+			jsonRules, err := json.Marshal(getTestRules())
+			require.Nil(t, err)
+			api.On("KVGet", getRulesKey(UserID)).Return(jsonRules, nil)
+			api.On("KVGet", getTrashKey(UserID)).Return(nil, nil)
+
+			api.On("SendEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				actual := args.Get(1).(*model.Post).Message
+				for _, s := range tt.expectedContains {
+					assert.Contains(t, actual, s)
+				}
+				for _, s := range tt.expectedNot {
+					assert.NotContains(t, actual, s)
+				}
+			}).Once().Return(&model.Post{})
+
+			p := makePlugin(api)
+			args := &model.CommandArgs{Command: fmt.Sprintf("/bookmarks add %v", tt.postID), UserId: UserID}
+			cmdResponse, appError := p.ExecuteCommand(&plugin.Context{}, args)
+			require.Nil(t, appError)
+			require.NotNil(t, cmdResponse)
+		})
+	}
+}
+
+func TestExecuteCommandAddKinds(t *testing.T) {
+	tests := map[string]struct {
+		command           string
+		expectedMsgPrefix string
+		expectedContains  []string
+	}{
+		"add-url without url": {
+			command:           "/bookmarks add-url",
+			expectedMsgPrefix: "Please specify a URL to bookmark",
+		},
+		"add-url with invalid url": {
+			command:           "/bookmarks add-url example.com Docs",
+			expectedMsgPrefix: "URL `example.com` is not valid",
+		},
+		"add-url with title and labels": {
+			command:           "/bookmarks add-url https://example.com/docs Project Docs --labels label1",
+			expectedMsgPrefix: "Added bookmark: [:globe_with_meridians:](https://example.com/docs) `label1` **_Project Docs_**",
+		},
+		"add channel": {
+			command:           "/bookmarks add ~town-square",
+			expectedMsgPrefix: "Added bookmark: [:hash:](https://myhost.com/myteam/channels/town-square) Town Square",
+		},
+		"add unknown channel": {
+			command:           "/bookmarks add ~unknown",
+			expectedMsgPrefix: "Channel `~unknown` does not exist",
+		},
+		"add thread of a reply": {
+			command:           "/bookmarks add ReplyIDxxxxxxxxxxxxxxxxxxx --thread",
+			expectedMsgPrefix: "Added bookmark: [:speech_balloon:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx)",
+			expectedContains:  []string{"this is the root message"},
+		},
+		"add thread with notifications": {
+			command:           "/bookmarks add ID1xxxxxxxxxxxxxxxxxxxxxxx --thread --notify",
+			expectedMsgPrefix: "Added bookmark: [:speech_balloon:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx)",
+		},
+		"notify without thread": {
+			command:           "/bookmarks add ID1xxxxxxxxxxxxxxxxxxxxxxx --notify",
+			expectedMsgPrefix: "`--notify` can only be used with `--thread`",
+		},
+		"add https permalink with query string": {
+			command:           "/bookmarks add https://myhost.com/myteam/pl/" + permalinkPostID + "/?view=full",
+			expectedMsgPrefix: "Added bookmark: [:link:](https://myhost.com/_redirect/pl/" + permalinkPostID + ")",
+		},
+		"add thread link": {
+			command:           "/bookmarks add https://myhost.com/myteam/threads/" + permalinkPostID,
+			expectedMsgPrefix: "Added bookmark: [:speech_balloon:](https://myhost.com/_redirect/pl/" + permalinkPostID + ")",
+		},
+		"add link to another server": {
+			command:           "/bookmarks add https://chat.example.com/myteam/pl/" + permalinkPostID,
+			expectedMsgPrefix: "Link `https://chat.example.com/myteam/pl/" + permalinkPostID + "` points to another server, `chat.example.com`",
+		},
+		"add-file": {
+			command:           "/bookmarks add-file FileID",
+			expectedMsgPrefix: "Added bookmark: [:paperclip:](https://myhost.com/api/v4/files/FileID) report.pdf",
+		},
+		"add-file unknown file": {
+			command:           "/bookmarks add-file UnknownFileID",
+			expectedMsgPrefix: "FileID `UnknownFileID` is not a valid fileID",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := makeAPIMock()
+			siteURL := "https://myhost.com"
+			api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+			api.On("GetPost", p1ID).Return(&model.Post{Id: p1ID, ChannelId: "ChannelID", Message: "this is the root message"}, nil)
+			api.On("GetPostThread", p1ID).Return(getTestThread(p1ID, 1), nil)
+			api.On("GetPost", permalinkPostID).Return(&model.Post{Id: permalinkPostID, Message: "linked post"}, nil)
+			api.On("GetPostThread", permalinkPostID).Return(getTestThread(permalinkPostID, 0), nil)
+			api.On("KVGet", getThreadWatchersKey(permalinkPostID)).Return(nil, nil)
+			api.On("GetPost", "ReplyIDxxxxxxxxxxxxxxxxxxx").Return(&model.Post{Id: "ReplyIDxxxxxxxxxxxxxxxxxxx", RootId: p1ID, ChannelId: "ChannelID", Message: "this is a reply"}, nil)
+			channel := &model.Channel{Id: "ChannelID", TeamId: teamID1, Name: "town-square", DisplayName: "Town Square"}
+			api.On("GetChannelByName", teamID1, "town-square", false).Return(channel, nil)
+			api.On("GetChannelByName", teamID1, "unknown", false).Return(nil, &model.AppError{Message: "not found"})
+			api.On("GetChannel", "ChannelID").Return(channel, nil)
+			api.On("HasPermissionToChannel", UserID, "ChannelID", model.PERMISSION_READ_CHANNEL).Return(true)
+			api.On("GetTeam", teamID1).Return(&model.Team{Id: teamID1, Name: "myteam"}, nil)
+			api.On("GetFileInfo", "FileID").Return(&model.FileInfo{Id: "FileID", PostId: p1ID, Name: "report.pdf"}, nil)
+			api.On("GetFileInfo", "UnknownFileID").Return(nil, &model.AppError{Message: "not found"})
+			api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+
+			jsonBmarks, err := json.Marshal(getExecuteCommandTestBookmarks())
+			require.Nil(t, err)
+			api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+			jsonLabels, err := json.Marshal(getExecuteCommandTestLabels())
+			require.Nil(t, err)
+			api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+			api.On("KVGet", getRulesKey(UserID)).Return(nil, nil)
+			api.On("KVGet", getTrashKey(UserID)).Return(nil, nil)
+			api.On("KVGet", getThreadWatchersKey(p1ID)).Return(nil, nil)
+
+			api.On("SendEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				actual := strings.TrimSpace(args.Get(1).(*model.Post).Message)
+				assert.True(t, strings.HasPrefix(actual, tt.expectedMsgPrefix), "Expected returned message to start with: \n%s\nActual:\n%s", tt.expectedMsgPrefix, actual)
+				for _, s := range tt.expectedContains {
+					assert.Contains(t, actual, s)
+				}
+			}).Once().Return(&model.Post{})
+
+			p := makePlugin(api)
+			args := &model.CommandArgs{Command: tt.command, UserId: UserID, TeamId: teamID1}
+			cmdResponse, appError := p.ExecuteCommand(&plugin.Context{}, args)
+			require.Nil(t, appError)
+			require.NotNil(t, cmdResponse)
+		})
+	}
+}
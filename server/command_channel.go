@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+// executeCommandChannel executes a channel board sub-command.  Boards belong
+// to the channel the command is run in
+func (p *Plugin) executeCommandChannel(args *model.CommandArgs) *model.CommandResponse {
+	split := strings.Fields(args.Command)
+	if len(split) < 3 {
+		return p.executeCommandChannelView(args)
+	}
+
+	action := split[2]
+
+	switch action {
+	case "add":
+		return p.executeCommandChannelAdd(args)
+	case "view":
+		return p.executeCommandChannelView(args)
+	case "remove":
+		return p.executeCommandChannelRemove(args)
+	case "pin":
+		return p.executeCommandChannelPin(args)
+	case "unpin":
+		return p.executeCommandChannelUnpin(args)
+	case "help":
+		return p.responsef(args, getHelp(channelCommandText))
+
+	default:
+		return p.responsef(args, fmt.Sprintf("Unknown command: "+args.Command))
+	}
+}
+
+// getCuratedBoard returns the board of the current channel if the user can
+// change it
+func (p *Plugin) getCuratedBoard(args *model.CommandArgs) (*Board, error) {
+	if !p.canCurateBoard(args.UserId, args.ChannelId) {
+		if p.getConfiguration().getChannelBoardCuration() == boardCurationMembers {
+			return nil, errors.New("Only channel members can change the channel bookmark board")
+		}
+		return nil, errors.New("Only channel admins can change the channel bookmark board")
+	}
+
+	return NewBoardWithChannel(p.API, args.ChannelId).getBoard()
+}
+
+// executeCommandChannelAdd adds a post of the channel to its board
+func (p *Plugin) executeCommandChannelAdd(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+	if len(subCommand) < 4 {
+		return p.responsef(args, "Please specify a post_id to add%v", getHelp(channelCommandText))
+	}
+
+	board, err := p.getCuratedBoard(args)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	postID := p.getPostIDFromLink(subCommand[3])
+	post, appErr := p.API.GetPost(postID)
+	if appErr != nil {
+		return p.responsef(args, "PostID `%s` is not a valid postID", postID)
+	}
+	// boards are visible to every channel member, so only posts of the
+	// channel can be added
+	if post.ChannelId != args.ChannelId {
+		return p.responsef(args, "Only posts of this channel can be added to its bookmark board")
+	}
+	if board.get(postID) != nil {
+		return p.responsef(args, "Post `%s` is already on the channel bookmark board", postID)
+	}
+	if err = p.checkEntriesLimit("The channel bookmark board", len(board.Entries)); err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	entry := &CollectionEntry{
+		PostID:   postID,
+		Title:    strings.Join(subCommand[4:], " "),
+		AddedBy:  args.UserId,
+		CreateAt: model.GetMillis(),
+	}
+	if err = p.checkTitleLength(entry.Title); err != nil {
+		return p.responsef(args, err.Error())
+	}
+	if err = board.add(entry); err != nil {
+		return p.responsef(args, err.Error())
+	}
+	if err = p.updateBoardPost(board); err != nil {
+		p.API.LogWarn("Unable to update the board post", "error", err.Error())
+	}
+
+	text, err := p.getBmarkTextOneLine(&Bookmark{PostID: postID, Title: entry.Title}, nil)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	return p.responsef(args, "Added to the channel bookmark board: %s", text)
+}
+
+// executeCommandChannelView shows the board of the channel
+func (p *Plugin) executeCommandChannelView(args *model.CommandArgs) *model.CommandResponse {
+	if !p.API.HasPermissionToChannel(args.UserId, args.ChannelId, model.PERMISSION_READ_CHANNEL) {
+		return p.responsef(args, "You do not have access to this channel")
+	}
+
+	board, err := NewBoardWithChannel(p.API, args.ChannelId).getBoard()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	text, err := p.getBoardText(board)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	return p.responsef(args, text)
+}
+
+// executeCommandChannelRemove removes posts from the board of the channel
+func (p *Plugin) executeCommandChannelRemove(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+	if len(subCommand) < 4 {
+		return p.responsef(args, "Please specify a post_id to remove%v", getHelp(channelCommandText))
+	}
+
+	board, err := p.getCuratedBoard(args)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	postID := p.getPostIDFromLink(subCommand[3])
+	if board.get(postID) == nil {
+		return p.responsef(args, "Post `%s` is not on the channel bookmark board", postID)
+	}
+	if err = board.delete(postID); err != nil {
+		return p.responsef(args, err.Error())
+	}
+	if err = p.updateBoardPost(board); err != nil {
+		p.API.LogWarn("Unable to update the board post", "error", err.Error())
+	}
+
+	return p.responsef(args, "Removed post `%s` from the channel bookmark board", postID)
+}
+
+// executeCommandChannelPin posts the board to the channel and pins it.  The
+// bot keeps the post up to date when the board changes
+func (p *Plugin) executeCommandChannelPin(args *model.CommandArgs) *model.CommandResponse {
+	board, err := p.getCuratedBoard(args)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	if err = p.pinBoardPost(board); err != nil {
+		return p.responsef(args, err.Error())
+	}
+	return p.responsef(args, "Pinned the channel bookmark board")
+}
+
+// executeCommandChannelUnpin deletes the pinned board post
+func (p *Plugin) executeCommandChannelUnpin(args *model.CommandArgs) *model.CommandResponse {
+	board, err := p.getCuratedBoard(args)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	if board.PostID == "" {
+		return p.responsef(args, "The channel bookmark board is not pinned")
+	}
+
+	if err = p.unpinBoardPost(board); err != nil {
+		return p.responsef(args, err.Error())
+	}
+	return p.responsef(args, "Removed the pinned channel bookmark board")
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	boardChannelID = "BoardChannelID"
+	boardPostID    = "BoardPostID"
+	adminID        = "AdminID"
+)
+
+func getTestBoard(pinned bool) *Board {
+	board := NewBoardWithChannel(nil, boardChannelID)
+	board.Entries["ID1xxxxxxxxxxxxxxxxxxxxxxx"] = &CollectionEntry{PostID: "ID1xxxxxxxxxxxxxxxxxxxxxxx", Title: "Meeting notes", AddedBy: adminID, CreateAt: 1}
+	if pinned {
+		board.PostID = boardPostID
+	}
+	return board
+}
+
+func TestExecuteCommandChannel(t *testing.T) {
+	tests := map[string]struct {
+		commandArgs         *model.CommandArgs
+		board               *Board
+		curation            string
+		config              *configuration
+		expectedMsgPrefix   string
+		expectedContains    []string
+		expectedNotContains []string
+		expectedStored      func(t *testing.T, stored *Board)
+		expectedBoardPost   string
+	}{
+		"VIEW empty board": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks channel view"},
+			expectedMsgPrefix: "#### Channel Bookmarks",
+			expectedContains:  []string{"There are no bookmarks on this channel board"},
+		},
+		"VIEW board": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks channel"},
+			board:             getTestBoard(false),
+			expectedMsgPrefix: "#### Channel Bookmarks",
+			expectedContains:  []string{"[:link:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx) **_Meeting notes_** - added by @admin"},
+		},
+		"ADD as a member": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks channel add ID2xxxxxxxxxxxxxxxxxxxxxxx"},
+			board:             getTestBoard(false),
+			expectedMsgPrefix: "Only channel admins can change the channel bookmark board",
+		},
+		"ADD as a member when members can curate": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks channel add ID2xxxxxxxxxxxxxxxxxxxxxxx Agenda"},
+			board:             getTestBoard(false),
+			curation:          boardCurationMembers,
+			expectedMsgPrefix: "Added to the channel bookmark board:",
+			expectedContains:  []string{"**_Agenda_**"},
+			expectedStored: func(t *testing.T, stored *Board) {
+				require.NotNil(t, stored.get("ID2xxxxxxxxxxxxxxxxxxxxxxx"))
+				assert.Equal(t, UserID, stored.get("ID2xxxxxxxxxxxxxxxxxxxxxxx").AddedBy)
+			},
+		},
+		"ADD as an admin updates the board post": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks channel add ID2xxxxxxxxxxxxxxxxxxxxxxx", UserId: adminID},
+			board:             getTestBoard(true),
+			expectedMsgPrefix: "Added to the channel bookmark board:",
+			expectedBoardPost: "pl/ID2xxxxxxxxxxxxxxxxxxxxxxx",
+		},
+		"ADD post of another channel": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks channel add ID3xxxxxxxxxxxxxxxxxxxxxxx", UserId: adminID},
+			board:             getTestBoard(false),
+			expectedMsgPrefix: "Only posts of this channel can be added to its bookmark board",
+		},
+		"ADD post already on the board": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks channel add ID1xxxxxxxxxxxxxxxxxxxxxxx", UserId: adminID},
+			board:             getTestBoard(false),
+			expectedMsgPrefix: "Post `ID1xxxxxxxxxxxxxxxxxxxxxxx` is already on the channel bookmark board",
+		},
+		"ADD post to a full board": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks channel add ID2xxxxxxxxxxxxxxxxxxxxxxx", UserId: adminID},
+			board:             getTestBoard(false),
+			config:            &configuration{MaxBookmarksPerUser: 1},
+			expectedMsgPrefix: "The channel bookmark board has reached the limit of 1 bookmarks. Remove some bookmarks before adding more",
+			expectedStored: func(t *testing.T, stored *Board) {
+				assert.Nil(t, stored.get("ID2xxxxxxxxxxxxxxxxxxxxxxx"))
+			},
+		},
+		"ADD post with a title that is too long": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks channel add ID2xxxxxxxxxxxxxxxxxxxxxxx Weekly agenda", UserId: adminID},
+			board:             getTestBoard(false),
+			config:            &configuration{MaxTitleLength: 5},
+			expectedMsgPrefix: "Titles can be at most 5 characters long. This title is 13 characters long",
+			expectedStored: func(t *testing.T, stored *Board) {
+				assert.Nil(t, stored.get("ID2xxxxxxxxxxxxxxxxxxxxxxx"))
+			},
+		},
+		"REMOVE post": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks channel remove ID1xxxxxxxxxxxxxxxxxxxxxxx", UserId: adminID},
+			board:             getTestBoard(true),
+			expectedMsgPrefix: "Removed post `ID1xxxxxxxxxxxxxxxxxxxxxxx` from the channel bookmark board",
+			expectedBoardPost: "There are no bookmarks on this channel board",
+			expectedStored: func(t *testing.T, stored *Board) {
+				assert.Nil(t, stored.get("ID1xxxxxxxxxxxxxxxxxxxxxxx"))
+			},
+		},
+		"REMOVE post not on the board": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks channel remove ID2xxxxxxxxxxxxxxxxxxxxxxx", UserId: adminID},
+			board:             getTestBoard(false),
+			expectedMsgPrefix: "Post `ID2xxxxxxxxxxxxxxxxxxxxxxx` is not on the channel bookmark board",
+		},
+		"PIN board": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks channel pin", UserId: adminID},
+			board:             getTestBoard(false),
+			expectedMsgPrefix: "Pinned the channel bookmark board",
+			expectedBoardPost: "pl/ID1xxxxxxxxxxxxxxxxxxxxxxx",
+			expectedStored: func(t *testing.T, stored *Board) {
+				assert.Equal(t, "NewBoardPostID", stored.PostID)
+			},
+		},
+		"UNPIN board": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks channel unpin", UserId: adminID},
+			board:             getTestBoard(true),
+			expectedMsgPrefix: "Removed the pinned channel bookmark board",
+			expectedStored: func(t *testing.T, stored *Board) {
+				assert.Empty(t, stored.PostID)
+			},
+		},
+		"UNPIN board that is not pinned": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks channel unpin", UserId: adminID},
+			board:             getTestBoard(false),
+			expectedMsgPrefix: "The channel bookmark board is not pinned",
+		},
+	}
+	for name, tt := range tests {
+		api := makeAPIMock()
+		if tt.commandArgs.UserId == "" {
+			tt.commandArgs.UserId = UserID
+		}
+		tt.commandArgs.ChannelId = boardChannelID
+		siteURL := "https://myhost.com"
+		api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+		api.On("GetPost", "ID1xxxxxxxxxxxxxxxxxxxxxxx").Return(&model.Post{Id: "ID1xxxxxxxxxxxxxxxxxxxxxxx", ChannelId: boardChannelID, Message: "notes"}, nil)
+		api.On("GetPost", "ID2xxxxxxxxxxxxxxxxxxxxxxx").Return(&model.Post{Id: "ID2xxxxxxxxxxxxxxxxxxxxxxx", ChannelId: boardChannelID, Message: "agenda"}, nil)
+		api.On("GetPost", "ID3xxxxxxxxxxxxxxxxxxxxxxx").Return(&model.Post{Id: "ID3xxxxxxxxxxxxxxxxxxxxxxx", ChannelId: "OtherChannelID", Message: "other"}, nil)
+		api.On("GetPost", boardPostID).Return(&model.Post{Id: boardPostID, ChannelId: boardChannelID}, nil)
+		api.On("HasPermissionToChannel", mock.Anything, boardChannelID, model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("HasPermissionToChannel", adminID, boardChannelID, model.PERMISSION_MANAGE_CHANNEL_ROLES).Return(true)
+		api.On("HasPermissionToChannel", UserID, boardChannelID, model.PERMISSION_MANAGE_CHANNEL_ROLES).Return(false)
+		api.On("GetUser", adminID).Return(&model.User{Id: adminID, Username: "admin"}, nil)
+		api.On("GetUser", UserID).Return(&model.User{Id: UserID, Username: "user"}, nil)
+		api.On("DeletePost", boardPostID).Return(nil)
+
+		var boardPost string
+		api.On("UpdatePost", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+			boardPost = args.Get(0).(*model.Post).Message
+		}).Return(&model.Post{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+			post := args.Get(0).(*model.Post)
+			assert.True(t, post.IsPinned)
+			boardPost = post.Message
+		}).Return(&model.Post{Id: "NewBoardPostID"}, nil)
+
+		jsonBoard, err := json.Marshal(tt.board)
+		require.Nil(t, err)
+		api.On("KVGet", getBoardKey(boardChannelID)).Return(jsonBoard, nil)
+
+		stored := NewBoardWithChannel(api, boardChannelID)
+		api.On("KVSet", getBoardKey(boardChannelID), mock.Anything).Run(func(args mock.Arguments) {
+			stored = NewBoardWithChannel(api, boardChannelID)
+			require.Nil(t, json.Unmarshal(args.Get(1).([]byte), stored))
+		}).Return(nil)
+
+		t.Run(name, func(t *testing.T) {
+			api.On("SendEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				post := args.Get(1).(*model.Post)
+				actual := strings.TrimSpace(post.Message)
+				assert.True(t, strings.HasPrefix(actual, tt.expectedMsgPrefix), "Expected returned message to start with: \n%s\nActual:\n%s", tt.expectedMsgPrefix, actual)
+				for i := range tt.expectedContains {
+					assert.Contains(t, actual, tt.expectedContains[i])
+				}
+				for i := range tt.expectedNotContains {
+					assert.NotContains(t, actual, tt.expectedNotContains[i])
+				}
+			}).Once().Return(&model.Post{})
+
+			p := makePlugin(api)
+			config := &configuration{ChannelBoardCuration: tt.curation}
+			if tt.config != nil {
+				config = tt.config
+			}
+			p.setConfiguration(config)
+			cmdResponse, appError := p.ExecuteCommand(&plugin.Context{}, tt.commandArgs)
+			require.Nil(t, appError)
+			require.NotNil(t, cmdResponse)
+
+			if tt.expectedStored != nil {
+				tt.expectedStored(t, stored)
+			}
+			if tt.expectedBoardPost != "" {
+				assert.Contains(t, boardPost, tt.expectedBoardPost)
+			}
+		})
+	}
+}
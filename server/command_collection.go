@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const flagOwner = "--owner"
+
+// executeCommandCollection executes a collection sub-command
+func (p *Plugin) executeCommandCollection(args *model.CommandArgs) *model.CommandResponse {
+	split := strings.Fields(args.Command)
+	if len(split) < 3 {
+		return p.responsef(args, "Missing collection sub-command. You can try %v", getHelp(collectionCommandText))
+	}
+
+	action := split[2]
+
+	switch action {
+	case "create":
+		return p.executeCommandCollectionCreate(args)
+	case "add":
+		return p.executeCommandCollectionAdd(args)
+	case "view":
+		return p.executeCommandCollectionView(args)
+	case "share":
+		return p.executeCommandCollectionShare(args)
+	case "leave":
+		return p.executeCommandCollectionLeave(args)
+	case "help":
+		return p.responsef(args, getHelp(collectionCommandText))
+
+	default:
+		return p.responsef(args, fmt.Sprintf("Unknown command: "+args.Command))
+	}
+}
+
+// getMemberCollection returns the collection with a name in the current team.
+// Users who are not members get the same error as for a missing collection,
+// so collection names are not revealed
+func (p *Plugin) getMemberCollection(args *model.CommandArgs, name string) (*Collections, *Collection, error) {
+	collections, err := NewCollectionsWithTeam(p.API, args.TeamId).getCollections()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	collection := collections.getByName(name)
+	if collection == nil || !collection.isMember(args.UserId) {
+		return nil, nil, errors.New(fmt.Sprintf("Collection `%s` does not exist", name))
+	}
+	return collections, collection, nil
+}
+
+// executeCommandCollectionCreate creates a collection owned by the user
+func (p *Plugin) executeCommandCollectionCreate(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+	if len(subCommand) != 4 {
+		return p.responsef(args, "Please specify a collection name%v", getHelp(collectionCommandText))
+	}
+	name := subCommand[3]
+	if err := validateCollectionName(name); err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	collections, err := NewCollectionsWithTeam(p.API, args.TeamId).getCollections()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	if collections.getByName(name) != nil {
+		return p.responsef(args, "Collection with name `%s` already exists", name)
+	}
+
+	if err = collections.add(newCollection(args.TeamId, name, args.UserId)); err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	return p.responsef(args, "Created collection `%s`. Use `/bookmarks collection share %s @user` to add members", name, name)
+}
+
+// executeCommandCollectionAdd adds a post to a collection
+func (p *Plugin) executeCommandCollectionAdd(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+	if len(subCommand) < 5 {
+		return p.responsef(args, "Please specify a collection name and a post_id%v", getHelp(collectionCommandText))
+	}
+
+	collections, collection, err := p.getMemberCollection(args, subCommand[3])
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	postID := p.getPostIDFromLink(subCommand[4])
+	post, appErr := p.API.GetPost(postID)
+	if appErr != nil || !p.canReadPost(args.UserId, post) {
+		return p.responsef(args, "PostID `%s` is not a valid postID", postID)
+	}
+
+	title := strings.Join(subCommand[5:], " ")
+	if err = p.checkTitleLength(title); err != nil {
+		return p.responsef(args, err.Error())
+	}
+	if _, ok := collection.Entries[postID]; !ok {
+		if err = p.checkEntriesLimit(fmt.Sprintf("Collection `%s`", collection.Name), len(collection.Entries)); err != nil {
+			return p.responsef(args, err.Error())
+		}
+	}
+	if !collection.addEntry(postID, title, args.UserId) {
+		return p.responsef(args, "Post `%s` is already in collection `%s`", postID, collection.Name)
+	}
+	if err = collections.storeCollections(); err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	text, err := p.getBmarkTextOneLine(&Bookmark{PostID: postID, Title: title}, nil)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	return p.responsef(args, "Added to collection `%s`: %s", collection.Name, text)
+}
+
+// executeCommandCollectionView lists the users collections, or the bookmarks
+// of a collection
+func (p *Plugin) executeCommandCollectionView(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+	if len(subCommand) < 4 {
+		return p.executeCommandCollectionList(args)
+	}
+
+	_, collection, err := p.getMemberCollection(args, subCommand[3])
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	text := fmt.Sprintf("#### Collection %s\n", collection.Name)
+	hidden := 0
+	for _, entry := range collection.sortedEntries() {
+		// skip posts the viewer is not allowed to read
+		post, appErr := p.API.GetPost(entry.PostID)
+		if appErr != nil || !p.canReadPost(args.UserId, post) {
+			hidden++
+			continue
+		}
+
+		nextText, err := p.getBmarkTextOneLine(&Bookmark{PostID: entry.PostID, Title: entry.Title}, nil)
+		if err != nil {
+			return p.responsef(args, err.Error())
+		}
+		text += strings.TrimSuffix(nextText, "\n") + fmt.Sprintf(" - added by %s\n", p.getUsernameText(entry.AddedBy))
+	}
+
+	if len(collection.Entries) == 0 {
+		text += "This collection is empty\n"
+	}
+	if hidden != 0 {
+		text += fmt.Sprintf("%v bookmarks are hidden because you do not have access to their channels\n", hidden)
+	}
+
+	return p.responsef(args, text)
+}
+
+// executeCommandCollectionList lists the collections the user is a member of
+// in the current team
+func (p *Plugin) executeCommandCollectionList(args *model.CommandArgs) *model.CommandResponse {
+	collections, err := NewCollectionsWithTeam(p.API, args.TeamId).getCollections()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	member := collections.getForMember(args.UserId)
+	if len(member) == 0 {
+		return p.responsef(args, "You are not a member of any collections in this team")
+	}
+
+	text := "#### Collections\n"
+	for _, collection := range member {
+		role := "member"
+		if collection.isOwner(args.UserId) {
+			role = "owner"
+		}
+		text += fmt.Sprintf("* `%s` (%v bookmarks, %v members) - %s\n", collection.Name, len(collection.Entries), len(collection.MemberIDs), role)
+	}
+
+	return p.responsef(args, text)
+}
+
+// executeCommandCollectionShare adds a member to a collection.  Only owners
+// can share a collection
+func (p *Plugin) executeCommandCollectionShare(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+	if len(subCommand) < 5 || !strings.HasPrefix(subCommand[4], "@") {
+		return p.responsef(args, "Please specify a collection name and a @user%v", getHelp(collectionCommandText))
+	}
+	owner := len(subCommand) > 5 && subCommand[5] == flagOwner
+
+	collections, collection, err := p.getMemberCollection(args, subCommand[3])
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	if !collection.isOwner(args.UserId) {
+		return p.responsef(args, "Only owners can share collection `%s`", collection.Name)
+	}
+
+	username := subCommand[4]
+	user, appErr := p.API.GetUserByUsername(strings.TrimPrefix(username, "@"))
+	if appErr != nil || user == nil {
+		return p.responsef(args, "User `%s` does not exist", username)
+	}
+	if member, appErr := p.API.GetTeamMember(collection.TeamID, user.Id); appErr != nil || member == nil || member.DeleteAt != 0 {
+		return p.responsef(args, "User `%s` is not a member of this team", username)
+	}
+
+	if !collection.addMember(user.Id, owner) {
+		return p.responsef(args, "User `%s` is already a member of collection `%s`", username, collection.Name)
+	}
+	if err = collections.storeCollections(); err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	if owner {
+		return p.responsef(args, "Shared collection `%s` with %s as an owner", collection.Name, username)
+	}
+	return p.responsef(args, "Shared collection `%s` with %s", collection.Name, username)
+}
+
+// executeCommandCollectionLeave removes the user from a collection.  The
+// collection is deleted when its last member leaves
+func (p *Plugin) executeCommandCollectionLeave(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+	if len(subCommand) < 4 {
+		return p.responsef(args, "Please specify a collection name%v", getHelp(collectionCommandText))
+	}
+
+	collections, collection, err := p.getMemberCollection(args, subCommand[3])
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	collection.removeMember(args.UserId)
+	if len(collection.MemberIDs) == 0 {
+		if err = collections.delete(collection.ID); err != nil {
+			return p.responsef(args, err.Error())
+		}
+		return p.responsef(args, "Left collection `%s`. The collection was deleted because it has no members left", collection.Name)
+	}
+	if len(collection.OwnerIDs) == 0 {
+		return p.responsef(args, "You are the only owner of collection `%s`. Share it with another member using %s first", collection.Name, flagOwner)
+	}
+
+	if err = collections.storeCollections(); err != nil {
+		return p.responsef(args, err.Error())
+	}
+	return p.responsef(args, "Left collection `%s`", collection.Name)
+}
+
+// getUsernameText returns @username for a user ID, or the ID if the user
+// does not exist
+func (p *Plugin) getUsernameText(userID string) string {
+	if user, appErr := p.API.GetUser(userID); appErr == nil {
+		return "@" + user.Username
+	}
+	return userID
+}
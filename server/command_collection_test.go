@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestCollections() *Collections {
+	collections := NewCollectionsWithTeam(nil, teamID1)
+	collections.ByID = map[string]*Collection{
+		"CollectionID": {
+			ID:        "CollectionID",
+			Name:      "Onboarding",
+			TeamID:    teamID1,
+			OwnerIDs:  []string{UserID},
+			MemberIDs: []string{UserID, "MemberID"},
+			Entries: map[string]*CollectionEntry{
+				"ID1xxxxxxxxxxxxxxxxxxxxxxx": {PostID: "ID1xxxxxxxxxxxxxxxxxxxxxxx", Title: "Read this first", AddedBy: UserID, CreateAt: 1},
+				"ID3xxxxxxxxxxxxxxxxxxxxxxx": {PostID: "ID3xxxxxxxxxxxxxxxxxxxxxxx", AddedBy: "MemberID", CreateAt: 2},
+			},
+		},
+		"PrivateCollectionID": {
+			ID:        "PrivateCollectionID",
+			Name:      "Secret",
+			TeamID:    teamID1,
+			OwnerIDs:  []string{"MemberID"},
+			MemberIDs: []string{"MemberID"},
+		},
+	}
+	return collections
+}
+
+func TestExecuteCommandCollection(t *testing.T) {
+	tests := map[string]struct {
+		commandArgs         *model.CommandArgs
+		collections         *Collections
+		config              *configuration
+		expectedMsgPrefix   string
+		expectedContains    []string
+		expectedNotContains []string
+		expectedStored      func(t *testing.T, stored *Collections)
+	}{
+		"CREATE collection": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks collection create Links"},
+			expectedMsgPrefix: "Created collection `Links`",
+			expectedStored: func(t *testing.T, stored *Collections) {
+				collection := stored.getByName("Links")
+				require.NotNil(t, collection)
+				assert.True(t, collection.isOwner(UserID))
+				assert.True(t, collection.isMember(UserID))
+			},
+		},
+		"CREATE collection that exists": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks collection create onboarding"},
+			collections:       getTestCollections(),
+			expectedMsgPrefix: "Collection with name `onboarding` already exists",
+		},
+		"CREATE invalid name": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks collection create a/b"},
+			expectedMsgPrefix: "Collection name `a/b` is not valid",
+		},
+		"ADD post": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks collection add onboarding ID2xxxxxxxxxxxxxxxxxxxxxxx Team wiki"},
+			collections:       getTestCollections(),
+			expectedMsgPrefix: "Added to collection `Onboarding`:",
+			expectedContains:  []string{"**_Team wiki_**"},
+			expectedStored: func(t *testing.T, stored *Collections) {
+				entry := stored.get("CollectionID").Entries["ID2xxxxxxxxxxxxxxxxxxxxxxx"]
+				require.NotNil(t, entry)
+				assert.Equal(t, UserID, entry.AddedBy)
+			},
+		},
+		"ADD post already in collection": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks collection add onboarding ID1xxxxxxxxxxxxxxxxxxxxxxx"},
+			collections:       getTestCollections(),
+			expectedMsgPrefix: "Post `ID1xxxxxxxxxxxxxxxxxxxxxxx` is already in collection `Onboarding`",
+		},
+		"ADD post over the bookmark limit": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks collection add onboarding ID2xxxxxxxxxxxxxxxxxxxxxxx"},
+			collections:       getTestCollections(),
+			config:            &configuration{MaxBookmarksPerUser: 2},
+			expectedMsgPrefix: "Collection `Onboarding` has reached the limit of 2 bookmarks. Remove some bookmarks before adding more",
+		},
+		"ADD post with a title that is too long": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks collection add onboarding ID2xxxxxxxxxxxxxxxxxxxxxxx Team wiki"},
+			collections:       getTestCollections(),
+			config:            &configuration{MaxTitleLength: 5},
+			expectedMsgPrefix: "Titles can be at most 5 characters long. This title is 9 characters long",
+		},
+		"ADD post the user cannot read": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks collection add onboarding ID4xxxxxxxxxxxxxxxxxxxxxxx"},
+			collections:       getTestCollections(),
+			expectedMsgPrefix: "PostID `ID4xxxxxxxxxxxxxxxxxxxxxxx` is not a valid postID",
+		},
+		"ADD to collection the user is not a member of": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks collection add secret ID2xxxxxxxxxxxxxxxxxxxxxxx"},
+			collections:       getTestCollections(),
+			expectedMsgPrefix: "Collection `secret` does not exist",
+		},
+		"VIEW collections": {
+			commandArgs:         &model.CommandArgs{Command: "/bookmarks collection view"},
+			collections:         getTestCollections(),
+			expectedMsgPrefix:   "#### Collections",
+			expectedContains:    []string{"* `Onboarding` (2 bookmarks, 2 members) - owner"},
+			expectedNotContains: []string{"Secret"},
+		},
+		"VIEW without collections": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks collection view"},
+			expectedMsgPrefix: "You are not a member of any collections in this team",
+		},
+		"VIEW collection hides posts the user cannot read": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks collection view onboarding"},
+			collections:       getTestCollections(),
+			expectedMsgPrefix: "#### Collection Onboarding",
+			expectedContains: []string{
+				"[:link:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx) **_Read this first_** - added by @user",
+				"1 bookmarks are hidden because you do not have access to their channels",
+			},
+			expectedNotContains: []string{"pl/ID3xxxxxxxxxxxxxxxxxxxxxxx"},
+		},
+		"SHARE collection": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks collection share onboarding @alice"},
+			collections:       getTestCollections(),
+			expectedMsgPrefix: "Shared collection `Onboarding` with @alice",
+			expectedStored: func(t *testing.T, stored *Collections) {
+				collection := stored.get("CollectionID")
+				assert.True(t, collection.isMember("AliceID"))
+				assert.False(t, collection.isOwner("AliceID"))
+			},
+		},
+		"SHARE collection as owner": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks collection share onboarding @alice --owner"},
+			collections:       getTestCollections(),
+			expectedMsgPrefix: "Shared collection `Onboarding` with @alice as an owner",
+		},
+		"SHARE with user outside the team": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks collection share onboarding @bob"},
+			collections:       getTestCollections(),
+			expectedMsgPrefix: "User `@bob` is not a member of this team",
+		},
+		"SHARE as a member": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks collection share onboarding @alice", UserId: "MemberID"},
+			collections:       getTestCollections(),
+			expectedMsgPrefix: "Only owners can share collection `Onboarding`",
+		},
+		"LEAVE as the only owner": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks collection leave onboarding"},
+			collections:       getTestCollections(),
+			expectedMsgPrefix: "You are the only owner of collection `Onboarding`",
+		},
+		"LEAVE as a member": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks collection leave onboarding", UserId: "MemberID"},
+			collections:       getTestCollections(),
+			expectedMsgPrefix: "Left collection `Onboarding`",
+			expectedStored: func(t *testing.T, stored *Collections) {
+				assert.False(t, stored.get("CollectionID").isMember("MemberID"))
+			},
+		},
+		"LEAVE as the last member": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks collection leave secret", UserId: "MemberID"},
+			collections:       getTestCollections(),
+			expectedMsgPrefix: "Left collection `Secret`. The collection was deleted",
+			expectedStored: func(t *testing.T, stored *Collections) {
+				assert.Nil(t, stored.get("PrivateCollectionID"))
+			},
+		},
+	}
+	for name, tt := range tests {
+		api := makeAPIMock()
+		if tt.commandArgs.UserId == "" {
+			tt.commandArgs.UserId = UserID
+		}
+		tt.commandArgs.TeamId = teamID1
+		siteURL := "https://myhost.com"
+		api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+		api.On("GetPost", "ID1xxxxxxxxxxxxxxxxxxxxxxx").Return(&model.Post{Id: "ID1xxxxxxxxxxxxxxxxxxxxxxx", ChannelId: "ChannelID", Message: "welcome"}, nil)
+		api.On("GetPost", "ID2xxxxxxxxxxxxxxxxxxxxxxx").Return(&model.Post{Id: "ID2xxxxxxxxxxxxxxxxxxxxxxx", ChannelId: "ChannelID", Message: "wiki"}, nil)
+		api.On("GetPost", "ID3xxxxxxxxxxxxxxxxxxxxxxx").Return(&model.Post{Id: "ID3xxxxxxxxxxxxxxxxxxxxxxx", ChannelId: "PrivateID", Message: "secret"}, nil)
+		api.On("GetPost", "ID4xxxxxxxxxxxxxxxxxxxxxxx").Return(&model.Post{Id: "ID4xxxxxxxxxxxxxxxxxxxxxxx", ChannelId: "PrivateID", Message: "secret"}, nil)
+		api.On("HasPermissionToChannel", UserID, "PrivateID", model.PERMISSION_READ_CHANNEL).Return(false)
+		api.On("HasPermissionToChannel", mock.Anything, mock.Anything, model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetUser", UserID).Return(&model.User{Id: UserID, Username: "user"}, nil)
+		api.On("GetUserByUsername", "alice").Return(&model.User{Id: "AliceID", Username: "alice"}, nil)
+		api.On("GetUserByUsername", "bob").Return(&model.User{Id: "BobID", Username: "bob"}, nil)
+		api.On("GetTeamMember", teamID1, "AliceID").Return(&model.TeamMember{TeamId: teamID1, UserId: "AliceID"}, nil)
+		api.On("GetTeamMember", teamID1, "BobID").Return(nil, &model.AppError{Message: "not found"})
+
+		jsonCollections, err := json.Marshal(tt.collections)
+		require.Nil(t, err)
+		api.On("KVGet", getCollectionsKey(teamID1)).Return(jsonCollections, nil)
+
+		stored := NewCollectionsWithTeam(api, teamID1)
+		api.On("KVSet", getCollectionsKey(teamID1), mock.Anything).Run(func(args mock.Arguments) {
+			require.Nil(t, json.Unmarshal(args.Get(1).([]byte), stored))
+		}).Return(nil)
+
+		t.Run(name, func(t *testing.T) {
+			api.On("SendEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				post := args.Get(1).(*model.Post)
+				actual := strings.TrimSpace(post.Message)
+				assert.True(t, strings.HasPrefix(actual, tt.expectedMsgPrefix), "Expected returned message to start with: \n%s\nActual:\n%s", tt.expectedMsgPrefix, actual)
+				for i := range tt.expectedContains {
+					assert.Contains(t, actual, tt.expectedContains[i])
+				}
+				for i := range tt.expectedNotContains {
+					assert.NotContains(t, actual, tt.expectedNotContains[i])
+				}
+			}).Once().Return(&model.Post{})
+
+			p := makePlugin(api)
+			if tt.config != nil {
+				p.setConfiguration(tt.config)
+			}
+			cmdResponse, appError := p.ExecuteCommand(&plugin.Context{}, tt.commandArgs)
+			require.Nil(t, appError)
+			require.NotNil(t, cmdResponse)
+
+			if tt.expectedStored != nil {
+				tt.expectedStored(t, stored)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// executeCommandDue sets or removes the due date of a bookmark
+func (p *Plugin) executeCommandDue(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+
+	if len(subCommand) != 4 {
+		return p.responsef(args, "Please specify a post_id and a due date%v", getHelp(dueCommandText))
+	}
+
+	now := p.getNow(args.UserId)
+	due, err := parseDueDate(subCommand[3], now)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	bmark, err := p.setBookmarkDue(args.UserId, p.getPostIDFromLink(subCommand[2]), due)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	text, err := p.getBmarkTextOneLine(bmark, nil)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	if due == "" {
+		return p.responsef(args, "Removed due date: %s", text)
+	}
+	return p.responsef(args, "Set due date to %s: %s", formatDueDate(due), strings.TrimSuffix(text, "\n")+getDueText(bmark, now))
+}
@@ -3,19 +3,35 @@ package main
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 )
 
 const (
 	flagForce = "force"
+	flagSort  = "sort"
+
+	bulkFlagTo   = "--to"
+	bulkFlagFrom = "--from"
 )
 
 type removeLabelOptions struct {
 	force bool
 }
 
+type viewLabelOptions struct {
+	sort string
+}
+
+type bulkLabelOptions struct {
+	names []string
+	query []string
+	force bool
+}
+
 func getLabelRemoveFlagSet() *pflag.FlagSet {
 	flagSet := pflag.NewFlagSet("remove labels", pflag.ContinueOnError)
 	flagSet.Bool(flagForce, false, "force removal of labels when they currently exist on a bookmark")
@@ -40,6 +56,64 @@ func parseLabelRemoveArgs(args []string) (removeLabelOptions, error) {
 	return options, nil
 }
 
+func getLabelViewFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("view labels", pflag.ContinueOnError)
+	flagSet.String(flagSort, labelSortName, "sort labels by name, count or used")
+
+	return flagSet
+}
+
+func parseLabelViewArgs(args []string) (viewLabelOptions, error) {
+	var options viewLabelOptions
+
+	viewLabelFlagSet := getLabelViewFlagSet()
+	err := viewLabelFlagSet.Parse(args)
+	if err != nil {
+		return options, err
+	}
+
+	options.sort, err = viewLabelFlagSet.GetString(flagSort)
+	if err != nil {
+		return options, err
+	}
+
+	return options, nil
+}
+
+// parseBulkLabelArgs parses the arguments of a bulk label command, e.g.
+// <labels> --to <query> --force.  The query is everything after queryFlag
+func parseBulkLabelArgs(args []string, queryFlag string) (bulkLabelOptions, error) {
+	var options bulkLabelOptions
+
+	i := 0
+	for ; i < len(args) && args[i] != queryFlag; i++ {
+		if args[i] == "--"+flagForce {
+			options.force = true
+			continue
+		}
+		for _, name := range strings.Split(args[i], ",") {
+			if name != "" {
+				options.names = append(options.names, name)
+			}
+		}
+	}
+	if len(options.names) == 0 {
+		return options, errors.New("Please specify the labels")
+	}
+	if i == len(args) {
+		return options, errors.New(fmt.Sprintf("Please specify the bookmarks with %s <query>", queryFlag))
+	}
+
+	for _, arg := range args[i+1:] {
+		if arg == "--"+flagForce {
+			options.force = true
+			continue
+		}
+		options.query = append(options.query, arg)
+	}
+	return options, nil
+}
+
 // ExecuteCommandLabel executes a label sub-command
 func (p *Plugin) executeCommandLabel(args *model.CommandArgs) *model.CommandResponse {
 	split := strings.Fields(args.Command)
@@ -58,6 +132,22 @@ func (p *Plugin) executeCommandLabel(args *model.CommandArgs) *model.CommandResp
 		return p.executeCommandLabelRename(args)
 	case "view":
 		return p.executeCommandLabelView(args)
+	case "color":
+		return p.executeCommandLabelColor(args)
+	case "describe":
+		return p.executeCommandLabelDescribe(args)
+	case "move":
+		return p.executeCommandLabelMove(args)
+	case "merge":
+		return p.executeCommandLabelMerge(args)
+	case "prune":
+		return p.executeCommandLabelPrune(args)
+	case "dedupe":
+		return p.executeCommandLabelDedupe(args)
+	case "apply":
+		return p.executeCommandLabelBulk(args, true)
+	case "strip":
+		return p.executeCommandLabelBulk(args, false)
 	case "help":
 		return p.responsef(args, "Please specify a label name %v", getHelp(labelCommandText))
 
@@ -72,6 +162,9 @@ func (p *Plugin) executeCommandLabelAdd(args *model.CommandArgs) *model.CommandR
 		return p.responsef(args, "Please specify a label name %v", getHelp(labelCommandText))
 	}
 
+	if len(subCommand) > 4 {
+		return p.responsef(args, "Label names cannot contain spaces. You can only create one label at a time")
+	}
 	labelName := subCommand[3]
 
 	labels := NewLabelsWithUser(p.API, args.UserId)
@@ -80,6 +173,10 @@ func (p *Plugin) executeCommandLabelAdd(args *model.CommandArgs) *model.CommandR
 		return p.responsef(args, err.Error())
 	}
 
+	if err = p.checkLabelsLimit(labels, []string{labelName}); err != nil {
+		return p.responsef(args, err.Error())
+	}
+
 	_, err = labels.addLabel(labelName)
 	if err != nil {
 		return p.responsef(args, err.Error())
@@ -105,19 +202,24 @@ func (p *Plugin) executeCommandLabelRename(args *model.CommandArgs) *model.Comma
 		return p.responsef(args, err.Error())
 	}
 
-	lfrom := labels.getLabelByName(from)
-	if lfrom == nil {
+	fromID, err := labels.getIDFromName(from)
+	if err != nil {
 		return p.responsef(args, fmt.Sprintf("Label `%v` does not exist", from))
 	}
 
-	// if the "to" label already exists, alert the user with options
-	lto := labels.getLabelByName(to)
-	if lto != nil {
+	// if the "to" label already exists, alert the user with options.  A
+	// label can be renamed to change the case of its name
+	toID, err := labels.getIDFromName(to)
+	if err == nil && (toID != fromID || labels.ByID[fromID].Name == to) {
 		return p.responsef(args, fmt.Sprintf("Cannot rename Label `%v` to `%v`. Label already exists. Please choose a different label name", from, to))
 	}
 
-	lfrom.Name = to
-	err = labels.add(lfrom.ID, lfrom)
+	// renaming a label also renames the labels nested under it and creates
+	// its missing parents
+	if err = p.checkLabelsLimit(labels, []string{getLabelParentName(to)}); err != nil {
+		return p.responsef(args, err.Error())
+	}
+	err = labels.moveLabel(fromID, to)
 	if err != nil {
 		return p.responsef(args, err.Error())
 	}
@@ -127,6 +229,181 @@ func (p *Plugin) executeCommandLabelRename(args *model.CommandArgs) *model.Comma
 	return p.responsef(args, fmt.Sprint(text))
 }
 
+// executeCommandLabelMove moves a label and the labels nested under it to a
+// new parent label.  A parent of / moves the label to the top level
+func (p *Plugin) executeCommandLabelMove(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+	if len(subCommand) != 5 {
+		return p.responsef(args, "Please specify a label name and a new parent label%v", getHelp(labelCommandText))
+	}
+
+	from := subCommand[3]
+	parent := strings.Trim(subCommand[4], labelPathSeparator)
+
+	labels, err := NewLabelsWithUser(p.API, args.UserId).getLabels()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	fromID, err := labels.getIDFromName(from)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	to := getLabelLeafName(from)
+	if parent != "" {
+		to = parent + labelPathSeparator + to
+	}
+	if labelNamesEqual(to, from) {
+		return p.responsef(args, "Label `%v` is already there", from)
+	}
+
+	if err = p.checkLabelsLimit(labels, []string{parent}); err != nil {
+		return p.responsef(args, err.Error())
+	}
+	err = labels.moveLabel(fromID, to)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	return p.responsef(args, "Moved label from `%v` to `%v`", from, to)
+}
+
+// executeCommandLabelMerge merges one or more labels into another label
+func (p *Plugin) executeCommandLabelMerge(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+	if len(subCommand) < 5 {
+		return p.responsef(args, "Please specify the labels to merge and the label to merge them into%v", getHelp(labelCommandText))
+	}
+
+	from := subCommand[3 : len(subCommand)-1]
+	into := subCommand[len(subCommand)-1]
+
+	labels, err := NewLabelsWithUser(p.API, args.UserId).getLabels()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	fromIDs, intoID, err := labels.getMergeIDs(from, into)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	changed, err := p.mergeLabels(args.UserId, labels, fromIDs, intoID)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	return p.responsef(args, "Merged labels:%s into `%v`. Changed %v bookmarks",
+		getCodeBlockedLabels(append([]string{}, from...)), into, changed)
+}
+
+// executeCommandLabelBulk adds labels to, or strips labels from, all
+// bookmarks matching a query.  Without the --force flag only the number of
+// bookmarks that would change is shown
+func (p *Plugin) executeCommandLabelBulk(args *model.CommandArgs, apply bool) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+
+	queryFlag := bulkFlagFrom
+	if apply {
+		queryFlag = bulkFlagTo
+	}
+	options, err := parseBulkLabelArgs(subCommand[3:], queryFlag)
+	if err != nil {
+		return p.responsef(args, "%s%v", err.Error(), getHelp(labelCommandText))
+	}
+	filters, err := parseBookmarksQuery(options.query)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	if filters.Due != "" {
+		filters.Now = p.getNow(args.UserId)
+	}
+
+	labels, err := NewLabelsWithUser(p.API, args.UserId).getLabels()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	var missing []string
+	for _, name := range options.names {
+		if labels.getLabelByName(name) == nil {
+			missing = append(missing, name)
+		}
+	}
+	if !apply && len(missing) != 0 {
+		return p.responsef(args, "Label: `%v` does not exist", missing[0])
+	}
+
+	bmarks, err := NewBookmarksWithUser(p.API, args.UserId).getBookmarks()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	if bmarks == nil || len(bmarks.ByID) == 0 {
+		return p.responsef(args, "You do not have any saved bookmarks")
+	}
+	matched, err := bmarks.applyFilters(filters)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	// new labels are only created when the change is applied
+	if options.force {
+		if err = p.checkLabelsLimit(labels, missing); err != nil {
+			return p.responsef(args, err.Error())
+		}
+		for _, name := range missing {
+			if _, err = labels.addLabel(name); err != nil {
+				return p.responsef(args, err.Error())
+			}
+		}
+	}
+	var ids []string
+	for _, name := range options.names {
+		if id, idErr := labels.getIDFromName(name); idErr == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	var changed []*Bookmark
+	for _, bmark := range matched.ByID {
+		if apply && (len(missing) != 0 || len(subtractIDs(ids, bmark.getLabelIDs())) != 0) {
+			changed = append(changed, bmark)
+		}
+		if !apply && len(subtractIDs(bmark.getLabelIDs(), ids)) != len(bmark.getLabelIDs()) {
+			changed = append(changed, bmark)
+		}
+	}
+
+	names := getCodeBlockedLabels(append([]string{}, options.names...))
+	if !options.force {
+		action := "Adding labels:%s would change %v of them"
+		if !apply {
+			action = "Removing labels:%s would change %v of them"
+		}
+		return p.responsef(args, "The query matches %v bookmarks. "+action+". Use the --force flag to apply the change.",
+			len(matched.ByID), names, len(changed))
+	}
+
+	for _, bmark := range changed {
+		if apply {
+			bmark.updateLabelIDs(append(bmark.getLabelIDs(), subtractIDs(ids, bmark.getLabelIDs())...))
+		} else {
+			bmark.updateLabelIDs(subtractIDs(bmark.getLabelIDs(), ids))
+		}
+	}
+	if len(changed) != 0 {
+		if err = bmarks.storeBookmarks(); err != nil {
+			return p.responsef(args, err.Error())
+		}
+	}
+
+	if apply {
+		return p.responsef(args, "Added labels:%s to %v bookmarks", names, len(changed))
+	}
+	return p.responsef(args, "Removed labels:%s from %v bookmarks", names, len(changed))
+}
+
 // executeCommandLabelRemove removes a given bookmark from the store
 func (p *Plugin) executeCommandLabelRemove(args *model.CommandArgs) *model.CommandResponse {
 	subCommand := strings.Fields(args.Command)
@@ -150,6 +427,25 @@ func (p *Plugin) executeCommandLabelRemove(args *model.CommandArgs) *model.Comma
 	if err != nil {
 		return p.responsef(args, err.Error())
 	}
+	if len(labels.getChildIDs(labelID)) != 0 {
+		return p.responsef(args, "Label `%v` has nested labels. Remove or move the nested labels first", labelName)
+	}
+
+	// rules keep adding the label, so they are removed by the user first
+	rules, err := NewRulesWithUser(p.API, args.UserId).getRules()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	if ruleIDs := rules.getRuleIDsWithLabelID(labelID); len(ruleIDs) != 0 {
+		var conditions []string
+		for _, ruleID := range ruleIDs {
+			conditions = append(conditions, p.getRuleConditionText(rules.get(ruleID)))
+		}
+		return p.responsef(args, "Label `%v` is used by the labeling rules for %s. Remove the rules with `/bookmarks rule remove` first",
+			labelName, strings.Join(conditions, ", "))
+	}
+	removed := *labels.ByID[labelID]
+	removed.ID = labelID
 	b := NewBookmarksWithUser(p.API, args.UserId)
 	bmarks, err := b.getBookmarks()
 	if err != nil {
@@ -161,13 +457,15 @@ func (p *Plugin) executeCommandLabelRemove(args *model.CommandArgs) *model.Comma
 		return p.responsef(args, "Unable to parse options, %s", err)
 	}
 
+	var strippedIDs []string
 	if bmarks != nil {
 		// check to see if any bookmarks currently have the label
-		bmarks, err = bmarks.getBookmarksWithLabelID(labelID)
+		var bmarksWithLabel *Bookmarks
+		bmarksWithLabel, err = bmarks.getBookmarksWithLabelID(labelID)
 		if err != nil {
 			return p.responsef(args, err.Error())
 		}
-		numBmarksWithLabel := len(bmarks.ByID)
+		numBmarksWithLabel := len(bmarksWithLabel.ByID)
 		if numBmarksWithLabel != 0 && !options.force {
 			return p.responsef(
 				args,
@@ -177,11 +475,12 @@ func (p *Plugin) executeCommandLabelRemove(args *model.CommandArgs) *model.Comma
 		}
 
 		// delete label from bookmarks
-		for _, bmark := range bmarks.ByID {
+		for _, bmark := range bmarksWithLabel.ByID {
 			err = bmarks.deleteLabel(bmark.PostID, labelID)
 			if err != nil {
 				return p.responsef(args, err.Error())
 			}
+			strippedIDs = append(strippedIDs, bmark.PostID)
 		}
 	}
 
@@ -191,16 +490,26 @@ func (p *Plugin) executeCommandLabelRemove(args *model.CommandArgs) *model.Comma
 		return p.responsef(args, err.Error())
 	}
 
+	op := p.journalOperation(args.UserId, &Operation{
+		Type:        opRemoveLabel,
+		Label:       &removed,
+		BookmarkIDs: strippedIDs,
+	})
+
 	text := "Removed label: "
 	text += fmt.Sprintf("`%v`", labelName)
-	return p.responsef(args, fmt.Sprint(text))
+	return p.responseWithAttachments(args, fmt.Sprint(text), getUndoAttachments(op))
 }
 
 func (p *Plugin) executeCommandLabelView(args *model.CommandArgs) *model.CommandResponse {
 	subCommand := strings.Fields(args.Command)
 
-	if len(subCommand) != 3 {
-		return p.responsef(args, "view subcommand takes no arguments%v", getHelp(labelCommandText))
+	options, err := parseLabelViewArgs(subCommand)
+	if err != nil {
+		return p.responsef(args, "Unable to parse options, %s", err)
+	}
+	if !isValidLabelSort(options.sort) {
+		return p.responsef(args, "Unknown sort order `%v`. Sort labels by `%v`, `%v` or `%v`", options.sort, labelSortName, labelSortCount, labelSortUsed)
 	}
 
 	l := NewLabelsWithUser(p.API, args.UserId)
@@ -212,11 +521,197 @@ func (p *Plugin) executeCommandLabelView(args *model.CommandArgs) *model.Command
 		return p.responsef(args, "You do not have any saved labels")
 	}
 
+	bmarks, err := NewBookmarksWithUser(p.API, args.UserId).getBookmarks()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	stats := bmarks.getLabelStats()
 	text := "#### Labels List\n"
-	for _, label := range labels.ByID {
-		v := fmt.Sprintf("`%s`\n", label.Name)
-		text += v
+	text += labels.getLabelTreeText(stats, options.sort, p.getUserLocation(args.UserId))
+	if len(labels.getDuplicateLabelGroups(stats)) != 0 {
+		text += "\nSome labels only differ by case or spaces. Use `/bookmarks label dedupe` to merge them\n"
 	}
 
 	return p.responsef(args, fmt.Sprint(text))
 }
+
+// executeCommandLabelDedupe merges labels whose names only differ by case or
+// surrounding whitespace.  Without the --force flag the labels are only
+// listed
+func (p *Plugin) executeCommandLabelDedupe(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+
+	options, err := parseLabelRemoveArgs(subCommand)
+	if err != nil {
+		return p.responsef(args, "Unable to parse options, %s", err)
+	}
+
+	labels, err := NewLabelsWithUser(p.API, args.UserId).getLabels()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	bmarks, err := NewBookmarksWithUser(p.API, args.UserId).getBookmarks()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	groups := labels.getDuplicateLabelGroups(bmarks.getLabelStats())
+	if len(groups) == 0 {
+		return p.responsef(args, "You do not have any duplicate labels")
+	}
+
+	merges := make(map[string]string)
+	text := ""
+	for _, group := range groups {
+		into := labels.ByID[group[0]].Name
+		var from []string
+		var nested []string
+		for _, id := range group[1:] {
+			from = append(from, labels.ByID[id].Name)
+			if len(labels.getChildIDs(id)) != 0 {
+				nested = append(nested, labels.ByID[id].Name)
+			}
+		}
+
+		if len(nested) != 0 {
+			text += fmt.Sprintf("* Cannot merge%s into `%v`. Merge or move the labels nested under%s first\n",
+				getCodeBlockedLabels(from), into, getCodeBlockedLabels(nested))
+			continue
+		}
+		for _, id := range group[1:] {
+			merges[id] = group[0]
+		}
+		text += fmt.Sprintf("*%s into `%v`\n", getCodeBlockedLabels(from), into)
+	}
+
+	if !options.force || len(merges) == 0 {
+		return p.responsef(args, "#### Duplicate Labels\nThese labels only differ by case or spaces and can be merged:\n%sUse the --force flag to merge them.", text)
+	}
+
+	changed, err := p.mergeLabelsInto(args.UserId, labels, merges)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	return p.responsef(args, "#### Merged Duplicate Labels\n%sChanged %v bookmarks", text, changed)
+}
+
+// executeCommandLabelPrune deletes the labels that are not used by any
+// bookmark.  Without the --force flag the labels are only listed
+func (p *Plugin) executeCommandLabelPrune(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+
+	options, err := parseLabelRemoveArgs(subCommand)
+	if err != nil {
+		return p.responsef(args, "Unable to parse options, %s", err)
+	}
+
+	labels, err := NewLabelsWithUser(p.API, args.UserId).getLabels()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	bmarks, err := NewBookmarksWithUser(p.API, args.UserId).getBookmarks()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	rules, err := NewRulesWithUser(p.API, args.UserId).getRules()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	unused := labels.getUnusedLabelIDs(bmarks.getLabelStats(), rules.getLabelIDs())
+	if len(unused) == 0 {
+		return p.responsef(args, "All of your labels are used by bookmarks or labeling rules")
+	}
+
+	var names []string
+	for _, id := range unused {
+		names = append(names, labels.ByID[id].Name)
+	}
+
+	if !options.force {
+		return p.responsef(args, "There are %v labels not used by any bookmarks or labeling rules:%s. Use the --force flag to remove them.",
+			len(unused), getCodeBlockedLabels(names))
+	}
+
+	for _, id := range unused {
+		delete(labels.ByID, id)
+	}
+	if err = labels.storeLabels(); err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	return p.responsef(args, "Removed %v unused labels:%s", len(unused), getCodeBlockedLabels(names))
+}
+
+// getLabelText returns the label name followed by its color, usage stats and
+// description.  Times are shown in the timezone loc
+func getLabelText(name string, label *Label, stats *LabelStats, loc *time.Location) string {
+	text := fmt.Sprintf("`%s`", name)
+	if label.Color != "" {
+		text += fmt.Sprintf(" `%s`", label.Color)
+	}
+	text += " " + getLabelStatsText(stats, loc)
+	if label.Description != "" {
+		text += " - " + label.Description
+	}
+	return text
+}
+
+// executeCommandLabelColor sets or clears the color of a label
+func (p *Plugin) executeCommandLabelColor(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+	if len(subCommand) != 5 {
+		return p.responsef(args, "Please specify a label name and a color%v", getHelp(labelCommandText))
+	}
+
+	labelName := subCommand[3]
+	color := subCommand[4]
+
+	labels, err := NewLabelsWithUser(p.API, args.UserId).getLabels()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	label, err := labels.setColor(labelName, color)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	if label.Color == "" {
+		return p.responsef(args, "Cleared the color of label `%v`", labelName)
+	}
+	return p.responsef(args, "Set the color of label `%v` to `%v`", labelName, label.Color)
+}
+
+// executeCommandLabelDescribe sets or clears the description of a label
+func (p *Plugin) executeCommandLabelDescribe(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+	if len(subCommand) < 4 {
+		return p.responsef(args, "Please specify a label name%v", getHelp(labelCommandText))
+	}
+
+	labelName := subCommand[3]
+	description := strings.Join(subCommand[4:], " ")
+
+	labels, err := NewLabelsWithUser(p.API, args.UserId).getLabels()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	if err = p.checkDescriptionLength(description); err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	_, err = labels.setDescription(labelName, description)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	if description == "" {
+		return p.responsef(args, "Cleared the description of label `%v`", labelName)
+	}
+	return p.responsef(args, "Set the description of label `%v` to: %v", labelName, description)
+}
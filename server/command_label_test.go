@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/mattermost/mattermost-server/v5/plugin"
@@ -33,6 +34,242 @@ func getExecuteCommandTestLabels() *Labels {
 	return labels
 }
 
+func getExecuteCommandTestColoredLabels() *Labels {
+	labels := getExecuteCommandTestLabels()
+	labels.ByID["UUID1"].Color = "#d24b4e"
+	labels.ByID["UUID1"].Description = "Things to read later"
+	return labels
+}
+
+func getExecuteCommandTestNestedLabels() *Labels {
+	labels := getExecuteCommandTestLabels()
+	labels.ByID["UUID4"] = &Label{Name: "project", ID: "UUID4"}
+	labels.ByID["UUID5"] = &Label{Name: "project/alpha", ID: "UUID5", ParentID: "UUID4"}
+	labels.ByID["UUID6"] = &Label{Name: "project/alpha/bugs", ID: "UUID6", ParentID: "UUID5"}
+	labels.ByID["UUID7"] = &Label{Name: "project/alpha/docs", ID: "UUID7", ParentID: "UUID5"}
+	labels.ByID["UUID8"] = &Label{Name: "project/beta", ID: "UUID8", ParentID: "UUID4"}
+	return labels
+}
+
+func getExecuteCommandTestStatsBookmarks() *Bookmarks {
+	day := int64(24 * 60 * 60 * 1000)
+	jan1 := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)
+
+	bmarks := NewBookmarksWithUser(nil, UserID)
+	bmarks.ByID[p1ID] = &Bookmark{
+		PostID:   p1ID,
+		LabelIDs: []string{"UUID1", "UUID2"},
+		History: []*BookmarkEvent{
+			{Type: eventCreated, CreateAt: jan1, LabelIDs: []string{"UUID2"}},
+			{Type: eventLabelsAdded, CreateAt: jan1 + 4*day, LabelIDs: []string{"UUID1"}},
+		},
+	}
+	bmarks.ByID[p2ID] = &Bookmark{
+		PostID:     p2ID,
+		LabelIDs:   []string{"UUID2"},
+		ModifiedAt: jan1 + 2*day,
+	}
+	bmarks.ByID[p3ID] = &Bookmark{
+		PostID:     p3ID,
+		ModifiedAt: jan1 + 9*day,
+	}
+	return bmarks
+}
+
+func getExecuteCommandTestStatsBookmarksAllLabels() *Bookmarks {
+	bmarks := getExecuteCommandTestStatsBookmarks()
+	bmarks.ByID[p3ID].LabelIDs = []string{"UUID3"}
+	return bmarks
+}
+
+func getExecuteCommandTestDuplicateLabels() *Labels {
+	labels := getExecuteCommandTestLabels()
+	labels.ByID["UUID4"] = &Label{Name: "Label1", ID: "UUID4"}
+	labels.ByID["UUID5"] = &Label{Name: "label1 ", ID: "UUID5"}
+	labels.ByID["UUID6"] = &Label{Name: "Work", ID: "UUID6"}
+	labels.ByID["UUID7"] = &Label{Name: "WORK", ID: "UUID7"}
+	return labels
+}
+
+func TestParseBulkLabelArgs(t *testing.T) {
+	tests := map[string]struct {
+		args        string
+		expected    bulkLabelOptions
+		expectedErr string
+	}{
+		"no labels": {
+			args:        "--to all",
+			expectedErr: "Please specify the labels",
+		},
+		"no query flag": {
+			args:        "label1 all",
+			expectedErr: "Please specify the bookmarks with --to <query>",
+		},
+		"labels and query": {
+			args:     "label1,label2 label3 --to label:label4 title:foo",
+			expected: bulkLabelOptions{names: []string{"label1", "label2", "label3"}, query: []string{"label:label4", "title:foo"}},
+		},
+		"force after the query": {
+			args:     "label1 --to all --force",
+			expected: bulkLabelOptions{names: []string{"label1"}, query: []string{"all"}, force: true},
+		},
+		"force before the query": {
+			args:     "label1 --force --to all",
+			expected: bulkLabelOptions{names: []string{"label1"}, query: []string{"all"}, force: true},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			options, err := parseBulkLabelArgs(strings.Fields(tt.args), bulkFlagTo)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, options)
+		})
+	}
+}
+
+func TestExecuteCommandLabelBulkStoresOnce(t *testing.T) {
+	api := makeAPIMock()
+	siteURL := "https://myhost.com"
+	api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+
+	jsonBmarks, err := json.Marshal(getExecuteCommandTestBookmarks())
+	require.Nil(t, err)
+	jsonLabels, err := json.Marshal(getExecuteCommandTestLabels())
+	require.Nil(t, err)
+	api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+	api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+	api.On("KVGet", getRulesKey(UserID)).Return(nil, nil)
+
+	store := mockBookmarksStore(api, UserID)
+	api.On("KVSet", getLabelsKey(UserID), mock.Anything).Return(nil)
+	api.On("SendEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+
+	p := makePlugin(api)
+	args := &model.CommandArgs{Command: "/bookmarks label apply label8,newlabel --to all --force", UserId: UserID}
+	_, appErr := p.ExecuteCommand(&plugin.Context{}, args)
+	require.Nil(t, appErr)
+
+	// the bookmarks are migrated from the single value layout, the bucket of
+	// the 4 bookmarks, the layout header and the labels are written once
+	api.AssertNumberOfCalls(t, "KVSet", 3)
+	assert.Equal(t, 1, store.headerWrites)
+	stored := store.getBookmarks()
+	require.Len(t, stored.ByID, 4)
+	for _, bmark := range stored.ByID {
+		assert.Contains(t, bmark.LabelIDs, "UUID3")
+		assert.Len(t, bmark.History, 1)
+	}
+	assert.Len(t, stored.ByID[p1ID].LabelIDs, 4)
+}
+
+func TestExecuteCommandLabelBulkDueInUserTimezone(t *testing.T) {
+	// one of the timezones is on a different day than UTC at any time
+	timezone := "Pacific/Kiritimati"
+	now := time.Now()
+	if loc, err := time.LoadLocation(timezone); err == nil && now.In(loc).Format(dueDateFormat) == now.UTC().Format(dueDateFormat) {
+		timezone = "Pacific/Pago_Pago"
+	}
+	loc, err := time.LoadLocation(timezone)
+	require.Nil(t, err)
+
+	bmarks := getExecuteCommandTestBookmarks()
+	bmarks.ByID[p1ID].Due = now.In(loc).Format(dueDateFormat)
+	jsonBmarks, err := json.Marshal(bmarks)
+	require.Nil(t, err)
+	jsonLabels, err := json.Marshal(getExecuteCommandTestLabels())
+	require.Nil(t, err)
+
+	api := makeAPIMock()
+	api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+	api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+	api.On("GetUser", UserID).Return(&model.User{Id: UserID, Timezone: model.StringMap{"useAutomaticTimezone": "false", "manualTimezone": timezone}}, nil)
+	var message string
+	api.On("SendEphemeralPost", UserID, mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+		message = args.Get(1).(*model.Post).Message
+	}).Return(&model.Post{})
+
+	p := makePlugin(api)
+	_, appErr := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{Command: "/bookmarks label apply label8 --to due:today", UserId: UserID})
+	require.Nil(t, appErr)
+	assert.Equal(t, "The query matches 1 bookmarks. Adding labels: `label8` would change 1 of them. Use the --force flag to apply the change.", message)
+}
+
+func TestExecuteCommandLabelUsedByRules(t *testing.T) {
+	tests := map[string]struct {
+		command  string
+		expected string
+	}{
+		"REMOVE is refused": {
+			command:  "/bookmarks label remove label8 --force",
+			expected: "Label `label8` is used by the labeling rules for \"deploy\". Remove the rules with `/bookmarks rule remove` first",
+		},
+		"PRUNE keeps the label": {
+			command:  "/bookmarks label prune --force",
+			expected: "All of your labels are used by bookmarks or labeling rules",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := makeAPIMock()
+			jsonBmarks, err := json.Marshal(getExecuteCommandTestStatsBookmarks())
+			require.Nil(t, err)
+			jsonLabels, err := json.Marshal(getExecuteCommandTestLabels())
+			require.Nil(t, err)
+			rules := getTestRules()
+			rules.ByID["keyword:deploy"] = &Rule{ID: "keyword:deploy", Type: ruleTypeKeyword, Value: "deploy", LabelIDs: []string{"UUID3"}}
+			jsonRules, err := json.Marshal(rules)
+			require.Nil(t, err)
+			api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+			api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+			api.On("KVGet", getRulesKey(UserID)).Return(jsonRules, nil)
+
+			var message string
+			api.On("SendEphemeralPost", UserID, mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				message = args.Get(1).(*model.Post).Message
+			}).Return(&model.Post{})
+
+			p := makePlugin(api)
+			_, appErr := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{Command: tt.command, UserId: UserID})
+			require.Nil(t, appErr)
+			assert.Equal(t, tt.expected, message)
+			api.AssertNotCalled(t, "KVSet", getLabelsKey(UserID), mock.Anything)
+		})
+	}
+}
+
+func TestNormalizeLabelColor(t *testing.T) {
+	tests := map[string]struct {
+		color       string
+		expected    string
+		expectedErr bool
+	}{
+		"empty clears the color":     {color: "", expected: ""},
+		"none clears the color":      {color: "None", expected: ""},
+		"named color":                {color: "teal", expected: "#2dbfbf"},
+		"six digit hex":              {color: "#1C58D9", expected: "#1c58d9"},
+		"three digit hex":            {color: "#abc", expected: "#abc"},
+		"hex without hash":           {color: "abc123", expected: "#abc123"},
+		"unknown color name":         {color: "mauve", expectedErr: true},
+		"hex with invalid character": {color: "#abcdeg", expectedErr: true},
+		"hex with wrong length":      {color: "#abcd", expectedErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			color, err := normalizeLabelColor(tt.color)
+			if tt.expectedErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, color)
+		})
+	}
+}
+
 func TestExecuteCommandLabel(t *testing.T) {
 	tests := map[string]struct {
 		commandArgs       *model.CommandArgs
@@ -163,6 +400,396 @@ func TestExecuteCommandLabel(t *testing.T) {
 			expectedMsgPrefix: "",
 			expectedContains:  []string{"#### Labels List", "label1", "label2"},
 		},
+		"VIEW Labels show color and description": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label view"},
+			labels:            getExecuteCommandTestColoredLabels(),
+			expectedMsgPrefix: "#### Labels List\n- `label1` `#d24b4e` (0 bookmarks) - Things to read later\n- `label2` (0 bookmarks)\n- `label8` (0 bookmarks)",
+			expectedContains:  nil,
+		},
+		"VIEW Nested labels are shown as a tree": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label view"},
+			labels:            getExecuteCommandTestNestedLabels(),
+			expectedMsgPrefix: "#### Labels List\n- `label1` (0 bookmarks)\n- `label2` (0 bookmarks)\n- `label8` (0 bookmarks)\n- `project` (0 bookmarks)\n  - `alpha` (0 bookmarks)\n    - `bugs` (0 bookmarks)\n    - `docs` (0 bookmarks)\n  - `beta` (0 bookmarks)",
+			expectedContains:  nil,
+		},
+		"VIEW Labels show usage stats": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label view"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestStatsBookmarks(),
+			expectedMsgPrefix: "#### Labels List\n- `label1` (1 bookmark, last used Jan 5, 2020 12:00 UTC)\n- `label2` (2 bookmarks, last used Jan 3, 2020 12:00 UTC)\n- `label8` (0 bookmarks)",
+			expectedContains:  nil,
+		},
+		"VIEW Labels sorted by count": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label view --sort count"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestStatsBookmarks(),
+			expectedMsgPrefix: "#### Labels List\n- `label2` (2 bookmarks, last used Jan 3, 2020 12:00 UTC)\n- `label1` (1 bookmark, last used Jan 5, 2020 12:00 UTC)\n- `label8` (0 bookmarks)",
+			expectedContains:  nil,
+		},
+		"VIEW Labels sorted by last used": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label view --sort used"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestStatsBookmarks(),
+			expectedMsgPrefix: "#### Labels List\n- `label1` (1 bookmark, last used Jan 5, 2020 12:00 UTC)\n- `label2` (2 bookmarks, last used Jan 3, 2020 12:00 UTC)\n- `label8` (0 bookmarks)",
+			expectedContains:  nil,
+		},
+		"VIEW Labels with unknown sort order": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label view --sort size"},
+			labels:            getExecuteCommandTestLabels(),
+			expectedMsgPrefix: "Unknown sort order `size`. Sort labels by `name`, `count` or `used`",
+			expectedContains:  nil,
+		},
+
+		// PRUNE
+		"PRUNE User lists unused labels": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label prune"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestStatsBookmarks(),
+			expectedMsgPrefix: "There are 1 labels not used by any bookmarks or labeling rules: `label8`. Use the --force flag to remove them.",
+			expectedContains:  nil,
+		},
+		"PRUNE Unused nested labels are listed with their parents": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label prune"},
+			labels:            getExecuteCommandTestNestedLabels(),
+			bookmarks:         getExecuteCommandTestStatsBookmarks(),
+			expectedMsgPrefix: "There are 6 labels not used by any bookmarks or labeling rules: `label8` `project` `project/alpha` `project/alpha/bugs` `project/alpha/docs` `project/beta`.",
+			expectedContains:  nil,
+		},
+		"PRUNE User removes unused labels": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label prune --force"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestStatsBookmarks(),
+			expectedMsgPrefix: "Removed 1 unused labels: `label8`",
+			expectedContains:  nil,
+		},
+		"PRUNE No unused labels": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label prune --force"},
+			labels:            getExecuteCommandTestColoredLabels(),
+			bookmarks:         getExecuteCommandTestStatsBookmarksAllLabels(),
+			expectedMsgPrefix: "All of your labels are used by bookmarks or labeling rules",
+			expectedContains:  nil,
+		},
+
+		// APPLY / STRIP
+		"APPLY User does not provide a query": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label apply label8"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestBookmarks(),
+			expectedMsgPrefix: "Please specify the bookmarks with --to <query>",
+			expectedContains:  nil,
+		},
+		"APPLY User provides an empty query": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label apply label8 --to --force"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestBookmarks(),
+			expectedMsgPrefix: "Please specify a query",
+			expectedContains:  nil,
+		},
+		"APPLY Preview the bookmarks that would change": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label apply label2,label8 --to label:label1"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestBookmarks(),
+			expectedMsgPrefix: "The query matches 2 bookmarks. Adding labels: `label2` `label8` would change 2 of them. Use the --force flag to apply the change.",
+			expectedContains:  nil,
+		},
+		"APPLY Preview with labels already on the bookmarks": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label apply label2 --to all"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestBookmarks(),
+			expectedMsgPrefix: "The query matches 4 bookmarks. Adding labels: `label2` would change 2 of them.",
+			expectedContains:  nil,
+		},
+		"APPLY Add labels to all bookmarks": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label apply label2 --to all --force"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestBookmarks(),
+			expectedMsgPrefix: "Added labels: `label2` to 2 bookmarks",
+			expectedContains:  nil,
+		},
+		"APPLY Add a new label to bookmarks matching a title": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label apply newlabel --to title:^Title[13] --force"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestBookmarks(),
+			expectedMsgPrefix: "Added labels: `newlabel` to 2 bookmarks",
+			expectedContains:  nil,
+		},
+		"STRIP Label does not exist": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label strip labeldoesnotexist --from all"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestBookmarks(),
+			expectedMsgPrefix: "Label: `labeldoesnotexist` does not exist",
+			expectedContains:  nil,
+		},
+		"STRIP Preview the bookmarks that would change": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label strip label1 --from Title2 - bookmarks"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestBookmarks(),
+			expectedMsgPrefix: "The query matches 1 bookmarks. Removing labels: `label1` would change 1 of them. Use the --force flag to apply the change.",
+			expectedContains:  nil,
+		},
+		"STRIP Remove labels from all bookmarks": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label strip label1,label2 --from all --force"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestBookmarks(),
+			expectedMsgPrefix: "Removed labels: `label1` `label2` from 2 bookmarks",
+			expectedContains:  nil,
+		},
+
+		// NAME VALIDATION
+		"ADD Label name differs only by case": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label add LABEL1"},
+			labels:            getExecuteCommandTestLabels(),
+			expectedMsgPrefix: "Label with name `label1` already exists",
+			expectedContains:  nil,
+		},
+		"ADD Multi-word label name": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label add read   later"},
+			labels:            getExecuteCommandTestLabels(),
+			expectedMsgPrefix: "Label names cannot contain spaces. You can only create one label at a time",
+			expectedContains:  nil,
+		},
+		"ADD Label name with a comma": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label add work,home"},
+			labels:            getExecuteCommandTestLabels(),
+			expectedMsgPrefix: "Label name `work,home` is not valid. Label names can only contain letters, numbers, `-`, `_`, `.` and `/` for nested labels",
+			expectedContains:  nil,
+		},
+		"ADD Reserved label name": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label add tfp"},
+			labels:            getExecuteCommandTestLabels(),
+			expectedMsgPrefix: "Label name `tfp` is reserved",
+			expectedContains:  nil,
+		},
+		"ADD Label name too long": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label add " + strings.Repeat("a", 65)},
+			labels:            getExecuteCommandTestLabels(),
+			expectedMsgPrefix: "Label name `" + strings.Repeat("a", 65) + "` is too long. Label names can have at most 64 characters",
+			expectedContains:  nil,
+		},
+		"RENAME User changes the case of a label": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label rename label1 Label1"},
+			labels:            getExecuteCommandTestLabels(),
+			expectedMsgPrefix: "Renamed label from `label1` to `Label1`",
+			expectedContains:  nil,
+		},
+		"RENAME User renames to a name differing only by case": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label rename label1 LABEL2"},
+			labels:            getExecuteCommandTestLabels(),
+			expectedMsgPrefix: "Cannot rename Label `label1` to `LABEL2`. Label already exists",
+			expectedContains:  nil,
+		},
+		"RENAME User renames to an invalid name": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label rename label1 a,b"},
+			labels:            getExecuteCommandTestLabels(),
+			expectedMsgPrefix: "Label name `a,b` is not valid",
+			expectedContains:  nil,
+		},
+		"VIEW Labels with duplicates offer to merge them": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label view"},
+			labels:            getExecuteCommandTestDuplicateLabels(),
+			expectedMsgPrefix: "",
+			expectedContains:  []string{"Some labels only differ by case or spaces. Use `/bookmarks label dedupe` to merge them"},
+		},
+
+		// DEDUPE
+		"DEDUPE User has no duplicate labels": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label dedupe"},
+			labels:            getExecuteCommandTestLabels(),
+			expectedMsgPrefix: "You do not have any duplicate labels",
+			expectedContains:  nil,
+		},
+		"DEDUPE User lists duplicate labels": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label dedupe"},
+			labels:            getExecuteCommandTestDuplicateLabels(),
+			bookmarks:         getExecuteCommandTestBookmarks(),
+			expectedMsgPrefix: "#### Duplicate Labels\nThese labels only differ by case or spaces and can be merged:\n* `Label1` `label1 ` into `label1`\n* `Work` into `WORK`\nUse the --force flag to merge them.",
+			expectedContains:  nil,
+		},
+		"DEDUPE User merges duplicate labels": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label dedupe --force"},
+			labels:            getExecuteCommandTestDuplicateLabels(),
+			bookmarks:         getExecuteCommandTestBookmarks(),
+			expectedMsgPrefix: "#### Merged Duplicate Labels\n* `Label1` `label1 ` into `label1`\n* `Work` into `WORK`\nChanged 0 bookmarks",
+			expectedContains:  nil,
+		},
+
+		// NESTED
+		"ADD User adds a nested label": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label add project/gamma/bugs"},
+			labels:            getExecuteCommandTestNestedLabels(),
+			expectedMsgPrefix: "Added Label: project/gamma/bugs",
+			expectedContains:  nil,
+		},
+		"ADD User adds a nested label with an empty part": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label add project//bugs"},
+			labels:            getExecuteCommandTestNestedLabels(),
+			expectedMsgPrefix: "Label name `project//bugs` is not valid",
+			expectedContains:  nil,
+		},
+		"RENAME User renames a label with nested labels": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label rename project/alpha project/archived"},
+			labels:            getExecuteCommandTestNestedLabels(),
+			expectedMsgPrefix: "Renamed label from `project/alpha` to `project/archived`",
+			expectedContains:  nil,
+		},
+		"RENAME User renames a label into its own nested label": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label rename project project/new"},
+			labels:            getExecuteCommandTestNestedLabels(),
+			expectedMsgPrefix: "Cannot move label `project` into its own nested label `project/new`",
+			expectedContains:  nil,
+		},
+		"MOVE User does not provide a parent": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label move project/alpha"},
+			labels:            getExecuteCommandTestNestedLabels(),
+			expectedMsgPrefix: "Please specify a label name and a new parent label",
+			expectedContains:  nil,
+		},
+		"MOVE User moves a label to another parent": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label move project/alpha label1"},
+			labels:            getExecuteCommandTestNestedLabels(),
+			expectedMsgPrefix: "Moved label from `project/alpha` to `label1/alpha`",
+			expectedContains:  nil,
+		},
+		"MOVE User moves a label to the top level": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label move project/alpha /"},
+			labels:            getExecuteCommandTestNestedLabels(),
+			expectedMsgPrefix: "Moved label from `project/alpha` to `alpha`",
+			expectedContains:  nil,
+		},
+		"MOVE User moves a label to its current parent": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label move project/alpha project"},
+			labels:            getExecuteCommandTestNestedLabels(),
+			expectedMsgPrefix: "Label `project/alpha` is already there",
+			expectedContains:  nil,
+		},
+		"REMOVE User tries to remove a label with nested labels": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label remove project/alpha"},
+			labels:            getExecuteCommandTestNestedLabels(),
+			expectedMsgPrefix: "Label `project/alpha` has nested labels. Remove or move the nested labels first",
+			expectedContains:  nil,
+		},
+
+		// MERGE
+		"MERGE User does not provide a target label": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label merge label1"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestBookmarks(),
+			expectedMsgPrefix: "Please specify the labels to merge and the label to merge them into",
+			expectedContains:  nil,
+		},
+		"MERGE Target label does not exist": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label merge label1 labeldoesnotexist"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestBookmarks(),
+			expectedMsgPrefix: "Label: `labeldoesnotexist` does not exist",
+			expectedContains:  nil,
+		},
+		"MERGE Source label does not exist": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label merge labeldoesnotexist label1"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestBookmarks(),
+			expectedMsgPrefix: "Label: `labeldoesnotexist` does not exist",
+			expectedContains:  nil,
+		},
+		"MERGE Label into itself": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label merge label1 label1"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestBookmarks(),
+			expectedMsgPrefix: "Cannot merge label `label1` into itself",
+			expectedContains:  nil,
+		},
+		"MERGE Label with nested labels": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label merge project/alpha label1"},
+			labels:            getExecuteCommandTestNestedLabels(),
+			bookmarks:         getExecuteCommandTestBookmarks(),
+			expectedMsgPrefix: "Label `project/alpha` has nested labels. Merge or move the nested labels first",
+			expectedContains:  nil,
+		},
+		"MERGE One label into another": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label merge label1 label2"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestBookmarks(),
+			expectedMsgPrefix: "Merged labels: `label1` into `label2`. Changed 2 bookmarks",
+			expectedContains:  nil,
+		},
+		"MERGE Several labels into another": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label merge label2 label1 label8"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestBookmarks(),
+			expectedMsgPrefix: "Merged labels: `label1` `label2` into `label8`. Changed 2 bookmarks",
+			expectedContains:  nil,
+		},
+		"MERGE Label without bookmarks": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label merge label8 label1"},
+			labels:            getExecuteCommandTestLabels(),
+			bookmarks:         getExecuteCommandTestBookmarks(),
+			expectedMsgPrefix: "Merged labels: `label8` into `label1`. Changed 0 bookmarks",
+			expectedContains:  nil,
+		},
+
+		// COLOR
+		"COLOR User does not provide a color": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label color label1"},
+			labels:            getExecuteCommandTestLabels(),
+			expectedMsgPrefix: "Please specify a label name and a color",
+			expectedContains:  nil,
+		},
+		"COLOR Label does not exist": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label color labeldoesnotexist red"},
+			labels:            getExecuteCommandTestLabels(),
+			expectedMsgPrefix: "Label: `labeldoesnotexist` does not exist",
+			expectedContains:  nil,
+		},
+		"COLOR Invalid color": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label color label1 #12345g"},
+			labels:            getExecuteCommandTestLabels(),
+			expectedMsgPrefix: "Invalid color `#12345g`",
+			expectedContains:  []string{"blue", "red"},
+		},
+		"COLOR Named color": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label color label1 Red"},
+			labels:            getExecuteCommandTestLabels(),
+			expectedMsgPrefix: "Set the color of label `label1` to `#d24b4e`",
+			expectedContains:  nil,
+		},
+		"COLOR Hex color": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label color label1 #ABC"},
+			labels:            getExecuteCommandTestLabels(),
+			expectedMsgPrefix: "Set the color of label `label1` to `#abc`",
+			expectedContains:  nil,
+		},
+		"COLOR Clear color": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label color label1 none"},
+			labels:            getExecuteCommandTestColoredLabels(),
+			expectedMsgPrefix: "Cleared the color of label `label1`",
+			expectedContains:  nil,
+		},
+
+		// DESCRIBE
+		"DESCRIBE User does not provide label name": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label describe"},
+			labels:            getExecuteCommandTestLabels(),
+			expectedMsgPrefix: "Please specify a label name",
+			expectedContains:  nil,
+		},
+		"DESCRIBE Label does not exist": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label describe labeldoesnotexist Some text"},
+			labels:            getExecuteCommandTestLabels(),
+			expectedMsgPrefix: "Label: `labeldoesnotexist` does not exist",
+			expectedContains:  nil,
+		},
+		"DESCRIBE Set description": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label describe label2 Posts about the   release"},
+			labels:            getExecuteCommandTestLabels(),
+			expectedMsgPrefix: "Set the description of label `label2` to: Posts about the release",
+			expectedContains:  nil,
+		},
+		"DESCRIBE Clear description": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks label describe label1"},
+			labels:            getExecuteCommandTestColoredLabels(),
+			expectedMsgPrefix: "Cleared the description of label `label1`",
+			expectedContains:  nil,
+		},
 	}
 	for name, tt := range tests {
 		api := makeAPIMock()
@@ -176,7 +803,10 @@ func TestExecuteCommandLabel(t *testing.T) {
 
 		bb, err := json.Marshal(tt.labels)
 		api.On("KVGet", getLabelsKey(tt.commandArgs.UserId)).Return(bb, nil)
+		api.On("KVGet", getJournalKey(tt.commandArgs.UserId)).Return(nil, nil)
+		api.On("KVGet", getRulesKey(tt.commandArgs.UserId)).Return(nil, nil)
 		api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+		api.On("GetUser", tt.commandArgs.UserId).Return(&model.User{Timezone: model.StringMap{"useAutomaticTimezone": "false", "manualTimezone": "UTC"}}, nil)
 
 		t.Run(name, func(t *testing.T) {
 			assert.Nil(t, err)
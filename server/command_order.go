@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/spf13/pflag"
+)
+
+const (
+	flagMoveTop    = "top"
+	flagMoveBefore = "before"
+)
+
+type moveBookmarkOptions struct {
+	top    bool
+	before string
+}
+
+func getMoveBookmarkFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("move bookmarks", pflag.ContinueOnError)
+	flagSet.Bool(flagMoveTop, false, "Move the bookmark to the top")
+	flagSet.String(flagMoveBefore, "", "Move the bookmark before another bookmark")
+
+	return flagSet
+}
+
+func parseMoveBookmarkArgs(args []string) (moveBookmarkOptions, error) {
+	var options moveBookmarkOptions
+
+	moveBookmarkFlagSet := getMoveBookmarkFlagSet()
+	err := moveBookmarkFlagSet.Parse(args)
+	if err != nil {
+		return options, err
+	}
+
+	options.top, err = moveBookmarkFlagSet.GetBool(flagMoveTop)
+	if err != nil {
+		return options, err
+	}
+
+	options.before, err = moveBookmarkFlagSet.GetString(flagMoveBefore)
+	if err != nil {
+		return options, err
+	}
+
+	return options, nil
+}
+
+// executeCommandMove moves a bookmark in the manual order
+func (p *Plugin) executeCommandMove(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+	subCommand = subCommand[2:]
+
+	if len(subCommand) < 2 || strings.HasPrefix(subCommand[0], "--") {
+		return p.responsef(args, "Please specify a post_id and where to move it%v", getHelp(orderCommandText))
+	}
+
+	options, err := parseMoveBookmarkArgs(subCommand)
+	if err != nil {
+		return p.responsef(args, "Unable to parse options, %s", err)
+	}
+	if options.top == (options.before != "") {
+		return p.responsef(args, "Please specify either `--%s` or `--%s <post_id>`", flagMoveTop, flagMoveBefore)
+	}
+
+	bmarks, err := NewBookmarksWithUser(p.API, args.UserId).getBookmarks()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	if bmarks == nil {
+		return p.responsef(args, "You do not have any saved bookmarks")
+	}
+
+	bmarkID := p.getPostIDFromLink(subCommand[0])
+	beforeID := ""
+	if options.before != "" {
+		beforeID = p.getPostIDFromLink(options.before)
+	}
+	if err = bmarks.moveBookmark(bmarkID, beforeID); err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	text, err := p.getBmarkTextOneLine(bmarks.get(bmarkID), nil)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	return p.responsef(args, "Moved bookmark: %sView bookmarks in this order with `/bookmarks view --sort manual`", text)
+}
+
+// executeCommandPriority sets the priority of a bookmark
+func (p *Plugin) executeCommandPriority(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+
+	if len(subCommand) != 4 {
+		return p.responsef(args, "Please specify a post_id and a priority%v", getHelp(orderCommandText))
+	}
+
+	priority := subCommand[3]
+	if err := validatePriority(priority); err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	bmark, err := p.updateBookmark(args.UserId, p.getPostIDFromLink(subCommand[2]), func(bmark *Bookmark) {
+		bmark.setPriority(priority)
+	})
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	text, err := p.getBmarkTextOneLine(bmark, nil)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	return p.responsef(args, "Set priority to `%s`: %s", priority, text)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// executeCommandQuota shows a user their usage of the limits, or lists the
+// users near their limits to system admins
+func (p *Plugin) executeCommandQuota(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+
+	if len(subCommand) == 2 {
+		return p.executeCommandQuotaView(args)
+	}
+
+	switch subCommand[2] {
+	case "report":
+		return p.executeCommandQuotaReport(args)
+	case "help":
+		return p.responsef(args, getHelp(quotaCommandText))
+	default:
+		return p.responsef(args, "Unknown command: "+args.Command)
+	}
+}
+
+// executeCommandQuotaView shows a user how many bookmarks and labels they
+// have and the limits
+func (p *Plugin) executeCommandQuotaView(args *model.CommandArgs) *model.CommandResponse {
+	usage, err := p.getQuotaUsage(args.UserId)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	config := p.getConfiguration()
+	text := "#### Your Limits\n"
+	text += "| | Used | Limit |\n|:--|--:|--:|\n"
+	text += fmt.Sprintf("| Bookmarks | %v | %v |\n", usage.Bookmarks, formatLimit(config.getMaxBookmarks()))
+	text += fmt.Sprintf("| Labels | %v | %v |\n", usage.Labels, config.getMaxLabels())
+	text += fmt.Sprintf("\nTitles can be at most %v characters long and label descriptions %v characters long\n", config.getMaxTitleLength(), config.getMaxDescriptionLength())
+	return p.responsef(args, "%s", text)
+}
+
+// executeCommandQuotaReport lists the users near their limits.  Only system
+// admins can view the report
+func (p *Plugin) executeCommandQuotaReport(args *model.CommandArgs) *model.CommandResponse {
+	if !p.API.HasPermissionTo(args.UserId, model.PERMISSION_MANAGE_SYSTEM) {
+		return p.responsef(args, "Only system admins can view the quota report")
+	}
+
+	near, err := p.getUsersNearLimits()
+	if err != nil {
+		return p.responsef(args, "Unable to get the quota report, %s", err)
+	}
+	if len(near) == 0 {
+		return p.responsef(args, "No users have %v%% or more of their bookmark or label limits", nearLimitPercent)
+	}
+
+	config := p.getConfiguration()
+	text := "#### Users Near Their Limits\n"
+	text += fmt.Sprintf("Users with %v%% or more of their bookmark or label limits\n\n", nearLimitPercent)
+	text += "| User | Bookmarks | Labels |\n|:--|--:|--:|\n"
+	for _, usage := range near {
+		name := usage.UserID
+		if user, appErr := p.API.GetUser(usage.UserID); appErr == nil {
+			name = "@" + user.Username
+		}
+		text += fmt.Sprintf("| %s | %v / %v | %v / %v |\n", name, usage.Bookmarks, formatLimit(config.getMaxBookmarks()), usage.Labels, config.getMaxLabels())
+	}
+	return p.responsef(args, "%s", text)
+}
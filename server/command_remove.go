@@ -23,7 +23,7 @@ func (p *Plugin) executeCommandRemove(args *model.CommandArgs) *model.CommandRes
 	}
 
 	b := NewBookmarksWithUser(p.API, args.UserId)
-	bmarks, err := b.getBookmarks()
+	bmarks, err := b.getBookmarksIndex()
 	if err != nil {
 		return p.responsef(args, err.Error())
 	}
@@ -37,11 +37,14 @@ func (p *Plugin) executeCommandRemove(args *model.CommandArgs) *model.CommandRes
 		return p.responsef(args, "Unable to get labels for user, %s", err)
 	}
 
+	var removed []*Bookmark
+	var failed error
 	for _, id := range bookmarkIDs {
 		bookmarkID := p.getPostIDFromLink(id)
 		bmark, err := bmarks.getBookmark(bookmarkID)
 		if err != nil {
-			return p.responsef(args, err.Error())
+			failed = err
+			break
 		}
 
 		var labelNames []string
@@ -52,16 +55,32 @@ func (p *Plugin) executeCommandRemove(args *model.CommandArgs) *model.CommandRes
 
 		newText, err := p.getBmarkTextOneLine(bmark, labelNames)
 		if err != nil {
-			return p.responsef(args, err.Error())
+			failed = err
+			break
 		}
 
 		_, err = bmarks.deleteBookmark(bookmarkID)
 		if err != nil {
-			return p.responsef(args, err.Error())
+			failed = err
+			break
 		}
+		removed = append(removed, bmark)
 
 		text += newText
 	}
+	if len(removed) == 0 {
+		return p.responsef(args, failed.Error())
+	}
+
+	// bookmarks removed before a failure are journaled too, so they can be
+	// restored with undo
+	op := p.journalOperation(args.UserId, &Operation{
+		Type:      opRemoveBookmarks,
+		Bookmarks: removed,
+	})
+	if failed != nil {
+		text += fmt.Sprintf("\nUnable to remove the other bookmarks: %s\n", failed.Error())
+	}
 
-	return p.responsef(args, fmt.Sprint(text))
+	return p.responseWithAttachments(args, fmt.Sprint(text), getUndoAttachments(op))
 }
@@ -33,15 +33,15 @@ func TestExecuteCommandRemove(t *testing.T) {
 			expectedContains:  nil,
 		},
 		"User has bmarks tries to delete bookmark that doesnt exist": {
-			commandArgs:       &model.CommandArgs{Command: fmt.Sprintf("/bookmarks remove %v", PostIDDoesNotExist)},
+			commandArgs:       &model.CommandArgs{Command: fmt.Sprintf("/bookmarks remove %v", PostIDDoesNotExistxxxxxxxx)},
 			bookmarks:         getExecuteCommandTestBookmarks(),
-			expectedMsgPrefix: strings.TrimSpace(fmt.Sprintf("Bookmark `%v` does not exist", PostIDDoesNotExist)),
+			expectedMsgPrefix: strings.TrimSpace(fmt.Sprintf("Bookmark `%v` does not exist", PostIDDoesNotExistxxxxxxxx)),
 			expectedContains:  nil,
 		},
 		"User successfully deletes 1 bookmark": {
 			commandArgs:       &model.CommandArgs{Command: fmt.Sprintf("/bookmarks remove %v", PostIDExists)},
 			bookmarks:         getExecuteCommandTestBookmarks(),
-			expectedMsgPrefix: strings.TrimSpace("Removed bookmark: [:link:](https://myhost.com/_redirect/pl/ID2) `label1` `label2` **_Title2 - "),
+			expectedMsgPrefix: strings.TrimSpace("Removed bookmark: [:link:](https://myhost.com/_redirect/pl/ID2xxxxxxxxxxxxxxxxxxxxxxx) `label1` `label2` **_Title2 - "),
 			expectedContains:  nil,
 		},
 		"User successfully deletes 3 bookmark": {
@@ -50,9 +50,9 @@ func TestExecuteCommandRemove(t *testing.T) {
 			expectedMsgPrefix: "",
 			expectedContains: []string{
 				"Removed bookmarks:",
-				"[:link:](https://myhost.com/_redirect/pl/ID1) `label1` `label2` **_Title1 - New Bookmark - times are zero",
-				"[:link:](https://myhost.com/_redirect/pl/ID2) `label1` `label2` **_Title2 - bookmarks initialized. Times created and same",
-				"[:link:](https://myhost.com/_redirect/pl/ID3) **_Title3 - bookmarks already updated once_**",
+				"[:link:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx) `label1` `label2` **_Title1 - New Bookmark - times are zero",
+				"[:link:](https://myhost.com/_redirect/pl/ID2xxxxxxxxxxxxxxxxxxxxxxx) `label1` `label2` **_Title2 - bookmarks initialized. Times created and same",
+				"[:link:](https://myhost.com/_redirect/pl/ID3xxxxxxxxxxxxxxxxxxxxxxx) **_Title3 - bookmarks already updated once_**",
 			},
 		},
 	}
@@ -60,7 +60,7 @@ func TestExecuteCommandRemove(t *testing.T) {
 		api := makeAPIMock()
 		tt.commandArgs.UserId = UserID
 		siteURL := "https://myhost.com"
-		api.On("GetPost", PostIDDoesNotExist).Return(nil, &model.AppError{Message: "An Error Occurred"})
+		api.On("GetPost", PostIDDoesNotExistxxxxxxxx).Return(nil, &model.AppError{Message: "An Error Occurred"})
 		api.On("GetPost", p1ID).Return(&model.Post{Message: "this is the post.Message"}, nil)
 		api.On("GetPost", p2ID).Return(&model.Post{Message: "this is the post.Message"}, nil)
 		api.On("GetPost", p3ID).Return(&model.Post{Message: "this is the post.Message"}, nil)
@@ -73,10 +73,13 @@ func TestExecuteCommandRemove(t *testing.T) {
 		jsonBmarks, err := json.Marshal(tt.bookmarks)
 		api.On("KVGet", getBookmarksKey(tt.commandArgs.UserId)).Return(jsonBmarks, nil)
 		api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+		api.On("KVDelete", mock.Anything).Return(nil)
 
 		labels := getExecuteCommandTestLabels()
 		jsonLabels, err := json.Marshal(labels)
 		api.On("KVGet", getLabelsKey(tt.commandArgs.UserId)).Return(jsonLabels, nil)
+		api.On("KVGet", getJournalKey(tt.commandArgs.UserId)).Return(nil, nil)
+		api.On("KVGet", getTrashKey(tt.commandArgs.UserId)).Return(nil, nil)
 
 		t.Run(name, func(t *testing.T) {
 			assert.Nil(t, err)
@@ -99,3 +102,44 @@ func TestExecuteCommandRemove(t *testing.T) {
 		})
 	}
 }
+
+func TestExecuteCommandRemoveJournalsPartialRemoval(t *testing.T) {
+	api := makeAPIMock()
+	siteURL := "https://myhost.com"
+	api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+	api.On("GetPost", mock.AnythingOfType("string")).Return(&model.Post{Message: "this is the post.Message"}, nil)
+
+	jsonBmarks, err := json.Marshal(getExecuteCommandTestBookmarks())
+	require.Nil(t, err)
+	api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+	jsonLabels, err := json.Marshal(getExecuteCommandTestLabels())
+	require.Nil(t, err)
+	api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+	api.On("KVGet", getJournalKey(UserID)).Return(nil, nil)
+	api.On("KVGet", getTrashKey(UserID)).Return(nil, nil)
+
+	journal := NewJournalWithUser(api, UserID)
+	api.On("KVSet", getJournalKey(UserID), mock.Anything).Run(func(args mock.Arguments) {
+		require.Nil(t, json.Unmarshal(args.Get(1).([]byte), journal))
+	}).Return(nil).Once()
+	api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+	api.On("KVDelete", mock.Anything).Return(nil)
+
+	api.On("SendEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+		post := args.Get(1).(*model.Post)
+		assert.Contains(t, post.Message, "Removed bookmarks:")
+		assert.Contains(t, post.Message, "**_Title1 - New Bookmark - times are zero")
+		assert.Contains(t, post.Message, fmt.Sprintf("Unable to remove the other bookmarks: Bookmark `%v` does not exist", PostIDDoesNotExistxxxxxxxx))
+		assert.Len(t, post.Attachments(), 1)
+	}).Once().Return(&model.Post{})
+
+	p := makePlugin(api)
+	args := &model.CommandArgs{Command: fmt.Sprintf("/bookmarks remove %v %v %v", p1ID, PostIDDoesNotExistxxxxxxxx, p2ID), UserId: UserID}
+	_, appErr := p.ExecuteCommand(&plugin.Context{}, args)
+	require.Nil(t, appErr)
+
+	// the removal before the failure can be undone
+	require.Len(t, journal.Operations, 1)
+	require.Len(t, journal.Operations[0].Bookmarks, 1)
+	assert.Equal(t, p1ID, journal.Operations[0].Bookmarks[0].PostID)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// executeCommandRule executes a rule sub-command
+func (p *Plugin) executeCommandRule(args *model.CommandArgs) *model.CommandResponse {
+	split := strings.Fields(args.Command)
+	if len(split) < 3 {
+		return p.responsef(args, "Missing rule sub-command. You can try %v", getHelp(ruleCommandText))
+	}
+
+	action := split[2]
+
+	switch action {
+	case "add":
+		return p.executeCommandRuleAdd(args)
+	case "list":
+		return p.executeCommandRuleList(args)
+	case "remove":
+		return p.executeCommandRuleRemove(args)
+	case "help":
+		return p.responsef(args, getHelp(ruleCommandText))
+
+	default:
+		return p.responsef(args, fmt.Sprintf("Unknown command: "+args.Command))
+	}
+}
+
+// executeCommandRuleAdd adds labels to the rule for a condition, creating the
+// rule and any new labels
+func (p *Plugin) executeCommandRuleAdd(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+	if len(subCommand) < 4 || strings.HasPrefix(subCommand[3], "--") {
+		return p.responsef(args, "Please specify a channel, user, hashtag or keyword%v", getHelp(ruleCommandText))
+	}
+
+	options, err := parseAddBookmarkArgs(subCommand)
+	if err != nil {
+		return p.responsef(args, "Unable to parse options, %s", err)
+	}
+	if len(options.labels) == 0 {
+		return p.responsef(args, "Please specify the labels to apply with --labels <labels>%v", getHelp(ruleCommandText))
+	}
+
+	ruleType, value, err := p.parseRuleCondition(args.TeamId, subCommand[3])
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	labels, err := NewLabelsWithUser(p.API, args.UserId).getLabels()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	rules, err := NewRulesWithUser(p.API, args.UserId).getRules()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	rule := rules.get(getRuleID(ruleType, value))
+	if rule == nil {
+		rule = &Rule{
+			ID:    getRuleID(ruleType, value),
+			Type:  ruleType,
+			Value: value,
+		}
+	}
+
+	if err = p.checkLabelsLimit(labels, options.labels); err != nil {
+		return p.responsef(args, err.Error())
+	}
+	for _, name := range options.labels {
+		// create new label in labels store and add ID to rule
+		if labels.getLabelByName(name) == nil {
+			if _, err = labels.addLabel(name); err != nil {
+				return p.responsef(args, "Unable to add new label for: %s, err=%s", name, err.Error())
+			}
+		}
+		var labelID string
+		labelID, err = labels.getIDFromName(name)
+		if err != nil {
+			return p.responsef(args, err.Error())
+		}
+		if len(subtractIDs([]string{labelID}, rule.LabelIDs)) != 0 {
+			rule.LabelIDs = append(rule.LabelIDs, labelID)
+		}
+	}
+
+	if err = rules.add(rule); err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	return p.responsef(args, "Added rule: %s", p.getRuleText(rule, labels))
+}
+
+// executeCommandRuleList lists the users labeling rules
+func (p *Plugin) executeCommandRuleList(args *model.CommandArgs) *model.CommandResponse {
+	rules, err := NewRulesWithUser(p.API, args.UserId).getRules()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	if len(rules.ByID) == 0 {
+		return p.responsef(args, "You do not have any labeling rules")
+	}
+
+	labels, err := NewLabelsWithUser(p.API, args.UserId).getLabels()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	text := "#### Labeling Rules\nNew bookmarks of posts matching a rule get its labels\n"
+	for _, id := range rules.sortedIDs() {
+		text += fmt.Sprintf("* %s\n", p.getRuleText(rules.get(id), labels))
+	}
+
+	return p.responsef(args, text)
+}
+
+// executeCommandRuleRemove removes the rule for a condition
+func (p *Plugin) executeCommandRuleRemove(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+	if len(subCommand) < 4 {
+		return p.responsef(args, "Please specify the channel, user, hashtag or keyword of the rule%v", getHelp(ruleCommandText))
+	}
+	condition := subCommand[3]
+
+	rules, err := NewRulesWithUser(p.API, args.UserId).getRules()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	rule := p.findRule(rules, args.TeamId, condition)
+	if rule == nil {
+		return p.responsef(args, "You do not have a rule for `%s`", condition)
+	}
+
+	if err = rules.delete(rule.ID); err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	return p.responsef(args, "Removed rule for %s", p.getRuleConditionText(rule))
+}
+
+// findRule returns the rule for a condition.  Rules for channels or users
+// that no longer exist are found by their listed condition
+func (p *Plugin) findRule(rules *Rules, teamID, condition string) *Rule {
+	if ruleType, value, err := p.parseRuleCondition(teamID, condition); err == nil {
+		if rule := rules.get(getRuleID(ruleType, value)); rule != nil {
+			return rule
+		}
+	}
+
+	for _, id := range rules.sortedIDs() {
+		rule := rules.get(id)
+		if p.getRuleConditionText(rule) == condition || rule.ID == condition {
+			return rule
+		}
+	}
+	return nil
+}
+
+// getRuleText returns the condition of a rule followed by its labels
+func (p *Plugin) getRuleText(rule *Rule, labels *Labels) string {
+	names, _ := labels.getNamesFromIDs(rule.LabelIDs)
+	return fmt.Sprintf("%s:%s", p.getRuleConditionText(rule), getCodeBlockedLabels(names))
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteCommandRule(t *testing.T) {
+	tests := map[string]struct {
+		commandArgs         *model.CommandArgs
+		rules               *Rules
+		expectedMsgPrefix   string
+		expectedContains    []string
+		expectedNotContains []string
+	}{
+		"ADD without condition": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks rule add --labels label1"},
+			expectedMsgPrefix: "Please specify a channel, user, hashtag or keyword",
+		},
+		"ADD without labels": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks rule add ~town-square"},
+			expectedMsgPrefix: "Please specify the labels to apply with --labels <labels>",
+		},
+		"ADD unknown channel": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks rule add ~nowhere --labels label1"},
+			expectedMsgPrefix: "Channel `~nowhere` does not exist",
+		},
+		"ADD unknown user": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks rule add @nobody --labels label1"},
+			expectedMsgPrefix: "User `@nobody` does not exist",
+		},
+		"ADD channel rule": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks rule add ~town-square --labels label1"},
+			expectedMsgPrefix: "Added rule: ~town-square: `label1`",
+		},
+		"ADD author rule with new label": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks rule add @alice --labels label2,newlabel"},
+			expectedMsgPrefix: "Added rule: @alice: `label2` `newlabel`",
+		},
+		"ADD labels to existing rule": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks rule add #Release --labels label2"},
+			rules:             getTestRules(),
+			expectedMsgPrefix: "Added rule: #release: `label2`",
+		},
+		"LIST without rules": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks rule list"},
+			expectedMsgPrefix: "You do not have any labeling rules",
+		},
+		"LIST rules": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks rule list"},
+			rules:             getTestRules(),
+			expectedMsgPrefix: "#### Labeling Rules",
+			expectedContains:  []string{"* ~town-square: `label1`", "* #release: `label2`"},
+		},
+		"REMOVE unknown rule": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks rule remove #other"},
+			rules:             getTestRules(),
+			expectedMsgPrefix: "You do not have a rule for `#other`",
+		},
+		"REMOVE rule": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks rule remove ~town-square"},
+			rules:             getTestRules(),
+			expectedMsgPrefix: "Removed rule for ~town-square",
+		},
+	}
+	for name, tt := range tests {
+		api := makeAPIMock()
+		tt.commandArgs.UserId = UserID
+		api.On("GetChannelByName", mock.Anything, "town-square", false).Return(&model.Channel{Id: "ChannelID", Name: "town-square"}, nil)
+		api.On("GetChannelByName", mock.Anything, "nowhere", false).Return(nil, &model.AppError{Message: "not found"})
+		api.On("GetChannel", "ChannelID").Return(&model.Channel{Id: "ChannelID", Name: "town-square"}, nil)
+		api.On("GetUserByUsername", "alice").Return(&model.User{Id: "AliceID", Username: "alice"}, nil)
+		api.On("GetUserByUsername", "nobody").Return(nil, &model.AppError{Message: "not found"})
+		api.On("GetUser", "AliceID").Return(&model.User{Id: "AliceID", Username: "alice"}, nil)
+		api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+
+		jsonLabels, err := json.Marshal(getExecuteCommandTestLabels())
+		require.Nil(t, err)
+		api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+		jsonRules, err := json.Marshal(tt.rules)
+		require.Nil(t, err)
+		api.On("KVGet", getRulesKey(UserID)).Return(jsonRules, nil)
+
+		t.Run(name, func(t *testing.T) {
+			api.On("SendEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				post := args.Get(1).(*model.Post)
+				actual := strings.TrimSpace(post.Message)
+				assert.True(t, strings.HasPrefix(actual, tt.expectedMsgPrefix), "Expected returned message to start with: \n%s\nActual:\n%s", tt.expectedMsgPrefix, actual)
+				for i := range tt.expectedContains {
+					assert.Contains(t, actual, tt.expectedContains[i])
+				}
+				for i := range tt.expectedNotContains {
+					assert.NotContains(t, actual, tt.expectedNotContains[i])
+				}
+			}).Once().Return(&model.Post{})
+
+			p := makePlugin(api)
+			cmdResponse, appError := p.ExecuteCommand(&plugin.Context{}, tt.commandArgs)
+			require.Nil(t, appError)
+			require.NotNil(t, cmdResponse)
+		})
+	}
+}
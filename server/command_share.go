@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+func getShareFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("share bookmarks", pflag.ContinueOnError)
+	flagSet.StringSlice(flagFilterLabels, nil, "share bookmarks with labels")
+
+	return flagSet
+}
+
+type shareOptions struct {
+	postIDs  []string
+	labels   []string
+	username string
+}
+
+// parseShareArgs parses the bookmarks to share and the @user to share them
+// with
+func parseShareArgs(args []string) (shareOptions, error) {
+	var options shareOptions
+
+	flagSet := getShareFlagSet()
+	err := flagSet.Parse(args)
+	if err != nil {
+		return options, err
+	}
+
+	options.labels, err = flagSet.GetStringSlice(flagFilterLabels)
+	if err != nil {
+		return options, err
+	}
+
+	for _, arg := range flagSet.Args() {
+		if strings.HasPrefix(arg, "@") {
+			options.username = arg
+			continue
+		}
+		options.postIDs = append(options.postIDs, arg)
+	}
+
+	return options, nil
+}
+
+// executeCommandShare sends bookmarks to another user in a bot DM with a
+// button to add them to their bookmarks
+func (p *Plugin) executeCommandShare(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+
+	options, err := parseShareArgs(subCommand[2:])
+	if err != nil {
+		return p.responsef(args, "Unable to parse options, %s", err)
+	}
+	if options.username == "" {
+		return p.responsef(args, "Please specify a @user to share bookmarks with%v", getHelp(shareCommandText))
+	}
+	if len(options.postIDs) == 0 && len(options.labels) == 0 {
+		return p.responsef(args, "Please specify the bookmarks to share by post_id or with --%s%v", flagFilterLabels, getHelp(shareCommandText))
+	}
+
+	user, appErr := p.API.GetUserByUsername(strings.TrimPrefix(options.username, "@"))
+	if appErr != nil || user == nil {
+		return p.responsef(args, "User `%s` does not exist", options.username)
+	}
+	if user.Id == args.UserId {
+		return p.responsef(args, "You cannot share bookmarks with yourself")
+	}
+
+	bmarks, err := p.getBookmarksToShare(args.UserId, options)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	labels, err := NewLabelsWithUser(p.API, args.UserId).getLabels()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	var shared []*SharedBookmark
+	var text string
+	skipped := 0
+	for _, bmark := range bmarks {
+		// the recipient only gets bookmarks of posts they can read
+		if !p.canAccessBookmark(user.Id, bmark) {
+			skipped++
+			continue
+		}
+
+		labelNames, _ := labels.getNamesFromIDs(bmark.getLabelIDs())
+		shared = append(shared, &SharedBookmark{
+			PostID: bmark.PostID,
+			Kind:   bmark.Kind,
+			Target: bmark.Target,
+			Title:  bmark.getTitle(),
+			Labels: labelNames,
+		})
+
+		nextText, err := p.getBmarkTextOneLine(bmark, labelNames)
+		if err != nil {
+			return p.responsef(args, err.Error())
+		}
+		text += nextText
+	}
+
+	if len(shared) == 0 {
+		return p.responsef(args, "%s cannot access any of these bookmarks", options.username)
+	}
+
+	attachments, err := getShareAttachments(shared)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	message := fmt.Sprintf("%s shared %v bookmarks with you:\n%s", p.getUsernameText(args.UserId), len(shared), text)
+	if err = p.postBotDMWithAttachments(user.Id, message, attachments); err != nil {
+		return p.responsef(args, "Unable to send bookmarks to %s, %s", options.username, err.Error())
+	}
+
+	response := fmt.Sprintf("Shared %v bookmarks with %s", len(shared), options.username)
+	if skipped != 0 {
+		response += fmt.Sprintf("\nSkipped %v bookmarks of posts %s cannot access", skipped, options.username)
+	}
+	return p.responsef(args, response)
+}
+
+// getBookmarksToShare returns the users bookmarks by post ID, or the
+// bookmarks with the filter labels, sorted by post creation time
+func (p *Plugin) getBookmarksToShare(userID string, options shareOptions) ([]*Bookmark, error) {
+	bmarks, err := NewBookmarksWithUser(p.API, userID).getBookmarks()
+	if err != nil {
+		return nil, err
+	}
+	if bmarks == nil || len(bmarks.ByID) == 0 {
+		return nil, errors.New("You do not have any saved bookmarks")
+	}
+
+	selected := NewBookmarksWithUser(p.API, userID)
+	for _, id := range options.postIDs {
+		bmark, err := bmarks.getBookmark(p.getPostIDFromLink(id))
+		if err != nil {
+			return nil, err
+		}
+		selected.ByID[bmark.PostID] = bmark
+	}
+
+	if len(options.labels) != 0 {
+		filtered, err := bmarks.applyFilters(&BookmarksFilters{LabelNames: options.labels})
+		if err != nil {
+			return nil, err
+		}
+		for id, bmark := range filtered.ByID {
+			selected.ByID[id] = bmark
+		}
+	}
+
+	if len(selected.ByID) == 0 {
+		return nil, errors.New(fmt.Sprintf("You do not have any bookmarks with labels:%s", getCodeBlockedLabels(options.labels)))
+	}
+	return selected.ByPostCreateAt()
+}
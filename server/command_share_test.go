@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const recipientID = "RecipientID"
+
+func TestParseShareArgs(t *testing.T) {
+	options, err := parseShareArgs([]string{"ID1xxxxxxxxxxxxxxxxxxxxxxx", "@alice", "ID2xxxxxxxxxxxxxxxxxxxxxxx"})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"ID1xxxxxxxxxxxxxxxxxxxxxxx", "ID2xxxxxxxxxxxxxxxxxxxxxxx"}, options.postIDs)
+	assert.Equal(t, "@alice", options.username)
+
+	options, err = parseShareArgs([]string{"--filter-labels", "label1,label2", "@alice"})
+	require.Nil(t, err)
+	assert.Empty(t, options.postIDs)
+	assert.Equal(t, []string{"label1", "label2"}, options.labels)
+	assert.Equal(t, "@alice", options.username)
+}
+
+func TestExecuteCommandShare(t *testing.T) {
+	tests := map[string]struct {
+		commandArgs       *model.CommandArgs
+		expectedMsgPrefix string
+		expectedContains  []string
+		expectedDM        []string
+		expectedShared    []string
+	}{
+		"SHARE without user": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks share ID1xxxxxxxxxxxxxxxxxxxxxxx"},
+			expectedMsgPrefix: "Please specify a @user to share bookmarks with",
+		},
+		"SHARE without bookmarks": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks share @alice"},
+			expectedMsgPrefix: "Please specify the bookmarks to share by post_id or with --filter-labels",
+		},
+		"SHARE with unknown user": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks share ID1xxxxxxxxxxxxxxxxxxxxxxx @nobody"},
+			expectedMsgPrefix: "User `@nobody` does not exist",
+		},
+		"SHARE with yourself": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks share ID1xxxxxxxxxxxxxxxxxxxxxxx @user"},
+			expectedMsgPrefix: "You cannot share bookmarks with yourself",
+		},
+		"SHARE unknown bookmark": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks share ID9xxxxxxxxxxxxxxxxxxxxxxx @alice"},
+			expectedMsgPrefix: "Bookmark `ID9xxxxxxxxxxxxxxxxxxxxxxx` does not exist",
+		},
+		"SHARE bookmark by post_id": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks share ID1xxxxxxxxxxxxxxxxxxxxxxx @alice"},
+			expectedMsgPrefix: "Shared 1 bookmarks with @alice",
+			expectedDM:        []string{"@user shared 1 bookmarks with you:", "pl/ID1xxxxxxxxxxxxxxxxxxxxxxx"},
+			expectedShared:    []string{"ID1xxxxxxxxxxxxxxxxxxxxxxx"},
+		},
+		"SHARE bookmarks by label skips inaccessible posts": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks share --filter-labels label1 @alice"},
+			expectedMsgPrefix: "Shared 1 bookmarks with @alice",
+			expectedContains:  []string{"Skipped 1 bookmarks of posts @alice cannot access"},
+			expectedShared:    []string{"ID1xxxxxxxxxxxxxxxxxxxxxxx"},
+		},
+		"SHARE only inaccessible bookmarks": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks share ID2xxxxxxxxxxxxxxxxxxxxxxx @alice"},
+			expectedMsgPrefix: "@alice cannot access any of these bookmarks",
+		},
+	}
+	for name, tt := range tests {
+		api := makeAPIMock()
+		tt.commandArgs.UserId = UserID
+		siteURL := "https://myhost.com"
+		api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+		api.On("GetPost", p1ID).Return(&model.Post{Id: p1ID, ChannelId: "ChannelID", Message: "public", CreateAt: 1}, nil)
+		api.On("GetPost", p2ID).Return(&model.Post{Id: p2ID, ChannelId: "PrivateID", Message: "private", CreateAt: 2}, nil)
+		api.On("HasPermissionToChannel", recipientID, "ChannelID", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("HasPermissionToChannel", recipientID, "PrivateID", model.PERMISSION_READ_CHANNEL).Return(false)
+		api.On("GetUser", UserID).Return(&model.User{Id: UserID, Username: "user"}, nil)
+		api.On("GetUserByUsername", "alice").Return(&model.User{Id: recipientID, Username: "alice"}, nil)
+		api.On("GetUserByUsername", "user").Return(&model.User{Id: UserID, Username: "user"}, nil)
+		api.On("GetUserByUsername", "nobody").Return(nil, &model.AppError{Message: "not found"})
+		api.On("GetDirectChannel", recipientID, mock.Anything).Return(&model.Channel{Id: "DMChannelID"}, nil)
+
+		var dm *model.Post
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+			dm = args.Get(0).(*model.Post)
+		}).Return(&model.Post{}, nil)
+
+		jsonBmarks, err := json.Marshal(getExecuteCommandTestBookmarks())
+		require.Nil(t, err)
+		api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+		jsonLabels, err := json.Marshal(getExecuteCommandTestLabels())
+		require.Nil(t, err)
+		api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+
+		t.Run(name, func(t *testing.T) {
+			api.On("SendEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				post := args.Get(1).(*model.Post)
+				actual := strings.TrimSpace(post.Message)
+				assert.True(t, strings.HasPrefix(actual, tt.expectedMsgPrefix), "Expected returned message to start with: \n%s\nActual:\n%s", tt.expectedMsgPrefix, actual)
+				for i := range tt.expectedContains {
+					assert.Contains(t, actual, tt.expectedContains[i])
+				}
+			}).Once().Return(&model.Post{})
+
+			p := makePlugin(api)
+			cmdResponse, appError := p.ExecuteCommand(&plugin.Context{}, tt.commandArgs)
+			require.Nil(t, appError)
+			require.NotNil(t, cmdResponse)
+
+			if tt.expectedShared == nil {
+				assert.Nil(t, dm)
+				return
+			}
+			require.NotNil(t, dm)
+			for i := range tt.expectedDM {
+				assert.Contains(t, dm.Message, tt.expectedDM[i])
+			}
+
+			attachments := dm.Attachments()
+			require.Len(t, attachments, 1)
+			action := attachments[0].Actions[0]
+			assert.Equal(t, "Add to my bookmarks", action.Name)
+			var shared []*SharedBookmark
+			require.Nil(t, json.Unmarshal([]byte(action.Integration.Context["bookmarks"].(string)), &shared))
+			var ids []string
+			for _, s := range shared {
+				ids = append(ids, s.PostID)
+				assert.ElementsMatch(t, []string{"label1", "label2"}, s.Labels)
+			}
+			assert.Equal(t, tt.expectedShared, ids)
+		})
+	}
+}
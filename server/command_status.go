@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// executeCommandDone marks bookmarks as done
+func (p *Plugin) executeCommandDone(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+
+	if len(subCommand) < 3 {
+		return p.responsef(args, "Please specify a post_id to mark as done%v", getHelp(statusCommandText))
+	}
+
+	text := "Marked as done:\n"
+	for _, id := range subCommand[2:] {
+		bmark, err := p.setBookmarkStatus(args.UserId, p.getPostIDFromLink(id), statusDone)
+		if err != nil {
+			return p.responsef(args, err.Error())
+		}
+		nextText, err := p.getBmarkTextOneLine(bmark, nil)
+		if err != nil {
+			return p.responsef(args, err.Error())
+		}
+		text += nextText
+	}
+
+	return p.responsef(args, text)
+}
+
+// executeCommandStatus shows the number of bookmarks of each status, or sets
+// the status of a bookmark
+func (p *Plugin) executeCommandStatus(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+
+	if len(subCommand) == 2 {
+		bmarks, err := NewBookmarksWithUser(p.API, args.UserId).getBookmarks()
+		if err != nil {
+			return p.responsef(args, err.Error())
+		}
+		if bmarks == nil || len(bmarks.ByID) == 0 {
+			return p.responsef(args, "You do not have any saved bookmarks")
+		}
+		return p.responsef(args, getStatusCountsText(bmarks))
+	}
+
+	if len(subCommand) != 4 {
+		return p.responsef(args, "Please specify a post_id and a status%v", getHelp(statusCommandText))
+	}
+
+	status := subCommand[3]
+	if err := validateStatus(status); err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	bmark, err := p.setBookmarkStatus(args.UserId, p.getPostIDFromLink(subCommand[2]), status)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	text, err := p.getBmarkTextOneLine(bmark, nil)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	return p.responsef(args, "Marked as `%s`: %s", status, text)
+}
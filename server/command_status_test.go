@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteCommandStatus(t *testing.T) {
+	tests := map[string]struct {
+		command             string
+		expectedMsgPrefix   string
+		expectedContains    []string
+		expectedNotContains []string
+		expectedStatus      string
+		expectedButtons     int
+	}{
+		"DONE without post_id": {
+			command:           "/bookmarks done",
+			expectedMsgPrefix: "Please specify a post_id to mark as done",
+		},
+		"DONE unknown bookmark": {
+			command:           "/bookmarks done ID9xxxxxxxxxxxxxxxxxxxxxxx",
+			expectedMsgPrefix: "Bookmark `ID9xxxxxxxxxxxxxxxxxxxxxxx` does not exist",
+		},
+		"DONE bookmark": {
+			command:           "/bookmarks done ID1xxxxxxxxxxxxxxxxxxxxxxx",
+			expectedMsgPrefix: "Marked as done:\n[:link:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx) :white_check_mark: **_Title1",
+			expectedStatus:    statusDone,
+		},
+		"STATUS counts": {
+			command:           "/bookmarks status",
+			expectedMsgPrefix: "#### Bookmark Status",
+			expectedContains:  []string{"* `unread`: 3", "* :hourglass_flowing_sand: `in-progress`: 0", "* :white_check_mark: `done`: 1"},
+		},
+		"STATUS set": {
+			command:           "/bookmarks status ID1xxxxxxxxxxxxxxxxxxxxxxx in-progress",
+			expectedMsgPrefix: "Marked as `in-progress`: [:link:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx) :hourglass_flowing_sand:",
+			expectedStatus:    statusInProgress,
+		},
+		"STATUS invalid": {
+			command:           "/bookmarks status ID1xxxxxxxxxxxxxxxxxxxxxxx later",
+			expectedMsgPrefix: "Status `later` is not valid",
+		},
+		"STATUS missing status": {
+			command:           "/bookmarks status ID1xxxxxxxxxxxxxxxxxxxxxxx",
+			expectedMsgPrefix: "Please specify a post_id and a status",
+		},
+		"VIEW hides done bookmarks": {
+			command:             "/bookmarks view",
+			expectedMsgPrefix:   strings.TrimSpace(getLegendText()),
+			expectedContains:    []string{"ID1xxxxxxxxxxxxxxxxxxxxxxx", "ID2xxxxxxxxxxxxxxxxxxxxxxx", "ID3xxxxxxxxxxxxxxxxxxxxxxx", "1 bookmarks with other statuses are hidden"},
+			expectedNotContains: []string{"ID4xxxxxxxxxxxxxxxxxxxxxxx"},
+			expectedButtons:     3,
+		},
+		"VIEW done bookmarks": {
+			command:             "/bookmarks view --status done",
+			expectedMsgPrefix:   strings.TrimSpace(getLegendText()),
+			expectedContains:    []string{"[:link:](https://myhost.com/_redirect/pl/ID4xxxxxxxxxxxxxxxxxxxxxxx) :white_check_mark:"},
+			expectedNotContains: []string{"ID1xxxxxxxxxxxxxxxxxxxxxxx", "hidden"},
+		},
+		"VIEW all statuses": {
+			command:           "/bookmarks view --status all",
+			expectedMsgPrefix: strings.TrimSpace(getLegendText()),
+			expectedContains:  []string{"ID1xxxxxxxxxxxxxxxxxxxxxxx", "ID2xxxxxxxxxxxxxxxxxxxxxxx", "ID3xxxxxxxxxxxxxxxxxxxxxxx", "ID4xxxxxxxxxxxxxxxxxxxxxxx"},
+			expectedButtons:   3,
+		},
+		"VIEW invalid status": {
+			command:           "/bookmarks view --status later",
+			expectedMsgPrefix: "Unable to parse options, Status `later` is not valid",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := makeAPIMock()
+			siteURL := "https://myhost.com"
+			for i, id := range []string{p1ID, p2ID, p3ID, p4ID} {
+				api.On("GetPost", id).Return(&model.Post{Message: "this is the post.Message", CreateAt: int64(i)}, nil)
+			}
+			api.On("GetPost", mock.Anything).Return(nil, &model.AppError{Message: "not found"})
+			api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+
+			bmarks := getExecuteCommandTestBookmarks()
+			bmarks.get(p4ID).Status = statusDone
+			jsonBmarks, err := json.Marshal(bmarks)
+			require.Nil(t, err)
+			api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+			jsonLabels, err := json.Marshal(getExecuteCommandTestLabels())
+			require.Nil(t, err)
+			api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+
+			store := mockBookmarksStore(api, UserID)
+
+			api.On("SendEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				post := args.Get(1).(*model.Post)
+				actual := strings.TrimSpace(post.Message)
+				assert.True(t, strings.HasPrefix(actual, tt.expectedMsgPrefix), "Expected returned message to start with: \n%s\nActual:\n%s", tt.expectedMsgPrefix, actual)
+				for _, s := range tt.expectedContains {
+					assert.Contains(t, actual, s)
+				}
+				for _, s := range tt.expectedNotContains {
+					assert.NotContains(t, actual, s)
+				}
+				var buttons int
+				for _, attachment := range post.Attachments() {
+					buttons += len(attachment.Actions)
+				}
+				assert.Equal(t, tt.expectedButtons, buttons)
+			}).Once().Return(&model.Post{})
+
+			p := makePlugin(api)
+			args := &model.CommandArgs{Command: tt.command, UserId: UserID}
+			cmdResponse, appError := p.ExecuteCommand(&plugin.Context{}, args)
+			require.Nil(t, appError)
+			require.NotNil(t, cmdResponse)
+
+			if tt.expectedStatus != "" {
+				stored := store.getBookmarks()
+				require.NotNil(t, stored.get(p1ID))
+				assert.Equal(t, tt.expectedStatus, stored.get(p1ID).getStatus())
+			}
+		})
+	}
+}
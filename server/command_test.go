@@ -14,15 +14,15 @@ import (
 )
 
 const (
-	PostIDDoesNotExist = "PostIDDoesNotExist"
-	PostIDExists       = "ID2"
-	UserID             = "UserID"
-	teamID1            = "teamID1"
+	PostIDDoesNotExistxxxxxxxx = "PostIDDoesNotExistxxxxxxxx"
+	PostIDExists               = "ID2xxxxxxxxxxxxxxxxxxxxxxx"
+	UserID                     = "UserID"
+	teamID1                    = "teamID1"
 
-	p1ID = "ID1"
-	p2ID = "ID2"
-	p3ID = "ID3"
-	p4ID = "ID4"
+	p1ID = "ID1xxxxxxxxxxxxxxxxxxxxxxx"
+	p2ID = "ID2xxxxxxxxxxxxxxxxxxxxxxx"
+	p3ID = "ID3xxxxxxxxxxxxxxxxxxxxxxx"
+	p4ID = "ID4xxxxxxxxxxxxxxxxxxxxxxx"
 
 	b1Title = "Title1 - New Bookmark - times are zero"
 	b2Title = "Title2 - bookmarks initialized. Times created and same"
@@ -31,6 +31,7 @@ const (
 
 func getExecuteCommandTestBookmarks() *Bookmarks {
 	api := makeAPIMock()
+	mockBookmarksStore(api, UserID)
 	api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
 	p := makePlugin(api)
 	bmarks := NewBookmarksWithUser(p.API, UserID)
@@ -126,7 +127,7 @@ func TestExecuteCommand(t *testing.T) {
 		api := makeAPIMock()
 		tt.commandArgs.UserId = UserID
 		siteURL := "https://myhost.com"
-		api.On("GetPost", PostIDDoesNotExist).Return(nil, &model.AppError{Message: "An Error Occurred"})
+		api.On("GetPost", PostIDDoesNotExistxxxxxxxx).Return(nil, &model.AppError{Message: "An Error Occurred"})
 		api.On("GetPost", p1ID).Return(p1IDmodel, nil)
 		api.On("GetPost", p2ID).Return(p2IDmodel, nil)
 		api.On("GetPost", p3ID).Return(p3IDmodel, nil)
@@ -168,6 +169,18 @@ func TestExecuteCommand(t *testing.T) {
 	}
 }
 
+func TestGetCommand(t *testing.T) {
+	cmd := getCommand()
+	require.NotNil(t, cmd.AutocompleteData)
+	assert.Nil(t, cmd.AutocompleteData.IsValid())
+
+	var triggers []string
+	for _, sub := range cmd.AutocompleteData.SubCommands {
+		triggers = append(triggers, sub.Trigger)
+	}
+	assert.ElementsMatch(t, []string{"add", "add-url", "add-file", "view", "remove", "done", "status", "priority", "move", "due", "label", "undo", "trash", "rule", "collection", "channel", "share", "quota", "help"}, triggers)
+}
+
 func makeAPIMock() *plugintest.API {
 	api := &plugintest.API{}
 
@@ -175,5 +188,10 @@ func makeAPIMock() *plugintest.API {
 	api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything).Maybe()
 	api.On("LogError", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
 
+	// storing bookmarks locks them
+	isLock := mock.MatchedBy(func(key string) bool { return strings.HasPrefix(key, StoreBookmarksLockKey+"_") })
+	api.On("KVSetWithOptions", isLock, mock.Anything, mock.Anything).Return(true, nil).Maybe()
+	api.On("KVCompareAndDelete", isLock, mock.Anything).Return(true, nil).Maybe()
+
 	return api
 }
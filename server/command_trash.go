@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// executeCommandTrash executes a trash sub-command
+func (p *Plugin) executeCommandTrash(args *model.CommandArgs) *model.CommandResponse {
+	split := strings.Fields(args.Command)
+	if len(split) < 3 {
+		return p.executeCommandTrashView(args)
+	}
+
+	action := split[2]
+
+	switch action {
+	case "view":
+		return p.executeCommandTrashView(args)
+	case "restore":
+		return p.executeCommandTrashRestore(args)
+	case "empty":
+		return p.executeCommandTrashEmpty(args)
+	case "help":
+		return p.responsef(args, getHelp(trashCommandText))
+
+	default:
+		return p.responsef(args, fmt.Sprintf("Unknown command: "+args.Command))
+	}
+}
+
+// getUserTrash returns the users trash after purging expired bookmarks
+func (p *Plugin) getUserTrash(userID string) (*Trash, error) {
+	trash, err := NewTrashWithUser(p.API, userID).getTrash()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = trash.purgeExpired(p.getConfiguration().getTrashRetention()); err != nil {
+		return nil, err
+	}
+	return trash, nil
+}
+
+// executeCommandTrashView shows all removed bookmarks in an ephemeral post
+func (p *Plugin) executeCommandTrashView(args *model.CommandArgs) *model.CommandResponse {
+	trash, err := p.getUserTrash(args.UserId)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+	if len(trash.ByID) == 0 {
+		return p.responsef(args, "Your trash is empty")
+	}
+
+	labels, err := NewLabelsWithUser(p.API, args.UserId).getLabels()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	days := int(p.getConfiguration().getTrashRetention().Hours() / 24)
+	text := "#### Trash\n"
+	text += fmt.Sprintf("Removed bookmarks are permanently deleted after %v days\n", days)
+	for _, bmark := range trash.ByDeleteAt() {
+		labelNames, _ := labels.getNamesFromIDs(bmark.getLabelIDs())
+		nextText, err := p.getBmarkTextOneLine(bmark, labelNames)
+		if err != nil {
+			return p.responsef(args, err.Error())
+		}
+		text += nextText
+	}
+
+	return p.responsef(args, text)
+}
+
+// executeCommandTrashRestore restores removed bookmarks from the trash
+func (p *Plugin) executeCommandTrashRestore(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+	if len(subCommand) < 4 {
+		return p.responsef(args, "Please specify a post_id to restore%v", getHelp(trashCommandText))
+	}
+
+	trash, err := p.getUserTrash(args.UserId)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	var restore []*Bookmark
+	for _, id := range subCommand[3:] {
+		bmarkID := p.getPostIDFromLink(id)
+		bmark := trash.get(bmarkID)
+		if bmark == nil {
+			return p.responsef(args, "Bookmark `%v` is not in the trash", bmarkID)
+		}
+		restore = append(restore, bmark)
+	}
+
+	text, err := p.restoreBookmarks(args.UserId, restore)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	return p.responsef(args, text)
+}
+
+// executeCommandTrashEmpty permanently deletes all bookmarks in the trash
+func (p *Plugin) executeCommandTrashEmpty(args *model.CommandArgs) *model.CommandResponse {
+	trash, err := NewTrashWithUser(p.API, args.UserId).getTrash()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	count, err := trash.empty()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	return p.responsef(args, "Permanently deleted %v bookmarks from the trash", count)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteCommandTrash(t *testing.T) {
+	tests := map[string]struct {
+		commandArgs         *model.CommandArgs
+		trash               *Trash
+		expectedMsgPrefix   string
+		expectedContains    []string
+		expectedNotContains []string
+	}{
+		"VIEW empty trash": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks trash view"},
+			expectedMsgPrefix: "Your trash is empty",
+		},
+		"VIEW purges expired bookmarks": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks trash"},
+			trash:             getTestTrash(),
+			expectedMsgPrefix: "#### Trash",
+			expectedContains: []string{
+				"permanently deleted after 30 days",
+				"**_Removed today_**",
+				"**_Removed 10 days ago_**",
+			},
+			expectedNotContains: []string{"Removed 40 days ago"},
+		},
+		"RESTORE without post_id": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks trash restore"},
+			trash:             getTestTrash(),
+			expectedMsgPrefix: "Please specify a post_id to restore",
+		},
+		"RESTORE bookmark not in trash": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks trash restore ID1xxxxxxxxxxxxxxxxxxxxxxx"},
+			trash:             getTestTrash(),
+			expectedMsgPrefix: "Bookmark `ID1xxxxxxxxxxxxxxxxxxxxxxx` is not in the trash",
+		},
+		"RESTORE bookmarks": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks trash restore ID5xxxxxxxxxxxxxxxxxxxxxxx ID6xxxxxxxxxxxxxxxxxxxxxxx"},
+			trash:             getTestTrash(),
+			expectedMsgPrefix: "Restored bookmarks:",
+			expectedContains: []string{
+				"[:link:](https://myhost.com/_redirect/pl/ID5xxxxxxxxxxxxxxxxxxxxxxx) **_Removed today_**",
+				"[:link:](https://myhost.com/_redirect/pl/ID6xxxxxxxxxxxxxxxxxxxxxxx) **_Removed 10 days ago_**",
+			},
+		},
+		"EMPTY trash": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks trash empty"},
+			trash:             getTestTrash(),
+			expectedMsgPrefix: "Permanently deleted 3 bookmarks from the trash",
+		},
+	}
+	for name, tt := range tests {
+		api := makeAPIMock()
+		tt.commandArgs.UserId = UserID
+		siteURL := "https://myhost.com"
+		api.On("GetPost", mock.Anything).Return(&model.Post{Message: "this is the post.Message"}, nil)
+		api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+		api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+
+		jsonBmarks, err := json.Marshal(getExecuteCommandTestBookmarks())
+		require.Nil(t, err)
+		api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+		jsonLabels, err := json.Marshal(getExecuteCommandTestLabels())
+		require.Nil(t, err)
+		api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+		jsonTrash, err := json.Marshal(tt.trash)
+		require.Nil(t, err)
+		api.On("KVGet", getTrashKey(UserID)).Return(jsonTrash, nil)
+
+		t.Run(name, func(t *testing.T) {
+			api.On("SendEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				post := args.Get(1).(*model.Post)
+				actual := strings.TrimSpace(post.Message)
+				assert.True(t, strings.HasPrefix(actual, tt.expectedMsgPrefix), "Expected returned message to start with: \n%s\nActual:\n%s", tt.expectedMsgPrefix, actual)
+				for i := range tt.expectedContains {
+					assert.Contains(t, actual, tt.expectedContains[i])
+				}
+				for i := range tt.expectedNotContains {
+					assert.NotContains(t, actual, tt.expectedNotContains[i])
+				}
+			}).Once().Return(&model.Post{})
+
+			p := makePlugin(api)
+			cmdResponse, appError := p.ExecuteCommand(&plugin.Context{}, tt.commandArgs)
+			require.Nil(t, appError)
+			require.NotNil(t, cmdResponse)
+		})
+	}
+}
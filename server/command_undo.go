@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+// executeCommandUndo restores the state removed by the users most recent
+// destructive operation
+func (p *Plugin) executeCommandUndo(args *model.CommandArgs) *model.CommandResponse {
+	subCommand := strings.Fields(args.Command)
+	if len(subCommand) != 2 {
+		return p.responsef(args, "undo subcommand takes no arguments%v", getHelp(undoCommandText))
+	}
+
+	journal, err := NewJournalWithUser(p.API, args.UserId).getJournal()
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	op := journal.getLastOperation(p.getConfiguration().getUndoWindow())
+	if op == nil {
+		return p.responsef(args, "There is nothing to undo")
+	}
+
+	text, err := p.undoOperation(args.UserId, journal, op)
+	if err != nil {
+		return p.responsef(args, err.Error())
+	}
+
+	return p.responsef(args, text)
+}
+
+// undoOperationByID restores the state removed by a journaled operation
+func (p *Plugin) undoOperationByID(userID, opID string) (string, error) {
+	journal, err := NewJournalWithUser(p.API, userID).getJournal()
+	if err != nil {
+		return "", err
+	}
+
+	op, err := journal.getOperation(opID, p.getConfiguration().getUndoWindow())
+	if err != nil {
+		return "", err
+	}
+
+	return p.undoOperation(userID, journal, op)
+}
+
+// undoOperation restores the state removed by op and removes op from the
+// journal
+func (p *Plugin) undoOperation(userID string, journal *Journal, op *Operation) (string, error) {
+	var text string
+	var err error
+
+	switch op.Type {
+	case opRemoveBookmarks:
+		text, err = p.restoreBookmarks(userID, op.Bookmarks)
+	case opRemoveLabel:
+		text, err = p.undoRemoveLabel(userID, op)
+	default:
+		err = errors.New(fmt.Sprintf("Unknown operation type: %s", op.Type))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err = journal.delete(op.ID); err != nil {
+		return "", err
+	}
+
+	return text, nil
+}
+
+// undoRemoveLabel restores a removed label and re-adds it to the bookmarks
+// it was stripped from
+func (p *Plugin) undoRemoveLabel(userID string, op *Operation) (string, error) {
+	labels, err := NewLabelsWithUser(p.API, userID).getLabels()
+	if err != nil {
+		return "", err
+	}
+
+	if labels.getLabelByName(op.Label.Name) != nil {
+		return "", errors.New(fmt.Sprintf("Cannot restore label `%v`. Label already exists", op.Label.Name))
+	}
+	if err = p.checkLabelsLimit(labels, []string{op.Label.Name}); err != nil {
+		return "", err
+	}
+
+	bmarks, err := NewBookmarksWithUser(p.API, userID).getBookmarks()
+	if err != nil {
+		return "", err
+	}
+
+	// the parent of a nested label may have been removed since
+	if op.Label.ParentID, err = labels.ensureParents(op.Label.Name); err != nil {
+		return "", err
+	}
+	if err = labels.add(op.Label.ID, op.Label); err != nil {
+		return "", err
+	}
+
+	var count int
+	for _, id := range op.BookmarkIDs {
+		bmark, ok := bmarks.exists(id)
+		if !ok {
+			continue
+		}
+		bmark.updateLabelIDs(append(bmark.getLabelIDs(), op.Label.ID))
+		count++
+	}
+
+	if err = bmarks.storeBookmarks(); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Restored label `%v` on %v bookmarks", op.Label.Name, count), nil
+}
+
+// journalOperation journals a destructive operation for a user.  Failing to
+// journal does not fail the operation, the user is just unable to undo it
+func (p *Plugin) journalOperation(userID string, op *Operation) *Operation {
+	journal, err := NewJournalWithUser(p.API, userID).getJournal()
+	if err == nil {
+		op, err = journal.addOperation(op)
+	}
+	if err != nil {
+		p.API.LogWarn("Unable to journal operation", "error", err.Error())
+		return nil
+	}
+	return op
+}
+
+// getUndoAttachments returns an attachment with an Undo button for a
+// journaled operation
+func getUndoAttachments(op *Operation) []*model.SlackAttachment {
+	if op == nil {
+		return nil
+	}
+
+	return []*model.SlackAttachment{{
+		Actions: []*model.PostAction{{
+			Name: "Undo",
+			Integration: &model.PostActionIntegration{
+				URL: getPluginActionURL("/api/v1/undo"),
+				Context: map[string]interface{}{
+					"operation_id": op.ID,
+				},
+			},
+		}},
+	}}
+}
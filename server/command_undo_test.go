@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func getExecuteCommandUndoJournal(ops ...*Operation) *Journal {
+	api := makeAPIMock()
+	api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+	journal := NewJournalWithUser(api, UserID)
+	for _, op := range ops {
+		_, _ = journal.addOperation(op)
+	}
+	return journal
+}
+
+func TestExecuteCommandUndo(t *testing.T) {
+	removedBmark := &Bookmark{
+		PostID:   "ID5xxxxxxxxxxxxxxxxxxxxxxx",
+		Title:    "Removed Title",
+		LabelIDs: []string{"UUID1", "DeletedLabelID"},
+	}
+
+	expiredOp := &Operation{Type: opRemoveBookmarks, Bookmarks: []*Bookmark{removedBmark}}
+	expiredJournal := getExecuteCommandUndoJournal(expiredOp)
+	expiredOp.CreateAt = model.GetMillis() - int64(defaultUndoWindowMinutes*60*1000) - 1
+
+	tests := map[string]struct {
+		commandArgs       *model.CommandArgs
+		bookmarks         *Bookmarks
+		labels            *Labels
+		journal           *Journal
+		expectedMsgPrefix string
+		expectedContains  []string
+	}{
+		"UNDO with arguments": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks undo something"},
+			expectedMsgPrefix: "undo subcommand takes no arguments",
+		},
+		"UNDO with empty journal": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks undo"},
+			expectedMsgPrefix: "There is nothing to undo",
+		},
+		"UNDO expired operation": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks undo"},
+			journal:           expiredJournal,
+			expectedMsgPrefix: "There is nothing to undo",
+		},
+		"UNDO remove bookmark drops deleted labels": {
+			commandArgs: &model.CommandArgs{Command: "/bookmarks undo"},
+			bookmarks:   getExecuteCommandTestBookmarks(),
+			labels:      getExecuteCommandTestLabels(),
+			journal: getExecuteCommandUndoJournal(&Operation{
+				Type:      opRemoveBookmarks,
+				Bookmarks: []*Bookmark{removedBmark},
+			}),
+			expectedMsgPrefix: "Restored bookmark: [:link:](https://myhost.com/_redirect/pl/ID5xxxxxxxxxxxxxxxxxxxxxxx) `label1` **_Removed Title_**",
+		},
+		"UNDO remove label": {
+			commandArgs: &model.CommandArgs{Command: "/bookmarks undo"},
+			bookmarks:   getExecuteCommandTestBookmarks(),
+			labels:      getExecuteCommandTestLabels(),
+			journal: getExecuteCommandUndoJournal(&Operation{
+				Type:        opRemoveLabel,
+				Label:       &Label{Name: "label9", ID: "UUID9"},
+				BookmarkIDs: []string{p1ID, p3ID, PostIDDoesNotExistxxxxxxxx},
+			}),
+			expectedMsgPrefix: "Restored label `label9` on 2 bookmarks",
+		},
+		"UNDO remove label that was created again": {
+			commandArgs: &model.CommandArgs{Command: "/bookmarks undo"},
+			bookmarks:   getExecuteCommandTestBookmarks(),
+			labels:      getExecuteCommandTestLabels(),
+			journal: getExecuteCommandUndoJournal(&Operation{
+				Type:  opRemoveLabel,
+				Label: &Label{Name: "label1", ID: "UUID9"},
+			}),
+			expectedMsgPrefix: "Cannot restore label `label1`. Label already exists",
+		},
+	}
+	for name, tt := range tests {
+		api := makeAPIMock()
+		tt.commandArgs.UserId = UserID
+		siteURL := "https://myhost.com"
+		api.On("GetPost", mock.Anything).Return(&model.Post{Message: "this is the post.Message"}, nil)
+		api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+		api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+
+		jsonBmarks, err := json.Marshal(tt.bookmarks)
+		require.Nil(t, err)
+		api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+		jsonLabels, err := json.Marshal(tt.labels)
+		require.Nil(t, err)
+		api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+		jsonJournal, err := json.Marshal(tt.journal)
+		require.Nil(t, err)
+		api.On("KVGet", getJournalKey(UserID)).Return(jsonJournal, nil)
+		api.On("KVGet", getTrashKey(UserID)).Return(nil, nil)
+
+		t.Run(name, func(t *testing.T) {
+			api.On("SendEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				post := args.Get(1).(*model.Post)
+				actual := strings.TrimSpace(post.Message)
+				assert.True(t, strings.HasPrefix(actual, tt.expectedMsgPrefix), "Expected returned message to start with: \n%s\nActual:\n%s", tt.expectedMsgPrefix, actual)
+				for i := range tt.expectedContains {
+					assert.Contains(t, actual, tt.expectedContains[i])
+				}
+			}).Once().Return(&model.Post{})
+
+			p := makePlugin(api)
+			cmdResponse, appError := p.ExecuteCommand(&plugin.Context{}, tt.commandArgs)
+			require.Nil(t, appError)
+			require.NotNil(t, cmdResponse)
+		})
+	}
+}
+
+func TestGetUndoAttachments(t *testing.T) {
+	assert.Nil(t, getUndoAttachments(nil))
+
+	attachments := getUndoAttachments(&Operation{ID: "OpID"})
+	require.Len(t, attachments, 1)
+	require.Len(t, attachments[0].Actions, 1)
+
+	action := attachments[0].Actions[0]
+	assert.Equal(t, "Undo", action.Name)
+	assert.Equal(t, "/plugins/com.mattermost.bookmarks/api/v1/undo", action.Integration.URL)
+	assert.Equal(t, "OpID", action.Integration.Context["operation_id"])
+}
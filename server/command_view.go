@@ -10,17 +10,28 @@ import (
 
 const (
 	flagFilterLabels = "filter-labels"
+	flagHistory      = "history"
+	flagStatus       = "status"
+	flagDue          = "due"
 )
 
 func getViewBookmarkFlagSet() *pflag.FlagSet {
 	flagSet := pflag.NewFlagSet("filter bookmarks by label", pflag.ContinueOnError)
 	flagSet.StringSlice(flagFilterLabels, nil, "filter by label")
+	flagSet.Bool(flagHistory, false, "show the history of a bookmark")
+	flagSet.StringSlice(flagStatus, nil, "filter by status")
+	flagSet.String(flagDue, "", "filter by due date")
+	flagSet.String(flagSort, bookmarkSortTime, "sort bookmarks by post time, creation time, priority or manual order")
 
 	return flagSet
 }
 
 type viewBookmarkOptions struct {
-	labels []string
+	labels   []string
+	history  bool
+	statuses []string
+	sort     string
+	due      string
 }
 
 func parseViewBookmarkArgs(args []string) (viewBookmarkOptions, error) {
@@ -37,6 +48,38 @@ func parseViewBookmarkArgs(args []string) (viewBookmarkOptions, error) {
 		return options, err
 	}
 
+	options.history, err = viewBookmarkFlagSet.GetBool(flagHistory)
+	if err != nil {
+		return options, err
+	}
+
+	statuses, err := viewBookmarkFlagSet.GetStringSlice(flagStatus)
+	if err != nil {
+		return options, err
+	}
+	options.statuses, err = parseStatuses(statuses)
+	if err != nil {
+		return options, err
+	}
+
+	options.due, err = viewBookmarkFlagSet.GetString(flagDue)
+	if err != nil {
+		return options, err
+	}
+	if options.due != "" {
+		if err = validateDueFilter(options.due); err != nil {
+			return options, err
+		}
+	}
+
+	options.sort, err = viewBookmarkFlagSet.GetString(flagSort)
+	if err != nil {
+		return options, err
+	}
+	if err = validateBookmarkSort(options.sort); err != nil {
+		return options, err
+	}
+
 	return options, nil
 }
 
@@ -56,32 +99,44 @@ func (p *Plugin) executeCommandView(args *model.CommandArgs) *model.CommandRespo
 		return p.responsef(args, "You do not have any saved bookmarks")
 	}
 
-	// user requests to view an individual bookmark
-	if len(subCommand) == 3 && !strings.HasPrefix(subCommand[2], "--") {
-		postID := subCommand[2]
-		postID = p.getPostIDFromLink(postID)
-		text, _ := p.commandViewPostID(postID, bmarks, args)
-		return p.responsef(args, text)
-	}
-
 	options, err := parseViewBookmarkArgs(subCommand)
 	if err != nil {
 		return p.responsef(args, "Unable to parse options, %s", err)
 	}
 
+	// user requests to view an individual bookmark
+	if len(subCommand) >= 3 && !strings.HasPrefix(subCommand[2], "--") {
+		postID := subCommand[2]
+		postID = p.getPostIDFromLink(postID)
+		text, err := p.commandViewPostID(postID, bmarks, args, options.history)
+		if err != nil {
+			return p.responsef(args, err.Error())
+		}
+		var attachments []*model.SlackAttachment
+		if bmark, ok := bmarks.exists(postID); ok {
+			attachments = p.getDoneAttachments([]*Bookmark{bmark})
+		}
+		return p.responseWithAttachments(args, text, attachments)
+	}
+
 	var bmarkFilters BookmarksFilters
 	bmarkFilters.LabelNames = options.labels
+	bmarkFilters.Statuses = options.statuses
+	bmarkFilters.Due = options.due
+	if options.due != "" {
+		bmarkFilters.Now = p.getNow(args.UserId)
+	}
 
-	text, err := p.getBmarksEphemeralText(args.UserId, &bmarkFilters)
+	text, attachments, err := p.getBmarksEphemeralText(args.UserId, &bmarkFilters, options.sort)
 	if err != nil {
 		return p.responsef(args, text)
 	}
 
-	return p.responsef(args, text)
+	return p.responseWithAttachments(args, text, attachments)
 }
 
 // executeCommandView shows all bookmarks in an ephemeral post
-func (p *Plugin) commandViewPostID(postID string, bmarks *Bookmarks, args *model.CommandArgs) (string, error) {
+func (p *Plugin) commandViewPostID(postID string, bmarks *Bookmarks, args *model.CommandArgs, history bool) (string, error) {
 	postID = p.getPostIDFromLink(postID)
 
 	var bmark *Bookmark
@@ -97,9 +152,19 @@ func (p *Plugin) commandViewPostID(postID string, bmarks *Bookmarks, args *model
 	}
 
 	var text string
-	text, err = p.getBmarkTextDetailed(bmark, labelNames, args)
+	text, err = p.getBmarkTextDetailed(bmark, labelNames, args, history)
 	if err != nil {
 		return "", errors.Wrap(err, "Unable to get bookmark text")
 	}
+
+	// replies shown to the user are no longer new
+	if bmark.getKind() == bookmarkKindThread {
+		if err = p.markThreadSeen(bmark); err != nil {
+			return "", err
+		}
+		if err = bmarks.add(bmark); err != nil {
+			return "", err
+		}
+	}
 	return text, nil
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/mattermost/mattermost-server/v5/plugin"
@@ -14,6 +15,7 @@ import (
 
 func getExecuteCommandViewBookmarks() *Bookmarks {
 	api := makeAPIMock()
+	mockBookmarksStore(api, UserID)
 	api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
 	p := makePlugin(api)
 	bmarks := NewBookmarksWithUser(p.API, UserID)
@@ -66,6 +68,24 @@ func getExecuteCommandViewLabels() *Labels {
 	return labels
 }
 
+func getExecuteCommandViewHistoryBookmarks() *Bookmarks {
+	day := int64(24 * 60 * 60 * 1000)
+	created := time.Date(2020, 1, 2, 15, 4, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)
+
+	bmarks := NewBookmarksWithUser(nil, UserID)
+	bmarks.ByID[p1ID] = &Bookmark{
+		PostID:   p1ID,
+		Title:    "Title1",
+		LabelIDs: []string{"UUID1", "UUID2"},
+		History: []*BookmarkEvent{
+			{Type: eventCreated, CreateAt: created, Title: "Old Title", LabelIDs: []string{"UUID1"}},
+			{Type: eventRetitled, CreateAt: created + day, Title: "Title1"},
+			{Type: eventLabelsAdded, CreateAt: created + 2*day, LabelIDs: []string{"UUID2", "UUID9"}},
+		},
+	}
+	return bmarks
+}
+
 func TestExecuteCommandView(t *testing.T) {
 	p1IDmodel := &model.Post{
 		Message:  "this is the post.Message",
@@ -87,10 +107,10 @@ func TestExecuteCommandView(t *testing.T) {
 	defaultSortString := []string{
 		strings.TrimSpace(getLegendText()),
 		"#### Bookmarks",
-		"[:link:](https://myhost.com/_redirect/pl/ID1) `label1` `label2` **_Title1 - New Bookmark - times are zero_**",
-		"[:link:](https://myhost.com/_redirect/pl/ID3) `label3` **_Title3 - bookmarks already updated once_**",
-		"[:link:](https://myhost.com/_redirect/pl/ID4) **`TFP`** this is the post.Message",
-		"[:link:](https://myhost.com/_redirect/pl/ID2) `label1` `label2` `label3` **_Title2 - bookmarks initialized. Times created and same_**",
+		"[:link:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx) `label1` `label2` **_Title1 - New Bookmark - times are zero_**",
+		"[:link:](https://myhost.com/_redirect/pl/ID3xxxxxxxxxxxxxxxxxxxxxxx) `label3` **_Title3 - bookmarks already updated once_**",
+		"[:link:](https://myhost.com/_redirect/pl/ID4xxxxxxxxxxxxxxxxxxxxxxx) **`TFP`** this is the post.Message",
+		"[:link:](https://myhost.com/_redirect/pl/ID2xxxxxxxxxxxxxxxxxxxxxxx) `label1` `label2` `label3` **_Title2 - bookmarks initialized. Times created and same_**",
 	}
 
 	tests := map[string]struct {
@@ -107,6 +127,10 @@ func TestExecuteCommandView(t *testing.T) {
 			expectedMsgPrefix: strings.TrimSpace("You do not have any saved bookmarks"),
 			expectedContains:  nil,
 		},
+		"User views a bookmark that does not exist": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks view " + PostIDDoesNotExistxxxxxxxx},
+			expectedMsgPrefix: "Bookmark `" + PostIDDoesNotExistxxxxxxxx + "` does not exist",
+		},
 		"User has no bookmarks2": {
 			commandArgs:       &model.CommandArgs{Command: "/bookmarks view"},
 			bookmarks:         &Bookmarks{},
@@ -116,10 +140,10 @@ func TestExecuteCommandView(t *testing.T) {
 
 		// View individual bookmark
 		"User requests to view bookmark by ID that has a title defined": {
-			commandArgs:       &model.CommandArgs{Command: "/bookmarks view ID2"},
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks view ID2xxxxxxxxxxxxxxxxxxxxxxx"},
 			expectedMsgPrefix: "",
 			expectedContains: []string{
-				"#### Bookmark Title [:link:](https://myhost.com/_redirect/pl/ID2)",
+				"#### Bookmark Title [:link:](https://myhost.com/_redirect/pl/ID2xxxxxxxxxxxxxxxxxxxxxxx)",
 				"`label1` `label2`",
 				"**Title2 - bookmarks initialized. Times created and same**",
 				"##### Post Message",
@@ -127,11 +151,31 @@ func TestExecuteCommandView(t *testing.T) {
 			},
 		},
 
+		"User requests to view bookmark history": {
+			commandArgs:       &model.CommandArgs{Command: "/bookmarks view ID1xxxxxxxxxxxxxxxxxxxxxxx --history"},
+			bookmarks:         getExecuteCommandViewHistoryBookmarks(),
+			expectedMsgPrefix: "",
+			expectedContains: []string{
+				"#### Bookmark Title [:link:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx)",
+				"##### History",
+				"Jan 2, 2020 15:04 UTC - Created with title **_Old Title_** and labels `label1`",
+				"Jan 3, 2020 15:04 UTC - Retitled to **_Title1_**",
+				"Jan 4, 2020 15:04 UTC - Added labels `deleted label UUID9` `label2`",
+			},
+		},
+		"User requests to view bookmark without history": {
+			commandArgs:         &model.CommandArgs{Command: "/bookmarks view ID1xxxxxxxxxxxxxxxxxxxxxxx"},
+			bookmarks:           getExecuteCommandViewHistoryBookmarks(),
+			expectedMsgPrefix:   "",
+			expectedContains:    []string{"#### Bookmark Title [:link:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx)"},
+			expectedNotContains: []string{"##### History"},
+		},
+
 		// View all bookmarks
 		"User has 3 bookmarks  All with titles provided": {
 			commandArgs:       &model.CommandArgs{Command: "/bookmarks view"},
 			expectedMsgPrefix: strings.TrimSpace(getLegendText()),
-			expectedContains:  []string{"Bookmarks", "ID1", "ID2", "ID3"},
+			expectedContains:  []string{"Bookmarks", "ID1xxxxxxxxxxxxxxxxxxxxxxx", "ID2xxxxxxxxxxxxxxxxxxxxxxx", "ID3xxxxxxxxxxxxxxxxxxxxxxx"},
 		},
 		"User has 4 bookmarks  All with titles  One without": {
 			commandArgs:       &model.CommandArgs{Command: "/bookmarks view"},
@@ -150,32 +194,32 @@ func TestExecuteCommandView(t *testing.T) {
 		"User filter by label  filter one label  label1": {
 			commandArgs:       &model.CommandArgs{Command: "/bookmarks view --filter-labels label1"},
 			expectedMsgPrefix: strings.TrimSpace(getLegendText()),
-			expectedContains:  []string{"Bookmarks", "ID1", "ID2"},
+			expectedContains:  []string{"Bookmarks", "ID1xxxxxxxxxxxxxxxxxxxxxxx", "ID2xxxxxxxxxxxxxxxxxxxxxxx"},
 		},
 		"User filter by label  filter two labels": {
 			commandArgs:         &model.CommandArgs{Command: "/bookmarks view --filter-labels label1,label2"},
 			expectedMsgPrefix:   strings.TrimSpace(getLegendText()),
-			expectedContains:    []string{"Bookmarks", "ID1", "ID2"},
-			expectedNotContains: []string{"ID3", "ID4"},
+			expectedContains:    []string{"Bookmarks", "ID1xxxxxxxxxxxxxxxxxxxxxxx", "ID2xxxxxxxxxxxxxxxxxxxxxxx"},
+			expectedNotContains: []string{"ID3xxxxxxxxxxxxxxxxxxxxxxx", "ID4xxxxxxxxxxxxxxxxxxxxxxx"},
 		},
 		"User filter by label  filter one label  label3": {
 			commandArgs:         &model.CommandArgs{Command: "/bookmarks view --filter-labels label3"},
 			expectedMsgPrefix:   strings.TrimSpace(getLegendText()),
-			expectedContains:    []string{"Bookmarks", "ID3", "ID2"},
-			expectedNotContains: []string{"ID1", "ID4"},
+			expectedContains:    []string{"Bookmarks", "ID3xxxxxxxxxxxxxxxxxxxxxxx", "ID2xxxxxxxxxxxxxxxxxxxxxxx"},
+			expectedNotContains: []string{"ID1xxxxxxxxxxxxxxxxxxxxxxx", "ID4xxxxxxxxxxxxxxxxxxxxxxx"},
 		},
 		"User filter by label  filter all available labels": {
 			commandArgs:         &model.CommandArgs{Command: "/bookmarks view --filter-labels label1,label2,label3"},
 			expectedMsgPrefix:   strings.TrimSpace(getLegendText()),
-			expectedContains:    []string{"Bookmarks", "ID1", "ID2", "ID3"},
-			expectedNotContains: []string{"ID4"},
+			expectedContains:    []string{"Bookmarks", "ID1xxxxxxxxxxxxxxxxxxxxxxx", "ID2xxxxxxxxxxxxxxxxxxxxxxx", "ID3xxxxxxxxxxxxxxxxxxxxxxx"},
+			expectedNotContains: []string{"ID4xxxxxxxxxxxxxxxxxxxxxxx"},
 		},
 	}
 	for name, tt := range tests {
 		api := makeAPIMock()
 		tt.commandArgs.UserId = UserID
 		siteURL := "https://myhost.com"
-		api.On("GetPost", PostIDDoesNotExist).Return(nil, &model.AppError{Message: "An Error Occurred"})
+		api.On("GetPost", PostIDDoesNotExistxxxxxxxx).Return(nil, &model.AppError{Message: "An Error Occurred"})
 		api.On("GetPost", p1ID).Return(p1IDmodel, nil)
 		api.On("GetPost", p2ID).Return(p2IDmodel, nil)
 		api.On("GetPost", p3ID).Return(p3IDmodel, nil)
@@ -184,6 +228,7 @@ func TestExecuteCommandView(t *testing.T) {
 		api.On("GetTeam", mock.Anything).Return(&model.Team{Id: teamID1}, nil)
 		api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
 		api.On("exists", mock.Anything).Return(true)
+		api.On("GetUser", mock.Anything).Return(&model.User{Timezone: model.StringMap{"useAutomaticTimezone": "false", "manualTimezone": "UTC"}}, nil)
 
 		bookmarks := getExecuteCommandViewBookmarks()
 		if tt.bookmarks != nil {
@@ -2,6 +2,7 @@ package main
 
 import (
 	"reflect"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -18,6 +19,119 @@ import (
 // If you add non-reference types to your configuration struct, be sure to rewrite Clone as a deep
 // copy appropriate for your types.
 type configuration struct {
+	// UndoWindowMinutes is the number of minutes a destructive operation can
+	// be undone
+	UndoWindowMinutes int
+
+	// TrashRetentionDays is the number of days removed bookmarks are kept in
+	// the trash
+	TrashRetentionDays int
+
+	// ChannelBoardCuration is who can change channel bookmark boards, either
+	// channel admins or all channel members
+	ChannelBoardCuration string
+
+	// GeneratedTitleLength is the maximum number of characters of titles
+	// generated from post messages
+	GeneratedTitleLength int
+
+	// MaxBookmarksPerUser is the maximum number of bookmarks of a user.  0
+	// means no limit
+	MaxBookmarksPerUser int
+
+	// MaxLabelsPerUser is the maximum number of labels of a user
+	MaxLabelsPerUser int
+
+	// MaxTitleLength is the maximum number of characters of bookmark titles
+	MaxTitleLength int
+
+	// MaxDescriptionLength is the maximum number of characters of label
+	// descriptions
+	MaxDescriptionLength int
+}
+
+const (
+	defaultUndoWindowMinutes    = 10
+	defaultTrashRetentionDays   = 30
+	defaultGeneratedTitleLength = 80
+
+	defaultMaxLabelsPerUser     = 200
+	defaultMaxTitleLength       = 300
+	defaultMaxDescriptionLength = 500
+
+	boardCurationAdmins  = "admins"
+	boardCurationMembers = "members"
+)
+
+// getUndoWindow returns the duration a destructive operation can be undone
+func (c *configuration) getUndoWindow() time.Duration {
+	minutes := c.UndoWindowMinutes
+	if minutes <= 0 {
+		minutes = defaultUndoWindowMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// getTrashRetention returns the duration removed bookmarks are kept in the
+// trash
+func (c *configuration) getTrashRetention() time.Duration {
+	days := c.TrashRetentionDays
+	if days <= 0 {
+		days = defaultTrashRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// getChannelBoardCuration returns who can change channel bookmark boards
+func (c *configuration) getChannelBoardCuration() string {
+	if c.ChannelBoardCuration == boardCurationMembers {
+		return boardCurationMembers
+	}
+	return boardCurationAdmins
+}
+
+// getGeneratedTitleLength returns the maximum number of characters of titles
+// generated from post messages
+func (c *configuration) getGeneratedTitleLength() int {
+	if c.GeneratedTitleLength <= 0 {
+		return defaultGeneratedTitleLength
+	}
+	return c.GeneratedTitleLength
+}
+
+// getMaxBookmarks returns the maximum number of bookmarks of a user, or 0 if
+// the number of bookmarks is not limited
+func (c *configuration) getMaxBookmarks() int {
+	if c.MaxBookmarksPerUser <= 0 {
+		return 0
+	}
+	return c.MaxBookmarksPerUser
+}
+
+// getMaxLabels returns the maximum number of labels of a user
+func (c *configuration) getMaxLabels() int {
+	if c.MaxLabelsPerUser <= 0 {
+		return defaultMaxLabelsPerUser
+	}
+	return c.MaxLabelsPerUser
+}
+
+// getMaxTitleLength returns the maximum number of characters of bookmark
+// titles
+func (c *configuration) getMaxTitleLength() int {
+	if c.MaxTitleLength <= 0 {
+		return defaultMaxTitleLength
+	}
+	return c.MaxTitleLength
+}
+
+// getMaxDescriptionLength returns the maximum number of characters of label
+// descriptions
+func (c *configuration) getMaxDescriptionLength() int {
+	if c.MaxDescriptionLength <= 0 {
+		return defaultMaxDescriptionLength
+	}
+	return c.MaxDescriptionLength
 }
 
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// StoreDueSummaryKey is the key used to store the date of the last due
+	// summary sent to a user in the plugin KV store
+	StoreDueSummaryKey = "due_summary"
+
+	dueDateFormat    = "2006-01-02"
+	dueDisplayFormat = "Mon Jan 2"
+
+	// dueNone removes the due date of a bookmark
+	dueNone = "none"
+
+	dueFilterOverdue  = "overdue"
+	dueFilterToday    = "today"
+	dueFilterThisWeek = "this-week"
+
+	// dueSummaryHour is the hour of the day, in the users timezone, after
+	// which the daily summary of due bookmarks is sent
+	dueSummaryHour = 8
+
+	// dueSummaryInterval is how often the due summary job checks for users
+	// to send the summary to
+	dueSummaryInterval = time.Hour
+
+	// timeFormat is the format of the times of posts and bookmark events
+	// shown to users
+	timeFormat = "Jan 2, 2006 15:04 MST"
+)
+
+// dueFilters are the due date filters of bookmarks
+var dueFilters = []string{dueFilterOverdue, dueFilterToday, dueFilterThisWeek}
+
+// getUserLocation returns the timezone of a user, or UTC if the timezone of
+// the user is unknown
+func (p *Plugin) getUserLocation(userID string) *time.Location {
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(user.GetPreferredTimezone())
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// parseDueDate returns the date of a due date given as YYYY-MM-DD, today,
+// tomorrow or a weekday, relative to now in the users timezone.  An empty
+// date is returned for none
+func parseDueDate(text string, now time.Time) (string, error) {
+	text = strings.ToLower(text)
+	switch text {
+	case dueNone:
+		return "", nil
+	case "today":
+		return now.Format(dueDateFormat), nil
+	case "tomorrow":
+		return now.AddDate(0, 0, 1).Format(dueDateFormat), nil
+	}
+
+	// weekdays are the next such day, never today
+	for day := 1; day <= 7; day++ {
+		date := now.AddDate(0, 0, day)
+		name := strings.ToLower(date.Weekday().String())
+		if text == name || text == name[:3] {
+			return date.Format(dueDateFormat), nil
+		}
+	}
+
+	date, err := time.ParseInLocation(dueDateFormat, text, now.Location())
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Due date `%s` is not valid. Use YYYY-MM-DD, today, tomorrow, a weekday or none", text))
+	}
+	return date.Format(dueDateFormat), nil
+}
+
+// setDue changes the due date of the bookmark and records the change
+func (bm *Bookmark) setDue(due string) {
+	if bm.Due == due {
+		return
+	}
+	bm.Due = due
+	bm.recordEvent(&BookmarkEvent{Type: eventDueChanged, Due: due})
+}
+
+// validateDueFilter checks that filter is a due date filter
+func validateDueFilter(filter string) error {
+	if !containsID(dueFilters, filter) {
+		return errors.New(fmt.Sprintf("Due filter `%s` is not valid. Use one of: %s", filter, strings.Join(dueFilters, ", ")))
+	}
+	return nil
+}
+
+// isOverdue returns whether an unfinished bookmark was due before today
+func (bm *Bookmark) isOverdue(now time.Time) bool {
+	return bm.Due != "" && bm.Due < now.Format(dueDateFormat) && containsID(activeStatuses, bm.getStatus())
+}
+
+// isDueThisWeek returns whether the bookmark is due between Monday and
+// Sunday of the current week
+func (bm *Bookmark) isDueThisWeek(now time.Time) bool {
+	if bm.Due == "" {
+		return false
+	}
+	// weeks start on Monday
+	offset := (int(now.Weekday()) + 6) % 7
+	monday := now.AddDate(0, 0, -offset).Format(dueDateFormat)
+	sunday := now.AddDate(0, 0, 6-offset).Format(dueDateFormat)
+	return bm.Due >= monday && bm.Due <= sunday
+}
+
+// formatDueDate returns a due date for display
+func formatDueDate(due string) string {
+	date, err := time.Parse(dueDateFormat, due)
+	if err != nil {
+		return due
+	}
+	return date.Format(dueDisplayFormat)
+}
+
+// hasDueDates returns whether any of the bookmarks has a due date
+func (b *Bookmarks) hasDueDates() bool {
+	for _, bmark := range b.ByID {
+		if bmark.Due != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// getDueText returns the due date shown after a bookmark, highlighting
+// bookmarks that are overdue or due today
+func getDueText(bmark *Bookmark, now time.Time) string {
+	switch {
+	case bmark.Due == "":
+		return ""
+	case bmark.isOverdue(now):
+		return fmt.Sprintf(" :warning: **overdue since %s**", formatDueDate(bmark.Due))
+	case bmark.Due == now.Format(dueDateFormat) && containsID(activeStatuses, bmark.getStatus()):
+		return " :alarm_clock: **due today**"
+	}
+	return fmt.Sprintf(" :calendar: due %s", formatDueDate(bmark.Due))
+}
+
+// sendDueSummaries sends the daily summary of due bookmarks to the users
+// whose summary time has passed today
+func (p *Plugin) sendDueSummaries() {
+	userIDs, err := p.listUserIDsWithPrefix(StoreBookmarksKey + "_")
+	if err != nil {
+		p.API.LogError("Unable to list keys to send due summaries", "error", err.Error())
+		return
+	}
+
+	for _, userID := range userIDs {
+		if err := p.sendDueSummary(userID, p.getNow(userID)); err != nil {
+			p.API.LogError("Unable to send due summary", "user_id", userID, "error", err.Error())
+		}
+	}
+}
+
+// sendDueSummary sends a user the bookmarks overdue and due today, once a day
+// after the summary hour.  The day is marked as done for every user, users
+// without due bookmarks get no message
+func (p *Plugin) sendDueSummary(userID string, now time.Time) error {
+	if now.Hour() < dueSummaryHour {
+		return nil
+	}
+
+	today := now.Format(dueDateFormat)
+	key := getDueSummaryKey(userID)
+	last, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return appErr
+	}
+	if string(last) == today {
+		return nil
+	}
+
+	// another server may be sending the same summary
+	ok, appErr := p.API.KVCompareAndSet(key, last, []byte(today))
+	if appErr != nil {
+		return appErr
+	}
+	if !ok {
+		return nil
+	}
+
+	bmarks, err := NewBookmarksWithUser(p.API, userID).getBookmarks()
+	if err != nil {
+		return err
+	}
+	if bmarks == nil || !bmarks.hasDueDates() {
+		return nil
+	}
+	overdue, err := bmarks.applyFilters(&BookmarksFilters{Due: dueFilterOverdue, Now: now})
+	if err != nil {
+		return err
+	}
+	due, err := bmarks.applyFilters(&BookmarksFilters{Due: dueFilterToday, Statuses: activeStatuses, Now: now})
+	if err != nil {
+		return err
+	}
+	if len(due.ByID) == 0 && len(overdue.ByID) == 0 {
+		return nil
+	}
+
+	text := ""
+	sections := []struct {
+		title  string
+		bmarks *Bookmarks
+	}{
+		{title: "Overdue bookmarks", bmarks: overdue},
+		{title: "Bookmarks due today", bmarks: due},
+	}
+	for _, section := range sections {
+		if len(section.bmarks.ByID) == 0 {
+			continue
+		}
+		sorted, err := section.bmarks.sortBookmarks(bookmarkSortTime)
+		if err != nil {
+			return err
+		}
+		text += fmt.Sprintf("#### %s\n", section.title)
+		for _, bmark := range sorted {
+			labelNames, err := bmarks.getBmarkLabelNames(bmark)
+			if err != nil {
+				return err
+			}
+			nextText, err := p.getBmarkTextOneLine(bmark, labelNames)
+			if err != nil {
+				return err
+			}
+			text += strings.TrimSuffix(nextText, "\n") + getDueText(bmark, now) + "\n"
+		}
+	}
+	return p.PostBotDM(userID, text)
+}
+
+func getDueSummaryKey(userID string) string {
+	return fmt.Sprintf("%s_%s", StoreDueSummaryKey, userID)
+}
+
+// setBookmarkDue sets the due date of a users bookmark and returns the
+// bookmark
+func (p *Plugin) setBookmarkDue(userID, bmarkID, due string) (*Bookmark, error) {
+	return p.updateBookmark(userID, bmarkID, func(bmark *Bookmark) {
+		bmark.setDue(due)
+	})
+}
+
+// formatTime returns a time given in milliseconds in the timezone loc
+func formatTime(millis int64, loc *time.Location) string {
+	return time.Unix(0, millis*int64(time.Millisecond)).In(loc).Format(timeFormat)
+}
+
+// getNow returns the current time in the users timezone
+func (p *Plugin) getNow(userID string) time.Time {
+	return time.Now().In(p.getUserLocation(userID))
+}
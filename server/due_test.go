@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// getDueTestNow returns Wednesday, January 15, 2020 at 9:00 in New York
+func getDueTestNow(t *testing.T) time.Time {
+	loc, err := time.LoadLocation("America/New_York")
+	require.Nil(t, err)
+	return time.Date(2020, time.January, 15, 9, 0, 0, 0, loc)
+}
+
+func TestParseDueDate(t *testing.T) {
+	now := getDueTestNow(t)
+	tests := map[string]struct {
+		text        string
+		expected    string
+		expectedErr bool
+	}{
+		"date":              {text: "2020-02-01", expected: "2020-02-01"},
+		"today":             {text: "today", expected: "2020-01-15"},
+		"tomorrow":          {text: "Tomorrow", expected: "2020-01-16"},
+		"weekday":           {text: "friday", expected: "2020-01-17"},
+		"short weekday":     {text: "mon", expected: "2020-01-20"},
+		"weekday of today":  {text: "wednesday", expected: "2020-01-22"},
+		"none":              {text: "none", expected: ""},
+		"invalid date":      {text: "2020-13-01", expectedErr: true},
+		"unsupported value": {text: "soon", expectedErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			due, err := parseDueDate(tt.text, now)
+			if tt.expectedErr {
+				assert.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, tt.expected, due)
+		})
+	}
+
+	// dates are relative to the users timezone
+	late := time.Date(2020, time.January, 15, 23, 30, 0, 0, now.Location())
+	due, err := parseDueDate("today", late)
+	require.Nil(t, err)
+	assert.Equal(t, "2020-01-15", due)
+}
+
+func TestDueFilters(t *testing.T) {
+	now := getDueTestNow(t)
+	bmarks := NewBookmarksWithUser(nil, UserID)
+	bmarks.ByID["overdue"] = &Bookmark{PostID: "overdue", Due: "2020-01-14"}
+	bmarks.ByID["done"] = &Bookmark{PostID: "done", Due: "2020-01-10", Status: statusDone}
+	bmarks.ByID["today"] = &Bookmark{PostID: "today", Due: "2020-01-15"}
+	bmarks.ByID["sunday"] = &Bookmark{PostID: "sunday", Due: "2020-01-19"}
+	bmarks.ByID["next-week"] = &Bookmark{PostID: "next-week", Due: "2020-01-20"}
+	bmarks.ByID["none"] = &Bookmark{PostID: "none"}
+
+	tests := map[string][]string{
+		dueFilterOverdue:  {"overdue"},
+		dueFilterToday:    {"today"},
+		dueFilterThisWeek: {"overdue", "today", "sunday"},
+	}
+	for filter, expected := range tests {
+		t.Run(filter, func(t *testing.T) {
+			filtered, err := bmarks.applyFilters(&BookmarksFilters{Due: filter, Now: now})
+			require.Nil(t, err)
+			var ids []string
+			for id := range filtered.ByID {
+				ids = append(ids, id)
+			}
+			assert.ElementsMatch(t, expected, ids)
+		})
+	}
+
+	assert.Equal(t, " :warning: **overdue since Tue Jan 14**", getDueText(bmarks.get("overdue"), now))
+	assert.Equal(t, " :calendar: due Fri Jan 10", getDueText(bmarks.get("done"), now))
+	assert.Equal(t, " :alarm_clock: **due today**", getDueText(bmarks.get("today"), now))
+	assert.Equal(t, " :calendar: due Sun Jan 19", getDueText(bmarks.get("sunday"), now))
+	assert.Equal(t, "", getDueText(bmarks.get("none"), now))
+}
+
+func TestSendDueSummary(t *testing.T) {
+	now := getDueTestNow(t)
+	tests := map[string]struct {
+		now            time.Time
+		lastSummary    []byte
+		due            []string
+		swapped        bool
+		expectedMarked bool
+		expectedMsg    string
+	}{
+		"sends bookmarks due today": {
+			now:            now,
+			due:            []string{"2020-01-15"},
+			swapped:        true,
+			expectedMarked: true,
+			expectedMsg:    "#### Bookmarks due today\n[:link:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx) `label1` **_Title1_** :alarm_clock: **due today**\n",
+		},
+		"sends overdue bookmarks": {
+			now:            now,
+			lastSummary:    []byte("2020-01-14"),
+			due:            []string{"2020-01-10"},
+			swapped:        true,
+			expectedMarked: true,
+			expectedMsg:    "#### Overdue bookmarks\n[:link:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx) `label1` **_Title1_** :warning: **overdue since Fri Jan 10**\n",
+		},
+		"sends overdue bookmarks before bookmarks due today": {
+			now:            now,
+			due:            []string{"2020-01-15", "2020-01-10"},
+			swapped:        true,
+			expectedMarked: true,
+			expectedMsg: "#### Overdue bookmarks\n[:link:](https://myhost.com/_redirect/pl/ID2xxxxxxxxxxxxxxxxxxxxxxx) `label1` **_Title2_** :warning: **overdue since Fri Jan 10**\n" +
+				"#### Bookmarks due today\n[:link:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx) `label1` **_Title1_** :alarm_clock: **due today**\n",
+		},
+		"waits for the summary hour": {
+			now: now.Add(-2 * time.Hour),
+			due: []string{"2020-01-15"},
+		},
+		"sends once a day": {
+			now:         now,
+			lastSummary: []byte("2020-01-15"),
+			due:         []string{"2020-01-15"},
+		},
+		"marks users without due bookmarks": {
+			now:            now,
+			due:            []string{"2020-01-16"},
+			swapped:        true,
+			expectedMarked: true,
+		},
+		"skips summaries sent by another server": {
+			now:            now,
+			due:            []string{"2020-01-15"},
+			expectedMarked: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := makeAPIMock()
+			siteURL := "https://myhost.com"
+			api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+			api.On("GetPost", mock.AnythingOfType("string")).Return(&model.Post{Message: "this is the post.Message"}, nil)
+			api.On("KVGet", getDueSummaryKey(UserID)).Return(tt.lastSummary, nil)
+			api.On("KVCompareAndSet", getDueSummaryKey(UserID), tt.lastSummary, []byte("2020-01-15")).Return(tt.swapped, nil)
+
+			bmarks := NewBookmarksWithUser(api, UserID)
+			for i, due := range tt.due {
+				id := []string{p1ID, p2ID}[i]
+				bmarks.ByID[id] = &Bookmark{PostID: id, Title: fmt.Sprintf("Title%v", i+1), Due: due, LabelIDs: []string{"UUID1"}}
+			}
+			jsonBmarks, err := json.Marshal(bmarks)
+			require.Nil(t, err)
+			api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+			jsonLabels, err := json.Marshal(getExecuteCommandTestLabels())
+			require.Nil(t, err)
+			api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+
+			api.On("GetDirectChannel", UserID, mock.Anything).Return(&model.Channel{Id: "DMChannelID"}, nil)
+			api.On("CreatePost", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				assert.Equal(t, tt.expectedMsg, args.Get(0).(*model.Post).Message)
+			}).Return(&model.Post{}, nil)
+
+			p := makePlugin(api)
+			require.Nil(t, p.sendDueSummary(UserID, tt.now))
+
+			if tt.expectedMarked {
+				api.AssertCalled(t, "KVCompareAndSet", getDueSummaryKey(UserID), tt.lastSummary, []byte("2020-01-15"))
+			} else {
+				api.AssertNotCalled(t, "KVCompareAndSet", mock.Anything, mock.Anything, mock.Anything)
+			}
+			if tt.expectedMsg != "" {
+				api.AssertNumberOfCalls(t, "CreatePost", 1)
+			} else {
+				api.AssertNotCalled(t, "CreatePost", mock.Anything)
+			}
+		})
+	}
+}
+
+func TestExecuteCommandDue(t *testing.T) {
+	tests := map[string]struct {
+		command             string
+		expectedMsgPrefix   string
+		expectedContains    []string
+		expectedNotContains []string
+		expectedDue         string
+	}{
+		"DUE set date": {
+			command:           "/bookmarks due ID1xxxxxxxxxxxxxxxxxxxxxxx 2030-02-01",
+			expectedMsgPrefix: "Set due date to Fri Feb 1: [:link:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx) **_Title1 - New Bookmark - times are zero_** :calendar: due Fri Feb 1",
+			expectedDue:       "2030-02-01",
+		},
+		"DUE remove": {
+			command:           "/bookmarks due ID2xxxxxxxxxxxxxxxxxxxxxxx none",
+			expectedMsgPrefix: "Removed due date: [:link:](https://myhost.com/_redirect/pl/ID2xxxxxxxxxxxxxxxxxxxxxxx)",
+		},
+		"DUE invalid date": {
+			command:           "/bookmarks due ID1xxxxxxxxxxxxxxxxxxxxxxx soon",
+			expectedMsgPrefix: "Due date `soon` is not valid",
+		},
+		"DUE missing date": {
+			command:           "/bookmarks due ID1xxxxxxxxxxxxxxxxxxxxxxx",
+			expectedMsgPrefix: "Please specify a post_id and a due date",
+		},
+		"VIEW overdue": {
+			command:             "/bookmarks view --due overdue",
+			expectedMsgPrefix:   strings.TrimSpace(getLegendText()),
+			expectedContains:    []string{"[:link:](https://myhost.com/_redirect/pl/ID2xxxxxxxxxxxxxxxxxxxxxxx) `label1` `label2` **_Title2 - bookmarks initialized. Times created and same_** :warning: **overdue since Wed Jan 1**"},
+			expectedNotContains: []string{"ID1xxxxxxxxxxxxxxxxxxxxxxx"},
+		},
+		"VIEW invalid due filter": {
+			command:           "/bookmarks view --due later",
+			expectedMsgPrefix: "Unable to parse options, Due filter `later` is not valid",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := makeAPIMock()
+			siteURL := "https://myhost.com"
+			api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+			api.On("GetPost", mock.Anything).Return(&model.Post{Message: "this is the post.Message"}, nil)
+			api.On("GetUser", UserID).Return(&model.User{Id: UserID, Timezone: model.StringMap{"useAutomaticTimezone": "false", "manualTimezone": "America/New_York"}}, nil)
+
+			bmarks := getExecuteCommandTestBookmarks()
+			bmarks.get(p2ID).Due = "2020-01-01"
+			jsonBmarks, err := json.Marshal(bmarks)
+			require.Nil(t, err)
+			api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+			jsonLabels, err := json.Marshal(getExecuteCommandTestLabels())
+			require.Nil(t, err)
+			api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+
+			store := mockBookmarksStore(api, UserID)
+
+			api.On("SendEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				actual := strings.TrimSpace(args.Get(1).(*model.Post).Message)
+				assert.True(t, strings.HasPrefix(actual, tt.expectedMsgPrefix), "Expected returned message to start with: \n%s\nActual:\n%s", tt.expectedMsgPrefix, actual)
+				for _, s := range tt.expectedContains {
+					assert.Contains(t, actual, s)
+				}
+				for _, s := range tt.expectedNotContains {
+					assert.NotContains(t, actual, s)
+				}
+			}).Once().Return(&model.Post{})
+
+			p := makePlugin(api)
+			args := &model.CommandArgs{Command: tt.command, UserId: UserID}
+			cmdResponse, appError := p.ExecuteCommand(&plugin.Context{}, args)
+			require.Nil(t, appError)
+			require.NotNil(t, cmdResponse)
+
+			if tt.expectedDue != "" {
+				stored := store.getBookmarks()
+				require.NotNil(t, stored.get(p1ID))
+				assert.Equal(t, tt.expectedDue, stored.get(p1ID).Due)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	eventCreated         = "created"
+	eventRetitled        = "retitled"
+	eventLabelsAdded     = "labels_added"
+	eventLabelsRemoved   = "labels_removed"
+	eventRemoved         = "removed"
+	eventRestored        = "restored"
+	eventStatusChanged   = "status_changed"
+	eventPriorityChanged = "priority_changed"
+	eventDueChanged      = "due_changed"
+
+	// maxBookmarkHistory is the number of events kept per bookmark
+	maxBookmarkHistory = 50
+)
+
+// BookmarkEvent records a single change to a bookmark
+type BookmarkEvent struct {
+	Type     string   `json:"type"`
+	CreateAt int64    `json:"create_at"`
+	Title    string   `json:"title,omitempty"`     // Title of the bookmark after the event
+	LabelIDs []string `json:"label_ids,omitempty"` // Labels added or removed by the event
+	Status   string   `json:"status,omitempty"`    // Status of the bookmark after the event
+	Priority string   `json:"priority,omitempty"`  // Priority of the bookmark after the event
+	Due      string   `json:"due,omitempty"`       // Due date of the bookmark after the event
+}
+
+// recordEvent adds an event to the bookmark history
+func (bm *Bookmark) recordEvent(event *BookmarkEvent) {
+	event.CreateAt = model.GetMillis()
+	bm.History = append(bm.History, event)
+	if len(bm.History) > maxBookmarkHistory {
+		bm.History = bm.History[len(bm.History)-maxBookmarkHistory:]
+	}
+}
+
+// recordChanges adds events for the title and label changes from orig to the
+// bookmark
+func (bm *Bookmark) recordChanges(orig *Bookmark) {
+	if bm.getTitle() != orig.getTitle() {
+		bm.recordEvent(&BookmarkEvent{Type: eventRetitled, Title: bm.getTitle()})
+	}
+	bm.recordLabelChanges(orig.getLabelIDs())
+	if bm.getStatus() != orig.getStatus() {
+		bm.recordEvent(&BookmarkEvent{Type: eventStatusChanged, Status: bm.getStatus()})
+	}
+	if bm.getPriority() != orig.getPriority() {
+		bm.recordEvent(&BookmarkEvent{Type: eventPriorityChanged, Priority: bm.getPriority()})
+	}
+	if bm.Due != orig.Due {
+		bm.recordEvent(&BookmarkEvent{Type: eventDueChanged, Due: bm.Due})
+	}
+}
+
+// recordLabelChanges adds events for the labels added and removed since the
+// bookmark had origIDs
+func (bm *Bookmark) recordLabelChanges(origIDs []string) {
+	added := subtractIDs(bm.getLabelIDs(), origIDs)
+	if len(added) != 0 {
+		bm.recordEvent(&BookmarkEvent{Type: eventLabelsAdded, LabelIDs: added})
+	}
+
+	removed := subtractIDs(origIDs, bm.getLabelIDs())
+	if len(removed) != 0 {
+		bm.recordEvent(&BookmarkEvent{Type: eventLabelsRemoved, LabelIDs: removed})
+	}
+}
+
+// updateLabelIDs replaces the bookmark labels and records the changes
+func (bm *Bookmark) updateLabelIDs(ids []string) {
+	origIDs := bm.getLabelIDs()
+	bm.addLabelIDs(ids)
+	bm.recordLabelChanges(origIDs)
+}
+
+// subtractIDs returns the ids in a that are not in b
+func subtractIDs(a, b []string) []string {
+	inB := make(map[string]bool)
+	for _, id := range b {
+		inB[id] = true
+	}
+
+	var ids []string
+	for _, id := range a {
+		if !inB[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// getBmarkHistoryText returns the history of a bookmark used for an ephemeral
+// post, with times in the timezone loc
+func getBmarkHistoryText(bmark *Bookmark, labels *Labels, loc *time.Location) string {
+	text := "##### History\n"
+	if len(bmark.History) == 0 {
+		return text + "No history recorded for this bookmark\n"
+	}
+
+	for _, event := range bmark.History {
+		when := formatTime(event.CreateAt, loc)
+		text += fmt.Sprintf("* %s - %s\n", when, getEventText(event, labels))
+	}
+	return text
+}
+
+// getEventText returns a description of a bookmark event
+func getEventText(event *BookmarkEvent, labels *Labels) string {
+	labelNames := getCodeBlockedLabels(getEventLabelNames(event, labels))
+
+	switch event.Type {
+	case eventCreated:
+		text := "Created"
+		if event.Title != "" {
+			text += fmt.Sprintf(" with title **_%s_**", event.Title)
+		}
+		if labelNames != "" && event.Title != "" {
+			text += " and labels" + labelNames
+		} else if labelNames != "" {
+			text += " with labels" + labelNames
+		}
+		return text
+	case eventRetitled:
+		if event.Title == "" {
+			return "Removed title"
+		}
+		return fmt.Sprintf("Retitled to **_%s_**", event.Title)
+	case eventLabelsAdded:
+		return "Added labels" + labelNames
+	case eventLabelsRemoved:
+		return "Removed labels" + labelNames
+	case eventRemoved:
+		return "Removed"
+	case eventRestored:
+		return "Restored"
+	case eventStatusChanged:
+		return fmt.Sprintf("Marked as `%s`", event.Status)
+	case eventPriorityChanged:
+		return fmt.Sprintf("Changed priority to `%s`", event.Priority)
+	case eventDueChanged:
+		if event.Due == "" {
+			return "Removed due date"
+		}
+		return fmt.Sprintf("Set due date to %s", formatDueDate(event.Due))
+	default:
+		return event.Type
+	}
+}
+
+// getEventLabelNames returns the names of the labels of an event.  Labels
+// deleted since the event are shown by ID
+func getEventLabelNames(event *BookmarkEvent, labels *Labels) []string {
+	var names []string
+	for _, id := range event.LabelIDs {
+		name, err := labels.getNameFromID(id)
+		if err != nil {
+			name = fmt.Sprintf("deleted label %s", id)
+		}
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func getEventTypes(bmark *Bookmark) []string {
+	var types []string
+	for _, event := range bmark.History {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestBookmark_recordChanges(t *testing.T) {
+	orig := &Bookmark{PostID: "ID1xxxxxxxxxxxxxxxxxxxxxxx", Title: "Title1", LabelIDs: []string{"UUID1", "UUID2"}}
+	bmark := &Bookmark{PostID: "ID1xxxxxxxxxxxxxxxxxxxxxxx", Title: "Title2", LabelIDs: []string{"UUID2", "UUID3"}}
+
+	bmark.recordChanges(orig)
+	require.Len(t, bmark.History, 3)
+	assert.Equal(t, []string{eventRetitled, eventLabelsAdded, eventLabelsRemoved}, getEventTypes(bmark))
+	assert.Equal(t, "Title2", bmark.History[0].Title)
+	assert.Equal(t, []string{"UUID3"}, bmark.History[1].LabelIDs)
+	assert.Equal(t, []string{"UUID1"}, bmark.History[2].LabelIDs)
+	assert.NotZero(t, bmark.History[0].CreateAt)
+
+	unchanged := &Bookmark{PostID: "ID1xxxxxxxxxxxxxxxxxxxxxxx", Title: "Title1", LabelIDs: []string{"UUID2", "UUID1"}}
+	unchanged.recordChanges(orig)
+	assert.Empty(t, unchanged.History)
+}
+
+func TestBookmark_updateLabelIDs(t *testing.T) {
+	bmark := &Bookmark{PostID: "ID1xxxxxxxxxxxxxxxxxxxxxxx", LabelIDs: []string{"UUID1", "UUID2"}}
+
+	bmark.updateLabelIDs([]string{"UUID2"})
+	assert.Equal(t, []string{"UUID2"}, bmark.getLabelIDs())
+	assert.Equal(t, []string{eventLabelsRemoved}, getEventTypes(bmark))
+}
+
+func TestBookmark_recordEventTrimsHistory(t *testing.T) {
+	bmark := &Bookmark{PostID: "ID1xxxxxxxxxxxxxxxxxxxxxxx"}
+	bmark.recordEvent(&BookmarkEvent{Type: eventCreated})
+	for i := 0; i < maxBookmarkHistory; i++ {
+		bmark.recordEvent(&BookmarkEvent{Type: eventRetitled})
+	}
+
+	assert.Len(t, bmark.History, maxBookmarkHistory)
+	assert.Equal(t, eventRetitled, bmark.History[0].Type)
+}
+
+func TestAddBookmarkRecordsHistory(t *testing.T) {
+	api := makeAPIMock()
+	mockBookmarksStore(api, UserID)
+	api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+	api.On("KVGet", getTrashKey(UserID)).Return(nil, nil).Once()
+
+	bmarks := NewBookmarksWithUser(api, UserID)
+
+	// new bookmark
+	err := bmarks.addBookmark(&Bookmark{PostID: "ID1xxxxxxxxxxxxxxxxxxxxxxx", Title: "Title1", LabelIDs: []string{"UUID1"}})
+	require.Nil(t, err)
+	created := bmarks.get("ID1xxxxxxxxxxxxxxxxxxxxxxx")
+	assert.Equal(t, []string{eventCreated}, getEventTypes(created))
+	assert.Equal(t, "Title1", created.History[0].Title)
+	assert.Equal(t, []string{"UUID1"}, created.History[0].LabelIDs)
+	assert.NotZero(t, created.CreateAt)
+
+	// bookmark updated by a caller providing its own history and times
+	api.On("KVGet", getTrashKey(UserID)).Return(nil, nil).Once()
+	err = bmarks.addBookmark(&Bookmark{
+		PostID:   "ID1xxxxxxxxxxxxxxxxxxxxxxx",
+		Title:    "Title2",
+		LabelIDs: []string{"UUID1"},
+		History:  []*BookmarkEvent{{Type: eventRemoved}},
+	})
+	require.Nil(t, err)
+	updated := bmarks.get("ID1xxxxxxxxxxxxxxxxxxxxxxx")
+	assert.Equal(t, []string{eventCreated, eventRetitled}, getEventTypes(updated))
+	assert.Equal(t, created.CreateAt, updated.CreateAt)
+
+	// bookmark added again after being removed to the trash
+	trashed := &Bookmark{PostID: "ID2xxxxxxxxxxxxxxxxxxxxxxx", CreateAt: 1, DeleteAt: 2, History: []*BookmarkEvent{
+		{Type: eventCreated},
+		{Type: eventRemoved},
+	}}
+	trash := NewTrashWithUser(api, UserID)
+	trash.ByID[trashed.PostID] = trashed
+	jsonTrash, err := json.Marshal(trash)
+	require.Nil(t, err)
+	api.On("KVGet", getTrashKey(UserID)).Return(jsonTrash, nil).Once()
+
+	err = bmarks.addBookmark(&Bookmark{PostID: "ID2xxxxxxxxxxxxxxxxxxxxxxx", LabelIDs: []string{"UUID1"}})
+	require.Nil(t, err)
+	restored := bmarks.get("ID2xxxxxxxxxxxxxxxxxxxxxxx")
+	assert.Equal(t, []string{eventCreated, eventRemoved, eventRestored, eventLabelsAdded}, getEventTypes(restored))
+	assert.Equal(t, int64(1), restored.CreateAt)
+	assert.Zero(t, restored.DeleteAt)
+}
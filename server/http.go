@@ -2,8 +2,11 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/gorilla/mux"
 
@@ -11,6 +14,17 @@ import (
 	"github.com/mattermost/mattermost-server/v5/plugin"
 )
 
+const (
+	autocompleteLabelsURL    = "/api/v1/autocomplete/labels"
+	autocompleteBookmarksURL = "/api/v1/autocomplete/bookmarks"
+	autocompleteTrashURL     = "/api/v1/autocomplete/trash"
+	autocompleteRulesURL     = "/api/v1/autocomplete/rules"
+
+	// maxAutocompleteBookmarks is the maximum number of recent bookmarks
+	// suggested by the bookmarks autocomplete endpoint
+	maxAutocompleteBookmarks = 25
+)
+
 type HTTPHandlerFuncWithUser func(w http.ResponseWriter, r *http.Request, userID string)
 
 type APIErrorResponse struct {
@@ -32,8 +46,21 @@ func (p *Plugin) initialiseAPI() {
 	apiRouter.HandleFunc("/view", p.extractUserMiddleWare(p.handleViewBookmarks, true)).Methods("POST")
 	apiRouter.HandleFunc("/add", p.extractUserMiddleWare(p.handleAddBookmark, true)).Methods("POST")
 	apiRouter.HandleFunc("/get", p.extractUserMiddleWare(p.handleGetBookmark, true)).Methods("GET")
+	apiRouter.HandleFunc("/bookmarks/{id}/history", p.extractUserMiddleWare(p.handleGetBookmarkHistory, true)).Methods("GET")
 	apiRouter.HandleFunc("/labels/get", p.extractUserMiddleWare(p.handleLabelsGet, true)).Methods("GET")
 	apiRouter.HandleFunc("/labels/add", p.extractUserMiddleWare(p.handleLabelsAdd, true)).Methods("POST")
+	apiRouter.HandleFunc("/labels/update", p.extractUserMiddleWare(p.handleLabelsUpdate, true)).Methods("POST")
+	apiRouter.HandleFunc("/labels/merge", p.extractUserMiddleWare(p.handleLabelsMerge, true)).Methods("POST")
+	apiRouter.HandleFunc("/undo", p.extractUserMiddleWare(p.handleUndo, true)).Methods("POST")
+	apiRouter.HandleFunc("/status/done", p.extractUserMiddleWare(p.handleStatusDone, true)).Methods("POST")
+	apiRouter.HandleFunc("/share/add", p.extractUserMiddleWare(p.handleShareAdd, true)).Methods("POST")
+	apiRouter.HandleFunc("/trash/get", p.extractUserMiddleWare(p.handleTrashGet, true)).Methods("GET")
+	apiRouter.HandleFunc("/trash/restore", p.extractUserMiddleWare(p.handleTrashRestore, true)).Methods("POST")
+	apiRouter.HandleFunc("/trash/empty", p.extractUserMiddleWare(p.handleTrashEmpty, true)).Methods("POST")
+	p.router.HandleFunc(autocompleteLabelsURL, p.extractUserMiddleWare(p.handleAutocompleteLabels, true)).Methods("GET")
+	p.router.HandleFunc(autocompleteBookmarksURL, p.extractUserMiddleWare(p.handleAutocompleteBookmarks, true)).Methods("GET")
+	p.router.HandleFunc(autocompleteTrashURL, p.extractUserMiddleWare(p.handleAutocompleteTrash, true)).Methods("GET")
+	p.router.HandleFunc(autocompleteRulesURL, p.extractUserMiddleWare(p.handleAutocompleteRules, true)).Methods("GET")
 }
 
 func (p *Plugin) ServeHTTP(c *plugin.Context, w http.ResponseWriter, r *http.Request) {
@@ -78,7 +105,7 @@ func (p *Plugin) handleAddBookmark(w http.ResponseWriter, r *http.Request, userI
 	bmark := req.Bookmark
 	channelID := req.ChannelID
 
-	bmarks, err := NewBookmarksWithUser(p.API, userID).getBookmarks()
+	bmarks, err := NewBookmarksWithUser(p.API, userID).getBookmarksIndex()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -87,15 +114,37 @@ func (p *Plugin) handleAddBookmark(w http.ResponseWriter, r *http.Request, userI
 	l, err := NewLabelsWithUser(p.API, userID).getLabels()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 	ids := bmark.getLabelIDs()
 
+	// labels that do not exist are given by name
+	var newNames []string
+	for _, id := range ids {
+		if label, _ := l.get(id); label == nil {
+			newNames = append(newNames, id)
+		}
+	}
+	if err = p.checkTitleLength(bmark.getTitle()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err = p.checkBookmarksLimit(bmarks, []string{bmark.PostID}); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err = p.checkLabelsLimit(l, newNames); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
 	var newIDs []string
 	var label *Label
 	for _, id := range ids {
 		label, err = l.get(id)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 
 		// if doesn't exist, this is a name and needs to be added to the labels
@@ -105,6 +154,7 @@ func (p *Plugin) handleAddBookmark(w http.ResponseWriter, r *http.Request, userI
 			labelNew, err = l.addLabel(id)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
 			}
 			newIDs = append(newIDs, labelNew.ID)
 			continue
@@ -114,6 +164,18 @@ func (p *Plugin) handleAddBookmark(w http.ResponseWriter, r *http.Request, userI
 
 	// update bmark with UUID values, not the names
 	bmark.LabelIDs = newIDs
+
+	// labeling rules and snapshots only apply to new bookmarks.  Snapshots
+	// are always taken by the server
+	bmark.Snapshot = nil
+	if _, ok := bmarks.exists(bmark.PostID); !ok {
+		if post, appErr := p.API.GetPost(bmark.PostID); appErr == nil {
+			p.applyLabelRulesOrWarn(userID, bmark, post)
+			newIDs = bmark.getLabelIDs()
+			bmark.Snapshot = newPostSnapshot(p.API, post)
+		}
+	}
+
 	err = bmarks.addBookmark(bmark)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -126,6 +188,7 @@ func (p *Plugin) handleAddBookmark(w http.ResponseWriter, r *http.Request, userI
 		name, err = l.getNameFromID(id)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 		names = append(names, name)
 	}
@@ -153,6 +216,7 @@ func (p *Plugin) handleAddBookmark(w http.ResponseWriter, r *http.Request, userI
 func (p *Plugin) handleViewBookmarks(w http.ResponseWriter, r *http.Request, userID string) {
 	type requestStruct struct {
 		ChannelID string `json:"channelId"`
+		Sort      string `json:"sort"`
 	}
 
 	body, err := ioutil.ReadAll(r.Body)
@@ -168,7 +232,16 @@ func (p *Plugin) handleViewBookmarks(w http.ResponseWriter, r *http.Request, use
 	}
 	channelID := req.ChannelID
 
-	text, err := p.getBmarksEphemeralText(userID, nil)
+	sortBy := req.Sort
+	if sortBy == "" {
+		sortBy = bookmarkSortTime
+	}
+	if err = validateBookmarkSort(sortBy); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	text, attachments, err := p.getBmarksEphemeralText(userID, nil, sortBy)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -179,6 +252,9 @@ func (p *Plugin) handleViewBookmarks(w http.ResponseWriter, r *http.Request, use
 		ChannelId: channelID,
 		Message:   text,
 	}
+	if len(attachments) != 0 {
+		model.ParseSlackAttachment(post, attachments)
+	}
 	_ = p.API.SendEphemeralPost(userID, post)
 }
 
@@ -187,9 +263,10 @@ func (p *Plugin) handleGetBookmark(w http.ResponseWriter, r *http.Request, userI
 	query := r.URL.Query()
 	postID := query["postID"][0]
 
-	bmarks, err := NewBookmarksWithUser(p.API, userID).getBookmarks()
+	bmarks, err := NewBookmarksWithUser(p.API, userID).getBookmarksIndex()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	// return nil if bookmark does not exist
@@ -220,12 +297,57 @@ func (p *Plugin) handleGetBookmark(w http.ResponseWriter, r *http.Request, userI
 	}
 }
 
+// handleGetBookmarkHistory returns the history of a bookmark, including
+// bookmarks in the trash
+func (p *Plugin) handleGetBookmarkHistory(w http.ResponseWriter, r *http.Request, userID string) {
+	bmarkID := mux.Vars(r)["id"]
+
+	bmarks, err := NewBookmarksWithUser(p.API, userID).getBookmarksIndex()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	bmark, ok := bmarks.exists(bmarkID)
+	if !ok {
+		var trash *Trash
+		trash, err = NewTrashWithUser(p.API, userID).getTrash()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		bmark = trash.get(bmarkID)
+	}
+	if bmark == nil {
+		http.Error(w, "Bookmark does not exist", http.StatusNotFound)
+		return
+	}
+
+	history := bmark.History
+	if history == nil {
+		history = []*BookmarkEvent{}
+	}
+
+	resp, err := json.Marshal(history)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = w.Write(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
 // handleLabelsGet returns all labels
 func (p *Plugin) handleLabelsGet(w http.ResponseWriter, r *http.Request, userID string) {
 	l := NewLabelsWithUser(p.API, userID)
 	labels, err := l.getLabels()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	resp, err := json.Marshal(labels)
@@ -244,7 +366,7 @@ func (p *Plugin) handleLabelsGet(w http.ResponseWriter, r *http.Request, userID
 // handleLabelsAdd adds a label to the labels store
 func (p *Plugin) handleLabelsAdd(w http.ResponseWriter, r *http.Request, userID string) {
 	query := r.URL.Query()
-	labelName := query["labelName"][0]
+	labelName := normalizeLabelName(query.Get("labelName"))
 	l := NewLabelsWithUser(p.API, userID)
 	labels, err := l.getLabels()
 	if err != nil {
@@ -252,12 +374,45 @@ func (p *Plugin) handleLabelsAdd(w http.ResponseWriter, r *http.Request, userID
 		return
 	}
 
+	if existing := labels.getLabelByName(labelName); existing != nil {
+		http.Error(w, fmt.Sprintf("Label with name `%s` already exists", existing.Name), http.StatusConflict)
+		return
+	}
+	if err = validateLabelName(labelName); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	color, err := normalizeLabelColor(query.Get("color"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = p.checkDescriptionLength(query.Get("description")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err = p.checkLabelsLimit(labels, []string{labelName}); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
 	label, err := labels.addLabel(labelName)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if color != "" || query.Get("description") != "" {
+		label.Color = color
+		label.Description = strings.TrimSpace(query.Get("description"))
+		if err = labels.add(label.ID, label); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
 	resp, err := json.Marshal(label)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -271,6 +426,476 @@ func (p *Plugin) handleLabelsAdd(w http.ResponseWriter, r *http.Request, userID
 	}
 }
 
+// labelsUpdateRequest is the body of a request to update a label.  Fields
+// missing from the request are left unchanged
+type labelsUpdateRequest struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Color       *string `json:"color"`
+	Description *string `json:"description"`
+}
+
+// handleLabelsUpdate updates the name, color and description of a label
+func (p *Plugin) handleLabelsUpdate(w http.ResponseWriter, r *http.Request, userID string) {
+	var update *labelsUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil || update == nil {
+		http.Error(w, "Unable to decode label", http.StatusBadRequest)
+		return
+	}
+
+	labels, err := NewLabelsWithUser(p.API, userID).getLabels()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	label := labels.ByID[update.ID]
+	if label == nil {
+		http.Error(w, fmt.Sprintf("Label ID `%s` does not exist", update.ID), http.StatusNotFound)
+		return
+	}
+
+	color := label.Color
+	if update.Color != nil {
+		if color, err = normalizeLabelColor(*update.Color); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	description := label.Description
+	if update.Description != nil {
+		if err = p.checkDescriptionLength(*update.Description); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		description = strings.TrimSpace(*update.Description)
+	}
+
+	if update.Name != "" && update.Name != label.Name {
+		if existingID, nameErr := labels.getIDFromName(update.Name); nameErr == nil && existingID != update.ID {
+			http.Error(w, fmt.Sprintf("Label with name `%s` already exists", update.Name), http.StatusConflict)
+			return
+		}
+		// renaming a label also renames the labels nested under it and creates
+		// its missing parents
+		if err = p.checkLabelsLimit(labels, []string{getLabelParentName(update.Name)}); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err = labels.moveLabel(update.ID, update.Name); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	label.Color = color
+	label.Description = description
+
+	if err = labels.add(update.ID, label); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.Marshal(label)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = w.Write(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// labelsMergeRequest is the body of a request to merge labels
+type labelsMergeRequest struct {
+	From []string `json:"from"`
+	Into string   `json:"into"`
+}
+
+// handleLabelsMerge merges labels into another label and returns the number
+// of bookmarks changed
+func (p *Plugin) handleLabelsMerge(w http.ResponseWriter, r *http.Request, userID string) {
+	var req labelsMergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Unable to decode merge request", http.StatusBadRequest)
+		return
+	}
+
+	labels, err := NewLabelsWithUser(p.API, userID).getLabels()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	fromIDs, intoID, err := labels.getMergeIDs(req.From, req.Into)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	changed, err := p.mergeLabels(userID, labels, fromIDs, intoID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.Marshal(map[string]int{"changed": changed})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = w.Write(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleTrashGet returns the removed bookmarks in the trash
+func (p *Plugin) handleTrashGet(w http.ResponseWriter, r *http.Request, userID string) {
+	trash, err := p.getUserTrash(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.Marshal(trash)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = w.Write(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleTrashRestore restores a removed bookmark from the trash and returns
+// the restored bookmark
+func (p *Plugin) handleTrashRestore(w http.ResponseWriter, r *http.Request, userID string) {
+	postID := r.URL.Query().Get("postID")
+
+	trash, err := p.getUserTrash(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	bmark := trash.get(postID)
+	if bmark == nil {
+		http.Error(w, "Bookmark is not in the trash", http.StatusNotFound)
+		return
+	}
+
+	_, err = p.restoreBookmarks(userID, []*Bookmark{bmark})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.Marshal(bmark)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = w.Write(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleTrashEmpty permanently deletes all bookmarks in the trash
+func (p *Plugin) handleTrashEmpty(w http.ResponseWriter, r *http.Request, userID string) {
+	trash, err := NewTrashWithUser(p.API, userID).getTrash()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	count, err := trash.empty()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.Marshal(map[string]int{"deleted": count})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = w.Write(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleUndo restores the state removed by the operation of an Undo button and
+// replaces the ephemeral confirmation with the result
+func (p *Plugin) handleUndo(w http.ResponseWriter, r *http.Request, userID string) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req *model.PostActionIntegrationRequest
+	if err = json.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opID, _ := req.Context["operation_id"].(string)
+	text, err := p.undoOperationByID(userID, opID)
+	if err != nil {
+		text = err.Error()
+	}
+
+	post := &model.Post{
+		Id:        req.PostId,
+		UserId:    p.getBotID(),
+		ChannelId: req.ChannelId,
+		Message:   text,
+	}
+	_ = p.API.UpdateEphemeralPost(userID, post)
+
+	resp := &model.PostActionIntegrationResponse{}
+	_, err = w.Write(resp.ToJson())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleShareAdd copies the bookmarks of an "Add to my bookmarks" button into
+// the users bookmarks and replies with the result
+func (p *Plugin) handleShareAdd(w http.ResponseWriter, r *http.Request, userID string) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req *model.PostActionIntegrationRequest
+	if err = json.Unmarshal(body, &req); err != nil || req == nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	bb, _ := req.Context["bookmarks"].(string)
+	var shared []*SharedBookmark
+	if err = json.Unmarshal([]byte(bb), &shared); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	text, err := p.addSharedBookmarks(userID, shared)
+	if err != nil {
+		text = err.Error()
+	}
+
+	resp := &model.PostActionIntegrationResponse{EphemeralText: text}
+	_, err = w.Write(resp.ToJson())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleStatusDone marks the bookmark of a "Mark done" button as done and
+// replies with the bookmark
+func (p *Plugin) handleStatusDone(w http.ResponseWriter, r *http.Request, userID string) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req *model.PostActionIntegrationRequest
+	if err = json.Unmarshal(body, &req); err != nil || req == nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	bmarkID, _ := req.Context["bookmark_id"].(string)
+	var text string
+	bmark, err := p.setBookmarkStatus(userID, bmarkID, statusDone)
+	if err != nil {
+		text = err.Error()
+	} else {
+		text, err = p.getBmarkTextOneLine(bmark, nil)
+		if err != nil {
+			text = err.Error()
+		} else {
+			text = "Marked as done: " + text
+		}
+	}
+
+	resp := &model.PostActionIntegrationResponse{EphemeralText: text}
+	_, err = w.Write(resp.ToJson())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleAutocompleteLabels returns the users label names as autocomplete
+// suggestions.  Labels are comma-separated, so suggestions complete the last
+// label being typed and skip labels already entered in the argument
+func (p *Plugin) handleAutocompleteLabels(w http.ResponseWriter, r *http.Request, userID string) {
+	labels, err := NewLabelsWithUser(p.API, userID).getLabels()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// the text typed for the current argument only
+	query := r.URL.Query()
+	typed := strings.TrimPrefix(strings.TrimPrefix(query.Get("user_input"), query.Get("parsed")), " ")
+
+	var prefix string
+	entered := make(map[string]bool)
+	if i := strings.LastIndex(typed, ","); i != -1 {
+		prefix = typed[:i+1]
+		for _, name := range strings.Split(typed[:i], ",") {
+			entered[name] = true
+		}
+	}
+
+	var names []string
+	for _, label := range labels.ByID {
+		if !entered[label.Name] {
+			names = append(names, label.Name)
+		}
+	}
+	sort.Strings(names)
+
+	items := []model.AutocompleteListItem{}
+	for _, name := range names {
+		items = append(items, model.AutocompleteListItem{
+			Item:     prefix + name,
+			HelpText: name,
+		})
+	}
+
+	_, err = w.Write(model.AutocompleteStaticListItemsToJSON(items))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleAutocompleteBookmarks returns the users most recently modified
+// bookmarks as autocomplete suggestions
+func (p *Plugin) handleAutocompleteBookmarks(w http.ResponseWriter, r *http.Request, userID string) {
+	bmarks, err := NewBookmarksWithUser(p.API, userID).getBookmarks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// users who never added a bookmark have nil bookmarks
+	if bmarks == nil {
+		bmarks = NewBookmarksWithUser(p.API, userID)
+	}
+
+	recent := make([]*Bookmark, 0, len(bmarks.ByID))
+	for _, bmark := range bmarks.ByID {
+		recent = append(recent, bmark)
+	}
+	sort.Slice(recent, func(i, j int) bool {
+		return recent[i].ModifiedAt > recent[j].ModifiedAt
+	})
+	if len(recent) > maxAutocompleteBookmarks {
+		recent = recent[:maxAutocompleteBookmarks]
+	}
+
+	items := p.getBookmarkAutocompleteItems(recent)
+	_, err = w.Write(model.AutocompleteStaticListItemsToJSON(items))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleAutocompleteTrash returns the users removed bookmarks as autocomplete
+// suggestions
+func (p *Plugin) handleAutocompleteTrash(w http.ResponseWriter, r *http.Request, userID string) {
+	trash, err := p.getUserTrash(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	items := p.getBookmarkAutocompleteItems(trash.ByDeleteAt())
+	_, err = w.Write(model.AutocompleteStaticListItemsToJSON(items))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleAutocompleteRules returns the conditions of the users labeling rules
+// as autocomplete suggestions
+func (p *Plugin) handleAutocompleteRules(w http.ResponseWriter, r *http.Request, userID string) {
+	rules, err := NewRulesWithUser(p.API, userID).getRules()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	labels, err := NewLabelsWithUser(p.API, userID).getLabels()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	items := []model.AutocompleteListItem{}
+	for _, id := range rules.sortedIDs() {
+		rule := rules.get(id)
+		names, _ := labels.getNamesFromIDs(rule.LabelIDs)
+		items = append(items, model.AutocompleteListItem{
+			Item:     strings.Trim(p.getRuleConditionText(rule), "\""),
+			HelpText: strings.TrimSpace(getCodeBlockedLabels(names)),
+		})
+	}
+
+	_, err = w.Write(model.AutocompleteStaticListItemsToJSON(items))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// getBookmarkAutocompleteItems returns autocomplete suggestions for bookmarks
+// with the bookmark title as help text
+func (p *Plugin) getBookmarkAutocompleteItems(bookmarks []*Bookmark) []model.AutocompleteListItem {
+	items := []model.AutocompleteListItem{}
+	for _, bmark := range bookmarks {
+		title := bmark.getTitle()
+		if !bmark.hasUserTitle() {
+			// skip bookmarks whose post is no longer available
+			var err error
+			title, err = p.getBmarkGeneratedTitle(bmark)
+			if err != nil {
+				continue
+			}
+		}
+		items = append(items, model.AutocompleteListItem{
+			Item:     bmark.PostID,
+			HelpText: title,
+		})
+	}
+	return items
+}
+
 func (p *Plugin) handleErrorWithCode(w http.ResponseWriter, code int, errTitle string, err error) {
 	w.WriteHeader(code)
 	b, _ := json.Marshal(struct {
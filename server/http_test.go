@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHandleAddBookmark(t *testing.T) {
@@ -20,11 +22,11 @@ func TestHandleAddBookmark(t *testing.T) {
 	}
 	b2 := &Bookmark{
 		Title:  "PostID-Title",
-		PostID: "ID1",
+		PostID: "ID1xxxxxxxxxxxxxxxxxxxxxxx",
 	}
 	b3 := &Bookmark{
 		Title:    "PostID-Title",
-		PostID:   "ID3",
+		PostID:   "ID3xxxxxxxxxxxxxxxxxxxxxxx",
 		LabelIDs: []string{"newLabel"},
 	}
 
@@ -97,6 +99,8 @@ func TestHandleAddBookmark(t *testing.T) {
 			api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
 			api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
 			api.On("KVGet", getLabelsKey(UserID)).Return(nil, nil)
+			api.On("KVGet", getRulesKey(UserID)).Return(nil, nil)
+			api.On("KVGet", getTrashKey(UserID)).Return(nil, nil)
 			api.On("GetPost", tt.bookmark.PostID).Return(&model.Post{Message: "this is the post.Message"}, nil)
 			api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
 
@@ -155,7 +159,7 @@ func TestHandleGetBookmark(t *testing.T) {
 	}
 	for name, tt := range tests {
 		bmarks := getExecuteCommandTestBookmarks()
-		bookmark := bmarks.ByID["ID1"]
+		bookmark := bmarks.ByID["ID1xxxxxxxxxxxxxxxxxxxxxxx"]
 
 		t.Run(name, func(t *testing.T) {
 			jsonBmark, err := json.Marshal(bookmark)
@@ -166,7 +170,7 @@ func TestHandleGetBookmark(t *testing.T) {
 			api.On("getBookmark", bookmark.PostID).Return(bookmark)
 			api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
 
-			r := httptest.NewRequest(http.MethodGet, "/api/v1/get?postID=ID1", strings.NewReader(string(jsonBmark)))
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/get?postID=ID1xxxxxxxxxxxxxxxxxxxxxxx", strings.NewReader(string(jsonBmark)))
 			r.Header.Add("Mattermost-User-Id", tt.userID)
 
 			p.initialiseAPI()
@@ -193,13 +197,13 @@ func TestHandleViewBookmarks(t *testing.T) {
 		expectedCode int
 	}{
 		"Unauthed User": {
-			bookmark:     bmarks.ByID["ID1"],
+			bookmark:     bmarks.ByID["ID1xxxxxxxxxxxxxxxxxxxxxxx"],
 			bookmarks:    bmarks,
 			expectedCode: http.StatusUnauthorized,
 		},
 		"get bookmark1": {
 			userID:       UserID,
-			bookmark:     bmarks.ByID["ID1"],
+			bookmark:     bmarks.ByID["ID1xxxxxxxxxxxxxxxxxxxxxxx"],
 			bookmarks:    bmarks,
 			expectedCode: http.StatusOK,
 		},
@@ -208,10 +212,12 @@ func TestHandleViewBookmarks(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			jsonBmarks, err := json.Marshal(tt.bookmarks)
 			assert.Nil(t, err)
+			jsonLabels, err := json.Marshal(getExecuteCommandTestLabels())
+			assert.Nil(t, err)
 
 			siteURL := "https://myhost.com"
 			api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
-			api.On("KVGet", getLabelsKey(UserID)).Return(nil, nil)
+			api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
 			api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
 
 			api.On("getBookmark", tt.bookmark.PostID).Return(tt.bookmark)
@@ -300,18 +306,51 @@ func TestHandleLabelsAdd(t *testing.T) {
 		userID       string
 		label        *Label
 		labels       *Labels
+		labelName    string
 		expectedCode int
+		expectedName string
 	}{
 		"Unauthed User": {
 			label:        l1,
 			labels:       labels,
+			labelName:    "LabelID1",
 			expectedCode: http.StatusUnauthorized,
 		},
 		"Add a Label": {
 			userID:       UserID,
 			label:        l1,
 			labels:       labels,
+			labelName:    "LabelID1",
+			expectedCode: http.StatusOK,
+			expectedName: "LabelID1",
+		},
+		"Add a Label with surrounding spaces": {
+			userID:       UserID,
+			label:        l1,
+			labels:       labels,
+			labelName:    "+Read+",
 			expectedCode: http.StatusOK,
+			expectedName: "Read",
+		},
+		"Label name with spaces": {
+			userID:       UserID,
+			label:        l1,
+			labels:       labels,
+			labelName:    "Read+later",
+			expectedCode: http.StatusBadRequest,
+		},
+		"Invalid label name": {
+			userID:       UserID,
+			label:        l1,
+			labels:       labels,
+			labelName:    "a,b",
+			expectedCode: http.StatusBadRequest,
+		},
+		"Missing label name": {
+			userID:       UserID,
+			label:        l1,
+			labels:       labels,
+			expectedCode: http.StatusBadRequest,
 		},
 	}
 	for name, tt := range tests {
@@ -324,7 +363,164 @@ func TestHandleLabelsAdd(t *testing.T) {
 			api.On("KVGet", getLabelsKey(UserID)).Return(nil, nil)
 			api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
 
-			r := httptest.NewRequest(http.MethodPost, "/api/v1/labels/add?labelName=LabelID1", strings.NewReader(string(jsonLabel)))
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/labels/add?labelName="+tt.labelName, strings.NewReader(string(jsonLabel)))
+			r.Header.Add("Mattermost-User-Id", tt.userID)
+
+			p.initialiseAPI()
+			w := httptest.NewRecorder()
+			p.ServeHTTP(nil, w, r)
+
+			result := w.Result()
+			assert.NotNil(t, result)
+			assert.Equal(t, tt.expectedCode, result.StatusCode)
+			if tt.expectedName == "" {
+				return
+			}
+
+			var label *Label
+			err = json.NewDecoder(result.Body).Decode(&label)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedName, label.Name)
+		})
+	}
+}
+
+func TestHandleLabelsUpdate(t *testing.T) {
+	tests := map[string]struct {
+		userID        string
+		body          string
+		expectedCode  int
+		expectedLabel *Label
+	}{
+		"Unauthed User": {
+			body:         `{"id":"UUID1","color":"red"}`,
+			expectedCode: http.StatusUnauthorized,
+		},
+		"Invalid body": {
+			userID:       UserID,
+			body:         `not json`,
+			expectedCode: http.StatusBadRequest,
+		},
+		"Label does not exist": {
+			userID:       UserID,
+			body:         `{"id":"UUID9","color":"red"}`,
+			expectedCode: http.StatusNotFound,
+		},
+		"Invalid color": {
+			userID:       UserID,
+			body:         `{"id":"UUID1","color":"mauve"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		"Name already exists": {
+			userID:       UserID,
+			body:         `{"id":"UUID1","name":"label2"}`,
+			expectedCode: http.StatusConflict,
+		},
+		"Update color and description": {
+			userID:        UserID,
+			body:          `{"id":"UUID1","color":"blue","description":" Read later "}`,
+			expectedCode:  http.StatusOK,
+			expectedLabel: &Label{Name: "label1", Color: "#1c58d9", Description: "Read later"},
+		},
+		"Rename label": {
+			userID:        UserID,
+			body:          `{"id":"UUID2","name":"renamed","color":"#ABCDEF"}`,
+			expectedCode:  http.StatusOK,
+			expectedLabel: &Label{Name: "renamed", Color: "#abcdef"},
+		},
+		"Rename label keeps color and description": {
+			userID:        UserID,
+			body:          `{"id":"UUID1","name":"renamed"}`,
+			expectedCode:  http.StatusOK,
+			expectedLabel: &Label{Name: "renamed", Color: "#d24b4e", Description: "Things to read later"},
+		},
+		"Clear description": {
+			userID:        UserID,
+			body:          `{"id":"UUID1","description":""}`,
+			expectedCode:  http.StatusOK,
+			expectedLabel: &Label{Name: "label1", Color: "#d24b4e"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := makeAPIMock()
+			p := makePlugin(api)
+
+			jsonLabels, err := json.Marshal(getExecuteCommandTestColoredLabels())
+			assert.Nil(t, err)
+			api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+			api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/labels/update", strings.NewReader(tt.body))
+			r.Header.Add("Mattermost-User-Id", tt.userID)
+
+			p.initialiseAPI()
+			w := httptest.NewRecorder()
+			p.ServeHTTP(nil, w, r)
+
+			result := w.Result()
+			assert.NotNil(t, result)
+			assert.Equal(t, tt.expectedCode, result.StatusCode)
+			if tt.expectedLabel == nil {
+				return
+			}
+
+			var label *Label
+			err = json.NewDecoder(result.Body).Decode(&label)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedLabel, label)
+		})
+	}
+}
+
+func TestHandleLabelsMerge(t *testing.T) {
+	tests := map[string]struct {
+		userID          string
+		body            string
+		expectedCode    int
+		expectedChanged int
+	}{
+		"Unauthed User": {
+			body:         `{"from":["label1"],"into":"label2"}`,
+			expectedCode: http.StatusUnauthorized,
+		},
+		"Invalid body": {
+			userID:       UserID,
+			body:         `not json`,
+			expectedCode: http.StatusBadRequest,
+		},
+		"No source labels": {
+			userID:       UserID,
+			body:         `{"into":"label2"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		"Target label does not exist": {
+			userID:       UserID,
+			body:         `{"from":["label1"],"into":"labeldoesnotexist"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		"Merge labels": {
+			userID:          UserID,
+			body:            `{"from":["label1","label2"],"into":"label8"}`,
+			expectedCode:    http.StatusOK,
+			expectedChanged: 2,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := makeAPIMock()
+			p := makePlugin(api)
+
+			jsonBmarks, err := json.Marshal(getExecuteCommandTestBookmarks())
+			assert.Nil(t, err)
+			jsonLabels, err := json.Marshal(getExecuteCommandTestLabels())
+			assert.Nil(t, err)
+			api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+			api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+			api.On("KVGet", getRulesKey(UserID)).Return(nil, nil)
+			api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/labels/merge", strings.NewReader(tt.body))
 			r.Header.Add("Mattermost-User-Id", tt.userID)
 
 			p.initialiseAPI()
@@ -334,6 +530,353 @@ func TestHandleLabelsAdd(t *testing.T) {
 			result := w.Result()
 			assert.NotNil(t, result)
 			assert.Equal(t, tt.expectedCode, result.StatusCode)
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var resp map[string]int
+			assert.Nil(t, json.NewDecoder(result.Body).Decode(&resp))
+			assert.Equal(t, tt.expectedChanged, resp["changed"])
+		})
+	}
+}
+
+func TestHandleAutocompleteLabels(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+
+	tests := map[string]struct {
+		userID        string
+		query         string
+		expectedCode  int
+		expectedItems []string
+	}{
+		"Unauthed User": {
+			expectedCode: http.StatusUnauthorized,
+		},
+		"first label": {
+			userID:        UserID,
+			query:         "parsed=/bookmarks+add+ID1xxxxxxxxxxxxxxxxxxxxxxx+--labels+&user_input=/bookmarks+add+ID1xxxxxxxxxxxxxxxxxxxxxxx+--labels+la",
+			expectedCode:  http.StatusOK,
+			expectedItems: []string{"label1", "label2", "label8"},
+		},
+		"second label skips entered labels": {
+			userID:        UserID,
+			query:         "parsed=/bookmarks+add+ID1xxxxxxxxxxxxxxxxxxxxxxx+--labels+&user_input=/bookmarks+add+ID1xxxxxxxxxxxxxxxxxxxxxxx+--labels+label2,la",
+			expectedCode:  http.StatusOK,
+			expectedItems: []string{"label2,label1", "label2,label8"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			jsonLabels, err := json.Marshal(getExecuteCommandTestLabels())
+			assert.Nil(t, err)
+			api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+
+			r := httptest.NewRequest(http.MethodGet, autocompleteLabelsURL+"?"+tt.query, nil)
+			r.Header.Add("Mattermost-User-Id", tt.userID)
+
+			p.initialiseAPI()
+			w := httptest.NewRecorder()
+			p.ServeHTTP(nil, w, r)
+
+			result := w.Result()
+			assert.Equal(t, tt.expectedCode, result.StatusCode)
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var items []string
+			for _, item := range model.AutocompleteStaticListItemsFromJSON(result.Body) {
+				items = append(items, item.Item)
+			}
+			assert.Equal(t, tt.expectedItems, items)
+		})
+	}
+}
+
+func TestHandleAutocompleteBookmarks(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+
+	jsonBmarks, err := json.Marshal(getExecuteCommandTestBookmarks())
+	assert.Nil(t, err)
+	api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+	api.On("GetPost", mock.Anything).Return(&model.Post{Message: "this is the post.Message"}, nil)
+
+	r := httptest.NewRequest(http.MethodGet, autocompleteBookmarksURL, nil)
+	r.Header.Add("Mattermost-User-Id", UserID)
+
+	p.initialiseAPI()
+	w := httptest.NewRecorder()
+	p.ServeHTTP(nil, w, r)
+
+	result := w.Result()
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+
+	items := model.AutocompleteStaticListItemsFromJSON(result.Body)
+	assert.Len(t, items, 4)
+	for _, item := range items {
+		if item.Item == p4ID {
+			assert.Equal(t, "this is the post.Message", item.HelpText)
+		}
+	}
+}
+
+func TestHandleAutocompleteBookmarksWithoutBookmarks(t *testing.T) {
+	tests := map[string]struct {
+		stored []byte
+	}{
+		"no value":   {stored: nil},
+		"null value": {stored: []byte("null")},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := makeAPIMock()
+			p := makePlugin(api)
+			api.On("KVGet", getBookmarksKey(UserID)).Return(tt.stored, nil)
+
+			r := httptest.NewRequest(http.MethodGet, autocompleteBookmarksURL, nil)
+			r.Header.Add("Mattermost-User-Id", UserID)
+
+			p.initialiseAPI()
+			w := httptest.NewRecorder()
+			p.ServeHTTP(nil, w, r)
+
+			result := w.Result()
+			assert.Equal(t, http.StatusOK, result.StatusCode)
+			assert.Empty(t, model.AutocompleteStaticListItemsFromJSON(result.Body))
+		})
+	}
+}
+
+func TestHandleGetBookmarkHistory(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+
+	trash := NewTrashWithUser(api, UserID)
+	trash.ByID["ID5xxxxxxxxxxxxxxxxxxxxxxx"] = &Bookmark{PostID: "ID5xxxxxxxxxxxxxxxxxxxxxxx", History: []*BookmarkEvent{{Type: eventCreated}, {Type: eventRemoved}}}
+
+	jsonBmarks, err := json.Marshal(getExecuteCommandViewHistoryBookmarks())
+	assert.Nil(t, err)
+	jsonTrash, err := json.Marshal(trash)
+	assert.Nil(t, err)
+	api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+	api.On("KVGet", getTrashKey(UserID)).Return(jsonTrash, nil)
+
+	tests := map[string]struct {
+		userID        string
+		bookmarkID    string
+		expectedCode  int
+		expectedTypes []string
+	}{
+		"Unauthed User": {
+			bookmarkID:   p1ID,
+			expectedCode: http.StatusUnauthorized,
+		},
+		"bookmark history": {
+			userID:        UserID,
+			bookmarkID:    p1ID,
+			expectedCode:  http.StatusOK,
+			expectedTypes: []string{eventCreated, eventRetitled, eventLabelsAdded},
+		},
+		"removed bookmark history": {
+			userID:        UserID,
+			bookmarkID:    "ID5xxxxxxxxxxxxxxxxxxxxxxx",
+			expectedCode:  http.StatusOK,
+			expectedTypes: []string{eventCreated, eventRemoved},
+		},
+		"bookmark does not exist": {
+			userID:       UserID,
+			bookmarkID:   PostIDDoesNotExistxxxxxxxx,
+			expectedCode: http.StatusNotFound,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/bookmarks/%s/history", tt.bookmarkID), nil)
+			r.Header.Add("Mattermost-User-Id", tt.userID)
+
+			p.initialiseAPI()
+			w := httptest.NewRecorder()
+			p.ServeHTTP(nil, w, r)
+
+			result := w.Result()
+			assert.Equal(t, tt.expectedCode, result.StatusCode)
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var history []*BookmarkEvent
+			assert.Nil(t, json.NewDecoder(result.Body).Decode(&history))
+			var types []string
+			for _, event := range history {
+				types = append(types, event.Type)
+			}
+			assert.Equal(t, tt.expectedTypes, types)
+		})
+	}
+}
+
+func TestHandleShareAdd(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+
+	jsonBmarks, err := json.Marshal(getExecuteCommandTestBookmarks())
+	require.Nil(t, err)
+	jsonLabels, err := json.Marshal(getExecuteCommandTestLabels())
+	require.Nil(t, err)
+	api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+	api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+	api.On("KVGet", getRulesKey(UserID)).Return(nil, nil)
+	api.On("KVGet", getTrashKey(UserID)).Return(nil, nil)
+	api.On("KVSet", getLabelsKey(UserID), mock.Anything).Return(nil)
+	siteURL := "https://myhost.com"
+	api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+	api.On("GetPost", "ID5xxxxxxxxxxxxxxxxxxxxxxx").Return(&model.Post{Id: "ID5xxxxxxxxxxxxxxxxxxxxxxx", ChannelId: "ChannelID", Message: "shared post"}, nil)
+	api.On("GetPost", "ID6xxxxxxxxxxxxxxxxxxxxxxx").Return(&model.Post{Id: "ID6xxxxxxxxxxxxxxxxxxxxxxx", ChannelId: "PrivateID", Message: "private post"}, nil)
+	api.On("GetChannel", "ChannelID").Return(&model.Channel{Id: "ChannelID", DisplayName: "Town Square"}, nil)
+	api.On("HasPermissionToChannel", UserID, "ChannelID", model.PERMISSION_READ_CHANNEL).Return(true)
+	api.On("HasPermissionToChannel", UserID, "PrivateID", model.PERMISSION_READ_CHANNEL).Return(false)
+
+	store := mockBookmarksStore(api, UserID)
+
+	shared, err := json.Marshal([]*SharedBookmark{
+		{PostID: p1ID, Title: "already bookmarked"},
+		{PostID: "ID5xxxxxxxxxxxxxxxxxxxxxxx", Title: "Shared", Labels: []string{"label1", "shared"}},
+		{PostID: "ID6xxxxxxxxxxxxxxxxxxxxxxx"},
+	})
+	require.Nil(t, err)
+	req := &model.PostActionIntegrationRequest{
+		UserId:  UserID,
+		Context: map[string]interface{}{"bookmarks": string(shared)},
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/share/add", bytes.NewReader(req.ToJson()))
+	r.Header.Add("Mattermost-User-Id", UserID)
+
+	p.initialiseAPI()
+	w := httptest.NewRecorder()
+	p.ServeHTTP(nil, w, r)
+
+	result := w.Result()
+	require.Equal(t, http.StatusOK, result.StatusCode)
+
+	var resp model.PostActionIntegrationResponse
+	require.Nil(t, json.NewDecoder(result.Body).Decode(&resp))
+	assert.Contains(t, resp.EphemeralText, "Added 1 bookmarks:")
+	assert.Contains(t, resp.EphemeralText, "`label1` `shared` **_Shared_**")
+	assert.Contains(t, resp.EphemeralText, "Skipped 1 bookmarks you already have")
+	assert.Contains(t, resp.EphemeralText, "Skipped 1 bookmarks of posts you cannot access")
+
+	stored := store.getBookmarks()
+	bmark := stored.get("ID5xxxxxxxxxxxxxxxxxxxxxxx")
+	require.NotNil(t, bmark)
+	assert.Len(t, bmark.getLabelIDs(), 2)
+	assert.Contains(t, bmark.getLabelIDs(), "UUID1")
+	require.NotNil(t, bmark.Snapshot)
+	assert.Equal(t, "shared post", bmark.Snapshot.Message)
+	assert.Equal(t, "Town Square", bmark.Snapshot.ChannelName)
+	assert.Equal(t, b1Title, stored.get(p1ID).Title)
+	assert.Nil(t, stored.get("ID6xxxxxxxxxxxxxxxxxxxxxxx"))
+}
+
+func TestHandleStatusDone(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+
+	jsonBmarks, err := json.Marshal(getExecuteCommandTestBookmarks())
+	require.Nil(t, err)
+	api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+	siteURL := "https://myhost.com"
+	api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+	api.On("GetPost", p1ID).Return(&model.Post{Message: "this is the post.Message"}, nil)
+
+	store := mockBookmarksStore(api, UserID)
+
+	req := &model.PostActionIntegrationRequest{
+		UserId:  UserID,
+		Context: map[string]interface{}{"bookmark_id": p1ID},
+	}
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/status/done", bytes.NewReader(req.ToJson()))
+	r.Header.Add("Mattermost-User-Id", UserID)
+
+	p.initialiseAPI()
+	w := httptest.NewRecorder()
+	p.ServeHTTP(nil, w, r)
+
+	result := w.Result()
+	require.Equal(t, http.StatusOK, result.StatusCode)
+
+	var resp model.PostActionIntegrationResponse
+	require.Nil(t, json.NewDecoder(result.Body).Decode(&resp))
+	assert.True(t, strings.HasPrefix(resp.EphemeralText, "Marked as done: [:link:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx) :white_check_mark:"))
+	stored := store.getBookmarks()
+	require.NotNil(t, stored.get(p1ID))
+	assert.Equal(t, statusDone, stored.get(p1ID).getStatus())
+}
+
+func TestHandleViewBookmarksSort(t *testing.T) {
+	tests := map[string]struct {
+		body         string
+		expectedCode int
+		expectedText string
+	}{
+		"sorted by priority": {
+			body:         `{"channelId": "ChannelID", "sort": "priority"}`,
+			expectedCode: http.StatusOK,
+			expectedText: "**_Title3_**\n[:link:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx) **_Title1_**\n[:link:](https://myhost.com/_redirect/pl/ID4xxxxxxxxxxxxxxxxxxxxxxx) **_Title4_**\n[:link:](https://myhost.com/_redirect/pl/ID2xxxxxxxxxxxxxxxxxxxxxxx) :small_red_triangle_down: **_Title2_**",
+		},
+		"unknown sort": {
+			body:         `{"channelId": "ChannelID", "sort": "size"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := makeAPIMock()
+			p := makePlugin(api)
+			siteURL := "https://myhost.com"
+			api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+			jsonBmarks, err := json.Marshal(getOrderTestBookmarks(api))
+			require.Nil(t, err)
+			api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+			api.On("KVGet", getLabelsKey(UserID)).Return(nil, nil)
+			api.On("SendEphemeralPost", UserID, mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				assert.Contains(t, args.Get(1).(*model.Post).Message, tt.expectedText)
+			}).Return(&model.Post{}).Maybe()
+
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/view", strings.NewReader(tt.body))
+			r.Header.Add("Mattermost-User-Id", UserID)
+
+			p.initialiseAPI()
+			w := httptest.NewRecorder()
+			p.ServeHTTP(nil, w, r)
+
+			assert.Equal(t, tt.expectedCode, w.Result().StatusCode)
 		})
 	}
 }
+
+func TestHandleAddBookmarkLabelsError(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+
+	jsonBmarks, err := json.Marshal(getExecuteCommandTestBookmarks())
+	require.Nil(t, err)
+	api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+	api.On("KVGet", getLabelsKey(UserID)).Return(nil, &model.AppError{Message: "KV store unavailable"})
+
+	body, err := json.Marshal(map[string]interface{}{"bookmark": &Bookmark{PostID: p1ID, Title: "title"}})
+	require.Nil(t, err)
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/add", strings.NewReader(string(body)))
+	r.Header.Add("Mattermost-User-Id", UserID)
+
+	p.initialiseAPI()
+	w := httptest.NewRecorder()
+	p.ServeHTTP(nil, w, r)
+
+	// the handler stops at the error, nothing is stored
+	assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+}
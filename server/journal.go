@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// StoreJournalKey is the key used to store the operation journal in the
+	// plugin KV store
+	StoreJournalKey = "journal"
+
+	// maxJournalOperations is the number of operations kept per user
+	maxJournalOperations = 10
+
+	opRemoveBookmarks = "remove_bookmarks"
+	opRemoveLabel     = "remove_label"
+)
+
+// getJournal returns a users operation journal
+func (j *Journal) getJournal() (*Journal, error) {
+	// if a user does not have a journal, bb will be nil
+	bb, appErr := j.api.KVGet(getJournalKey(j.userID))
+	if appErr != nil {
+		return nil, errors.Wrapf(appErr, "Unable to get journal for user %s", j.userID)
+	}
+
+	if bb == nil {
+		return j, nil
+	}
+
+	jsonErr := json.Unmarshal(bb, j)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+
+	return j, nil
+}
+
+// addOperation journals a new operation and returns it
+func (j *Journal) addOperation(op *Operation) (*Operation, error) {
+	op.ID = NewID()
+	op.CreateAt = model.GetMillis()
+	if err := j.add(op); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// getLastOperation returns the most recent operation journaled within the
+// window, or nil if there is none
+func (j *Journal) getLastOperation(window time.Duration) *Operation {
+	if len(j.Operations) == 0 {
+		return nil
+	}
+
+	op := j.Operations[len(j.Operations)-1]
+	if op.isExpired(window) {
+		return nil
+	}
+	return op
+}
+
+// getOperation returns the operation with the given ID if it was journaled
+// within the window
+func (j *Journal) getOperation(opID string, window time.Duration) (*Operation, error) {
+	for _, op := range j.Operations {
+		if op.ID != opID {
+			continue
+		}
+		if op.isExpired(window) {
+			return nil, errors.New("This operation can no longer be undone")
+		}
+		return op, nil
+	}
+	return nil, errors.New("This operation can no longer be undone")
+}
+
+func (op *Operation) isExpired(window time.Duration) bool {
+	return model.GetMillis()-op.CreateAt > int64(window/time.Millisecond)
+}
+
+func getJournalKey(userID string) string {
+	return fmt.Sprintf("%s_%s", StoreJournalKey, userID)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/pkg/errors"
+)
+
+// Board contains the bookmarks curated for a channel
+type Board struct {
+	Entries   map[string]*CollectionEntry `json:"entries,omitempty"` // Bookmarks keyed by PostID
+	PostID    string                      `json:"post_id,omitempty"` // The pinned board post maintained by the bot
+	api       plugin.API
+	channelID string
+}
+
+// NewBoardWithChannel returns an initialized Board for a Channel
+func NewBoardWithChannel(api plugin.API, channelID string) *Board {
+	return &Board{
+		Entries:   make(map[string]*CollectionEntry),
+		api:       api,
+		channelID: channelID,
+	}
+}
+
+func (b *Board) add(entry *CollectionEntry) error {
+	b.Entries[entry.PostID] = entry
+	if err := b.storeBoard(); err != nil {
+		return errors.Wrap(err, "failed to add bookmark to board")
+	}
+	return nil
+}
+
+func (b *Board) get(postID string) *CollectionEntry {
+	return b.Entries[postID]
+}
+
+func (b *Board) delete(postID string) error {
+	delete(b.Entries, postID)
+	if err := b.storeBoard(); err != nil {
+		return errors.Wrap(err, "failed to remove bookmark from board")
+	}
+	return nil
+}
+
+// storeBoard stores the board of the channel
+func (b *Board) storeBoard() error {
+	bb, jsonErr := json.Marshal(b)
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	key := getBoardKey(b.channelID)
+	appErr := b.api.KVSet(key, bb)
+	if appErr != nil {
+		return appErr
+	}
+
+	return nil
+}
@@ -1,18 +1,71 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"time"
 
 	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/mattermost/mattermost-server/v5/plugin"
 	"github.com/pkg/errors"
 )
 
-// Bookmarks contains a map of bookmarks
+const (
+	// StoreBookmarksBucketKey is the prefix of the keys of the buckets holding
+	// the bookmark records of a user
+	StoreBookmarksBucketKey = "bmarks_bucket"
+
+	// StoreBookmarksLockKey is the prefix of the key locking the bookmarks of a
+	// user while changes are stored
+	StoreBookmarksLockKey = "bmarks_lock"
+
+	// bookmarksLayoutVersion is the version of the bucket layout.  Bookmarks
+	// stored before it are one JSON value of all bookmarks
+	bookmarksLayoutVersion = 2
+
+	// bookmarksBucketSize is the number of bookmarks per bucket above which the
+	// number of buckets is doubled
+	bookmarksBucketSize = 100
+
+	// bookmarksLockExpiry is the number of seconds after which the lock of a
+	// writer that never released it expires
+	bookmarksLockExpiry = 10
+
+	// bookmarksLockAttempts is the number of times taking a held lock is
+	// tried, bookmarksLockWait apart.  Writers hold the lock for a few KV
+	// writes, a request waits less than 100ms before it gives up
+	bookmarksLockAttempts = 5
+	bookmarksLockWait     = 20 * time.Millisecond
+
+	// migrationBookmarksLayoutKey marks that the bookmarks stored in the
+	// single value layout have been stored in the bucket layout
+	migrationBookmarksLayoutKey = "migration_bookmarks_layout"
+)
+
+// Bookmarks contains a map of bookmarks.  The bookmarks of a user are stored
+// in buckets of records picked by a hash of the bookmark ID, so storing a
+// change only writes the buckets of the changed bookmarks and loading all
+// bookmarks reads one value per bucket.  The bookmarks key of a user holds
+// the layout version, the number of buckets and the number of bookmarks
 type Bookmarks struct {
 	ByID   map[string]*Bookmark
 	api    plugin.API
 	userID string
+
+	header *bookmarksHeader  // nil until stored in the bucket layout
+	loaded map[int]bool      // The buckets loaded into ByID
+	stored map[string][]byte // The stored records of the loaded bookmarks
+}
+
+// bookmarksHeader is the value stored under the bookmarks key of a user.
+// Values of the single value layout have bookmarks and no version
+type bookmarksHeader struct {
+	ByID    map[string]*Bookmark `json:"ByID,omitempty"`
+	Version int                  `json:"version,omitempty"`
+	Buckets int                  `json:"buckets,omitempty"`
+	Count   int                  `json:"count,omitempty"`
 }
 
 // NewBookmarksWithUser returns an initialized Labels for a User
@@ -21,7 +74,35 @@ func NewBookmarksWithUser(api plugin.API, userID string) *Bookmarks {
 		ByID:   make(map[string]*Bookmark),
 		api:    api,
 		userID: userID,
+		loaded: make(map[int]bool),
+		stored: make(map[string][]byte),
+	}
+}
+
+// getBookmarksBucketKey returns the key of a bucket of bookmark records
+func getBookmarksBucketKey(userID string, bucket int) string {
+	return fmt.Sprintf("%s_%s_%d", StoreBookmarksBucketKey, userID, bucket)
+}
+
+func getBookmarksLockKey(userID string) string {
+	return fmt.Sprintf("%s_%s", StoreBookmarksLockKey, userID)
+}
+
+// getBucket returns the bucket of a bookmark ID when there are count buckets
+func getBucket(bmarkID string, count int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(bmarkID))
+	return int(h.Sum32() % uint32(count))
+}
+
+// getBucketCount returns the number of buckets for count bookmarks, the
+// smallest power of two keeping buckets at most bookmarksBucketSize large
+func getBucketCount(count int) int {
+	buckets := 1
+	for buckets*bookmarksBucketSize < count {
+		buckets *= 2
 	}
+	return buckets
 }
 
 func (b *Bookmarks) add(bmark *Bookmark) error {
@@ -32,19 +113,61 @@ func (b *Bookmarks) add(bmark *Bookmark) error {
 	return nil
 }
 
+// get returns a bookmark, loading it from the store when it is not loaded yet
 func (b *Bookmarks) get(bmarkID string) *Bookmark {
-	return b.ByID[bmarkID]
+	bmark, _ := b.exists(bmarkID)
+	return bmark
 }
 
 func (b *Bookmarks) delete(bmarkID string) {
-	delete(b.ByID, bmarkID)
+	// the bookmark is loaded so storing the bookmarks removes its record
+	if _, ok := b.exists(bmarkID); ok {
+		delete(b.ByID, bmarkID)
+	}
 }
 
 func (b *Bookmarks) exists(bmarkID string) (*Bookmark, bool) {
 	if bmark, ok := b.ByID[bmarkID]; ok {
 		return bmark, true
 	}
-	return nil, false
+	// removed since loaded
+	if _, ok := b.stored[bmarkID]; ok {
+		return nil, false
+	}
+	if err := b.loadBucketOf(bmarkID); err != nil {
+		b.api.LogWarn("Unable to load bookmark", "error", err.Error())
+		return nil, false
+	}
+	bmark, ok := b.ByID[bmarkID]
+	return bmark, ok
+}
+
+// count returns the number of bookmarks of the user, including bookmarks
+// that are not loaded
+func (b *Bookmarks) count() int {
+	if b.header == nil {
+		return len(b.ByID)
+	}
+
+	count := b.header.Count
+	for id := range b.ByID {
+		if _, ok := b.stored[id]; ok {
+			continue
+		}
+		// bookmarks added without being loaded may replace stored bookmarks
+		if err := b.loadBucketOf(id); err != nil {
+			b.api.LogWarn("Unable to load bookmark", "error", err.Error())
+		}
+		if _, ok := b.stored[id]; !ok {
+			count++
+		}
+	}
+	for id := range b.stored {
+		if _, ok := b.ByID[id]; !ok {
+			count--
+		}
+	}
+	return count
 }
 
 func (b *Bookmarks) updateTimes(bmarkID string) *Bookmark {
@@ -57,24 +180,355 @@ func (b *Bookmarks) updateTimes(bmarkID string) *Bookmark {
 	return bmark
 }
 
-func (b *Bookmarks) updateLabels(bmark *Bookmark) *Bookmark {
-	bmarkOrig := b.get(bmark.PostID)
-	bmarkOrig.addLabelIDs(bmark.getLabelIDs())
-	return bmark
+// loadBucketOf loads the bucket of a bookmark ID unless it is loaded already
+func (b *Bookmarks) loadBucketOf(bmarkID string) error {
+	if b.header == nil {
+		return nil
+	}
+	bucket := getBucket(bmarkID, b.header.Buckets)
+	if b.loaded[bucket] {
+		return nil
+	}
+	return b.loadBucket(bucket)
+}
+
+// loadBucket loads the bookmarks of a bucket.  Bookmarks loaded before are
+// kept as they are, including the changes not stored yet
+func (b *Bookmarks) loadBucket(bucket int) error {
+	records, err := readBookmarksBucket(b.api, b.userID, bucket)
+	if err != nil {
+		return err
+	}
+	if err = b.loadRecords(records); err != nil {
+		return err
+	}
+	b.loaded[bucket] = true
+	return nil
+}
+
+// loadRecords adds the bookmarks of stored records that are not loaded yet
+func (b *Bookmarks) loadRecords(records map[string][]byte) error {
+	for id, data := range records {
+		if _, ok := b.stored[id]; ok {
+			continue
+		}
+		b.stored[id] = data
+		// added again without being loaded, the added bookmark replaces it
+		if _, ok := b.ByID[id]; ok {
+			continue
+		}
+
+		var bmark *Bookmark
+		if err := json.Unmarshal(data, &bmark); err != nil {
+			return errors.Wrapf(err, "Unable to load bookmark %s", id)
+		}
+		b.ByID[id] = bmark
+	}
+	return nil
+}
+
+// loadAllBookmarks loads the buckets that are not loaded yet
+func (b *Bookmarks) loadAllBookmarks() error {
+	if b.header == nil {
+		return nil
+	}
+	for bucket := 0; bucket < b.header.Buckets; bucket++ {
+		if b.loaded[bucket] {
+			continue
+		}
+		if err := b.loadBucket(bucket); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readBookmarksBucket returns the records stored in a bucket by bookmark ID
+func readBookmarksBucket(api plugin.API, userID string, bucket int) (map[string][]byte, error) {
+	data, appErr := api.KVGet(getBookmarksBucketKey(userID, bucket))
+	if appErr != nil {
+		return nil, errors.Wrapf(appErr, "Unable to get bookmarks of user %s", userID)
+	}
+
+	var raw map[string]json.RawMessage
+	if len(data) != 0 {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	}
+	records := make(map[string][]byte, len(raw))
+	for id, record := range raw {
+		records[id] = record
+	}
+	return records, nil
+}
+
+// writeBookmarksBucket stores the records of a bucket
+func writeBookmarksBucket(api plugin.API, userID string, bucket int, records map[string][]byte) error {
+	raw := make(map[string]json.RawMessage, len(records))
+	for id, record := range records {
+		raw[id] = record
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	if appErr := api.KVSet(getBookmarksBucketKey(userID, bucket), data); appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// loadBookmarksIndex loads the header of the bookmarks of a user, or all
+// bookmarks when they are stored in the single value layout.  Returns nil
+// bookmarks for a null value
+func (b *Bookmarks) loadBookmarksIndex(data []byte) (*Bookmarks, error) {
+	bmarks := NewBookmarksWithUser(b.api, b.userID)
+	if len(data) == 0 {
+		return bmarks, nil
+	}
+
+	var header *bookmarksHeader
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, nil
+	}
+
+	// bookmarks in the single value layout are stored in the bucket layout
+	// the next time they are stored
+	if header.Version < bookmarksLayoutVersion {
+		for id, bmark := range header.ByID {
+			record, err := json.Marshal(bmark)
+			if err != nil {
+				return nil, err
+			}
+			bmarks.ByID[id] = bmark
+			bmarks.stored[id] = record
+		}
+		return bmarks, nil
+	}
+
+	// a header without buckets has no bookmarks stored yet
+	if header.Buckets < 1 {
+		header.Buckets = 1
+	}
+	header.ByID = nil
+	bmarks.header = header
+	return bmarks, nil
+}
+
+// lock takes the lock of the users bookmarks and returns the function
+// releasing it.  The lock expires, so a writer that never releases it only
+// blocks the other writers for bookmarksLockExpiry seconds
+func (b *Bookmarks) lock() (func(), error) {
+	key := getBookmarksLockKey(b.userID)
+	token := []byte(model.NewId())
+	for attempt := 0; attempt < bookmarksLockAttempts; attempt++ {
+		if attempt != 0 {
+			time.Sleep(bookmarksLockWait)
+		}
+
+		ok, appErr := b.api.KVSetWithOptions(key, token, model.PluginKVSetOptions{
+			Atomic:          true,
+			ExpireInSeconds: bookmarksLockExpiry,
+		})
+		if appErr != nil {
+			return nil, appErr
+		}
+		if ok {
+			return func() {
+				// an expired lock may have been taken by another writer
+				if _, appErr := b.api.KVCompareAndDelete(key, token); appErr != nil {
+					b.api.LogWarn("Unable to release bookmarks lock", "error", appErr.Error())
+				}
+			}, nil
+		}
+	}
+	return nil, errors.New("Your bookmarks are being changed by another request. Try again later")
 }
 
-// storeBookmarks stores all the users bookmarks
+// storeBookmarks stores the changes to the users bookmarks.  Only the buckets
+// of added, changed and removed bookmarks and the header are written, so the
+// cost of storing does not grow with the number of bookmarks.  The changes
+// are stored under the lock of the users bookmarks and applied to the
+// buckets as they are stored when locked, so concurrent writers do not drop
+// each others changes.  Bookmarks in the single value layout are migrated
 func (b *Bookmarks) storeBookmarks() error {
-	jsonBookmarks, jsonErr := json.Marshal(b)
-	if jsonErr != nil {
-		return jsonErr
+	changed := make(map[string][]byte)
+	for id, bmark := range b.ByID {
+		data, err := json.Marshal(bmark)
+		if err != nil {
+			return err
+		}
+		if stored, ok := b.stored[id]; !ok || !bytes.Equal(data, stored) {
+			changed[id] = data
+		}
+	}
+	var removed []string
+	for id := range b.stored {
+		if _, ok := b.ByID[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	if b.header != nil && len(changed) == 0 && len(removed) == 0 {
+		return nil
 	}
 
-	key := getBookmarksKey(b.userID)
-	appErr := b.api.KVSet(key, jsonBookmarks)
+	unlock, err := b.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// the header and buckets are read again, other writers may have stored
+	// changes since the bookmarks were loaded
+	current, err := NewBookmarksWithUser(b.api, b.userID).getBookmarksIndex()
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		current = NewBookmarksWithUser(b.api, b.userID)
+	}
+
+	// records holds the current records of the buckets written
+	header := current.header
+	records := current.stored
+	if header == nil {
+		header = &bookmarksHeader{Version: bookmarksLayoutVersion, Count: len(records)}
+	} else {
+		for _, id := range changedAndRemoved(changed, removed) {
+			if err = current.loadBucketOf(id); err != nil {
+				return err
+			}
+		}
+	}
+
+	// growing the buckets writes all bookmarks once, so the cost of an add
+	// stays constant on average
+	count := header.Count + countChanges(records, changed, removed)
+	if buckets := getBucketCount(count); buckets > header.Buckets {
+		if err = current.loadAllBookmarks(); err != nil {
+			return err
+		}
+		count = len(records) + countChanges(records, changed, removed)
+		if buckets = getBucketCount(count); buckets > header.Buckets {
+			header.Buckets = buckets
+		}
+		current.loaded = make(map[int]bool)
+		for bucket := 0; bucket < header.Buckets; bucket++ {
+			current.loaded[bucket] = true
+		}
+	}
+
+	for id, data := range changed {
+		records[id] = data
+	}
+	for _, id := range removed {
+		delete(records, id)
+	}
+	header.Count = count
+
+	written := make(map[int]map[string][]byte)
+	for bucket := range current.loaded {
+		written[bucket] = make(map[string][]byte)
+	}
+	for id, data := range records {
+		bucket := getBucket(id, header.Buckets)
+		if written[bucket] != nil {
+			written[bucket][id] = data
+		}
+	}
+	for bucket, bucketRecords := range written {
+		if err = writeBookmarksBucket(b.api, b.userID, bucket, bucketRecords); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	if appErr := b.api.KVSet(getBookmarksKey(b.userID), data); appErr != nil {
+		return appErr
+	}
+
+	// the bookmarks stored by other writers in the written buckets are loaded
+	if b.header == nil || b.header.Buckets != header.Buckets {
+		b.loaded = make(map[int]bool)
+	}
+	b.header = header
+	for id, data := range changed {
+		b.stored[id] = data
+	}
+	for _, id := range removed {
+		delete(b.stored, id)
+	}
+	for bucket, bucketRecords := range written {
+		if err = b.loadRecords(bucketRecords); err != nil {
+			return err
+		}
+		b.loaded[bucket] = true
+	}
+	return nil
+}
+
+// countChanges returns the change in the number of records when the changed
+// records are stored and the removed records deleted
+func countChanges(records, changed map[string][]byte, removed []string) int {
+	count := 0
+	for id := range changed {
+		if _, ok := records[id]; !ok {
+			count++
+		}
+	}
+	for _, id := range removed {
+		if _, ok := records[id]; ok {
+			count--
+		}
+	}
+	return count
+}
+
+// changedAndRemoved returns the IDs of changed and removed bookmarks
+func changedAndRemoved(changed map[string][]byte, removed []string) []string {
+	ids := append([]string(nil), removed...)
+	for id := range changed {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// migrateBookmarksLayout stores the bookmarks of all users still in the
+// single value layout in the bucket layout.  Bookmarks are migrated on their
+// next write anyway, the migration keeps large single values from being read
+// again.  The migration only runs once
+func (p *Plugin) migrateBookmarksLayout() error {
+	done, appErr := p.API.KVGet(migrationBookmarksLayoutKey)
 	if appErr != nil {
 		return appErr
 	}
+	if done != nil {
+		return nil
+	}
 
+	userIDs, err := p.listUserIDsWithPrefix(StoreBookmarksKey + "_")
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		bmarks, err := NewBookmarksWithUser(p.API, userID).getBookmarksIndex()
+		if err != nil || bmarks == nil || bmarks.header != nil {
+			continue
+		}
+		if err = bmarks.storeBookmarks(); err != nil {
+			return err
+		}
+	}
+
+	if appErr = p.API.KVSet(migrationBookmarksLayoutKey, []byte("done")); appErr != nil {
+		return appErr
+	}
 	return nil
 }
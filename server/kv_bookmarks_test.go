@@ -1,26 +1,34 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func getTestBookmarks() *Bookmarks {
 	api := makeAPIMock()
-	api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+	mockBookmarksStore(api, UserID)
 	p := makePlugin(api)
 	bmarks := NewBookmarksWithUser(p.API, UserID)
 
 	b1 := &Bookmark{
-		PostID: "ID1",
+		PostID: "ID1xxxxxxxxxxxxxxxxxxxxxxx",
 		Title:  "Title1 - New Bookmark - times are zero",
 	}
 	b2 := &Bookmark{
-		PostID:     "ID2",
+		PostID:     "ID2xxxxxxxxxxxxxxxxxxxxxxx",
 		Title:      "Title2 - bookmarks initialized. Times created and same",
 		CreateAt:   model.GetMillis(),
 		ModifiedAt: model.GetMillis(),
@@ -28,7 +36,7 @@ func getTestBookmarks() *Bookmarks {
 
 	// no title provided
 	b3 := &Bookmark{
-		PostID:     "ID3",
+		PostID:     "ID3xxxxxxxxxxxxxxxxxxxxxxx",
 		CreateAt:   model.GetMillis(),
 		ModifiedAt: model.GetMillis(),
 	}
@@ -43,12 +51,12 @@ func getTestBookmarks() *Bookmarks {
 func TestBookmarks_get(t *testing.T) {
 	bmarks := getTestBookmarks()
 	assert.Equal(t, 3, len(bmarks.ByID))
-	bmark := bmarks.get("ID3")
+	bmark := bmarks.get("ID3xxxxxxxxxxxxxxxxxxxxxxx")
 	assert.Equal(t, "", bmark.getTitle())
 }
 
 func TestBookmarks_add(t *testing.T) {
-	b4 := &Bookmark{PostID: "ID4", Title: "Title4"}
+	b4 := &Bookmark{PostID: "ID4xxxxxxxxxxxxxxxxxxxxxxx", Title: "Title4"}
 	bmarks := getTestBookmarks()
 	assert.Equal(t, 3, len(bmarks.ByID))
 	err := bmarks.add(b4)
@@ -59,13 +67,13 @@ func TestBookmarks_add(t *testing.T) {
 func TestBookmarks_delete(t *testing.T) {
 	bmarks := getTestBookmarks()
 	assert.Equal(t, 3, len(bmarks.ByID))
-	bmarks.delete("ID2")
+	bmarks.delete("ID2xxxxxxxxxxxxxxxxxxxxxxx")
 	assert.Equal(t, 2, len(bmarks.ByID))
 }
 
 func TestBookmarks_exists(t *testing.T) {
 	bmarks := getTestBookmarks()
-	_, exists := bmarks.exists("ID2")
+	_, exists := bmarks.exists("ID2xxxxxxxxxxxxxxxxxxxxxxx")
 	assert.Equal(t, true, exists)
 }
 
@@ -73,19 +81,380 @@ func TestBookmarks_updateTimes(t *testing.T) {
 	bmarks := getTestBookmarks()
 
 	// bmark has been initialized. times not yet added
-	b1 := bmarks.get("ID1")
+	b1 := bmarks.get("ID1xxxxxxxxxxxxxxxxxxxxxxx")
 	assert.Equal(t, 0, int(b1.CreateAt))
 	assert.Equal(t, 0, int(b1.ModifiedAt))
 
 	// bmark has been added and times added
-	bmarks.updateTimes("ID1")
-	bmarks.get("ID1")
+	bmarks.updateTimes("ID1xxxxxxxxxxxxxxxxxxxxxxx")
+	bmarks.get("ID1xxxxxxxxxxxxxxxxxxxxxxx")
 	assert.Greater(t, int(b1.ModifiedAt), 0)
 	assert.Equal(t, int(b1.ModifiedAt), int(b1.CreateAt))
 
 	// bmark was already saved and modified time updates
 	time.Sleep(time.Millisecond)
-	bmarks.updateTimes("ID2")
-	b2 := bmarks.get("ID2")
+	bmarks.updateTimes("ID2xxxxxxxxxxxxxxxxxxxxxxx")
+	b2 := bmarks.get("ID2xxxxxxxxxxxxxxxxxxxxxxx")
 	assert.Greater(t, b2.ModifiedAt, b2.CreateAt)
 }
+
+// bookmarksStoreMock records the bookmarks of a user written to a mocked KV
+// store in the bucket layout
+type bookmarksStoreMock struct {
+	userID       string
+	header       []byte
+	buckets      map[string][]byte
+	headerWrites int
+}
+
+// mockBookmarksStore mocks writing the bookmarks of a user to the KV store
+func mockBookmarksStore(api *plugintest.API, userID string) *bookmarksStoreMock {
+	s := &bookmarksStoreMock{
+		userID:  userID,
+		buckets: make(map[string][]byte),
+	}
+
+	bucketPrefix := StoreBookmarksBucketKey + "_" + userID + "_"
+	isBucket := mock.MatchedBy(func(key string) bool { return strings.HasPrefix(key, bucketPrefix) })
+
+	api.On("KVSet", isBucket, mock.Anything).Run(func(args mock.Arguments) {
+		s.buckets[args.String(0)] = args.Get(1).([]byte)
+	}).Return(nil)
+	get := api.On("KVGet", isBucket)
+	get.Run(func(args mock.Arguments) {
+		get.ReturnArguments = mock.Arguments{s.buckets[args.String(0)], nil}
+	})
+	api.On("KVSet", getBookmarksKey(userID), mock.Anything).Run(func(args mock.Arguments) {
+		s.header = args.Get(1).([]byte)
+		s.headerWrites++
+	}).Return(nil)
+	// the written header is read unless the test mocks reading the bookmarks
+	getHeader := api.On("KVGet", getBookmarksKey(userID))
+	getHeader.Run(func(args mock.Arguments) {
+		getHeader.ReturnArguments = mock.Arguments{s.header, nil}
+	})
+	return s
+}
+
+// getBookmarks returns the stored bookmarks, the records of the written
+// buckets
+func (s *bookmarksStoreMock) getBookmarks() *Bookmarks {
+	bmarks := NewBookmarksWithUser(nil, s.userID)
+	for _, data := range s.buckets {
+		var bucket map[string]*Bookmark
+		if err := json.Unmarshal(data, &bucket); err != nil {
+			panic(err)
+		}
+		for id, bmark := range bucket {
+			bmarks.ByID[id] = bmark
+		}
+	}
+	return bmarks
+}
+
+// memoryKVStore is a plugin API keeping the KV store in memory and counting
+// the values read and written
+type memoryKVStore struct {
+	plugin.API
+	values  map[string][]byte
+	reads   int
+	writes  int
+	written int
+}
+
+func newMemoryKVStore() *memoryKVStore {
+	return &memoryKVStore{values: make(map[string][]byte)}
+}
+
+func (s *memoryKVStore) KVGet(key string) ([]byte, *model.AppError) {
+	s.reads++
+	return s.values[key], nil
+}
+
+func (s *memoryKVStore) KVSet(key string, value []byte) *model.AppError {
+	s.values[key] = value
+	s.writes++
+	s.written += len(value)
+	return nil
+}
+
+// KVSetWithOptions only supports setting values that do not exist, used by
+// locks.  Locks are not counted as writes
+func (s *memoryKVStore) KVSetWithOptions(key string, value []byte, options model.PluginKVSetOptions) (bool, *model.AppError) {
+	if !options.Atomic || options.OldValue != nil {
+		panic("not supported")
+	}
+	if _, ok := s.values[key]; ok {
+		return false, nil
+	}
+	s.values[key] = value
+	return true, nil
+}
+
+func (s *memoryKVStore) KVCompareAndDelete(key string, oldValue []byte) (bool, *model.AppError) {
+	if !bytes.Equal(s.values[key], oldValue) {
+		return false, nil
+	}
+	delete(s.values, key)
+	return true, nil
+}
+
+func (s *memoryKVStore) KVDelete(key string) *model.AppError {
+	delete(s.values, key)
+	s.writes++
+	return nil
+}
+
+func (s *memoryKVStore) KVList(page, perPage int) ([]string, *model.AppError) {
+	var keys []string
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	start, end := page*perPage, (page+1)*perPage
+	if start > len(keys) {
+		return nil, nil
+	}
+	if end > len(keys) {
+		end = len(keys)
+	}
+	return keys[start:end], nil
+}
+
+func (s *memoryKVStore) LogWarn(msg string, keyValuePairs ...interface{}) {}
+
+func (s *memoryKVStore) resetCounts() {
+	s.reads = 0
+	s.writes = 0
+	s.written = 0
+}
+
+func TestStoreBookmarksBuckets(t *testing.T) {
+	store := newMemoryKVStore()
+	bmarks := NewBookmarksWithUser(store, UserID)
+	require.Nil(t, bmarks.add(&Bookmark{PostID: "ID1xxxxxxxxxxxxxxxxxxxxxxx", Title: "Title1"}))
+	require.Nil(t, bmarks.add(&Bookmark{PostID: "ID2xxxxxxxxxxxxxxxxxxxxxxx", Title: "Title2"}))
+	require.Nil(t, bmarks.add(&Bookmark{PostID: "ID3xxxxxxxxxxxxxxxxxxxxxxx", Title: "Title3"}))
+	assert.JSONEq(t, `{"version":2,"buckets":1,"count":3}`, string(store.values[getBookmarksKey(UserID)]))
+	assert.Nil(t, store.values[getBookmarksLockKey(UserID)])
+
+	// loading all bookmarks reads the header and each bucket
+	store.resetCounts()
+	loaded, err := NewBookmarksWithUser(store, UserID).getBookmarks()
+	require.Nil(t, err)
+	require.Len(t, loaded.ByID, 3)
+	assert.Equal(t, "Title2", loaded.get("ID2xxxxxxxxxxxxxxxxxxxxxxx").Title)
+	assert.Equal(t, 2, store.reads)
+
+	// bookmarks are loaded when used
+	lazy, err := NewBookmarksWithUser(store, UserID).getBookmarksIndex()
+	require.Nil(t, err)
+	assert.Len(t, lazy.ByID, 0)
+	assert.Equal(t, 3, lazy.count())
+	bmark, ok := lazy.exists("ID2xxxxxxxxxxxxxxxxxxxxxxx")
+	require.True(t, ok)
+	assert.Equal(t, "Title2", bmark.Title)
+	_, ok = lazy.exists("ID4xxxxxxxxxxxxxxxxxxxxxxx")
+	assert.False(t, ok)
+
+	// changing a bookmark only writes its bucket and the header
+	store.resetCounts()
+	bmark.Title = "Changed"
+	require.Nil(t, lazy.add(bmark))
+	assert.Equal(t, 2, store.writes)
+
+	// unchanged bookmarks are not written again
+	store.resetCounts()
+	require.Nil(t, lazy.storeBookmarks())
+	assert.Equal(t, 0, store.writes)
+
+	// removing a bookmark removes its record from the bucket
+	store.resetCounts()
+	lazy.delete("ID1xxxxxxxxxxxxxxxxxxxxxxx")
+	assert.Equal(t, 2, lazy.count())
+	require.Nil(t, lazy.storeBookmarks())
+	assert.Equal(t, 2, store.writes)
+	assert.JSONEq(t, `{"version":2,"buckets":1,"count":2}`, string(store.values[getBookmarksKey(UserID)]))
+
+	loaded, err = NewBookmarksWithUser(store, UserID).getBookmarks()
+	require.Nil(t, err)
+	require.Len(t, loaded.ByID, 2)
+	assert.Equal(t, "Changed", loaded.ByID["ID2xxxxxxxxxxxxxxxxxxxxxxx"].Title)
+	assert.Equal(t, "Title3", loaded.ByID["ID3xxxxxxxxxxxxxxxxxxxxxxx"].Title)
+}
+
+func TestStoreBookmarksGrowsBuckets(t *testing.T) {
+	store := newMemoryKVStore()
+	bmarks := NewBookmarksWithUser(store, UserID)
+	for i := 0; i <= bookmarksBucketSize; i++ {
+		id := fmt.Sprintf("ID%d", i)
+		bmarks.ByID[id] = &Bookmark{PostID: id}
+	}
+	require.Nil(t, bmarks.storeBookmarks())
+	assert.JSONEq(t, `{"version":2,"buckets":2,"count":101}`, string(store.values[getBookmarksKey(UserID)]))
+
+	store.resetCounts()
+	loaded, err := NewBookmarksWithUser(store, UserID).getBookmarks()
+	require.Nil(t, err)
+	assert.Len(t, loaded.ByID, bookmarksBucketSize+1)
+	assert.Equal(t, 3, store.reads)
+
+	// adding a bookmark writes one bucket and the header
+	lazy, err := NewBookmarksWithUser(store, UserID).getBookmarksIndex()
+	require.Nil(t, err)
+	store.resetCounts()
+	require.Nil(t, lazy.add(&Bookmark{PostID: "New"}))
+	assert.Equal(t, 2, store.writes)
+	assert.Equal(t, bookmarksBucketSize+2, lazy.count())
+
+	loaded, err = NewBookmarksWithUser(store, UserID).getBookmarks()
+	require.Nil(t, err)
+	assert.Len(t, loaded.ByID, bookmarksBucketSize+2)
+	assert.Contains(t, loaded.ByID, "New")
+}
+
+func TestStoreBookmarksConcurrentWriters(t *testing.T) {
+	store := newMemoryKVStore()
+	bmarks := NewBookmarksWithUser(store, UserID)
+	bmarks.ByID[p1ID] = &Bookmark{PostID: p1ID, Title: "Title1"}
+	bmarks.ByID[p2ID] = &Bookmark{PostID: p2ID, Title: "Title2"}
+	require.Nil(t, bmarks.storeBookmarks())
+
+	// both writers load the bookmarks before either stores its changes
+	first, err := NewBookmarksWithUser(store, UserID).getBookmarksIndex()
+	require.Nil(t, err)
+	second, err := NewBookmarksWithUser(store, UserID).getBookmarksIndex()
+	require.Nil(t, err)
+
+	first.delete(p1ID)
+	require.Nil(t, first.add(&Bookmark{PostID: p3ID, Title: "Title3"}))
+	second.get(p2ID).Title = "Changed"
+	require.Nil(t, second.add(&Bookmark{PostID: p4ID, Title: "Title4"}))
+
+	loaded, err := NewBookmarksWithUser(store, UserID).getBookmarks()
+	require.Nil(t, err)
+	assert.Len(t, loaded.ByID, 3)
+	assert.NotContains(t, loaded.ByID, p1ID)
+	assert.Equal(t, "Changed", loaded.ByID[p2ID].Title)
+	assert.Contains(t, loaded.ByID, p3ID)
+	assert.Contains(t, loaded.ByID, p4ID)
+	assert.JSONEq(t, `{"version":2,"buckets":1,"count":3}`, string(store.values[getBookmarksKey(UserID)]))
+
+	// the writer stored last sees the bookmarks stored by the other writer
+	assert.Equal(t, 3, second.count())
+}
+
+func TestStoreBookmarksMigratesSingleValue(t *testing.T) {
+	store := newMemoryKVStore()
+	jsonBmarks, err := json.Marshal(getExecuteCommandTestBookmarks())
+	require.Nil(t, err)
+	store.values[getBookmarksKey(UserID)] = jsonBmarks
+
+	// bookmarks in the single value layout are read as they are
+	bmarks, err := NewBookmarksWithUser(store, UserID).getBookmarks()
+	require.Nil(t, err)
+	require.Len(t, bmarks.ByID, 4)
+	assert.Equal(t, 4, bmarks.count())
+
+	bmarks.delete(p4ID)
+	require.Nil(t, bmarks.add(&Bookmark{PostID: "ID5xxxxxxxxxxxxxxxxxxxxxxx", Title: "Title5"}))
+	assert.JSONEq(t, `{"version":2,"buckets":1,"count":4}`, string(store.values[getBookmarksKey(UserID)]))
+
+	loaded, err := NewBookmarksWithUser(store, UserID).getBookmarks()
+	require.Nil(t, err)
+	require.Len(t, loaded.ByID, 4)
+	for _, id := range []string{p1ID, p2ID, p3ID, "ID5xxxxxxxxxxxxxxxxxxxxxxx"} {
+		assert.Equal(t, bmarks.ByID[id], loaded.ByID[id])
+	}
+}
+
+func TestMigrateBookmarksLayout(t *testing.T) {
+	store := newMemoryKVStore()
+	p := &Plugin{}
+	p.SetAPI(store)
+
+	jsonBmarks, err := json.Marshal(getExecuteCommandTestBookmarks())
+	require.Nil(t, err)
+	store.values[getBookmarksKey(UserID)] = jsonBmarks
+	store.values[getBookmarksKey("User2")] = []byte(`{"version":2,"buckets":1}`)
+	store.values[getLabelsKey(UserID)] = []byte(`{}`)
+
+	require.Nil(t, p.migrateBookmarksLayout())
+	assert.JSONEq(t, `{"version":2,"buckets":1,"count":4}`, string(store.values[getBookmarksKey(UserID)]))
+	assert.JSONEq(t, `{"version":2,"buckets":1}`, string(store.values[getBookmarksKey("User2")]))
+	assert.Equal(t, []byte("done"), store.values[migrationBookmarksLayoutKey])
+
+	bmarks, err := NewBookmarksWithUser(store, UserID).getBookmarksIndex()
+	require.Nil(t, err)
+	assert.Equal(t, 4, bmarks.count())
+
+	// the migration only runs once
+	store.resetCounts()
+	require.Nil(t, p.migrateBookmarksLayout())
+	assert.Equal(t, 0, store.writes)
+}
+
+// benchmarkAddBookmark adds a bookmark to a user with count bookmarks and
+// reports the number of values read and written and the bytes written for
+// each add
+func benchmarkAddBookmark(b *testing.B, count int) {
+	store := newMemoryKVStore()
+	bmarks := NewBookmarksWithUser(store, UserID)
+	for i := 0; i < count; i++ {
+		id := model.NewId()
+		bmarks.ByID[id] = &Bookmark{PostID: id, Title: "A bookmark with a title of average length"}
+	}
+	if err := bmarks.storeBookmarks(); err != nil {
+		b.Fatal(err)
+	}
+
+	store.resetCounts()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		lazy, err := NewBookmarksWithUser(store, UserID).getBookmarksIndex()
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+
+		id := model.NewId()
+		if err = lazy.add(&Bookmark{PostID: id, Title: "A bookmark with a title of average length"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(store.reads)/float64(b.N), "reads/op")
+	b.ReportMetric(float64(store.writes)/float64(b.N), "writes/op")
+	b.ReportMetric(float64(store.written)/float64(b.N), "written-bytes/op")
+}
+
+func BenchmarkAddBookmark100(b *testing.B)   { benchmarkAddBookmark(b, 100) }
+func BenchmarkAddBookmark1000(b *testing.B)  { benchmarkAddBookmark(b, 1000) }
+func BenchmarkAddBookmark10000(b *testing.B) { benchmarkAddBookmark(b, 10000) }
+
+// benchmarkGetBookmarks loads all bookmarks of a user with count bookmarks and
+// reports the number of values read for each load
+func benchmarkGetBookmarks(b *testing.B, count int) {
+	store := newMemoryKVStore()
+	bmarks := NewBookmarksWithUser(store, UserID)
+	for i := 0; i < count; i++ {
+		id := model.NewId()
+		bmarks.ByID[id] = &Bookmark{PostID: id, Title: "A bookmark with a title of average length"}
+	}
+	if err := bmarks.storeBookmarks(); err != nil {
+		b.Fatal(err)
+	}
+
+	store.resetCounts()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewBookmarksWithUser(store, UserID).getBookmarks(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(store.reads)/float64(b.N), "reads/op")
+}
+
+func BenchmarkGetBookmarks100(b *testing.B)   { benchmarkGetBookmarks(b, 100) }
+func BenchmarkGetBookmarks1000(b *testing.B)  { benchmarkGetBookmarks(b, 1000) }
+func BenchmarkGetBookmarks10000(b *testing.B) { benchmarkGetBookmarks(b, 10000) }
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/pkg/errors"
+)
+
+// Collections contains a map of the bookmark collections shared in a team
+type Collections struct {
+	ByID   map[string]*Collection
+	api    plugin.API
+	teamID string
+}
+
+// Collection is a list of bookmarks shared by the members of a team
+type Collection struct {
+	ID        string                      `json:"id"`
+	Name      string                      `json:"name"`
+	TeamID    string                      `json:"team_id"`
+	OwnerIDs  []string                    `json:"owner_ids"`            // Members who can share the collection
+	MemberIDs []string                    `json:"member_ids"`           // Users who can view and add to the collection, including owners
+	Entries   map[string]*CollectionEntry `json:"entries,omitempty"`    // Bookmarks keyed by PostID
+	CreateAt  int64                       `json:"create_at"`            // The creation time of the collection
+	CreatorID string                      `json:"creator_id,omitempty"` // The user who created the collection
+}
+
+// CollectionEntry is a bookmark in a collection
+type CollectionEntry struct {
+	PostID   string `json:"postid"`
+	Title    string `json:"title,omitempty"`
+	AddedBy  string `json:"added_by"`
+	CreateAt int64  `json:"create_at"`
+}
+
+// NewCollectionsWithTeam returns an initialized Collections for a Team
+func NewCollectionsWithTeam(api plugin.API, teamID string) *Collections {
+	return &Collections{
+		ByID:   make(map[string]*Collection),
+		api:    api,
+		teamID: teamID,
+	}
+}
+
+func (c *Collections) add(collection *Collection) error {
+	c.ByID[collection.ID] = collection
+	if err := c.storeCollections(); err != nil {
+		return errors.Wrap(err, "failed to add collection")
+	}
+	return nil
+}
+
+func (c *Collections) get(id string) *Collection {
+	return c.ByID[id]
+}
+
+func (c *Collections) delete(id string) error {
+	delete(c.ByID, id)
+	if err := c.storeCollections(); err != nil {
+		return errors.Wrap(err, "failed to delete collection")
+	}
+	return nil
+}
+
+// storeCollections stores all the collections of the team
+func (c *Collections) storeCollections() error {
+	bb, jsonErr := json.Marshal(c)
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	key := getCollectionsKey(c.teamID)
+	appErr := c.api.KVSet(key, bb)
+	if appErr != nil {
+		return appErr
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/pkg/errors"
+)
+
+// Journal contains the most recent destructive operations of a user, oldest
+// first
+type Journal struct {
+	Operations []*Operation
+	api        plugin.API
+	userID     string
+}
+
+// Operation records the state removed by a destructive operation so that it
+// can be restored
+type Operation struct {
+	ID          string      `json:"id"`
+	Type        string      `json:"type"`
+	CreateAt    int64       `json:"create_at"`
+	Bookmarks   []*Bookmark `json:"bookmarks,omitempty"`    // Bookmarks removed by the operation
+	Label       *Label      `json:"label,omitempty"`        // Label removed by the operation
+	BookmarkIDs []string    `json:"bookmark_ids,omitempty"` // Bookmarks the removed label was stripped from
+}
+
+// NewJournalWithUser returns an initialized Journal for a User
+func NewJournalWithUser(api plugin.API, userID string) *Journal {
+	return &Journal{
+		api:    api,
+		userID: userID,
+	}
+}
+
+func (j *Journal) add(op *Operation) error {
+	j.Operations = append(j.Operations, op)
+	if len(j.Operations) > maxJournalOperations {
+		j.Operations = j.Operations[len(j.Operations)-maxJournalOperations:]
+	}
+	if err := j.storeJournal(); err != nil {
+		return errors.Wrap(err, "failed to add operation")
+	}
+	return nil
+}
+
+func (j *Journal) delete(opID string) error {
+	var ops []*Operation
+	for _, op := range j.Operations {
+		if op.ID == opID {
+			continue
+		}
+		ops = append(ops, op)
+	}
+	j.Operations = ops
+
+	if err := j.storeJournal(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// storeJournal stores the users operation journal
+func (j *Journal) storeJournal() error {
+	bb, jsonErr := json.Marshal(j)
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	key := getJournalKey(j.userID)
+	appErr := j.api.KVSet(key, bb)
+	if appErr != nil {
+		return appErr
+	}
+
+	return nil
+}
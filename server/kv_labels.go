@@ -16,9 +16,11 @@ type Labels struct {
 
 // Label defines the parameters of a label
 type Label struct {
-	Name string `json:"name"`
-	ID   string `json:"id"`
-	// Color string `json:"color"`
+	Name        string `json:"name"`
+	ID          string `json:"id"`
+	ParentID    string `json:"parent_id,omitempty"`
+	Color       string `json:"color,omitempty"`
+	Description string `json:"description,omitempty"`
 }
 
 // NewLabels returns an initialized Labels struct
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/pkg/errors"
+)
+
+// Rules contains a map of automatic labeling rules
+type Rules struct {
+	ByID   map[string]*Rule
+	api    plugin.API
+	userID string
+}
+
+// Rule applies labels to new bookmarks of posts matching a condition
+type Rule struct {
+	ID       string   `json:"id"`
+	Type     string   `json:"type"`
+	Value    string   `json:"value"`
+	LabelIDs []string `json:"label_ids"`
+}
+
+// NewRulesWithUser returns an initialized Rules for a User
+func NewRulesWithUser(api plugin.API, userID string) *Rules {
+	return &Rules{
+		ByID:   make(map[string]*Rule),
+		api:    api,
+		userID: userID,
+	}
+}
+
+func (r *Rules) add(rule *Rule) error {
+	r.ByID[rule.ID] = rule
+	if err := r.storeRules(); err != nil {
+		return errors.Wrap(err, "failed to add rule")
+	}
+	return nil
+}
+
+func (r *Rules) get(id string) *Rule {
+	return r.ByID[id]
+}
+
+func (r *Rules) delete(id string) error {
+	delete(r.ByID, id)
+	if err := r.storeRules(); err != nil {
+		return errors.Wrap(err, "failed to delete rule")
+	}
+	return nil
+}
+
+// storeRules stores all the users rules
+func (r *Rules) storeRules() error {
+	bb, jsonErr := json.Marshal(r)
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	key := getRulesKey(r.userID)
+	appErr := r.api.KVSet(key, bb)
+	if appErr != nil {
+		return appErr
+	}
+
+	return nil
+}
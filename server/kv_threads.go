@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/pkg/errors"
+)
+
+// ThreadWatchers contains the users notified of new replies in a thread
+type ThreadWatchers struct {
+	UserIDs []string `json:"user_ids,omitempty"`
+	api     plugin.API
+	rootID  string
+}
+
+// NewThreadWatchersWithRoot returns an initialized ThreadWatchers for a
+// thread root post
+func NewThreadWatchersWithRoot(api plugin.API, rootID string) *ThreadWatchers {
+	return &ThreadWatchers{
+		api:    api,
+		rootID: rootID,
+	}
+}
+
+func (w *ThreadWatchers) add(userID string) error {
+	if containsID(w.UserIDs, userID) {
+		return nil
+	}
+	w.UserIDs = append(w.UserIDs, userID)
+	if err := w.storeThreadWatchers(); err != nil {
+		return errors.Wrap(err, "failed to add thread watcher")
+	}
+	return nil
+}
+
+func (w *ThreadWatchers) delete(userID string) error {
+	var userIDs []string
+	for _, id := range w.UserIDs {
+		if id != userID {
+			userIDs = append(userIDs, id)
+		}
+	}
+	w.UserIDs = userIDs
+	if err := w.storeThreadWatchers(); err != nil {
+		return errors.Wrap(err, "failed to remove thread watcher")
+	}
+	return nil
+}
+
+// storeThreadWatchers stores the watchers of the thread, deleting the key
+// once nobody watches the thread
+func (w *ThreadWatchers) storeThreadWatchers() error {
+	key := getThreadWatchersKey(w.rootID)
+	if len(w.UserIDs) == 0 {
+		if appErr := w.api.KVDelete(key); appErr != nil {
+			return appErr
+		}
+		return nil
+	}
+
+	bb, jsonErr := json.Marshal(w)
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	appErr := w.api.KVSet(key, bb)
+	if appErr != nil {
+		return appErr
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/pkg/errors"
+)
+
+// Trash contains a map of removed bookmarks
+type Trash struct {
+	ByID   map[string]*Bookmark
+	api    plugin.API
+	userID string
+}
+
+// NewTrashWithUser returns an initialized Trash for a User
+func NewTrashWithUser(api plugin.API, userID string) *Trash {
+	return &Trash{
+		ByID:   make(map[string]*Bookmark),
+		api:    api,
+		userID: userID,
+	}
+}
+
+func (t *Trash) add(bmark *Bookmark) error {
+	t.ByID[bmark.PostID] = bmark
+	if err := t.storeTrash(); err != nil {
+		return errors.Wrap(err, "failed to add bookmark to trash")
+	}
+	return nil
+}
+
+func (t *Trash) get(bmarkID string) *Bookmark {
+	return t.ByID[bmarkID]
+}
+
+func (t *Trash) delete(bmarkID string) {
+	delete(t.ByID, bmarkID)
+}
+
+// storeTrash stores all the users removed bookmarks
+func (t *Trash) storeTrash() error {
+	bb, jsonErr := json.Marshal(t)
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	key := getTrashKey(t.userID)
+	appErr := t.api.KVSet(key, bb)
+	if appErr != nil {
+		return appErr
+	}
+
+	return nil
+}
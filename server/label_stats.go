@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	labelSortName  = "name"
+	labelSortCount = "count"
+	labelSortUsed  = "used"
+)
+
+// LabelStats holds how often and how recently a label is used on bookmarks
+type LabelStats struct {
+	Count    int
+	LastUsed int64
+}
+
+// getLabelStats returns the usage of every label on the bookmarks keyed by
+// label ID.  The stats are computed in one pass instead of calling
+// getBookmarksWithLabelID for each label
+func (b *Bookmarks) getLabelStats() map[string]*LabelStats {
+	stats := make(map[string]*LabelStats)
+	if b == nil {
+		return stats
+	}
+	for _, bmark := range b.ByID {
+		for _, id := range bmark.getLabelIDs() {
+			s, ok := stats[id]
+			if !ok {
+				s = &LabelStats{}
+				stats[id] = s
+			}
+			s.Count++
+			if used := bmark.getLabelAddedAt(id); used > s.LastUsed {
+				s.LastUsed = used
+			}
+		}
+	}
+	return stats
+}
+
+// getLabelAddedAt returns when a label was last added to the bookmark.
+// Bookmarks without history fall back to their modified time
+func (bm *Bookmark) getLabelAddedAt(labelID string) int64 {
+	for i := len(bm.History) - 1; i >= 0; i-- {
+		event := bm.History[i]
+		if event.Type != eventCreated && event.Type != eventLabelsAdded {
+			continue
+		}
+		for _, id := range event.LabelIDs {
+			if id == labelID {
+				return event.CreateAt
+			}
+		}
+	}
+	if bm.ModifiedAt != 0 {
+		return bm.ModifiedAt
+	}
+	return bm.CreateAt
+}
+
+// isValidLabelSort returns whether sortBy is a supported label sort order
+func isValidLabelSort(sortBy string) bool {
+	return sortBy == labelSortName || sortBy == labelSortCount || sortBy == labelSortUsed
+}
+
+// sortLabelIDs sorts label IDs by name, by bookmark count or by last used
+// time.  Counts and times sort most first, with ties sorted by name
+func (l *Labels) sortLabelIDs(ids []string, stats map[string]*LabelStats, sortBy string) {
+	get := func(id string) *LabelStats {
+		if s, ok := stats[id]; ok {
+			return s
+		}
+		return &LabelStats{}
+	}
+
+	sort.SliceStable(ids, func(i, j int) bool {
+		a, b := get(ids[i]), get(ids[j])
+		switch {
+		case sortBy == labelSortCount && a.Count != b.Count:
+			return a.Count > b.Count
+		case sortBy == labelSortUsed && a.LastUsed != b.LastUsed:
+			return a.LastUsed > b.LastUsed
+		}
+		return l.ByID[ids[i]].Name < l.ByID[ids[j]].Name
+	})
+}
+
+// getLabelStatsText returns the bookmark count and last used time of a label.
+// The time is shown in the timezone loc
+func getLabelStatsText(stats *LabelStats, loc *time.Location) string {
+	if stats == nil || stats.Count == 0 {
+		return "(0 bookmarks)"
+	}
+
+	noun := "bookmarks"
+	if stats.Count == 1 {
+		noun = "bookmark"
+	}
+	return fmt.Sprintf("(%v %s, last used %s)", stats.Count, noun, formatTime(stats.LastUsed, loc))
+}
+
+// getUnusedLabelIDs returns the IDs of the labels that are not used by any
+// bookmark or labeling rule.  A label is only unused if the labels nested
+// under it are unused too
+func (l *Labels) getUnusedLabelIDs(stats map[string]*LabelStats, ruleLabelIDs map[string]bool) []string {
+	var unused []string
+	for id := range l.ByID {
+		used := false
+		for _, descendantID := range l.getDescendantIDs(id) {
+			if s, ok := stats[descendantID]; (ok && s.Count != 0) || ruleLabelIDs[descendantID] {
+				used = true
+				break
+			}
+		}
+		if !used {
+			unused = append(unused, id)
+		}
+	}
+	l.sortLabelIDs(unused, stats, labelSortName)
+	return unused
+}
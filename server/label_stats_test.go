@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetLabelStats(t *testing.T) {
+	stats := getExecuteCommandTestStatsBookmarks().getLabelStats()
+	day := int64(24 * 60 * 60 * 1000)
+	jan1 := getExecuteCommandTestStatsBookmarks().ByID[p1ID].History[0].CreateAt
+
+	assert.Len(t, stats, 2)
+	assert.Equal(t, &LabelStats{Count: 1, LastUsed: jan1 + 4*day}, stats["UUID1"])
+	assert.Equal(t, &LabelStats{Count: 2, LastUsed: jan1 + 2*day}, stats["UUID2"])
+
+	var bmarks *Bookmarks
+	assert.Empty(t, bmarks.getLabelStats())
+}
+
+func TestGetLabelAddedAt(t *testing.T) {
+	bmark := &Bookmark{
+		CreateAt: 10,
+		LabelIDs: []string{"UUID1", "UUID2", "UUID3"},
+		History: []*BookmarkEvent{
+			{Type: eventCreated, CreateAt: 100, LabelIDs: []string{"UUID1"}},
+			{Type: eventLabelsAdded, CreateAt: 200, LabelIDs: []string{"UUID2"}},
+			{Type: eventLabelsRemoved, CreateAt: 300, LabelIDs: []string{"UUID1"}},
+			{Type: eventLabelsAdded, CreateAt: 400, LabelIDs: []string{"UUID1"}},
+		},
+	}
+
+	assert.Equal(t, int64(400), bmark.getLabelAddedAt("UUID1"))
+	assert.Equal(t, int64(200), bmark.getLabelAddedAt("UUID2"))
+	// labels without history fall back to the bookmark times
+	assert.Equal(t, int64(10), bmark.getLabelAddedAt("UUID3"))
+	bmark.ModifiedAt = 20
+	assert.Equal(t, int64(20), bmark.getLabelAddedAt("UUID3"))
+}
+
+func TestSortLabelIDs(t *testing.T) {
+	labels := getExecuteCommandTestLabels()
+	stats := map[string]*LabelStats{
+		"UUID1": {Count: 1, LastUsed: 300},
+		"UUID2": {Count: 3, LastUsed: 100},
+		"UUID3": {Count: 1, LastUsed: 200},
+	}
+
+	tests := map[string]struct {
+		sortBy   string
+		expected []string
+	}{
+		"by name":                    {sortBy: labelSortName, expected: []string{"UUID1", "UUID2", "UUID3"}},
+		"by count with ties by name": {sortBy: labelSortCount, expected: []string{"UUID2", "UUID1", "UUID3"}},
+		"by last used":               {sortBy: labelSortUsed, expected: []string{"UUID1", "UUID3", "UUID2"}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ids := []string{"UUID3", "UUID2", "UUID1"}
+			labels.sortLabelIDs(ids, stats, tt.sortBy)
+			assert.Equal(t, tt.expected, ids)
+		})
+	}
+
+	ids := []string{"UUID3", "UUID2", "UUID1"}
+	labels.sortLabelIDs(ids, map[string]*LabelStats{"UUID3": {Count: 1}}, labelSortCount)
+	assert.Equal(t, []string{"UUID3", "UUID1", "UUID2"}, ids)
+}
+
+func TestGetUnusedLabelIDs(t *testing.T) {
+	labels := getExecuteCommandTestNestedLabels()
+	stats := map[string]*LabelStats{
+		"UUID1": {Count: 1},
+		"UUID6": {Count: 2},
+	}
+
+	assert.Equal(t, []string{"UUID2", "UUID3", "UUID7", "UUID8"}, labels.getUnusedLabelIDs(stats, nil))
+
+	// labels added by rules are used, and so are their parents
+	assert.Equal(t, []string{"UUID2", "UUID3", "UUID7"}, labels.getUnusedLabelIDs(stats, map[string]bool{"UUID8": true}))
+	assert.Equal(t, []string{"UUID2", "UUID3", "UUID7", "UUID8"}, labels.getUnusedLabelIDs(stats, map[string]bool{"UUID4": true}))
+}
+
+func TestGetLabelStatsTextInUserTimezone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.Nil(t, err)
+	jan5 := time.Date(2020, 1, 5, 3, 0, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)
+
+	assert.Equal(t, "(0 bookmarks)", getLabelStatsText(nil, newYork))
+	assert.Equal(t, "(1 bookmark, last used Jan 4, 2020 22:00 EST)", getLabelStatsText(&LabelStats{Count: 1, LastUsed: jan5}, newYork))
+	assert.Equal(t, "(2 bookmarks, last used Jan 5, 2020 03:00 UTC)", getLabelStatsText(&LabelStats{Count: 2, LastUsed: jan5}, time.UTC))
+}
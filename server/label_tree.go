@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// labelPathSeparator separates the parts of a nested label name, e.g.
+// project/alpha/bugs
+const labelPathSeparator = "/"
+
+// getLabelParentName returns the name of the parent of a nested label, or an
+// empty string for a top-level label
+func getLabelParentName(name string) string {
+	i := strings.LastIndex(name, labelPathSeparator)
+	if i < 0 {
+		return ""
+	}
+	return name[:i]
+}
+
+// getLabelLeafName returns the last part of a nested label name
+func getLabelLeafName(name string) string {
+	return name[strings.LastIndex(name, labelPathSeparator)+1:]
+}
+
+// validateLabelPath checks that none of the parts of a nested label name
+// are empty
+func validateLabelPath(name string) error {
+	for _, part := range strings.Split(name, labelPathSeparator) {
+		if part == "" {
+			return errors.New(fmt.Sprintf("Label name `%s` is not valid. Nested label names cannot have empty parts, e.g. `project/alpha`", name))
+		}
+	}
+	return nil
+}
+
+// linkParents sets the parent references of nested labels from their names.
+// Labels stored before nesting was supported have no parent reference
+func (l *Labels) linkParents() {
+	for _, label := range l.ByID {
+		label.ParentID = ""
+		if parentName := getLabelParentName(label.Name); parentName != "" {
+			label.ParentID, _ = l.getIDFromName(parentName)
+		}
+	}
+}
+
+// ensureParents creates the missing ancestors of a nested label and returns
+// the ID of its parent.  The labels are not stored
+func (l *Labels) ensureParents(name string) (string, error) {
+	parentName := getLabelParentName(name)
+	if parentName == "" {
+		return "", nil
+	}
+
+	if l.getLabelByName(parentName) != nil {
+		return l.getIDFromName(parentName)
+	}
+
+	grandParentID, err := l.ensureParents(parentName)
+	if err != nil {
+		return "", err
+	}
+
+	parentID := NewID()
+	l.ByID[parentID] = &Label{
+		Name:     parentName,
+		ID:       parentID,
+		ParentID: grandParentID,
+	}
+	return parentID, nil
+}
+
+// getChildIDs returns the IDs of the direct children of a label, sorted by
+// name.  An empty parentID returns the top-level labels
+func (l *Labels) getChildIDs(parentID string) []string {
+	var ids []string
+	for id, label := range l.ByID {
+		if label.ParentID == parentID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return l.ByID[ids[i]].Name < l.ByID[ids[j]].Name
+	})
+	return ids
+}
+
+// getDescendantIDs returns the ID of a label followed by the IDs of all the
+// labels nested under it
+func (l *Labels) getDescendantIDs(id string) []string {
+	ids := []string{id}
+	for _, childID := range l.getChildIDs(id) {
+		ids = append(ids, l.getDescendantIDs(childID)...)
+	}
+	return ids
+}
+
+// moveLabel renames a label and all the labels nested under it.  Label IDs
+// do not change, so bookmarks keep their labels
+func (l *Labels) moveLabel(id, newName string) error {
+	label := l.ByID[id]
+	if label == nil {
+		return errors.New(fmt.Sprintf("Label ID `%s` does not exist", id))
+	}
+	newName = normalizeLabelName(newName)
+	if err := validateLabelName(newName); err != nil {
+		return err
+	}
+
+	oldName := label.Name
+	if strings.HasPrefix(strings.ToLower(newName), strings.ToLower(oldName+labelPathSeparator)) {
+		return errors.New(fmt.Sprintf("Cannot move label `%v` into its own nested label `%v`", oldName, newName))
+	}
+	newName = l.canonicalLabelName(newName)
+
+	// labels in the subtree may only change the case of their names
+	subtree := l.getDescendantIDs(id)
+	inSubtree := make(map[string]bool)
+	for _, subID := range subtree {
+		inSubtree[subID] = true
+	}
+	for _, subID := range subtree {
+		name := newName + strings.TrimPrefix(l.ByID[subID].Name, oldName)
+		if existingID, err := l.getIDFromName(name); err == nil && !inSubtree[existingID] {
+			return errors.New(fmt.Sprintf("Cannot move label `%v` to `%v`. Label `%v` already exists", oldName, newName, name))
+		}
+	}
+
+	parentID, err := l.ensureParents(newName)
+	if err != nil {
+		return err
+	}
+
+	for _, subID := range subtree {
+		sub := l.ByID[subID]
+		sub.Name = newName + strings.TrimPrefix(sub.Name, oldName)
+	}
+	label.ParentID = parentID
+
+	return l.storeLabels()
+}
+
+// getLabelTreeText returns the labels as a nested markdown list with their
+// usage stats, with times shown in the timezone loc.  Labels at each level
+// are sorted by sortBy
+func (l *Labels) getLabelTreeText(stats map[string]*LabelStats, sortBy string, loc *time.Location) string {
+	var text string
+	var walk func(parentID string, depth int)
+	walk = func(parentID string, depth int) {
+		ids := l.getChildIDs(parentID)
+		l.sortLabelIDs(ids, stats, sortBy)
+		for _, id := range ids {
+			label := l.ByID[id]
+			name := label.Name
+			if depth > 0 {
+				name = getLabelLeafName(name)
+			}
+			text += fmt.Sprintf("%s- %s\n", strings.Repeat("  ", depth), getLabelText(name, label, stats[id], loc))
+			walk(id, depth+1)
+		}
+	}
+	walk("", 0)
+	return text
+}
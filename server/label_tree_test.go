@@ -0,0 +1,143 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLabelPathNames(t *testing.T) {
+	assert.Equal(t, "", getLabelParentName("project"))
+	assert.Equal(t, "project", getLabelParentName("project/alpha"))
+	assert.Equal(t, "project/alpha", getLabelParentName("project/alpha/bugs"))
+	assert.Equal(t, "project", getLabelLeafName("project"))
+	assert.Equal(t, "bugs", getLabelLeafName("project/alpha/bugs"))
+
+	assert.Nil(t, validateLabelPath("project/alpha/bugs"))
+	assert.NotNil(t, validateLabelPath("/project"))
+	assert.NotNil(t, validateLabelPath("project/"))
+	assert.NotNil(t, validateLabelPath("project//bugs"))
+}
+
+func TestLinkParents(t *testing.T) {
+	labels := NewLabelsWithUser(nil, UserID)
+	labels.ByID["UUID1"] = &Label{Name: "project/alpha"}
+	labels.ByID["UUID2"] = &Label{Name: "project"}
+	labels.ByID["UUID3"] = &Label{Name: "other/alpha", ParentID: "UUID2"}
+
+	labels.linkParents()
+
+	assert.Equal(t, "UUID2", labels.ByID["UUID1"].ParentID)
+	assert.Equal(t, "", labels.ByID["UUID2"].ParentID)
+	// the parent of a legacy nested label may not exist
+	assert.Equal(t, "", labels.ByID["UUID3"].ParentID)
+}
+
+func TestAddLabelCreatesParents(t *testing.T) {
+	api := makeAPIMock()
+	api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+	labels := getExecuteCommandTestNestedLabels()
+	labels.api = api
+
+	label, err := labels.addLabel("project/gamma/bugs")
+	assert.Nil(t, err)
+
+	gamma := labels.getLabelByName("project/gamma")
+	assert.NotNil(t, gamma)
+	assert.Equal(t, gamma.ID, label.ParentID)
+	assert.Equal(t, "UUID4", gamma.ParentID)
+	assert.Len(t, labels.ByID, 10)
+}
+
+func TestGetDescendantIDs(t *testing.T) {
+	labels := getExecuteCommandTestNestedLabels()
+
+	assert.Equal(t, []string{"UUID4", "UUID5", "UUID6", "UUID7", "UUID8"}, labels.getDescendantIDs("UUID4"))
+	assert.Equal(t, []string{"UUID5", "UUID6", "UUID7"}, labels.getDescendantIDs("UUID5"))
+	assert.Equal(t, []string{"UUID6"}, labels.getDescendantIDs("UUID6"))
+}
+
+func TestMoveLabel(t *testing.T) {
+	tests := map[string]struct {
+		id            string
+		newName       string
+		expectedErr   string
+		expectedNames map[string]string
+	}{
+		"rename keeps the nested labels": {
+			id:      "UUID5",
+			newName: "project/archived",
+			expectedNames: map[string]string{
+				"UUID4": "project",
+				"UUID5": "project/archived",
+				"UUID6": "project/archived/bugs",
+				"UUID7": "project/archived/docs",
+				"UUID8": "project/beta",
+			},
+		},
+		"move to a new parent creates it": {
+			id:      "UUID5",
+			newName: "archive/alpha",
+			expectedNames: map[string]string{
+				"UUID4": "project",
+				"UUID5": "archive/alpha",
+				"UUID6": "archive/alpha/bugs",
+				"UUID7": "archive/alpha/docs",
+				"UUID8": "project/beta",
+			},
+		},
+		"move to the top level": {
+			id:      "UUID6",
+			newName: "bugs",
+			expectedNames: map[string]string{
+				"UUID5": "project/alpha",
+				"UUID6": "bugs",
+				"UUID7": "project/alpha/docs",
+			},
+		},
+		"move into its own nested label": {
+			id:          "UUID4",
+			newName:     "project/alpha/new",
+			expectedErr: "Cannot move label `project` into its own nested label `project/alpha/new`",
+		},
+		"nested label name already exists": {
+			id:          "UUID5",
+			newName:     "project/beta",
+			expectedErr: "Cannot move label `project/alpha` to `project/beta`. Label `project/beta` already exists",
+		},
+		"label does not exist": {
+			id:          "UUID9",
+			newName:     "project/new",
+			expectedErr: "Label ID `UUID9` does not exist",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := makeAPIMock()
+			api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+			labels := getExecuteCommandTestNestedLabels()
+			labels.api = api
+
+			err := labels.moveLabel(tt.id, tt.newName)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			assert.Nil(t, err)
+			for id, name := range tt.expectedNames {
+				assert.Equal(t, name, labels.ByID[id].Name)
+			}
+
+			// parent references still match the names after the move
+			parentIDs := make(map[string]string)
+			for id, label := range labels.ByID {
+				parentIDs[id] = label.ParentID
+			}
+			labels.linkParents()
+			for id, label := range labels.ByID {
+				assert.Equal(t, label.ParentID, parentIDs[id], "parent of %s", id)
+			}
+		})
+	}
+}
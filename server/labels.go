@@ -5,13 +5,83 @@ import (
 	"encoding/base32"
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/pborman/uuid"
 	"github.com/pkg/errors"
 )
 
-// StoreLabelsKey is the key used to store labels in the plugin KV store
-const StoreLabelsKey = "labels"
+const (
+	// StoreLabelsKey is the key used to store labels in the plugin KV store
+	StoreLabelsKey = "labels"
+
+	// migrationLabelNamesKey marks that the label names of all users were
+	// normalized and their duplicates pointed out
+	migrationLabelNamesKey = "migration_label_names"
+)
+
+const (
+	// labelColorNone clears the color of a label
+	labelColorNone = "none"
+
+	maxLabelNameLength = 64
+)
+
+// reservedLabelNames cannot be used as label names.  TFP marks bookmarks
+// without a title
+var reservedLabelNames = []string{"TFP"}
+
+// labelNameRegexp matches a part of a label name.  Label names are single
+// command arguments, so they cannot have spaces
+var labelNameRegexp = regexp.MustCompile(`^[\p{L}\p{N}_.\-]+$`)
+
+// labelColorPalette maps the named label colors to their hex values
+var labelColorPalette = map[string]string{
+	"red":    "#d24b4e",
+	"orange": "#f5a623",
+	"yellow": "#f8d34b",
+	"green":  "#3db887",
+	"teal":   "#2dbfbf",
+	"blue":   "#1c58d9",
+	"purple": "#8a4fcf",
+	"pink":   "#e25d9c",
+	"gray":   "#8b8f95",
+}
+
+var hexColorRegexp = regexp.MustCompile(`^#([0-9a-f]{3}|[0-9a-f]{6})$`)
+
+// getLabelColorNames returns the sorted names of the label color palette
+func getLabelColorNames() []string {
+	var names []string
+	for name := range labelColorPalette {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// normalizeLabelColor validates a hex color or palette color name and
+// returns its lowercase hex value.  An empty color, or none, clears the color
+func normalizeLabelColor(input string) (string, error) {
+	color := strings.ToLower(strings.TrimSpace(input))
+	if color == "" || color == labelColorNone {
+		return "", nil
+	}
+	if hex, ok := labelColorPalette[color]; ok {
+		return hex, nil
+	}
+	if !strings.HasPrefix(color, "#") {
+		color = "#" + color
+	}
+	if !hexColorRegexp.MatchString(color) {
+		return "", errors.New(fmt.Sprintf("Invalid color `%s`. Use a hex color like `#1c58d9` or one of: %s",
+			input, strings.Join(getLabelColorNames(), ", ")))
+	}
+	return color, nil
+}
 
 // getNameFromID returns the Name of a Label
 func (l *Labels) getNameFromID(id string) (string, error) {
@@ -19,10 +89,29 @@ func (l *Labels) getNameFromID(id string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if label == nil {
+		return "", errors.New(fmt.Sprintf("Label ID `%s` does not exist", id))
+	}
 
 	return label.Name, nil
 }
 
+// getNamesFromIDs returns the names of the labels with the given IDs along
+// with the IDs of the labels that exist.  Unknown label IDs are skipped
+func (l *Labels) getNamesFromIDs(ids []string) ([]string, []string) {
+	var names []string
+	var existingIDs []string
+	for _, id := range ids {
+		name, err := l.getNameFromID(id)
+		if err != nil {
+			continue
+		}
+		names = append(names, name)
+		existingIDs = append(existingIDs, id)
+	}
+	return names, existingIDs
+}
+
 // getLabels returns a users labels
 func (l *Labels) getLabels() (*Labels, error) {
 	// if a user does not have labels, bb will be nil
@@ -39,21 +128,22 @@ func (l *Labels) getLabels() (*Labels, error) {
 	if jsonErr != nil {
 		return nil, jsonErr
 	}
+	l.linkParents()
 
 	return l, nil
 }
 
 // getLabelByName returns a label with the provided label name
 func (l *Labels) getLabelByName(labelName string) *Label {
-	for _, label := range l.ByID {
-		if label.Name == labelName {
-			return label
-		}
+	id, err := l.getIDFromName(labelName)
+	if err != nil {
+		return nil
 	}
-	return nil
+	return l.ByID[id]
 }
 
-// getIDFromName returns a label name with the corresponding label ID
+// getIDFromName returns a label name with the corresponding label ID.  Names
+// are compared without case, preferring an exact match
 func (l *Labels) getIDFromName(labelName string) (string, error) {
 	if l == nil {
 		return "", errors.New("user does not have any labels")
@@ -65,11 +155,72 @@ func (l *Labels) getIDFromName(labelName string) (string, error) {
 			return id, nil
 		}
 	}
+	for id, label := range l.ByID {
+		if labelNamesEqual(label.Name, labelName) {
+			return id, nil
+		}
+	}
 	return "", errors.New(fmt.Sprintf("Label: `%s` does not exist", labelName))
 }
 
+// normalizeLabelName returns a label name without whitespace around its
+// parts
+func normalizeLabelName(name string) string {
+	parts := strings.Split(name, labelPathSeparator)
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return strings.Join(parts, labelPathSeparator)
+}
+
+// labelNamesEqual returns whether two label names are the same once
+// normalized, ignoring case
+func labelNamesEqual(a, b string) bool {
+	return strings.EqualFold(normalizeLabelName(a), normalizeLabelName(b))
+}
+
+// validateLabelName checks the length, characters and nesting of a label
+// name, and that it is not reserved
+func validateLabelName(name string) error {
+	if name == "" {
+		return errors.New("Label name cannot be empty")
+	}
+	if utf8.RuneCountInString(name) > maxLabelNameLength {
+		return errors.New(fmt.Sprintf("Label name `%s` is too long. Label names can have at most %v characters", name, maxLabelNameLength))
+	}
+	for _, reserved := range reservedLabelNames {
+		if strings.EqualFold(name, reserved) {
+			return errors.New(fmt.Sprintf("Label name `%s` is reserved", name))
+		}
+	}
+	if err := validateLabelPath(name); err != nil {
+		return err
+	}
+	for _, part := range strings.Split(name, labelPathSeparator) {
+		if !labelNameRegexp.MatchString(part) {
+			return errors.New(fmt.Sprintf("Label name `%s` is not valid. Label names can only contain letters, numbers, `-`, `_`, `.` and `/` for nested labels", name))
+		}
+	}
+	return nil
+}
+
+// canonicalLabelName returns a label name using the existing spelling of its
+// parent labels, so nested labels stay under their parents
+func (l *Labels) canonicalLabelName(name string) string {
+	parentName := getLabelParentName(name)
+	if parentName == "" {
+		return name
+	}
+	if parent := l.getLabelByName(parentName); parent != nil {
+		return parent.Name + labelPathSeparator + getLabelLeafName(name)
+	}
+	return l.canonicalLabelName(parentName) + labelPathSeparator + getLabelLeafName(name)
+}
+
 // addLabel stores a label into the users label store
 func (l *Labels) addLabel(labelName string) (*Label, error) {
+	labelName = normalizeLabelName(labelName)
+
 	// check if name already exists
 	label := l.getLabelByName(labelName)
 
@@ -78,10 +229,20 @@ func (l *Labels) addLabel(labelName string) (*Label, error) {
 		return nil, errors.New(fmt.Sprintf("Label with name `%s` already exists", label.Name))
 	}
 
+	if err := validateLabelName(labelName); err != nil {
+		return nil, err
+	}
+	labelName = l.canonicalLabelName(labelName)
+	parentID, err := l.ensureParents(labelName)
+	if err != nil {
+		return nil, err
+	}
+
 	labelID := NewID()
 	label = &Label{
-		Name: labelName,
-		ID:   labelID,
+		Name:     labelName,
+		ID:       labelID,
+		ParentID: parentID,
 	}
 	if err := l.add(labelID, label); err != nil {
 		return nil, err
@@ -90,6 +251,235 @@ func (l *Labels) addLabel(labelName string) (*Label, error) {
 	return label, nil
 }
 
+// setColor validates and sets the color of the label with the given name
+func (l *Labels) setColor(labelName, color string) (*Label, error) {
+	labelID, err := l.getIDFromName(labelName)
+	if err != nil {
+		return nil, err
+	}
+	hex, err := normalizeLabelColor(color)
+	if err != nil {
+		return nil, err
+	}
+
+	label := l.ByID[labelID]
+	label.Color = hex
+	if err = l.add(labelID, label); err != nil {
+		return nil, err
+	}
+	return label, nil
+}
+
+// setDescription sets the description of the label with the given name
+func (l *Labels) setDescription(labelName, description string) (*Label, error) {
+	labelID, err := l.getIDFromName(labelName)
+	if err != nil {
+		return nil, err
+	}
+
+	label := l.ByID[labelID]
+	label.Description = strings.TrimSpace(description)
+	if err = l.add(labelID, label); err != nil {
+		return nil, err
+	}
+	return label, nil
+}
+
+// getMergeIDs validates merging the labels named from into the label named
+// into, and returns the IDs of the source labels and of the target label
+func (l *Labels) getMergeIDs(from []string, into string) ([]string, string, error) {
+	if len(from) == 0 || into == "" {
+		return nil, "", errors.New("Please specify the labels to merge and the label to merge them into")
+	}
+
+	intoID, err := l.getIDFromName(into)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var fromIDs []string
+	for _, name := range from {
+		id, err := l.getIDFromName(name)
+		if err != nil {
+			return nil, "", err
+		}
+		if id == intoID {
+			return nil, "", errors.New(fmt.Sprintf("Cannot merge label `%v` into itself", name))
+		}
+		if len(l.getChildIDs(id)) != 0 {
+			return nil, "", errors.New(fmt.Sprintf("Label `%v` has nested labels. Merge or move the nested labels first", name))
+		}
+		fromIDs = append(fromIDs, id)
+	}
+	return fromIDs, intoID, nil
+}
+
+// mergeLabels replaces the labels fromIDs on all bookmarks with the label
+// intoID and deletes the merged labels.  It returns the number of bookmarks
+// changed
+func (p *Plugin) mergeLabels(userID string, labels *Labels, fromIDs []string, intoID string) (int, error) {
+	merges := make(map[string]string)
+	for _, id := range fromIDs {
+		merges[id] = intoID
+	}
+	return p.mergeLabelsInto(userID, labels, merges)
+}
+
+// mergeLabelsInto replaces every label in merges on all bookmarks with the
+// label it maps to, and deletes the merged labels.  Bookmarks and labels are
+// each stored once.  It returns the number of bookmarks changed
+func (p *Plugin) mergeLabelsInto(userID string, labels *Labels, merges map[string]string) (int, error) {
+	bmarks, err := NewBookmarksWithUser(p.API, userID).getBookmarks()
+	if err != nil {
+		return 0, err
+	}
+	// bookmarks is nil if user has never added a bookmark
+	if bmarks == nil {
+		bmarks = NewBookmarksWithUser(p.API, userID)
+	}
+
+	var changed int
+	for _, bmark := range bmarks.ByID {
+		var ids []string
+		seen := make(map[string]bool)
+		for _, id := range bmark.getLabelIDs() {
+			if intoID, ok := merges[id]; ok {
+				id = intoID
+			}
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		if len(subtractIDs(bmark.getLabelIDs(), ids)) == 0 {
+			continue
+		}
+		bmark.updateLabelIDs(ids)
+		changed++
+	}
+
+	if changed != 0 {
+		if err = bmarks.storeBookmarks(); err != nil {
+			return 0, err
+		}
+	}
+
+	for id := range merges {
+		delete(labels.ByID, id)
+	}
+	if err = labels.storeLabels(); err != nil {
+		return 0, err
+	}
+
+	if err = p.mergeRuleLabels(userID, merges); err != nil {
+		return 0, err
+	}
+
+	return changed, nil
+}
+
+// getDuplicateLabelGroups returns groups of label IDs whose names only differ
+// by case or surrounding whitespace.  The first ID in each group is the label
+// to keep: the one with nested labels, then the most used, then by name
+func (l *Labels) getDuplicateLabelGroups(stats map[string]*LabelStats) [][]string {
+	byName := make(map[string][]string)
+	for id, label := range l.ByID {
+		key := strings.ToLower(normalizeLabelName(label.Name))
+		byName[key] = append(byName[key], id)
+	}
+
+	var keys []string
+	for key, ids := range byName {
+		if len(ids) > 1 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var groups [][]string
+	for _, key := range keys {
+		ids := byName[key]
+		l.sortLabelIDs(ids, stats, labelSortCount)
+		sort.SliceStable(ids, func(i, j int) bool {
+			return len(l.getChildIDs(ids[i])) > 0 && len(l.getChildIDs(ids[j])) == 0
+		})
+		groups = append(groups, ids)
+	}
+	return groups
+}
+
+// migrateUserLabelNames normalizes the stored label names of a user.  Labels
+// whose names only differ by case or whitespace are left unchanged, they are
+// merged by the dedupe command.  It returns whether the user has duplicate
+// labels
+func (p *Plugin) migrateUserLabelNames(userID string) (bool, error) {
+	labels, err := NewLabelsWithUser(p.API, userID).getLabels()
+	if err != nil {
+		return false, err
+	}
+
+	groups := labels.getDuplicateLabelGroups(nil)
+	duplicates := make(map[string]bool)
+	for _, group := range groups {
+		for _, id := range group {
+			duplicates[id] = true
+		}
+	}
+
+	normalized := 0
+	for id, label := range labels.ByID {
+		if duplicates[id] {
+			continue
+		}
+		if name := normalizeLabelName(label.Name); name != label.Name {
+			label.Name = name
+			normalized++
+		}
+	}
+
+	if normalized != 0 {
+		if err = labels.storeLabels(); err != nil {
+			return false, err
+		}
+	}
+	return len(groups) != 0, nil
+}
+
+// migrateLabelNames normalizes the label names of all users and tells the
+// users with duplicate labels how to merge them.  The migration only runs
+// once
+func (p *Plugin) migrateLabelNames() error {
+	done, appErr := p.API.KVGet(migrationLabelNamesKey)
+	if appErr != nil {
+		return appErr
+	}
+	if done != nil {
+		return nil
+	}
+
+	userIDs, err := p.listUserIDsWithPrefix(StoreLabelsKey + "_")
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		duplicates, err := p.migrateUserLabelNames(userID)
+		if err != nil {
+			return err
+		}
+		if !duplicates {
+			continue
+		}
+		if err = p.PostBotDM(userID, "Some of your labels only differ by case or spaces. Use `/bookmarks label dedupe` to merge them"); err != nil {
+			p.API.LogWarn("Unable to send the duplicate labels message", "error", err.Error())
+		}
+	}
+
+	if appErr = p.API.KVSet(migrationLabelNamesKey, []byte("done")); appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
 // deleteByID deletes a label from the store
 func (l *Labels) deleteByID(labelID string) error {
 	if err := l.delete(labelID); err != nil {
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeLabels(t *testing.T) {
+	tests := map[string]struct {
+		from             []string
+		into             string
+		expectedChanged  int
+		expectedLabelIDs map[string][]string
+		expectedLabels   []string
+	}{
+		"merge into a label already on the bookmarks": {
+			from:            []string{"label1"},
+			into:            "label2",
+			expectedChanged: 2,
+			expectedLabelIDs: map[string][]string{
+				p1ID: {"UUID2"},
+				p2ID: {"UUID2"},
+				p3ID: nil,
+			},
+			expectedLabels: []string{"UUID2", "UUID3"},
+		},
+		"merge several labels into an unused label": {
+			from:            []string{"label1", "label2"},
+			into:            "label8",
+			expectedChanged: 2,
+			expectedLabelIDs: map[string][]string{
+				p1ID: {"UUID3"},
+				p2ID: {"UUID3"},
+				p3ID: nil,
+			},
+			expectedLabels: []string{"UUID3"},
+		},
+		"merge an unused label": {
+			from:            []string{"label8"},
+			into:            "label1",
+			expectedChanged: 0,
+			expectedLabels:  []string{"UUID1", "UUID2"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := makeAPIMock()
+			p := makePlugin(api)
+
+			jsonBmarks, err := json.Marshal(getExecuteCommandTestBookmarks())
+			require.Nil(t, err)
+			api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+			api.On("KVGet", getRulesKey(UserID)).Return(nil, nil)
+
+			store := mockBookmarksStore(api, UserID)
+			api.On("KVSet", getLabelsKey(UserID), mock.Anything).Return(nil)
+
+			labels := getExecuteCommandTestLabels()
+			labels.api = api
+
+			fromIDs, intoID, err := labels.getMergeIDs(tt.from, tt.into)
+			require.Nil(t, err)
+
+			changed, err := p.mergeLabels(UserID, labels, fromIDs, intoID)
+			require.Nil(t, err)
+			assert.Equal(t, tt.expectedChanged, changed)
+
+			if tt.expectedChanged == 0 {
+				assert.Empty(t, store.getBookmarks().ByID)
+			} else {
+				for id, labelIDs := range tt.expectedLabelIDs {
+					stored := store.getBookmarks()
+					assert.Equal(t, labelIDs, stored.ByID[id].LabelIDs, id)
+				}
+			}
+
+			var labelIDs []string
+			for id := range labels.ByID {
+				labelIDs = append(labelIDs, id)
+			}
+			assert.ElementsMatch(t, tt.expectedLabels, labelIDs)
+		})
+	}
+}
+
+func TestValidateLabelName(t *testing.T) {
+	tests := map[string]struct {
+		name        string
+		expectedErr string
+	}{
+		"simple name":          {name: "work"},
+		"name with separators": {name: "release-1.2_notes"},
+		"unicode name":         {name: "café"},
+		"nested name":          {name: "project/alpha/bugs"},
+		"max length":           {name: strings.Repeat("a", 64)},
+		"empty name":           {name: "", expectedErr: "Label name cannot be empty"},
+		"too long":             {name: strings.Repeat("a", 65), expectedErr: "is too long"},
+		"comma":                {name: "a,b", expectedErr: "is not valid"},
+		"space":                {name: "a b", expectedErr: "is not valid"},
+		"nested space":         {name: "project/open bugs", expectedErr: "is not valid"},
+		"surrounding space":    {name: "a/ b", expectedErr: "is not valid"},
+		"backtick":             {name: "a`b", expectedErr: "is not valid"},
+		"empty nested part":    {name: "a//b", expectedErr: "Nested label names cannot have empty parts"},
+		"reserved name":        {name: "TFP", expectedErr: "Label name `TFP` is reserved"},
+		"reserved any case":    {name: "Tfp", expectedErr: "Label name `Tfp` is reserved"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateLabelName(tt.name)
+			if tt.expectedErr == "" {
+				assert.Nil(t, err)
+				return
+			}
+			require.NotNil(t, err)
+			assert.Contains(t, err.Error(), tt.expectedErr)
+		})
+	}
+}
+
+func TestNormalizeLabelName(t *testing.T) {
+	assert.Equal(t, "work", normalizeLabelName(" work "))
+	assert.Equal(t, "project/bugs", normalizeLabelName(" project / bugs\t"))
+	assert.True(t, labelNamesEqual("Work ", "work"))
+}
+
+func TestGetIDFromNameIgnoresCase(t *testing.T) {
+	labels := getExecuteCommandTestDuplicateLabels()
+
+	id, err := labels.getIDFromName("label1")
+	assert.Nil(t, err)
+	assert.Equal(t, "UUID1", id)
+
+	id, err = labels.getIDFromName("Label1")
+	assert.Nil(t, err)
+	assert.Equal(t, "UUID4", id)
+
+	id, err = labels.getIDFromName(" LABEL2 ")
+	assert.Nil(t, err)
+	assert.Equal(t, "UUID2", id)
+
+	_, err = labels.getIDFromName("label")
+	assert.NotNil(t, err)
+}
+
+func TestCanonicalLabelName(t *testing.T) {
+	labels := getExecuteCommandTestNestedLabels()
+
+	assert.Equal(t, "Other", labels.canonicalLabelName("Other"))
+	assert.Equal(t, "project/Gamma", labels.canonicalLabelName("PROJECT/Gamma"))
+	assert.Equal(t, "project/alpha/New", labels.canonicalLabelName("Project/ALPHA/New"))
+	assert.Equal(t, "project/New/Child", labels.canonicalLabelName("Project/New/Child"))
+}
+
+func TestGetDuplicateLabelGroups(t *testing.T) {
+	labels := getExecuteCommandTestDuplicateLabels()
+
+	assert.Equal(t, [][]string{
+		{"UUID4", "UUID1", "UUID5"},
+		{"UUID7", "UUID6"},
+	}, labels.getDuplicateLabelGroups(nil))
+
+	// the most used label is kept
+	stats := map[string]*LabelStats{"UUID5": {Count: 1}}
+	assert.Equal(t, []string{"UUID5", "UUID4", "UUID1"}, labels.getDuplicateLabelGroups(stats)[0])
+
+	// a label with nested labels is kept
+	labels.ByID["UUID8"] = &Label{Name: "work/notes", ID: "UUID8", ParentID: "UUID6"}
+	assert.Equal(t, []string{"UUID6", "UUID7"}, labels.getDuplicateLabelGroups(stats)[1])
+
+	assert.Empty(t, getExecuteCommandTestLabels().getDuplicateLabelGroups(nil))
+}
+
+// dmKVStore is a memoryKVStore that records the DMs sent by the bot
+type dmKVStore struct {
+	*memoryKVStore
+	messages map[string]string
+}
+
+func (s *dmKVStore) GetDirectChannel(userID1, userID2 string) (*model.Channel, *model.AppError) {
+	return &model.Channel{Id: userID1 + "__" + userID2}, nil
+}
+
+func (s *dmKVStore) CreatePost(post *model.Post) (*model.Post, *model.AppError) {
+	s.messages[post.ChannelId] = post.Message
+	return post, nil
+}
+
+func TestMigrateLabelNames(t *testing.T) {
+	store := &dmKVStore{memoryKVStore: newMemoryKVStore(), messages: make(map[string]string)}
+	p := &Plugin{BotUserID: "BotID"}
+	p.SetAPI(store)
+
+	labels := getExecuteCommandTestDuplicateLabels()
+	labels.ByID["UUID8"] = &Label{Name: " read ", ID: "UUID8"}
+	jsonLabels, err := json.Marshal(labels)
+	require.Nil(t, err)
+	store.values[getLabelsKey(UserID)] = jsonLabels
+	jsonLabels, err = json.Marshal(getExecuteCommandTestLabels())
+	require.Nil(t, err)
+	store.values[getLabelsKey("OtherUserID")] = jsonLabels
+
+	require.Nil(t, p.migrateLabelNames())
+	assert.Equal(t, []byte("done"), store.values[migrationLabelNamesKey])
+
+	// names are normalized, the duplicate labels are left for the dedupe
+	// command
+	migrated, err := NewLabelsWithUser(store, UserID).getLabels()
+	require.Nil(t, err)
+	var names []string
+	for _, label := range migrated.ByID {
+		names = append(names, label.Name)
+	}
+	assert.ElementsMatch(t, []string{"label1", "label2", "label8", "Label1", "label1 ", "Work", "WORK", "read"}, names)
+
+	// only the user with duplicate labels is told about them
+	assert.Equal(t, map[string]string{
+		UserID + "__BotID": "Some of your labels only differ by case or spaces. Use `/bookmarks label dedupe` to merge them",
+	}, store.messages)
+
+	// the migration only runs once
+	store.resetCounts()
+	require.Nil(t, p.migrateLabelNames())
+	assert.Equal(t, 0, store.writes)
+	assert.Len(t, store.messages, 1)
+}
+
+func TestMergeLabelsInto(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+
+	jsonBmarks, err := json.Marshal(getExecuteCommandTestBookmarks())
+	require.Nil(t, err)
+	api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+	api.On("KVGet", getRulesKey(UserID)).Return(nil, nil)
+
+	store := mockBookmarksStore(api, UserID)
+	api.On("KVSet", getLabelsKey(UserID), mock.Anything).Once().Return(nil)
+
+	labels := getExecuteCommandTestDuplicateLabels()
+	labels.api = api
+
+	changed, err := p.mergeLabelsInto(UserID, labels, map[string]string{
+		"UUID1": "UUID4",
+		"UUID2": "UUID6",
+	})
+	require.Nil(t, err)
+	assert.Equal(t, 2, changed)
+	stored := store.getBookmarks()
+	assert.Equal(t, []string{"UUID4", "UUID6"}, stored.ByID[p1ID].LabelIDs)
+	assert.Equal(t, []string{"UUID4", "UUID6"}, stored.ByID[p2ID].LabelIDs)
+	assert.NotContains(t, labels.ByID, "UUID1")
+	assert.NotContains(t, labels.ByID, "UUID2")
+	// the bookmarks are stored once, migrating the 4 bookmarks to a bucket and
+	// writing the layout header, and the labels once
+	assert.Equal(t, 1, store.headerWrites)
+	api.AssertNumberOfCalls(t, "KVSet", 3)
+}
@@ -16,7 +16,7 @@ const manifestStr = `
   "name": "Bookmarks",
   "description": "Plugin bookmark posts in Mattermost.",
   "version": "0.1.0",
-  "min_server_version": "5.20.0",
+  "min_server_version": "5.24.0",
   "server": {
     "executables": {
       "linux-amd64": "server/dist/plugin-linux-amd64",
@@ -31,7 +31,42 @@ const manifestStr = `
   "settings_schema": {
     "header": "",
     "footer": "",
-    "settings": []
+    "settings": [
+      {
+        "key": "UndoWindowMinutes",
+        "display_name": "Undo Window (minutes):",
+        "type": "number",
+        "help_text": "The number of minutes during which users can undo removing bookmarks and labels.",
+        "placeholder": "",
+        "default": 10
+      },
+      {
+        "key": "TrashRetentionDays",
+        "display_name": "Trash Retention (days):",
+        "type": "number",
+        "help_text": "The number of days removed bookmarks are kept in the trash before they are permanently deleted.",
+        "placeholder": "",
+        "default": 30
+      },
+      {
+        "key": "ChannelBoardCuration",
+        "display_name": "Channel Board Curation:",
+        "type": "radio",
+        "help_text": "Who can add and remove bookmarks on the bookmark board of a channel. Every channel member can view the board.",
+        "placeholder": "",
+        "default": "admins",
+        "options": [
+          {
+            "display_name": "Channel admins",
+            "value": "admins"
+          },
+          {
+            "display_name": "All channel members",
+            "value": "members"
+          }
+        ]
+      }
+    ]
   }
 }
 `
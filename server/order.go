@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	priorityHigh   = "high"
+	priorityNormal = "normal"
+	priorityLow    = "low"
+
+	bookmarkSortTime     = "time"
+	bookmarkSortCreated  = "created"
+	bookmarkSortPriority = "priority"
+	bookmarkSortManual   = "manual"
+)
+
+// bookmarkPriorities are the priorities of a bookmark, highest first
+var bookmarkPriorities = []string{priorityHigh, priorityNormal, priorityLow}
+
+// bookmarkSorts are the orders bookmarks can be listed in
+var bookmarkSorts = []string{bookmarkSortTime, bookmarkSortCreated, bookmarkSortPriority, bookmarkSortManual}
+
+// getPriority returns the priority of the bookmark.  Bookmarks stored before
+// priorities existed are normal
+func (bm *Bookmark) getPriority() string {
+	if bm.Priority == "" {
+		return priorityNormal
+	}
+	return bm.Priority
+}
+
+// setPriority changes the priority of the bookmark and records the change
+func (bm *Bookmark) setPriority(priority string) {
+	if bm.getPriority() == priority {
+		return
+	}
+	bm.Priority = priority
+	bm.recordEvent(&BookmarkEvent{Type: eventPriorityChanged, Priority: priority})
+}
+
+// getPriorityRank returns the position of a priority, highest first
+func getPriorityRank(priority string) int {
+	for i, p := range bookmarkPriorities {
+		if p == priority {
+			return i
+		}
+	}
+	return len(bookmarkPriorities)
+}
+
+// validatePriority checks that priority is a bookmark priority
+func validatePriority(priority string) error {
+	if !containsID(bookmarkPriorities, priority) {
+		return errors.New(fmt.Sprintf("Priority `%s` is not valid. Use one of: %s", priority, strings.Join(bookmarkPriorities, ", ")))
+	}
+	return nil
+}
+
+// validateBookmarkSort checks that sortBy is a bookmark sort order
+func validateBookmarkSort(sortBy string) error {
+	if !containsID(bookmarkSorts, sortBy) {
+		return errors.New(fmt.Sprintf("Unknown sort order `%s`. Sort bookmarks by `%s`", sortBy, strings.Join(bookmarkSorts, "`, `")))
+	}
+	return nil
+}
+
+// getPriorityIcon returns the icon shown for bookmarks that are not normal
+// priority
+func getPriorityIcon(priority string) string {
+	switch priority {
+	case priorityHigh:
+		return ":small_red_triangle:"
+	case priorityLow:
+		return ":small_red_triangle_down:"
+	}
+	return ""
+}
+
+// sortBookmarks returns the bookmarks sorted by the time of the bookmarked
+// post, by bookmark creation time, by priority or in the order chosen by the
+// user.  Ties are sorted by post time, then by ID
+func (b *Bookmarks) sortBookmarks(sortBy string) ([]*Bookmark, error) {
+	postTimes := make(map[string]int64)
+	bookmarks := make([]*Bookmark, 0, len(b.ByID))
+	for _, bmark := range b.ByID {
+		// only posts have a post creation time
+		postTimes[bmark.PostID] = bmark.CreateAt
+		if bmark.isPost() {
+			post, appErr := b.api.GetPost(bmark.getTarget())
+			switch {
+			case appErr == nil:
+				postTimes[bmark.PostID] = post.CreateAt
+			case bmark.Snapshot != nil:
+				// the post is gone, use the time saved with the bookmark
+				postTimes[bmark.PostID] = bmark.Snapshot.CreateAt
+			default:
+				return nil, appErr
+			}
+		}
+		bookmarks = append(bookmarks, bmark)
+	}
+
+	sort.Slice(bookmarks, func(i, j int) bool {
+		x, y := bookmarks[i], bookmarks[j]
+		switch {
+		case sortBy == bookmarkSortCreated && x.CreateAt != y.CreateAt:
+			return x.CreateAt < y.CreateAt
+		case sortBy == bookmarkSortPriority && x.getPriority() != y.getPriority():
+			return getPriorityRank(x.getPriority()) < getPriorityRank(y.getPriority())
+		case sortBy == bookmarkSortManual && x.Position != y.Position:
+			// bookmarks never moved follow the moved bookmarks
+			if x.Position == 0 || y.Position == 0 {
+				return y.Position == 0
+			}
+			return x.Position < y.Position
+		case postTimes[x.PostID] != postTimes[y.PostID]:
+			return postTimes[x.PostID] < postTimes[y.PostID]
+		}
+		return x.PostID < y.PostID
+	})
+
+	return bookmarks, nil
+}
+
+// moveBookmark moves a bookmark in the manual order before another bookmark,
+// or to the top when beforeID is empty.  All bookmarks are renumbered
+func (b *Bookmarks) moveBookmark(bmarkID, beforeID string) error {
+	if _, err := b.getBookmark(bmarkID); err != nil {
+		return err
+	}
+	if beforeID != "" {
+		if _, err := b.getBookmark(beforeID); err != nil {
+			return err
+		}
+		if beforeID == bmarkID {
+			return errors.New("A bookmark cannot be moved before itself")
+		}
+	}
+
+	ordered, err := b.sortBookmarks(bookmarkSortManual)
+	if err != nil {
+		return err
+	}
+
+	var moved []*Bookmark
+	if beforeID == "" {
+		moved = append(moved, b.get(bmarkID))
+	}
+	for _, bmark := range ordered {
+		if bmark.PostID == bmarkID {
+			continue
+		}
+		if bmark.PostID == beforeID {
+			moved = append(moved, b.get(bmarkID))
+		}
+		moved = append(moved, bmark)
+	}
+
+	for i, bmark := range moved {
+		bmark.Position = int64(i + 1)
+	}
+	return b.storeBookmarks()
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// getOrderTestBookmarks returns bookmarks of posts that were all created at
+// the same time
+func getOrderTestBookmarks(api *plugintest.API) *Bookmarks {
+	api.On("GetPost", mock.Anything).Return(&model.Post{Message: "this is the post.Message", CreateAt: 100}, nil)
+
+	bmarks := NewBookmarksWithUser(api, UserID)
+	bmarks.ByID[p1ID] = &Bookmark{PostID: p1ID, Title: "Title1", CreateAt: 3}
+	bmarks.ByID[p2ID] = &Bookmark{PostID: p2ID, Title: "Title2", CreateAt: 1, Priority: priorityLow}
+	bmarks.ByID[p3ID] = &Bookmark{PostID: p3ID, Title: "Title3", CreateAt: 2, Priority: priorityHigh}
+	bmarks.ByID[p4ID] = &Bookmark{PostID: p4ID, Title: "Title4", CreateAt: 4}
+	return bmarks
+}
+
+func getSortedIDs(bmarks []*Bookmark) []string {
+	var ids []string
+	for _, bmark := range bmarks {
+		ids = append(ids, bmark.PostID)
+	}
+	return ids
+}
+
+func TestSortBookmarks(t *testing.T) {
+	tests := map[string]struct {
+		sortBy   string
+		expected []string
+	}{
+		"posts at the same time are all kept": {sortBy: bookmarkSortTime, expected: []string{p1ID, p2ID, p3ID, p4ID}},
+		"created":                             {sortBy: bookmarkSortCreated, expected: []string{p2ID, p3ID, p1ID, p4ID}},
+		"priority":                            {sortBy: bookmarkSortPriority, expected: []string{p3ID, p1ID, p4ID, p2ID}},
+		"manual without positions":            {sortBy: bookmarkSortManual, expected: []string{p1ID, p2ID, p3ID, p4ID}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			bmarks := getOrderTestBookmarks(makeAPIMock())
+			sorted, err := bmarks.sortBookmarks(tt.sortBy)
+			require.Nil(t, err)
+			assert.Equal(t, tt.expected, getSortedIDs(sorted))
+		})
+	}
+}
+
+func TestMoveBookmark(t *testing.T) {
+	api := makeAPIMock()
+	mockBookmarksStore(api, UserID)
+	api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+	bmarks := getOrderTestBookmarks(api)
+
+	require.Nil(t, bmarks.moveBookmark(p3ID, ""))
+	sorted, err := bmarks.sortBookmarks(bookmarkSortManual)
+	require.Nil(t, err)
+	assert.Equal(t, []string{p3ID, p1ID, p2ID, p4ID}, getSortedIDs(sorted))
+
+	require.Nil(t, bmarks.moveBookmark(p4ID, p1ID))
+	sorted, err = bmarks.sortBookmarks(bookmarkSortManual)
+	require.Nil(t, err)
+	assert.Equal(t, []string{p3ID, p4ID, p1ID, p2ID}, getSortedIDs(sorted))
+
+	// bookmarks added later follow the moved bookmarks
+	bmarks.ByID["ID0xxxxxxxxxxxxxxxxxxxxxxx"] = &Bookmark{PostID: "ID0xxxxxxxxxxxxxxxxxxxxxxx"}
+	sorted, err = bmarks.sortBookmarks(bookmarkSortManual)
+	require.Nil(t, err)
+	assert.Equal(t, []string{p3ID, p4ID, p1ID, p2ID, "ID0xxxxxxxxxxxxxxxxxxxxxxx"}, getSortedIDs(sorted))
+
+	assert.NotNil(t, bmarks.moveBookmark(p1ID, p1ID))
+	assert.NotNil(t, bmarks.moveBookmark("ID9xxxxxxxxxxxxxxxxxxxxxxx", ""))
+	assert.NotNil(t, bmarks.moveBookmark(p1ID, "ID9xxxxxxxxxxxxxxxxxxxxxxx"))
+}
+
+func TestSetPriority(t *testing.T) {
+	bmark := &Bookmark{PostID: p1ID}
+	assert.Equal(t, priorityNormal, bmark.getPriority())
+
+	bmark.setPriority(priorityNormal)
+	assert.Empty(t, bmark.History)
+
+	bmark.setPriority(priorityHigh)
+	assert.Equal(t, priorityHigh, bmark.getPriority())
+	require.Len(t, bmark.History, 1)
+	assert.Equal(t, "Changed priority to `high`", getEventText(bmark.History[0], nil))
+
+	assert.Nil(t, validatePriority(priorityLow))
+	assert.NotNil(t, validatePriority("urgent"))
+}
+
+func TestExecuteCommandOrder(t *testing.T) {
+	tests := map[string]struct {
+		command           string
+		expectedMsgPrefix string
+		expectedPriority  string
+		expectedPosition  int64
+	}{
+		"PRIORITY set": {
+			command:           "/bookmarks priority ID1xxxxxxxxxxxxxxxxxxxxxxx high",
+			expectedMsgPrefix: "Set priority to `high`: [:link:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx) :small_red_triangle: **_Title1_**",
+			expectedPriority:  priorityHigh,
+		},
+		"PRIORITY invalid": {
+			command:           "/bookmarks priority ID1xxxxxxxxxxxxxxxxxxxxxxx urgent",
+			expectedMsgPrefix: "Priority `urgent` is not valid",
+		},
+		"PRIORITY missing": {
+			command:           "/bookmarks priority ID1xxxxxxxxxxxxxxxxxxxxxxx",
+			expectedMsgPrefix: "Please specify a post_id and a priority",
+		},
+		"MOVE to top": {
+			command:           "/bookmarks move ID4xxxxxxxxxxxxxxxxxxxxxxx --top",
+			expectedMsgPrefix: "Moved bookmark: [:link:](https://myhost.com/_redirect/pl/ID4xxxxxxxxxxxxxxxxxxxxxxx) **_Title4_**",
+			expectedPosition:  1,
+		},
+		"MOVE before": {
+			command:           "/bookmarks move ID4xxxxxxxxxxxxxxxxxxxxxxx --before ID2xxxxxxxxxxxxxxxxxxxxxxx",
+			expectedMsgPrefix: "Moved bookmark:",
+			expectedPosition:  2,
+		},
+		"MOVE without destination": {
+			command:           "/bookmarks move ID4xxxxxxxxxxxxxxxxxxxxxxx",
+			expectedMsgPrefix: "Please specify a post_id and where to move it",
+		},
+		"MOVE with both destinations": {
+			command:           "/bookmarks move ID4xxxxxxxxxxxxxxxxxxxxxxx --top --before ID2xxxxxxxxxxxxxxxxxxxxxxx",
+			expectedMsgPrefix: "Please specify either `--top` or `--before <post_id>`",
+		},
+		"MOVE unknown bookmark": {
+			command:           "/bookmarks move ID4xxxxxxxxxxxxxxxxxxxxxxx --before ID9xxxxxxxxxxxxxxxxxxxxxxx",
+			expectedMsgPrefix: "Bookmark `ID9xxxxxxxxxxxxxxxxxxxxxxx` does not exist",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := makeAPIMock()
+			siteURL := "https://myhost.com"
+			api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+
+			bmarks := getOrderTestBookmarks(api)
+			bmarks.get(p3ID).Priority = ""
+			bmarks.get(p2ID).Priority = ""
+			jsonBmarks, err := json.Marshal(bmarks)
+			require.Nil(t, err)
+			api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+
+			store := mockBookmarksStore(api, UserID)
+
+			api.On("SendEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				actual := strings.TrimSpace(args.Get(1).(*model.Post).Message)
+				assert.True(t, strings.HasPrefix(actual, tt.expectedMsgPrefix), "Expected returned message to start with: \n%s\nActual:\n%s", tt.expectedMsgPrefix, actual)
+			}).Once().Return(&model.Post{})
+
+			p := makePlugin(api)
+			args := &model.CommandArgs{Command: tt.command, UserId: UserID}
+			cmdResponse, appError := p.ExecuteCommand(&plugin.Context{}, args)
+			require.Nil(t, appError)
+			require.NotNil(t, cmdResponse)
+
+			stored := store.getBookmarks()
+			if tt.expectedPriority != "" {
+				require.NotNil(t, stored.get(p1ID))
+				assert.Equal(t, tt.expectedPriority, stored.get(p1ID).getPriority())
+			}
+			if tt.expectedPosition != 0 {
+				require.NotNil(t, stored.get(p4ID))
+				assert.Equal(t, tt.expectedPosition, stored.get(p4ID).Position)
+			}
+		})
+	}
+}
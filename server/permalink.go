@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+// reasons a link cannot be used as a permalink
+const (
+	permalinkInvalidLink   = "invalid_link"
+	permalinkForeignServer = "foreign_server"
+	permalinkUnknownPath   = "unknown_path"
+	permalinkInvalidPostID = "invalid_post_id"
+)
+
+// Permalink is a parsed link to a post on this server
+type Permalink struct {
+	TeamName string // Empty for /_redirect/pl/ links
+	PostID   string
+	Thread   bool // Whether the link opens the thread of the post
+}
+
+// PermalinkError is returned for links that are not permalinks to a post on
+// this server
+type PermalinkError struct {
+	Reason string // One of the permalink* reasons
+	Link   string
+	Host   string // The server of links to other servers
+}
+
+func (e *PermalinkError) Error() string {
+	switch e.Reason {
+	case permalinkForeignServer:
+		return fmt.Sprintf("Link `%s` points to another server, `%s`", e.Link, e.Host)
+	case permalinkInvalidPostID:
+		return fmt.Sprintf("Link `%s` does not contain a valid post ID", e.Link)
+	case permalinkUnknownPath:
+		return fmt.Sprintf("Link `%s` is not a link to a post", e.Link)
+	}
+	return fmt.Sprintf("Link `%s` is not valid", e.Link)
+}
+
+// isLink returns whether text is a link rather than an ID
+func isLink(text string) bool {
+	return strings.Contains(text, "://") || strings.HasPrefix(text, "/")
+}
+
+// parsePermalink parses the links Mattermost uses for posts:
+// <team>/pl/<post_id>, _redirect/pl/<post_id> and <team>/threads/<post_id>,
+// with or without a query string, fragment or trailing slash.  Absolute links
+// must point to siteURL, including its subpath.  Relative links are relative
+// to siteURL
+func parsePermalink(link, siteURL string) (*Permalink, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, &PermalinkError{Reason: permalinkInvalidLink, Link: link}
+	}
+
+	path := u.Path
+	if u.IsAbs() || u.Host != "" {
+		site, err := url.Parse(siteURL)
+		if err != nil || site.Host == "" {
+			return nil, &PermalinkError{Reason: permalinkInvalidLink, Link: link}
+		}
+		sitePath := strings.TrimSuffix(site.Path, "/")
+		if !strings.EqualFold(u.Host, site.Host) || !strings.HasPrefix(path, sitePath+"/") {
+			return nil, &PermalinkError{Reason: permalinkForeignServer, Link: link, Host: u.Host}
+		}
+		path = strings.TrimPrefix(path, sitePath)
+	}
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != 3 {
+		return nil, &PermalinkError{Reason: permalinkUnknownPath, Link: link}
+	}
+
+	permalink := &Permalink{TeamName: parts[0], PostID: parts[2]}
+	switch {
+	case parts[0] == "_redirect" && parts[1] == "pl":
+		permalink.TeamName = ""
+	case parts[1] == "pl":
+	case parts[1] == "threads":
+		permalink.Thread = true
+	default:
+		return nil, &PermalinkError{Reason: permalinkUnknownPath, Link: link}
+	}
+
+	if !model.IsValidId(permalink.PostID) {
+		return nil, &PermalinkError{Reason: permalinkInvalidPostID, Link: link}
+	}
+	return permalink, nil
+}
+
+// parsePostLink returns the permalink of a post ID or a link
+func (p *Plugin) parsePostLink(text string) (*Permalink, error) {
+	if !isLink(text) {
+		if !model.IsValidId(text) {
+			return nil, errors.New(fmt.Sprintf("`%s` is not a valid post ID or permalink", text))
+		}
+		return &Permalink{PostID: text}, nil
+	}
+	return parsePermalink(text, p.GetSiteURL())
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const permalinkPostID = "pc5ywmxxb7n1bxo9hu1fzbacth"
+
+func TestParsePermalink(t *testing.T) {
+	tests := map[string]struct {
+		link     string
+		siteURL  string
+		expected *Permalink
+		reason   string
+	}{
+		"http permalink": {
+			link:     "http://myhost.com/myteam/pl/" + permalinkPostID,
+			siteURL:  "http://myhost.com",
+			expected: &Permalink{TeamName: "myteam", PostID: permalinkPostID},
+		},
+		"https permalink": {
+			link:     "https://myhost.com/myteam/pl/" + permalinkPostID,
+			siteURL:  "https://myhost.com/",
+			expected: &Permalink{TeamName: "myteam", PostID: permalinkPostID},
+		},
+		"redirect permalink": {
+			link:     "https://myhost.com/_redirect/pl/" + permalinkPostID,
+			siteURL:  "https://myhost.com",
+			expected: &Permalink{PostID: permalinkPostID},
+		},
+		"thread link": {
+			link:     "https://myhost.com/myteam/threads/" + permalinkPostID,
+			siteURL:  "https://myhost.com",
+			expected: &Permalink{TeamName: "myteam", PostID: permalinkPostID, Thread: true},
+		},
+		"query string, fragment and trailing slash": {
+			link:     "https://MyHost.com/myteam/pl/" + permalinkPostID + "/?view=full#top",
+			siteURL:  "https://myhost.com",
+			expected: &Permalink{TeamName: "myteam", PostID: permalinkPostID},
+		},
+		"site url with a subpath": {
+			link:     "https://myhost.com/chat/myteam/pl/" + permalinkPostID,
+			siteURL:  "https://myhost.com/chat",
+			expected: &Permalink{TeamName: "myteam", PostID: permalinkPostID},
+		},
+		"relative link": {
+			link:     "/myteam/pl/" + permalinkPostID,
+			siteURL:  "https://myhost.com",
+			expected: &Permalink{TeamName: "myteam", PostID: permalinkPostID},
+		},
+		"another server": {
+			link:    "https://chat.example.com/myteam/pl/" + permalinkPostID,
+			siteURL: "https://myhost.com",
+			reason:  permalinkForeignServer,
+		},
+		"outside of the site url subpath": {
+			link:    "https://myhost.com/other/myteam/pl/" + permalinkPostID,
+			siteURL: "https://myhost.com/chat",
+			reason:  permalinkForeignServer,
+		},
+		"not a post link": {
+			link:    "https://myhost.com/myteam/channels/town-square",
+			siteURL: "https://myhost.com",
+			reason:  permalinkUnknownPath,
+		},
+		"short post id": {
+			link:    "https://myhost.com/myteam/pl/ID1",
+			siteURL: "https://myhost.com",
+			reason:  permalinkInvalidPostID,
+		},
+		"site url not configured": {
+			link:   "https://myhost.com/myteam/pl/" + permalinkPostID,
+			reason: permalinkInvalidLink,
+		},
+		"unparsable link": {
+			link:    "https://myhost.com/%zz",
+			siteURL: "https://myhost.com",
+			reason:  permalinkInvalidLink,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			permalink, err := parsePermalink(tt.link, tt.siteURL)
+			if tt.reason == "" {
+				require.Nil(t, err)
+				assert.Equal(t, tt.expected, permalink)
+				return
+			}
+			require.NotNil(t, err)
+			linkErr, ok := err.(*PermalinkError)
+			require.True(t, ok)
+			assert.Equal(t, tt.reason, linkErr.Reason)
+			assert.Equal(t, tt.link, linkErr.Link)
+		})
+	}
+}
+
+func TestParsePostLink(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+
+	permalink, err := p.parsePostLink(permalinkPostID)
+	require.Nil(t, err)
+	assert.Equal(t, &Permalink{PostID: permalinkPostID}, permalink)
+
+	// bare IDs must be valid post IDs
+	for _, text := range []string{"ID1", "~town-square", permalinkPostID + "x"} {
+		_, err = p.parsePostLink(text)
+		require.NotNil(t, err)
+		assert.Equal(t, fmt.Sprintf("`%s` is not a valid post ID or permalink", text), err.Error())
+	}
+}
+
+func TestGetPostIDFromLink(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+
+	// IDs are returned without looking up the site URL
+	assert.Equal(t, p1ID, p.getPostIDFromLink(p1ID))
+	assert.Equal(t, "url:1234", p.getPostIDFromLink("url:1234"))
+
+	siteURL := "https://myhost.com"
+	api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+	assert.Equal(t, permalinkPostID, p.getPostIDFromLink("https://myhost.com/myteam/pl/"+permalinkPostID+"/"))
+	assert.Equal(t, "https://chat.example.com/myteam/pl/"+permalinkPostID, p.getPostIDFromLink("https://chat.example.com/myteam/pl/"+permalinkPostID))
+}
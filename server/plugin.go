@@ -2,6 +2,7 @@ package main
 
 import (
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -26,6 +27,10 @@ type Plugin struct {
 	BotUserID string
 
 	router *mux.Router
+
+	// stopJobs is closed to stop the background jobs when the plugin
+	// deactivates
+	stopJobs chan struct{}
 }
 
 // OnActivate runs when the plugin activates and ensures the plugin is properly
@@ -48,9 +53,58 @@ func (p *Plugin) OnActivate() error {
 	}
 	p.BotUserID = botID
 
+	// the migrations rewrite the values of every user, activation does not
+	// wait for them
+	go p.runMigrations()
+
+	p.stopJobs = make(chan struct{})
+	p.runJob(trashPurgeInterval, p.purgeExpiredTrash)
+	p.runJob(dueSummaryInterval, p.sendDueSummaries)
+
 	return p.API.RegisterCommand(getCommand())
 }
 
+// OnDeactivate stops the background jobs
+func (p *Plugin) OnDeactivate() error {
+	if p.stopJobs != nil {
+		close(p.stopJobs)
+	}
+	return nil
+}
+
+// runJob calls job every interval in the background until the plugin
+// deactivates
+func (p *Plugin) runJob(interval time.Duration, job func()) {
+	stop := p.stopJobs
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				job()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// runMigrations migrates the stored values of all users.  The bookmarks layout
+// is migrated first, the other migrations store bookmarks in the bucket
+// layout
+func (p *Plugin) runMigrations() {
+	if err := p.migrateBookmarksLayout(); err != nil {
+		p.API.LogError("Unable to migrate the bookmarks layout", "error", err.Error())
+	}
+	if err := p.migrateBookmarkKinds(); err != nil {
+		p.API.LogError("Unable to migrate bookmark kinds", "error", err.Error())
+	}
+	if err := p.migrateLabelNames(); err != nil {
+		p.API.LogError("Unable to migrate label names", "error", err.Error())
+	}
+}
+
 // GetSiteURL returns the SiteURL from the config settings
 func (p *Plugin) GetSiteURL() string {
 	ptr := p.API.GetConfig().ServiceSettings.SiteURL
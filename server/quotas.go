@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// nearLimitPercent is the share of a limit from which users are reported as
+// near their limit
+const nearLimitPercent = 80
+
+// QuotaUsage is the number of bookmarks and labels of a user
+type QuotaUsage struct {
+	UserID    string
+	Bookmarks int
+	Labels    int
+}
+
+// checkBookmarksLimit returns an error when adding the bookmarks with the
+// given IDs would take the user over the maximum number of bookmarks.
+// Bookmarks the user already has do not count
+func (p *Plugin) checkBookmarksLimit(bmarks *Bookmarks, ids []string) error {
+	max := p.getConfiguration().getMaxBookmarks()
+	if max == 0 {
+		return nil
+	}
+
+	added := make(map[string]bool)
+	for _, id := range ids {
+		if _, ok := bmarks.exists(id); !ok {
+			added[id] = true
+		}
+	}
+	count := bmarks.count()
+	if len(added) == 0 || count+len(added) <= max {
+		return nil
+	}
+
+	if count >= max {
+		return errors.New(fmt.Sprintf("You have reached the limit of %v bookmarks. Remove some bookmarks before adding more", max))
+	}
+	return errors.New(fmt.Sprintf("Adding %v bookmarks would take you over the limit of %v bookmarks. You can add %v more", len(added), max, max-count))
+}
+
+// checkEntriesLimit returns an error when adding a post to a collection or a
+// channel board that already has count posts would take it over the maximum
+// number of bookmarks.  name is shown in the error, e.g. "Collection `docs`"
+func (p *Plugin) checkEntriesLimit(name string, count int) error {
+	max := p.getConfiguration().getMaxBookmarks()
+	if max == 0 || count < max {
+		return nil
+	}
+	return errors.New(fmt.Sprintf("%s has reached the limit of %v bookmarks. Remove some bookmarks before adding more", name, max))
+}
+
+// checkLabelsLimit returns an error when creating the labels with the given
+// names, and their missing parents, would take the user over the maximum
+// number of labels
+func (p *Plugin) checkLabelsLimit(labels *Labels, names []string) error {
+	max := p.getConfiguration().getMaxLabels()
+
+	added := make(map[string]bool)
+	for _, name := range names {
+		for name = normalizeLabelName(name); name != ""; name = getLabelParentName(name) {
+			if labels.getLabelByName(name) == nil {
+				added[strings.ToLower(name)] = true
+			}
+		}
+	}
+	if len(added) == 0 || len(labels.ByID)+len(added) <= max {
+		return nil
+	}
+
+	if len(labels.ByID) >= max {
+		return errors.New(fmt.Sprintf("You have reached the limit of %v labels. Delete or merge some labels before adding more", max))
+	}
+	return errors.New(fmt.Sprintf("Adding %v labels would take you over the limit of %v labels. You can add %v more", len(added), max, max-len(labels.ByID)))
+}
+
+// checkTitleLength returns an error for bookmark titles longer than the
+// maximum title length
+func (p *Plugin) checkTitleLength(title string) error {
+	max := p.getConfiguration().getMaxTitleLength()
+	if length := len([]rune(title)); length > max {
+		return errors.New(fmt.Sprintf("Titles can be at most %v characters long. This title is %v characters long", max, length))
+	}
+	return nil
+}
+
+// checkDescriptionLength returns an error for label descriptions longer than
+// the maximum description length
+func (p *Plugin) checkDescriptionLength(description string) error {
+	max := p.getConfiguration().getMaxDescriptionLength()
+	if length := len([]rune(strings.TrimSpace(description))); length > max {
+		return errors.New(fmt.Sprintf("Label descriptions can be at most %v characters long. This description is %v characters long", max, length))
+	}
+	return nil
+}
+
+// getQuotaUsage returns the number of bookmarks and labels of a user
+func (p *Plugin) getQuotaUsage(userID string) (*QuotaUsage, error) {
+	bmarks, err := NewBookmarksWithUser(p.API, userID).getBookmarksIndex()
+	if err != nil {
+		return nil, err
+	}
+	labels, err := NewLabelsWithUser(p.API, userID).getLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &QuotaUsage{UserID: userID, Labels: len(labels.ByID)}
+	if bmarks != nil {
+		usage.Bookmarks = bmarks.count()
+	}
+	return usage, nil
+}
+
+// isNearLimit returns whether a count is at least nearLimitPercent of max.
+// Counts without a limit are never near it
+func isNearLimit(count, max int) bool {
+	return max > 0 && count*100 >= max*nearLimitPercent
+}
+
+// getLimitShare returns the share of a limit used by a count, or 0 without a
+// limit
+func getLimitShare(count, max int) float64 {
+	if max <= 0 {
+		return 0
+	}
+	return float64(count) / float64(max)
+}
+
+// formatLimit returns a limit for display
+func formatLimit(max int) string {
+	if max <= 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%v", max)
+}
+
+// getUsersNearLimits returns the usage of the users with bookmarks near the
+// maximum number of bookmarks or labels, the fullest first
+func (p *Plugin) getUsersNearLimits() ([]*QuotaUsage, error) {
+	config := p.getConfiguration()
+	userIDs, err := p.listUserIDsWithPrefix(StoreBookmarksKey + "_")
+	if err != nil {
+		return nil, err
+	}
+
+	var near []*QuotaUsage
+	for _, userID := range userIDs {
+		usage, err := p.getQuotaUsage(userID)
+		if err != nil {
+			return nil, err
+		}
+		if isNearLimit(usage.Bookmarks, config.getMaxBookmarks()) || isNearLimit(usage.Labels, config.getMaxLabels()) {
+			near = append(near, usage)
+		}
+	}
+
+	// users are sorted by their highest share of a limit
+	share := func(usage *QuotaUsage) float64 {
+		bookmarks := getLimitShare(usage.Bookmarks, config.getMaxBookmarks())
+		labels := getLimitShare(usage.Labels, config.getMaxLabels())
+		if bookmarks > labels {
+			return bookmarks
+		}
+		return labels
+	}
+	sort.Slice(near, func(i, j int) bool {
+		if share(near[i]) != share(near[j]) {
+			return share(near[i]) > share(near[j])
+		}
+		return near[i].UserID < near[j].UserID
+	})
+	return near, nil
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckBookmarksLimit(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+	p.setConfiguration(&configuration{MaxBookmarksPerUser: 5})
+	bmarks := getExecuteCommandTestBookmarks()
+
+	assert.Nil(t, p.checkBookmarksLimit(bmarks, []string{p1ID}))
+	assert.Nil(t, p.checkBookmarksLimit(bmarks, []string{"ID5xxxxxxxxxxxxxxxxxxxxxxx", p1ID}))
+	assert.Nil(t, p.checkBookmarksLimit(bmarks, nil))
+
+	err := p.checkBookmarksLimit(bmarks, []string{"ID5xxxxxxxxxxxxxxxxxxxxxxx", "ID6xxxxxxxxxxxxxxxxxxxxxxx", "ID6xxxxxxxxxxxxxxxxxxxxxxx"})
+	require.NotNil(t, err)
+	assert.Equal(t, "Adding 2 bookmarks would take you over the limit of 5 bookmarks. You can add 1 more", err.Error())
+
+	p.setConfiguration(&configuration{MaxBookmarksPerUser: 4})
+	err = p.checkBookmarksLimit(bmarks, []string{"ID5xxxxxxxxxxxxxxxxxxxxxxx"})
+	require.NotNil(t, err)
+	assert.Equal(t, "You have reached the limit of 4 bookmarks. Remove some bookmarks before adding more", err.Error())
+
+	// bookmarks are not limited without configuration
+	p.setConfiguration(&configuration{})
+	assert.Nil(t, p.checkBookmarksLimit(bmarks, []string{"ID5xxxxxxxxxxxxxxxxxxxxxxx", "ID6xxxxxxxxxxxxxxxxxxxxxxx"}))
+}
+
+func TestCheckLabelsLimit(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+	p.setConfiguration(&configuration{MaxLabelsPerUser: 5})
+	labels := getExecuteCommandTestLabels()
+
+	assert.Nil(t, p.checkLabelsLimit(labels, []string{"label1", "LABEL2"}))
+	assert.Nil(t, p.checkLabelsLimit(labels, []string{"new1", "new2"}))
+	assert.Nil(t, p.checkLabelsLimit(labels, []string{"label1/child"}))
+	assert.Nil(t, p.checkLabelsLimit(labels, []string{""}))
+
+	// the missing parents of nested labels count too
+	err := p.checkLabelsLimit(labels, []string{"a/b/c"})
+	require.NotNil(t, err)
+	assert.Equal(t, "Adding 3 labels would take you over the limit of 5 labels. You can add 2 more", err.Error())
+
+	p.setConfiguration(&configuration{MaxLabelsPerUser: 3})
+	err = p.checkLabelsLimit(labels, []string{"new1"})
+	require.NotNil(t, err)
+	assert.Equal(t, "You have reached the limit of 3 labels. Delete or merge some labels before adding more", err.Error())
+}
+
+func TestCheckLengths(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+	p.setConfiguration(&configuration{MaxTitleLength: 10, MaxDescriptionLength: 5})
+
+	assert.Nil(t, p.checkTitleLength("ten chars!"))
+	assert.Nil(t, p.checkTitleLength("éééééééééé"))
+	err := p.checkTitleLength("eleven char")
+	require.NotNil(t, err)
+	assert.Equal(t, "Titles can be at most 10 characters long. This title is 11 characters long", err.Error())
+
+	assert.Nil(t, p.checkDescriptionLength(" short "))
+	err = p.checkDescriptionLength("too long")
+	require.NotNil(t, err)
+	assert.Equal(t, "Label descriptions can be at most 5 characters long. This description is 8 characters long", err.Error())
+}
+
+func TestExecuteCommandQuotaLimits(t *testing.T) {
+	tests := map[string]struct {
+		command        string
+		config         *configuration
+		expectedPrefix string
+	}{
+		"add over the bookmark limit": {
+			command:        "/bookmarks add ID9xxxxxxxxxxxxxxxxxxxxxxx",
+			config:         &configuration{MaxBookmarksPerUser: 4},
+			expectedPrefix: "You have reached the limit of 4 bookmarks",
+		},
+		"add again at the bookmark limit": {
+			command:        "/bookmarks add " + p1ID + " new title",
+			config:         &configuration{MaxBookmarksPerUser: 4},
+			expectedPrefix: "Added bookmark:",
+		},
+		"add with a title that is too long": {
+			command:        "/bookmarks add ID9xxxxxxxxxxxxxxxxxxxxxxx a title that is too long",
+			config:         &configuration{MaxTitleLength: 10},
+			expectedPrefix: "Titles can be at most 10 characters long",
+		},
+		"add with labels over the label limit": {
+			command:        "/bookmarks add ID9xxxxxxxxxxxxxxxxxxxxxxx --labels label1,new",
+			config:         &configuration{MaxLabelsPerUser: 3},
+			expectedPrefix: "You have reached the limit of 3 labels",
+		},
+		"label add over the label limit": {
+			command:        "/bookmarks label add new",
+			config:         &configuration{MaxLabelsPerUser: 3},
+			expectedPrefix: "You have reached the limit of 3 labels",
+		},
+		"label describe with a description that is too long": {
+			command:        "/bookmarks label describe label1 a long description",
+			config:         &configuration{MaxDescriptionLength: 5},
+			expectedPrefix: "Label descriptions can be at most 5 characters long",
+		},
+		"view usage": {
+			command:        "/bookmarks quota",
+			config:         &configuration{MaxBookmarksPerUser: 10},
+			expectedPrefix: "#### Your Limits\n| | Used | Limit |\n|:--|--:|--:|\n| Bookmarks | 4 | 10 |\n| Labels | 3 | 200 |",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := makeAPIMock()
+			siteURL := "https://myhost.com"
+			api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+			api.On("GetPost", mock.AnythingOfType("string")).Return(&model.Post{Message: "this is the post.Message"}, nil)
+			api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+
+			jsonBmarks, err := json.Marshal(getExecuteCommandTestBookmarks())
+			require.Nil(t, err)
+			api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+			jsonLabels, err := json.Marshal(getExecuteCommandTestLabels())
+			require.Nil(t, err)
+			api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+			api.On("KVGet", getRulesKey(UserID)).Return(nil, nil)
+			api.On("KVGet", getTrashKey(UserID)).Return(nil, nil)
+
+			api.On("SendEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				actual := strings.TrimSpace(args.Get(1).(*model.Post).Message)
+				assert.True(t, strings.HasPrefix(actual, tt.expectedPrefix), "Expected returned message to start with: \n%s\nActual:\n%s", tt.expectedPrefix, actual)
+			}).Once().Return(&model.Post{})
+
+			p := makePlugin(api)
+			p.setConfiguration(tt.config)
+			args := &model.CommandArgs{Command: tt.command, UserId: UserID}
+			cmdResponse, appError := p.ExecuteCommand(&plugin.Context{}, args)
+			require.Nil(t, appError)
+			require.NotNil(t, cmdResponse)
+		})
+	}
+}
+
+func TestExecuteCommandQuotaReport(t *testing.T) {
+	tests := map[string]struct {
+		admin            bool
+		config           *configuration
+		expectedPrefix   string
+		expectedContains []string
+	}{
+		"not an admin": {
+			config:         &configuration{},
+			expectedPrefix: "Only system admins can view the quota report",
+		},
+		"no users near their limits": {
+			admin:          true,
+			config:         &configuration{},
+			expectedPrefix: "No users have 80% or more of their bookmark or label limits",
+		},
+		"users near their limits": {
+			admin:          true,
+			config:         &configuration{MaxBookmarksPerUser: 5, MaxLabelsPerUser: 3},
+			expectedPrefix: "#### Users Near Their Limits",
+			expectedContains: []string{
+				// both users have all of their labels, ties are sorted by user ID
+				"| User2 | 1 / 5 | 3 / 3 |\n| @alice | 4 / 5 | 3 / 3 |",
+			},
+		},
+		"users near their label limit without a bookmark limit": {
+			admin:          true,
+			config:         &configuration{MaxLabelsPerUser: 3},
+			expectedPrefix: "#### Users Near Their Limits",
+			expectedContains: []string{
+				"| User2 | 1 / unlimited | 3 / 3 |\n| @alice | 4 / unlimited | 3 / 3 |",
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := makeAPIMock()
+			api.On("HasPermissionTo", UserID, model.PERMISSION_MANAGE_SYSTEM).Return(tt.admin)
+			api.On("KVList", 0, kvListPerPage).Return([]string{getBookmarksKey(UserID), getLabelsKey(UserID), getBookmarksKey("User2")}, nil)
+
+			jsonBmarks, err := json.Marshal(getExecuteCommandTestBookmarks())
+			require.Nil(t, err)
+			api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+			jsonBmarks, err = json.Marshal(map[string]interface{}{"ByID": map[string]*Bookmark{p1ID: {PostID: p1ID}}})
+			require.Nil(t, err)
+			api.On("KVGet", getBookmarksKey("User2")).Return(jsonBmarks, nil)
+			jsonLabels, err := json.Marshal(getExecuteCommandTestLabels())
+			require.Nil(t, err)
+			api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+			api.On("KVGet", getLabelsKey("User2")).Return(jsonLabels, nil)
+			api.On("GetUser", UserID).Return(&model.User{Id: UserID, Username: "alice"}, nil)
+			api.On("GetUser", "User2").Return(nil, &model.AppError{Message: "not found"})
+
+			api.On("SendEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				actual := strings.TrimSpace(args.Get(1).(*model.Post).Message)
+				assert.True(t, strings.HasPrefix(actual, tt.expectedPrefix), "Expected returned message to start with: \n%s\nActual:\n%s", tt.expectedPrefix, actual)
+				for _, s := range tt.expectedContains {
+					assert.Contains(t, actual, s)
+				}
+			}).Once().Return(&model.Post{})
+
+			p := makePlugin(api)
+			p.setConfiguration(tt.config)
+			args := &model.CommandArgs{Command: "/bookmarks quota report", UserId: UserID}
+			cmdResponse, appError := p.ExecuteCommand(&plugin.Context{}, args)
+			require.Nil(t, appError)
+			require.NotNil(t, cmdResponse)
+		})
+	}
+}
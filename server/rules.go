@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// StoreRulesKey is the key used to store labeling rules in the plugin KV
+	// store
+	StoreRulesKey = "rules"
+
+	ruleTypeChannel = "channel"
+	ruleTypeAuthor  = "author"
+	ruleTypeHashtag = "hashtag"
+	ruleTypeKeyword = "keyword"
+)
+
+// getRules returns a users labeling rules
+func (r *Rules) getRules() (*Rules, error) {
+	// if a user does not have rules, bb will be nil
+	bb, appErr := r.api.KVGet(getRulesKey(r.userID))
+	if appErr != nil {
+		return nil, errors.Wrapf(appErr, "Unable to get rules for user %s", r.userID)
+	}
+
+	if bb == nil {
+		return r, nil
+	}
+
+	jsonErr := json.Unmarshal(bb, r)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+
+	return r, nil
+}
+
+// sortedIDs returns the rule IDs sorted so rules are listed and applied in
+// the same order
+func (r *Rules) sortedIDs() []string {
+	var ids []string
+	for id := range r.ByID {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func getRulesKey(userID string) string {
+	return fmt.Sprintf("%s_%s", StoreRulesKey, userID)
+}
+
+// getRuleID returns the ID of the rule for a condition.  There is one rule
+// per condition
+func getRuleID(ruleType, value string) string {
+	return ruleType + ":" + value
+}
+
+// parseRuleCondition returns the rule type and value of a condition:
+// ~channel, @user, #hashtag or a keyword.  Channels are looked up in teamID
+func (p *Plugin) parseRuleCondition(teamID, condition string) (string, string, error) {
+	switch {
+	case strings.HasPrefix(condition, "~"):
+		name := strings.TrimPrefix(condition, "~")
+		channel, appErr := p.API.GetChannelByName(teamID, name, false)
+		if appErr != nil || channel == nil {
+			return "", "", errors.New(fmt.Sprintf("Channel `%s` does not exist", condition))
+		}
+		return ruleTypeChannel, channel.Id, nil
+
+	case strings.HasPrefix(condition, "@"):
+		name := strings.TrimPrefix(condition, "@")
+		user, appErr := p.API.GetUserByUsername(name)
+		if appErr != nil || user == nil {
+			return "", "", errors.New(fmt.Sprintf("User `%s` does not exist", condition))
+		}
+		return ruleTypeAuthor, user.Id, nil
+
+	case strings.HasPrefix(condition, "#"):
+		tag := strings.ToLower(strings.TrimPrefix(condition, "#"))
+		if tag == "" {
+			return "", "", errors.New("Please specify a hashtag after `#`")
+		}
+		return ruleTypeHashtag, tag, nil
+
+	default:
+		keyword := strings.ToLower(condition)
+		if keyword == "" {
+			return "", "", errors.New("Please specify a channel, user, hashtag or keyword")
+		}
+		return ruleTypeKeyword, keyword, nil
+	}
+}
+
+// matches returns whether a post matches the rule condition.  Hashtags and
+// keywords are matched without case
+func (rule *Rule) matches(post *model.Post) bool {
+	switch rule.Type {
+	case ruleTypeChannel:
+		return post.ChannelId == rule.Value
+	case ruleTypeAuthor:
+		return post.UserId == rule.Value
+	case ruleTypeHashtag:
+		tags, _ := model.ParseHashtags(post.Message)
+		for _, tag := range strings.Fields(tags + " " + post.Hashtags) {
+			if strings.EqualFold(strings.TrimPrefix(tag, "#"), rule.Value) {
+				return true
+			}
+		}
+		return false
+	case ruleTypeKeyword:
+		re := regexp.MustCompile(`(?i)(^|\W)` + regexp.QuoteMeta(rule.Value) + `($|\W)`)
+		return re.MatchString(post.Message)
+	}
+	return false
+}
+
+// getRuleConditionText returns the condition of a rule as the user entered
+// it
+func (p *Plugin) getRuleConditionText(rule *Rule) string {
+	switch rule.Type {
+	case ruleTypeChannel:
+		if channel, appErr := p.API.GetChannel(rule.Value); appErr == nil {
+			return "~" + channel.Name
+		}
+	case ruleTypeAuthor:
+		if user, appErr := p.API.GetUser(rule.Value); appErr == nil {
+			return "@" + user.Username
+		}
+	case ruleTypeHashtag:
+		return "#" + rule.Value
+	case ruleTypeKeyword:
+		return fmt.Sprintf("\"%s\"", rule.Value)
+	}
+	return fmt.Sprintf("%s `%s`", rule.Type, rule.Value)
+}
+
+// applyLabelRules adds the labels of the users rules matching the post to a
+// new bookmark.  It returns the names of the labels added.  Labels removed
+// since the rule was created are skipped
+func (p *Plugin) applyLabelRules(userID string, bmark *Bookmark, post *model.Post) ([]string, error) {
+	rules, err := NewRulesWithUser(p.API, userID).getRules()
+	if err != nil {
+		return nil, err
+	}
+	if len(rules.ByID) == 0 || post == nil {
+		return nil, nil
+	}
+
+	labels, err := NewLabelsWithUser(p.API, userID).getLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := bmark.getLabelIDs()
+	var names []string
+	for _, ruleID := range rules.sortedIDs() {
+		rule := rules.get(ruleID)
+		if !rule.matches(post) {
+			continue
+		}
+		for _, id := range rule.LabelIDs {
+			label := labels.ByID[id]
+			if label == nil || len(subtractIDs([]string{id}, ids)) == 0 {
+				continue
+			}
+			ids = append(ids, id)
+			names = append(names, label.Name)
+		}
+	}
+	bmark.addLabelIDs(ids)
+
+	return names, nil
+}
+
+// applyLabelRulesOrWarn applies the labeling rules to a new bookmark.  Failing
+// to apply the rules does not fail adding the bookmark, so errors are only
+// logged
+func (p *Plugin) applyLabelRulesOrWarn(userID string, bmark *Bookmark, post *model.Post) []string {
+	names, err := p.applyLabelRules(userID, bmark, post)
+	if err != nil {
+		p.API.LogWarn("Unable to apply labeling rules", "error", err.Error())
+		return nil
+	}
+	return names
+}
+
+// mergeRuleLabels replaces merged labels in the users rules with the labels
+// they were merged into
+func (p *Plugin) mergeRuleLabels(userID string, merges map[string]string) error {
+	rules, err := NewRulesWithUser(p.API, userID).getRules()
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, rule := range rules.ByID {
+		var ids []string
+		for _, id := range rule.LabelIDs {
+			if intoID, ok := merges[id]; ok {
+				id = intoID
+				changed = true
+			}
+			if len(subtractIDs([]string{id}, ids)) != 0 {
+				ids = append(ids, id)
+			}
+		}
+		rule.LabelIDs = ids
+	}
+
+	if !changed {
+		return nil
+	}
+	return rules.storeRules()
+}
+
+// getLabelIDs returns the IDs of the labels added by any of the rules
+func (r *Rules) getLabelIDs() map[string]bool {
+	ids := make(map[string]bool)
+	for _, rule := range r.ByID {
+		for _, id := range rule.LabelIDs {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// getRuleIDsWithLabelID returns the sorted IDs of the rules adding a label
+func (r *Rules) getRuleIDsWithLabelID(labelID string) []string {
+	var ruleIDs []string
+	for _, ruleID := range r.sortedIDs() {
+		for _, id := range r.ByID[ruleID].LabelIDs {
+			if id == labelID {
+				ruleIDs = append(ruleIDs, ruleID)
+				break
+			}
+		}
+	}
+	return ruleIDs
+}
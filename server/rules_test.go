@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestRules() *Rules {
+	rules := NewRulesWithUser(nil, UserID)
+	rules.ByID = map[string]*Rule{
+		"channel:ChannelID": {ID: "channel:ChannelID", Type: ruleTypeChannel, Value: "ChannelID", LabelIDs: []string{"UUID1"}},
+		"hashtag:release":   {ID: "hashtag:release", Type: ruleTypeHashtag, Value: "release", LabelIDs: []string{"UUID2", "UUID9"}},
+	}
+	return rules
+}
+
+func TestRuleMatches(t *testing.T) {
+	tests := map[string]struct {
+		rule     *Rule
+		post     *model.Post
+		expected bool
+	}{
+		"channel matches": {
+			rule:     &Rule{Type: ruleTypeChannel, Value: "ChannelID"},
+			post:     &model.Post{ChannelId: "ChannelID"},
+			expected: true,
+		},
+		"channel does not match": {
+			rule: &Rule{Type: ruleTypeChannel, Value: "ChannelID"},
+			post: &model.Post{ChannelId: "OtherChannelID"},
+		},
+		"author matches": {
+			rule:     &Rule{Type: ruleTypeAuthor, Value: UserID},
+			post:     &model.Post{UserId: UserID},
+			expected: true,
+		},
+		"hashtag matches without case": {
+			rule:     &Rule{Type: ruleTypeHashtag, Value: "release"},
+			post:     &model.Post{Message: "shipping the #Release today"},
+			expected: true,
+		},
+		"hashtag does not match a word": {
+			rule: &Rule{Type: ruleTypeHashtag, Value: "release"},
+			post: &model.Post{Message: "shipping the release today"},
+		},
+		"keyword matches a whole word": {
+			rule:     &Rule{Type: ruleTypeKeyword, Value: "deploy"},
+			post:     &model.Post{Message: "Deploy is done."},
+			expected: true,
+		},
+		"keyword does not match part of a word": {
+			rule: &Rule{Type: ruleTypeKeyword, Value: "deploy"},
+			post: &model.Post{Message: "the deployment is done"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.rule.matches(tt.post))
+		})
+	}
+}
+
+func TestApplyLabelRules(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+
+	jsonRules, err := json.Marshal(getTestRules())
+	require.Nil(t, err)
+	jsonLabels, err := json.Marshal(getExecuteCommandTestLabels())
+	require.Nil(t, err)
+	api.On("KVGet", getRulesKey(UserID)).Return(jsonRules, nil)
+	api.On("KVGet", getLabelsKey(UserID)).Return(jsonLabels, nil)
+
+	bmark := &Bookmark{PostID: p1ID, LabelIDs: []string{"UUID1"}}
+	post := &model.Post{ChannelId: "ChannelID", Message: "#release notes"}
+	names, err := p.applyLabelRules(UserID, bmark, post)
+	require.Nil(t, err)
+
+	// UUID1 is already on the bookmark and UUID9 no longer exists
+	assert.Equal(t, []string{"label2"}, names)
+	assert.Equal(t, []string{"UUID1", "UUID2"}, bmark.getLabelIDs())
+}
+
+func TestMergeRuleLabels(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+
+	jsonRules, err := json.Marshal(getTestRules())
+	require.Nil(t, err)
+	api.On("KVGet", getRulesKey(UserID)).Return(jsonRules, nil)
+
+	stored := NewRulesWithUser(api, UserID)
+	api.On("KVSet", getRulesKey(UserID), mock.Anything).Run(func(args mock.Arguments) {
+		require.Nil(t, json.Unmarshal(args.Get(1).([]byte), stored))
+	}).Return(nil).Once()
+
+	err = p.mergeRuleLabels(UserID, map[string]string{"UUID2": "UUID9"})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"UUID1"}, stored.ByID["channel:ChannelID"].LabelIDs)
+	assert.Equal(t, []string{"UUID9"}, stored.ByID["hashtag:release"].LabelIDs)
+}
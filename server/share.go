@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// SharedBookmark is a bookmark sent to another user.  Labels are shared by
+// name because label IDs belong to the sender
+type SharedBookmark struct {
+	PostID string   `json:"postid"`
+	Kind   string   `json:"kind,omitempty"`
+	Target string   `json:"target,omitempty"`
+	Title  string   `json:"title,omitempty"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// getShareAttachments returns an attachment with an "Add to my bookmarks"
+// button that copies the shared bookmarks
+func getShareAttachments(shared []*SharedBookmark) ([]*model.SlackAttachment, error) {
+	bb, err := json.Marshal(shared)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*model.SlackAttachment{{
+		Actions: []*model.PostAction{{
+			Name: "Add to my bookmarks",
+			Integration: &model.PostActionIntegration{
+				URL: getPluginActionURL("/api/v1/share/add"),
+				Context: map[string]interface{}{
+					"bookmarks": string(bb),
+				},
+			},
+		}},
+	}}, nil
+}
+
+// addSharedBookmarks copies shared bookmarks into the users bookmarks.
+// Missing labels are created and labeling rules are applied.  Bookmarks the
+// user already has or of posts the user cannot access are skipped
+func (p *Plugin) addSharedBookmarks(userID string, shared []*SharedBookmark) (string, error) {
+	bmarks, err := NewBookmarksWithUser(p.API, userID).getBookmarksIndex()
+	if err != nil {
+		return "", err
+	}
+	if bmarks == nil {
+		bmarks = NewBookmarksWithUser(p.API, userID)
+	}
+
+	labels, err := NewLabelsWithUser(p.API, userID).getLabels()
+	if err != nil {
+		return "", err
+	}
+
+	// the limits are checked for all shared bookmarks the user does not have
+	var ids, names []string
+	for _, s := range shared {
+		ids = append(ids, s.PostID)
+		names = append(names, s.Labels...)
+	}
+	if err = p.checkBookmarksLimit(bmarks, ids); err != nil {
+		return "", err
+	}
+	if err = p.checkLabelsLimit(labels, names); err != nil {
+		return "", err
+	}
+	maxTitleLength := p.getConfiguration().getMaxTitleLength()
+
+	var added []*Bookmark
+	var text string
+	existing, inaccessible := 0, 0
+	for _, s := range shared {
+		if _, ok := bmarks.exists(s.PostID); ok {
+			existing++
+			continue
+		}
+		bmark := &Bookmark{PostID: s.PostID, Kind: s.Kind, Target: s.Target, Title: truncateTitle(s.Title, maxTitleLength)}
+		if !p.canAccessBookmark(userID, bmark) {
+			inaccessible++
+			continue
+		}
+		var ids []string
+		for _, name := range s.Labels {
+			// create new label in labels store and add ID to bookmark
+			if labels.getLabelByName(name) == nil {
+				if _, err = labels.addLabel(name); err != nil {
+					return "", err
+				}
+			}
+			var id string
+			id, err = labels.getIDFromName(name)
+			if err != nil {
+				return "", err
+			}
+			ids = append(ids, id)
+		}
+		bmark.addLabelIDs(ids)
+		if bmark.getKind() == bookmarkKindThread {
+			if err = p.markThreadSeen(bmark); err != nil {
+				return "", err
+			}
+		}
+		if bmark.isPost() {
+			if post, appErr := p.API.GetPost(bmark.getTarget()); appErr == nil {
+				p.applyLabelRulesOrWarn(userID, bmark, post)
+				bmark.Snapshot = newPostSnapshot(p.API, post)
+			}
+		}
+		added = append(added, bmark)
+
+		labelNames, _ := labels.getNamesFromIDs(bmark.getLabelIDs())
+		nextText, err := p.getBmarkTextOneLine(bmark, labelNames)
+		if err != nil {
+			return "", err
+		}
+		text += nextText
+	}
+
+	if len(added) != 0 {
+		if err = bmarks.addBookmarks(added); err != nil {
+			return "", err
+		}
+	}
+
+	text = fmt.Sprintf("Added %v bookmarks:\n", len(added)) + text
+	if existing != 0 {
+		text += fmt.Sprintf("Skipped %v bookmarks you already have\n", existing)
+	}
+	if inaccessible != 0 {
+		text += fmt.Sprintf("Skipped %v bookmarks of posts you cannot access\n", inaccessible)
+	}
+	return text, nil
+}
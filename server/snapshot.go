@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+// BookmarkSnapshot is a copy of a bookmarked post taken when the bookmark is
+// added, so the bookmark keeps its meaning after the post is edited or
+// deleted
+type BookmarkSnapshot struct {
+	Message     string   `json:"message"`
+	UserID      string   `json:"user_id,omitempty"`
+	Username    string   `json:"username,omitempty"`
+	ChannelID   string   `json:"channel_id,omitempty"`
+	ChannelName string   `json:"channel_name,omitempty"`
+	CreateAt    int64    `json:"create_at"`            // The creation time of the post
+	FileNames   []string `json:"file_names,omitempty"` // Names of the files attached to the post
+	TakenAt     int64    `json:"taken_at"`             // The time the snapshot was taken
+}
+
+// newPostSnapshot returns a snapshot of a post.  The author, channel and
+// file names are left out when they cannot be found
+func newPostSnapshot(api plugin.API, post *model.Post) *BookmarkSnapshot {
+	snapshot := &BookmarkSnapshot{
+		Message:   post.Message,
+		UserID:    post.UserId,
+		ChannelID: post.ChannelId,
+		CreateAt:  post.CreateAt,
+		TakenAt:   model.GetMillis(),
+	}
+
+	if post.UserId != "" {
+		if user, appErr := api.GetUser(post.UserId); appErr == nil {
+			snapshot.Username = user.Username
+		}
+	}
+	if post.ChannelId != "" {
+		if channel, appErr := api.GetChannel(post.ChannelId); appErr == nil {
+			snapshot.ChannelName = channel.DisplayName
+			if snapshot.ChannelName == "" {
+				snapshot.ChannelName = channel.Name
+			}
+		}
+	}
+	for _, id := range post.FileIds {
+		if info, appErr := api.GetFileInfo(id); appErr == nil {
+			snapshot.FileNames = append(snapshot.FileNames, info.Name)
+		}
+	}
+
+	return snapshot
+}
+
+// isEdited returns whether the post differs from the snapshot
+func (s *BookmarkSnapshot) isEdited(post *model.Post) bool {
+	return post.Message != s.Message || len(post.FileIds) != len(s.FileNames)
+}
+
+// getSnapshotIndicator returns a marker shown after bookmarks of posts that
+// were edited or deleted since they were bookmarked
+func (p *Plugin) getSnapshotIndicator(bmark *Bookmark) string {
+	if bmark.Snapshot == nil || !bmark.isPost() {
+		return ""
+	}
+	post, appErr := p.API.GetPost(bmark.getTarget())
+	if appErr != nil {
+		return " :ghost: _(post deleted, showing the saved copy)_"
+	}
+	if bmark.Snapshot.isEdited(post) {
+		return " :pencil2: _(edited since bookmarked)_"
+	}
+	return ""
+}
+
+// getSnapshotText returns the saved copy of a bookmarked post, with the post
+// time in the timezone loc
+func getSnapshotText(snapshot *BookmarkSnapshot, loc *time.Location) string {
+	text := snapshot.Message + "\n"
+
+	var details string
+	if snapshot.Username != "" {
+		details += fmt.Sprintf(" by @%s", snapshot.Username)
+	}
+	if snapshot.ChannelName != "" {
+		details += fmt.Sprintf(" in %s", snapshot.ChannelName)
+	}
+	if snapshot.CreateAt != 0 {
+		details += fmt.Sprintf(" on %s", formatTime(snapshot.CreateAt, loc))
+	}
+	if details != "" {
+		text += "_Posted" + details + "_\n"
+	}
+
+	for _, name := range snapshot.FileNames {
+		text += fmt.Sprintf("* :paperclip: %s\n", name)
+	}
+	return text
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// getTestSnapshot returns a snapshot of a post taken before it was edited
+func getTestSnapshot() *BookmarkSnapshot {
+	return &BookmarkSnapshot{
+		Message:     "the original message",
+		Username:    "alice",
+		ChannelName: "Town Square",
+		CreateAt:    1577880000000,
+		FileNames:   []string{"notes.txt"},
+	}
+}
+
+func TestNewPostSnapshot(t *testing.T) {
+	api := makeAPIMock()
+	api.On("GetUser", "AuthorID").Return(&model.User{Id: "AuthorID", Username: "alice"}, nil)
+	api.On("GetChannel", "ChannelID").Return(&model.Channel{Id: "ChannelID", Name: "town-square"}, nil)
+	api.On("GetFileInfo", "FileID1").Return(&model.FileInfo{Id: "FileID1", Name: "notes.txt"}, nil)
+	api.On("GetFileInfo", "FileID2").Return(nil, &model.AppError{Message: "deleted"})
+
+	post := &model.Post{
+		Id:        p1ID,
+		UserId:    "AuthorID",
+		ChannelId: "ChannelID",
+		Message:   "the original message",
+		CreateAt:  1577880000000,
+		FileIds:   []string{"FileID1", "FileID2"},
+	}
+	snapshot := newPostSnapshot(api, post)
+	assert.Equal(t, "the original message", snapshot.Message)
+	assert.Equal(t, "alice", snapshot.Username)
+	assert.Equal(t, "town-square", snapshot.ChannelName)
+	assert.Equal(t, int64(1577880000000), snapshot.CreateAt)
+	assert.Equal(t, []string{"notes.txt"}, snapshot.FileNames)
+	assert.NotZero(t, snapshot.TakenAt)
+
+	assert.Equal(t, "the original message\n_Posted by @alice in Town Square on Jan 1, 2020 12:00 UTC_\n* :paperclip: notes.txt\n", getSnapshotText(getTestSnapshot(), time.UTC))
+
+	// the post time is shown in the users timezone
+	loc, err := time.LoadLocation("America/New_York")
+	require.Nil(t, err)
+	assert.Contains(t, getSnapshotText(getTestSnapshot(), loc), "on Jan 1, 2020 07:00 EST")
+}
+
+func TestGetSnapshotIndicator(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+	api.On("GetPost", p1ID).Return(&model.Post{Id: p1ID, Message: "the original message", FileIds: []string{"FileID1"}}, nil)
+	api.On("GetPost", p2ID).Return(&model.Post{Id: p2ID, Message: "the edited message", FileIds: []string{"FileID1"}}, nil)
+	api.On("GetPost", p3ID).Return(nil, &model.AppError{Message: "deleted"})
+
+	tests := map[string]struct {
+		bmark    *Bookmark
+		expected string
+	}{
+		"unchanged post":        {bmark: &Bookmark{PostID: p1ID, Snapshot: getTestSnapshot()}, expected: ""},
+		"edited post":           {bmark: &Bookmark{PostID: p2ID, Snapshot: getTestSnapshot()}, expected: " :pencil2: _(edited since bookmarked)_"},
+		"deleted post":          {bmark: &Bookmark{PostID: p3ID, Snapshot: getTestSnapshot()}, expected: " :ghost: _(post deleted, showing the saved copy)_"},
+		"bookmark without copy": {bmark: &Bookmark{PostID: p2ID}, expected: ""},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, p.getSnapshotIndicator(tt.bmark))
+		})
+	}
+}
+
+func TestGetBmarkTextSnapshotFallback(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+	siteURL := "https://myhost.com"
+	api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+	api.On("GetPost", p2ID).Return(&model.Post{Id: p2ID, Message: "the edited message", FileIds: []string{"FileID1"}}, nil)
+	api.On("GetPost", p3ID).Return(nil, &model.AppError{Message: "deleted"})
+
+	deleted := &Bookmark{PostID: p3ID, Snapshot: getTestSnapshot()}
+	text, err := p.getBmarkTextOneLine(deleted, nil)
+	require.Nil(t, err)
+	assert.Contains(t, text, "the original message :ghost:")
+
+	text, err = p.getBmarkTextDetailed(deleted, nil, nil, false)
+	require.Nil(t, err)
+	assert.Contains(t, text, "##### Post Message :ghost: \n")
+	assert.Contains(t, text, "_Posted by @alice in Town Square on Jan 1, 2020 12:00 UTC_\n")
+
+	edited := &Bookmark{PostID: p2ID, Snapshot: getTestSnapshot()}
+	text, err = p.getBmarkTextDetailed(edited, nil, nil, false)
+	require.Nil(t, err)
+	assert.Contains(t, text, "##### Post Message :pencil2: \nthe edited message\n##### Saved Message \n")
+	assert.Contains(t, text, "the original message\n")
+
+	// the history is only shown for the user running a command
+	text, err = p.getBmarkTextDetailed(edited, nil, nil, true)
+	require.Nil(t, err)
+	assert.NotContains(t, text, "History")
+
+	// bookmarks of deleted posts without a copy cannot be shown
+	_, err = p.getBmarkTextOneLine(&Bookmark{PostID: p3ID}, nil)
+	assert.NotNil(t, err)
+
+	// deleted posts are sorted by the time saved with the bookmark
+	bmarks := NewBookmarksWithUser(api, UserID)
+	bmarks.ByID[p2ID] = &Bookmark{PostID: p2ID}
+	bmarks.ByID[p3ID] = deleted
+	sorted, err := bmarks.sortBookmarks(bookmarkSortTime)
+	require.Nil(t, err)
+	assert.Equal(t, []string{p2ID, p3ID}, getSortedIDs(sorted))
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	statusUnread     = "unread"
+	statusInProgress = "in-progress"
+	statusDone       = "done"
+	statusArchived   = "archived"
+
+	// statusAll selects bookmarks of every status
+	statusAll = "all"
+
+	// maxDoneButtons is the maximum number of "Mark done" buttons added to a
+	// bookmarks list
+	maxDoneButtons = 10
+
+	// maxButtonTitleLength is the maximum number of characters of a bookmark
+	// title shown on a button
+	maxButtonTitleLength = 30
+)
+
+// bookmarkStatuses are the statuses of a bookmark in reading order
+var bookmarkStatuses = []string{statusUnread, statusInProgress, statusDone, statusArchived}
+
+// activeStatuses are the statuses of bookmarks shown by default
+var activeStatuses = []string{statusUnread, statusInProgress}
+
+// getStatus returns the reading status of the bookmark.  Bookmarks stored
+// before statuses existed are unread
+func (bm *Bookmark) getStatus() string {
+	if bm.Status == "" {
+		return statusUnread
+	}
+	return bm.Status
+}
+
+// setStatus changes the reading status of the bookmark and records the change
+func (bm *Bookmark) setStatus(status string) {
+	if bm.getStatus() == status {
+		return
+	}
+	bm.Status = status
+	bm.recordEvent(&BookmarkEvent{Type: eventStatusChanged, Status: status})
+}
+
+// validateStatus checks that status is a bookmark status
+func validateStatus(status string) error {
+	if !containsID(bookmarkStatuses, status) {
+		return errors.New(fmt.Sprintf("Status `%s` is not valid. Use one of: %s", status, strings.Join(bookmarkStatuses, ", ")))
+	}
+	return nil
+}
+
+// parseStatuses returns the statuses in a list of status names.  `all`
+// selects every status
+func parseStatuses(names []string) ([]string, error) {
+	var statuses []string
+	for _, name := range names {
+		if name == statusAll {
+			return bookmarkStatuses, nil
+		}
+		if err := validateStatus(name); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, name)
+	}
+	return statuses, nil
+}
+
+// getStatusIcon returns the icon shown for bookmarks that are not unread
+func getStatusIcon(status string) string {
+	switch status {
+	case statusInProgress:
+		return ":hourglass_flowing_sand:"
+	case statusDone:
+		return ":white_check_mark:"
+	case statusArchived:
+		return ":file_cabinet:"
+	}
+	return ""
+}
+
+// getDoneAttachments returns an attachment with "Mark done" buttons for the
+// bookmarks that are not done or archived
+func (p *Plugin) getDoneAttachments(bmarks []*Bookmark) []*model.SlackAttachment {
+	var actions []*model.PostAction
+	for _, bmark := range bmarks {
+		if !containsID(activeStatuses, bmark.getStatus()) {
+			continue
+		}
+		if len(actions) == maxDoneButtons {
+			break
+		}
+
+		title := bmark.getTitle()
+		if title == "" {
+			title, _ = p.getBmarkGeneratedTitle(bmark)
+		}
+		actions = append(actions, &model.PostAction{
+			Name: "Mark done: " + truncateText(title, maxButtonTitleLength),
+			Integration: &model.PostActionIntegration{
+				URL: getPluginActionURL("/api/v1/status/done"),
+				Context: map[string]interface{}{
+					"bookmark_id": bmark.PostID,
+				},
+			},
+		})
+	}
+
+	if len(actions) == 0 {
+		return nil
+	}
+	return []*model.SlackAttachment{{Actions: actions}}
+}
+
+// truncateText shortens text to at most max characters
+func truncateText(text string, max int) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) <= max {
+		return string(runes)
+	}
+	return string(runes[:max-3]) + "..."
+}
+
+// setBookmarkStatus sets the status of a users bookmark and returns the
+// bookmark
+func (p *Plugin) setBookmarkStatus(userID, bmarkID, status string) (*Bookmark, error) {
+	return p.updateBookmark(userID, bmarkID, func(bmark *Bookmark) {
+		bmark.setStatus(status)
+	})
+}
+
+// getStatusCountsText returns the number of bookmarks of each status
+func getStatusCountsText(bmarks *Bookmarks) string {
+	counts := make(map[string]int)
+	for _, bmark := range bmarks.ByID {
+		counts[bmark.getStatus()]++
+	}
+
+	text := "#### Bookmark Status\n"
+	for _, status := range bookmarkStatuses {
+		text += "* "
+		if icon := getStatusIcon(status); icon != "" {
+			text += icon + " "
+		}
+		text += fmt.Sprintf("`%s`: %v\n", status, counts[status])
+	}
+	return text
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetStatus(t *testing.T) {
+	bmark := &Bookmark{PostID: p1ID}
+	assert.Equal(t, statusUnread, bmark.getStatus())
+
+	bmark.setStatus(statusUnread)
+	assert.Empty(t, bmark.History)
+
+	bmark.setStatus(statusDone)
+	assert.Equal(t, statusDone, bmark.getStatus())
+	require.Len(t, bmark.History, 1)
+	assert.Equal(t, eventStatusChanged, bmark.History[0].Type)
+	assert.Equal(t, "Marked as `done`", getEventText(bmark.History[0], nil))
+}
+
+func TestParseStatuses(t *testing.T) {
+	statuses, err := parseStatuses([]string{statusDone, statusArchived})
+	require.Nil(t, err)
+	assert.Equal(t, []string{statusDone, statusArchived}, statuses)
+
+	statuses, err = parseStatuses([]string{statusDone, statusAll})
+	require.Nil(t, err)
+	assert.Equal(t, bookmarkStatuses, statuses)
+
+	_, err = parseStatuses([]string{"later"})
+	assert.NotNil(t, err)
+}
+
+func TestApplyFiltersStatuses(t *testing.T) {
+	bmarks := getExecuteCommandTestBookmarks()
+	bmarks.get(p1ID).Status = statusDone
+	bmarks.get(p2ID).Status = statusInProgress
+
+	filtered, err := bmarks.applyFilters(&BookmarksFilters{Statuses: activeStatuses})
+	require.Nil(t, err)
+	assert.Len(t, filtered.ByID, 3)
+	assert.Nil(t, filtered.get(p1ID))
+
+	filtered, err = bmarks.applyFilters(&BookmarksFilters{Statuses: []string{statusDone}})
+	require.Nil(t, err)
+	assert.Len(t, filtered.ByID, 1)
+	assert.NotNil(t, filtered.get(p1ID))
+}
+
+func TestAddBookmarkKeepsStatus(t *testing.T) {
+	api := makeAPIMock()
+	mockBookmarksStore(api, UserID)
+	api.On("KVGet", getTrashKey(UserID)).Return(nil, nil)
+	api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+
+	bmarks := NewBookmarksWithUser(api, UserID)
+	bmarks.ByID[p1ID] = &Bookmark{PostID: p1ID, Status: statusInProgress}
+
+	require.Nil(t, bmarks.addBookmark(&Bookmark{PostID: p1ID, Title: "new title"}))
+	assert.Equal(t, statusInProgress, bmarks.get(p1ID).getStatus())
+
+	require.Nil(t, bmarks.addBookmark(&Bookmark{PostID: p1ID, Status: statusDone}))
+	assert.Equal(t, statusDone, bmarks.get(p1ID).getStatus())
+	last := bmarks.get(p1ID).History[len(bmarks.get(p1ID).History)-1]
+	assert.Equal(t, eventStatusChanged, last.Type)
+}
+
+func TestGetDoneAttachments(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+	api.On("GetPost", p4ID).Return(&model.Post{Message: "this is the post.Message"}, nil)
+
+	bmarks := []*Bookmark{
+		{PostID: p1ID, Title: "a very long title that does not fit on a button"},
+		{PostID: p2ID, Title: "done", Status: statusDone},
+		{PostID: p4ID},
+	}
+	attachments := p.getDoneAttachments(bmarks)
+	require.Len(t, attachments, 1)
+	actions := attachments[0].Actions
+	require.Len(t, actions, 2)
+	assert.Equal(t, "Mark done: a very long title that does...", actions[0].Name)
+	assert.Equal(t, p1ID, actions[0].Integration.Context["bookmark_id"])
+	assert.Equal(t, "Mark done: this is the post.Message", actions[1].Name)
+
+	assert.Nil(t, p.getDoneAttachments([]*Bookmark{{PostID: p2ID, Status: statusArchived}}))
+}
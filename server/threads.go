@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const (
+	// StoreThreadWatchersKey is the key used to store the users notified of
+	// new replies in a thread in the plugin KV store
+	StoreThreadWatchersKey = "thread_watchers"
+)
+
+// getThreadWatchers returns the users notified of new replies in a thread
+func (w *ThreadWatchers) getThreadWatchers() (*ThreadWatchers, error) {
+	bb, appErr := w.api.KVGet(getThreadWatchersKey(w.rootID))
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	watchers := NewThreadWatchersWithRoot(w.api, w.rootID)
+	if len(bb) != 0 {
+		if jsonErr := json.Unmarshal(bb, watchers); jsonErr != nil {
+			return nil, jsonErr
+		}
+	}
+	return watchers, nil
+}
+
+func getThreadWatchersKey(rootID string) string {
+	return fmt.Sprintf("%s_%s", StoreThreadWatchersKey, rootID)
+}
+
+// getThreadReplyCount returns the number of replies in a thread
+func (p *Plugin) getThreadReplyCount(rootID string) (int64, error) {
+	thread, appErr := p.API.GetPostThread(rootID)
+	if appErr != nil {
+		return 0, appErr
+	}
+
+	var count int64
+	for id := range thread.Posts {
+		if id != rootID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// markThreadSeen records the current number of replies of a thread bookmark
+// so only later replies are new
+func (p *Plugin) markThreadSeen(bmark *Bookmark) error {
+	count, err := p.getThreadReplyCount(bmark.getTarget())
+	if err != nil {
+		return err
+	}
+	bmark.ThreadReplyCount = count
+	bmark.ThreadSeenAt = model.GetMillis()
+	return nil
+}
+
+// getNewThreadReplies returns the number of replies to a thread bookmark
+// since the user last saw the thread
+func (p *Plugin) getNewThreadReplies(bmark *Bookmark) int64 {
+	if bmark.getKind() != bookmarkKindThread {
+		return 0
+	}
+	count, err := p.getThreadReplyCount(bmark.getTarget())
+	if err != nil || count < bmark.ThreadReplyCount {
+		return 0
+	}
+	return count - bmark.ThreadReplyCount
+}
+
+// getNewRepliesText returns the new replies indicator shown next to thread
+// bookmarks
+func getNewRepliesText(count int64) string {
+	if count == 1 {
+		return "_(1 new reply)_"
+	}
+	return fmt.Sprintf("_(%v new replies)_", count)
+}
+
+// watchThread adds or removes the user from the users notified of new
+// replies to a thread bookmark
+func (p *Plugin) watchThread(userID string, bmark *Bookmark) error {
+	watchers, err := NewThreadWatchersWithRoot(p.API, bmark.getTarget()).getThreadWatchers()
+	if err != nil {
+		return err
+	}
+	if bmark.ThreadNotify {
+		return watchers.add(userID)
+	}
+	if containsID(watchers.UserIDs, userID) {
+		return watchers.delete(userID)
+	}
+	return nil
+}
+
+// MessageHasBeenPosted notifies the users who bookmarked a thread with
+// notifications of the first new reply since they last saw the thread
+func (p *Plugin) MessageHasBeenPosted(c *plugin.Context, post *model.Post) {
+	if post.RootId == "" || post.UserId == p.getBotID() {
+		return
+	}
+
+	watchers, err := NewThreadWatchersWithRoot(p.API, post.RootId).getThreadWatchers()
+	if err != nil {
+		p.API.LogWarn("Unable to get thread watchers", "root_id", post.RootId, "error", err.Error())
+		return
+	}
+
+	for _, userID := range watchers.UserIDs {
+		if userID == post.UserId {
+			continue
+		}
+		if err := p.notifyThreadReply(watchers, userID, post); err != nil {
+			p.API.LogWarn("Unable to notify of thread reply", "root_id", post.RootId, "user_id", userID, "error", err.Error())
+		}
+	}
+}
+
+// notifyThreadReply sends a user a message about a new reply to a thread
+// bookmark.  Users who removed the bookmark or turned off notifications stop
+// watching the thread
+func (p *Plugin) notifyThreadReply(watchers *ThreadWatchers, userID string, post *model.Post) error {
+	bmarks, err := NewBookmarksWithUser(p.API, userID).getBookmarksIndex()
+	if err != nil {
+		return err
+	}
+
+	var bmark *Bookmark
+	if bmarks != nil {
+		bmark, _ = bmarks.exists(getBookmarkID(bookmarkKindThread, post.RootId))
+	}
+	if bmark == nil || !bmark.ThreadNotify || !p.canReadPost(userID, post) {
+		return watchers.delete(userID)
+	}
+
+	// only notify once until the user sees the thread again
+	if bmark.ThreadNotifiedAt > bmark.ThreadSeenAt {
+		return nil
+	}
+	bmark.ThreadNotifiedAt = model.GetMillis()
+	if err = bmarks.add(bmark); err != nil {
+		return err
+	}
+
+	title, err := p.getBmarkGeneratedTitle(bmark)
+	if err != nil {
+		return err
+	}
+	if bmark.hasUserTitle() {
+		title = bmark.getTitle()
+	}
+	message := fmt.Sprintf("New replies in your bookmarked thread %s %s\n", p.getBmarkIconLink(bmark), title)
+	message += fmt.Sprintf("View the thread with `/bookmarks view %s` to get notified of later replies", bmark.PostID)
+	return p.PostBotDM(userID, message)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// getTestThread returns a thread of a root post with a number of replies
+func getTestThread(rootID string, replies int) *model.PostList {
+	thread := model.NewPostList()
+	thread.AddPost(&model.Post{Id: rootID, Message: "this is the root message"})
+	thread.AddOrder(rootID)
+	for i := 0; i < replies; i++ {
+		id := fmt.Sprintf("Reply%v", i)
+		thread.AddPost(&model.Post{Id: id, RootId: rootID})
+		thread.AddOrder(id)
+	}
+	return thread
+}
+
+func TestGetNewThreadReplies(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+	api.On("GetPostThread", p1ID).Return(getTestThread(p1ID, 3), nil)
+	api.On("GetPostThread", p2ID).Return(nil, &model.AppError{Message: "deleted"})
+
+	bmark := newBookmark(bookmarkKindThread, p1ID)
+	assert.Equal(t, int64(3), p.getNewThreadReplies(bmark))
+
+	require.Nil(t, p.markThreadSeen(bmark))
+	assert.Equal(t, int64(3), bmark.ThreadReplyCount)
+	assert.NotZero(t, bmark.ThreadSeenAt)
+	assert.Equal(t, int64(0), p.getNewThreadReplies(bmark))
+
+	// replies are only counted for threads
+	assert.Equal(t, int64(0), p.getNewThreadReplies(newBookmark(bookmarkKindPost, p1ID)))
+	assert.Equal(t, int64(0), p.getNewThreadReplies(newBookmark(bookmarkKindThread, p2ID)))
+
+	assert.Equal(t, "_(1 new reply)_", getNewRepliesText(1))
+	assert.Equal(t, "_(3 new replies)_", getNewRepliesText(3))
+}
+
+func TestExecuteCommandViewMarksThreadSeen(t *testing.T) {
+	api := makeAPIMock()
+	siteURL := "https://myhost.com"
+	api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+	api.On("GetPost", p1ID).Return(&model.Post{Id: p1ID, Message: "this is the root message"}, nil)
+	api.On("GetPostThread", p1ID).Return(getTestThread(p1ID, 2), nil)
+
+	bmarks := NewBookmarksWithUser(api, UserID)
+	bmark := newBookmark(bookmarkKindThread, p1ID)
+	bmarks.ByID[bmark.PostID] = bmark
+	jsonBmarks, err := json.Marshal(bmarks)
+	require.Nil(t, err)
+	api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+	api.On("KVGet", getLabelsKey(UserID)).Return(nil, nil)
+
+	store := mockBookmarksStore(api, UserID)
+	api.On("SendEphemeralPost", UserID, mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+		actual := args.Get(1).(*model.Post).Message
+		assert.Contains(t, actual, "##### Thread \n2 replies _(2 new replies)_")
+	}).Once().Return(&model.Post{})
+
+	p := makePlugin(api)
+	args := &model.CommandArgs{Command: "/bookmarks view thread:" + p1ID, UserId: UserID}
+	_, appErr := p.ExecuteCommand(&plugin.Context{}, args)
+	require.Nil(t, appErr)
+
+	stored := store.getBookmarks()
+	require.NotNil(t, stored.get(bmark.PostID))
+	assert.Equal(t, int64(2), stored.get(bmark.PostID).ThreadReplyCount)
+}
+
+func TestExecuteCommandAddWatchesThreadAfterSave(t *testing.T) {
+	for name, storeErr := range map[string]*model.AppError{"saved": nil, "not saved": {Message: "store failed"}} {
+		t.Run(name, func(t *testing.T) {
+			api := makeAPIMock()
+			siteURL := "https://myhost.com"
+			api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+			api.On("GetPost", p1ID).Return(&model.Post{Id: p1ID, Message: "this is the root message"}, nil)
+			api.On("GetPostThread", p1ID).Return(getTestThread(p1ID, 1), nil)
+			api.On("KVGet", getBookmarksKey(UserID)).Return(nil, nil)
+			api.On("KVGet", getLabelsKey(UserID)).Return(nil, nil)
+			api.On("KVGet", getRulesKey(UserID)).Return(nil, nil)
+			api.On("KVGet", getTrashKey(UserID)).Return(nil, nil)
+			api.On("KVGet", getThreadWatchersKey(p1ID)).Return(nil, nil)
+			api.On("KVSet", getThreadWatchersKey(p1ID), mock.Anything).Return(nil)
+			api.On("KVSet", mock.Anything, mock.Anything).Return(storeErr)
+
+			expected := "Added bookmark: [:speech_balloon:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx)"
+			if storeErr != nil {
+				expected = "Unable to add bookmark"
+			}
+			api.On("SendEphemeralPost", UserID, mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				assert.True(t, strings.HasPrefix(args.Get(1).(*model.Post).Message, expected))
+			}).Once().Return(&model.Post{})
+
+			p := makePlugin(api)
+			args := &model.CommandArgs{Command: "/bookmarks add " + p1ID + " --thread --notify", UserId: UserID}
+			_, appErr := p.ExecuteCommand(&plugin.Context{}, args)
+			require.Nil(t, appErr)
+
+			// the user only watches threads that are bookmarked
+			if storeErr != nil {
+				api.AssertNotCalled(t, "KVSet", getThreadWatchersKey(p1ID), mock.Anything)
+			} else {
+				api.AssertCalled(t, "KVSet", getThreadWatchersKey(p1ID), mock.Anything)
+			}
+		})
+	}
+}
+
+func TestMessageHasBeenPosted(t *testing.T) {
+	const otherUserID = "OtherUserID"
+	tests := map[string]struct {
+		bmark          *Bookmark
+		expectNotified bool
+		expectRemoved  bool
+	}{
+		"notifies of the first new reply": {
+			bmark:          &Bookmark{PostID: "thread:" + p1ID, Kind: bookmarkKindThread, Target: p1ID, ThreadNotify: true, ThreadSeenAt: 10},
+			expectNotified: true,
+		},
+		"does not notify again until the thread is seen": {
+			bmark: &Bookmark{PostID: "thread:" + p1ID, Kind: bookmarkKindThread, Target: p1ID, ThreadNotify: true, ThreadSeenAt: 10, ThreadNotifiedAt: 20},
+		},
+		"stops watching when notifications are off": {
+			bmark:         &Bookmark{PostID: "thread:" + p1ID, Kind: bookmarkKindThread, Target: p1ID},
+			expectRemoved: true,
+		},
+		"stops watching when the bookmark was removed": {
+			expectRemoved: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := makeAPIMock()
+			siteURL := "https://myhost.com"
+			api.On("GetConfig", mock.Anything).Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+			api.On("GetPost", p1ID).Return(&model.Post{Id: p1ID, Message: "this is the root message"}, nil)
+			api.On("HasPermissionToChannel", UserID, "ChannelID", model.PERMISSION_READ_CHANNEL).Return(true)
+
+			watchers, err := json.Marshal(&ThreadWatchers{UserIDs: []string{UserID, otherUserID}})
+			require.Nil(t, err)
+			api.On("KVGet", getThreadWatchersKey(p1ID)).Return(watchers, nil)
+
+			bmarks := NewBookmarksWithUser(api, UserID)
+			if tt.bmark != nil {
+				bmarks.ByID[tt.bmark.PostID] = tt.bmark
+			}
+			jsonBmarks, err := json.Marshal(bmarks)
+			require.Nil(t, err)
+			api.On("KVGet", getBookmarksKey(UserID)).Return(jsonBmarks, nil)
+			mockBookmarksStore(api, UserID)
+
+			if tt.expectRemoved {
+				api.On("KVSet", getThreadWatchersKey(p1ID), mock.Anything).Run(func(args mock.Arguments) {
+					stored := &ThreadWatchers{}
+					require.Nil(t, json.Unmarshal(args.Get(1).([]byte), stored))
+					assert.Equal(t, []string{otherUserID}, stored.UserIDs)
+				}).Return(nil).Once()
+			}
+			if tt.expectNotified {
+				api.On("GetDirectChannel", UserID, mock.Anything).Return(&model.Channel{Id: "DMChannelID"}, nil)
+				api.On("CreatePost", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+					post := args.Get(0).(*model.Post)
+					assert.True(t, strings.HasPrefix(post.Message, "New replies in your bookmarked thread [:speech_balloon:](https://myhost.com/_redirect/pl/ID1xxxxxxxxxxxxxxxxxxxxxxx)"))
+				}).Return(&model.Post{}, nil).Once()
+			}
+
+			p := makePlugin(api)
+			// replies by the watcher do not notify the watcher
+			p.MessageHasBeenPosted(&plugin.Context{}, &model.Post{Id: "ReplyIDxxxxxxxxxxxxxxxxxxx", RootId: p1ID, ChannelId: "ChannelID", UserId: otherUserID})
+
+			if !tt.expectNotified {
+				api.AssertNotCalled(t, "CreatePost", mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	// emptyPostTitle is the title of posts without text, attachments or files
+	emptyPostTitle = "(empty post)"
+
+	titleEllipsis = "…"
+)
+
+var (
+	// markdown removed from generated titles.  Each expression keeps the text
+	// it wraps as the first submatch
+	markdownCodeFence  = regexp.MustCompile("(?m)^\\s*(```|~~~).*$")
+	markdownImage      = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	markdownLink       = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	markdownLinePrefix = regexp.MustCompile(`(?m)^\s*(#{1,6}\s+|>\s*|[-*+]\s+|\d+\.\s+|\|)`)
+	markdownRule       = regexp.MustCompile(`(?m)^\s*([-*_]\s*){3,}$`)
+	markdownTableRule  = regexp.MustCompile(`(?m)^[\s|:-]*-[\s|:-]*$`)
+	markdownEmphasis   = []*regexp.Regexp{
+		regexp.MustCompile(`\*\*([^*]+)\*\*`),
+		regexp.MustCompile(`__([^_]+)__`),
+		regexp.MustCompile(`~~([^~]+)~~`),
+		regexp.MustCompile(`\*([^*\s][^*]*)\*`),
+		regexp.MustCompile(`\b_([^_\s][^_]*)_\b`),
+		regexp.MustCompile("`([^`]*)`"),
+	}
+	markdownTableCell = regexp.MustCompile(`\s*\|\s*`)
+)
+
+// stripMarkdown returns the text of a markdown message on a single line
+func stripMarkdown(text string) string {
+	text = markdownCodeFence.ReplaceAllString(text, "")
+	text = markdownRule.ReplaceAllString(text, "")
+	text = markdownTableRule.ReplaceAllString(text, "")
+	text = markdownImage.ReplaceAllString(text, "$1")
+	text = markdownLink.ReplaceAllString(text, "$1")
+	text = markdownLinePrefix.ReplaceAllString(text, "")
+	for _, re := range markdownEmphasis {
+		text = re.ReplaceAllString(text, "$1")
+	}
+	text = markdownTableCell.ReplaceAllString(text, " ")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// truncateTitle shortens a title to at most max characters, cutting it after
+// the last whole word that fits.  Words longer than max are cut
+func truncateTitle(title string, max int) string {
+	runes := []rune(title)
+	if len(runes) <= max {
+		return title
+	}
+
+	limit := max - len([]rune(titleEllipsis))
+	if limit < 1 {
+		return string(runes[:max])
+	}
+	cut := limit
+	for i := limit; i > 0; i-- {
+		if runes[i] == ' ' {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimSpace(string(runes[:cut])) + titleEllipsis
+}
+
+// getAttachmentsTitle returns the text of the first message attachment with
+// a title, pretext, text or fallback
+func getAttachmentsTitle(attachments []*model.SlackAttachment) string {
+	for _, attachment := range attachments {
+		for _, text := range []string{attachment.Title, attachment.Pretext, attachment.Text, attachment.Fallback} {
+			if title := stripMarkdown(text); title != "" {
+				return title
+			}
+		}
+	}
+	return ""
+}
+
+// getFilesTitle returns a title naming the files uploaded with a post
+func getFilesTitle(fileNames []string) string {
+	switch len(fileNames) {
+	case 0:
+		return ""
+	case 1:
+		return fileNames[0]
+	case 2:
+		return fmt.Sprintf("%s and 1 more file", fileNames[0])
+	}
+	return fmt.Sprintf("%s and %v more files", fileNames[0], len(fileNames)-1)
+}
+
+// generateTitle returns a single line title of at most maxLength characters
+// for a post from its message, or its attachments or files when the message
+// is empty
+func generateTitle(message string, attachments []*model.SlackAttachment, fileNames []string, maxLength int) string {
+	title := stripMarkdown(message)
+	if title == "" {
+		title = getAttachmentsTitle(attachments)
+	}
+	if title == "" {
+		title = getFilesTitle(fileNames)
+	}
+	if title == "" {
+		return emptyPostTitle
+	}
+	return truncateTitle(title, maxLength)
+}
+
+// generatePostTitle returns the title of a post.  File names are only looked
+// up for posts without text
+func (p *Plugin) generatePostTitle(post *model.Post) string {
+	maxLength := p.getConfiguration().getGeneratedTitleLength()
+	attachments := post.Attachments()
+	if stripMarkdown(post.Message) != "" || getAttachmentsTitle(attachments) != "" {
+		return generateTitle(post.Message, attachments, nil, maxLength)
+	}
+
+	var fileNames []string
+	for _, id := range post.FileIds {
+		if info, appErr := p.API.GetFileInfo(id); appErr == nil {
+			fileNames = append(fileNames, info.Name)
+		}
+	}
+	return generateTitle(post.Message, attachments, fileNames, maxLength)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripMarkdown(t *testing.T) {
+	tests := map[string]struct {
+		text     string
+		expected string
+	}{
+		"plain text":        {text: "just some text", expected: "just some text"},
+		"whitespace":        {text: "  lots\tof \n\n white   space ", expected: "lots of white space"},
+		"headings":          {text: "## Release notes\nshipped today", expected: "Release notes shipped today"},
+		"hashtags are kept": {text: "#release notes", expected: "#release notes"},
+		"emphasis":          {text: "**bold** and _italic_ and ~~gone~~ and *more*", expected: "bold and italic and gone and more"},
+		"snake case":        {text: "call get_title_from_post", expected: "call get_title_from_post"},
+		"inline code":       {text: "run `make test` first", expected: "run make test first"},
+		"links and images":  {text: "see [the docs](https://example.com) ![diagram](https://example.com/d.png)", expected: "see the docs diagram"},
+		"lists and quotes":  {text: "> quoted\n- one\n* two\n1. three", expected: "quoted one two three"},
+		"code fences":       {text: "```go\nfmt.Println(1)\n```", expected: "fmt.Println(1)"},
+		"rules":             {text: "above\n---\nbelow", expected: "above below"},
+		"tables":            {text: "| a | b |\n|---|:-:|\n| 1 | 2 |", expected: "a b 1 2"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, stripMarkdown(tt.text))
+		})
+	}
+}
+
+func TestTruncateTitle(t *testing.T) {
+	tests := map[string]struct {
+		title    string
+		max      int
+		expected string
+	}{
+		"short title":     {title: "short title", max: 20, expected: "short title"},
+		"exact length":    {title: "exactly ten", max: 11, expected: "exactly ten"},
+		"word boundary":   {title: "the quick brown fox jumps", max: 15, expected: "the quick…"},
+		"space at cut":    {title: "the quick brown fox", max: 11, expected: "the quick…"},
+		"long word":       {title: "supercalifragilistic", max: 10, expected: "supercali…"},
+		"multibyte runes": {title: "ééééé ééééé", max: 8, expected: "ééééé…"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			actual := truncateTitle(tt.title, tt.max)
+			assert.Equal(t, tt.expected, actual)
+			assert.LessOrEqual(t, len([]rune(actual)), tt.max)
+		})
+	}
+}
+
+func TestGenerateTitle(t *testing.T) {
+	attachments := []*model.SlackAttachment{{Fallback: "fallback"}, {Title: "**Build** failed"}}
+
+	assert.Equal(t, "a message", generateTitle("a message", attachments, []string{"a.txt"}, 80))
+	assert.Equal(t, "fallback", generateTitle("", attachments, nil, 80))
+	assert.Equal(t, "Build failed", generateTitle("", attachments[1:], nil, 80))
+	assert.Equal(t, "a.txt", generateTitle("", nil, []string{"a.txt"}, 80))
+	assert.Equal(t, "a.txt and 1 more file", generateTitle("", nil, []string{"a.txt", "b.txt"}, 80))
+	assert.Equal(t, "a.txt and 2 more files", generateTitle("", nil, []string{"a.txt", "b.txt", "c.txt"}, 80))
+	assert.Equal(t, emptyPostTitle, generateTitle("```\n```", nil, nil, 80))
+	assert.Equal(t, "a long…", generateTitle("a long message", nil, nil, 8))
+}
+
+func TestGeneratePostTitle(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+	api.On("GetFileInfo", "FileID1").Return(&model.FileInfo{Id: "FileID1", Name: "report.pdf"}, nil)
+
+	post := &model.Post{Message: "# Weekly report\n\nAll **green** this week"}
+	assert.Equal(t, "Weekly report All green this week", p.generatePostTitle(post))
+
+	// file names are looked up for posts without text
+	post = &model.Post{FileIds: []string{"FileID1"}}
+	assert.Equal(t, "report.pdf", p.generatePostTitle(post))
+
+	post = &model.Post{}
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{{Pretext: "Deployed to production"}})
+	assert.Equal(t, "Deployed to production", p.generatePostTitle(post))
+
+	// the title length is configurable
+	p.setConfiguration(&configuration{GeneratedTitleLength: 10})
+	post = &model.Post{Message: "a message that is too long"}
+	title := p.generatePostTitle(post)
+	require.LessOrEqual(t, len([]rune(title)), 10)
+	assert.Equal(t, "a message…", title)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// StoreTrashKey is the key used to store removed bookmarks in the plugin
+	// KV store
+	StoreTrashKey = "trash"
+
+	// trashPurgeInterval is how often expired bookmarks are purged from the
+	// trash of all users
+	trashPurgeInterval = time.Hour
+
+	kvListPerPage = 100
+)
+
+// getTrash returns a users removed bookmarks
+func (t *Trash) getTrash() (*Trash, error) {
+	// if a user does not have a trash, bb will be nil
+	bb, appErr := t.api.KVGet(getTrashKey(t.userID))
+	if appErr != nil {
+		return nil, errors.Wrapf(appErr, "Unable to get trash for user %s", t.userID)
+	}
+
+	if bb == nil {
+		return t, nil
+	}
+
+	jsonErr := json.Unmarshal(bb, t)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+
+	return t, nil
+}
+
+// addBookmark moves a removed bookmark to the trash
+func (t *Trash) addBookmark(bmark *Bookmark) error {
+	bmark.DeleteAt = model.GetMillis()
+	return t.add(bmark)
+}
+
+// removeBookmarks takes bookmarks out of the trash and returns the bookmarks
+// that were in the trash
+func (t *Trash) removeBookmarks(bmarkIDs []string) ([]*Bookmark, error) {
+	var removed []*Bookmark
+	for _, id := range bmarkIDs {
+		bmark := t.get(id)
+		if bmark == nil {
+			continue
+		}
+		t.delete(id)
+		bmark.DeleteAt = 0
+		removed = append(removed, bmark)
+	}
+
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	if err := t.storeTrash(); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// purgeExpired permanently deletes bookmarks that have been in the trash
+// longer than retention and returns the number of deleted bookmarks
+func (t *Trash) purgeExpired(retention time.Duration) (int, error) {
+	cutoff := model.GetMillis() - int64(retention/time.Millisecond)
+
+	var count int
+	for id, bmark := range t.ByID {
+		if bmark.DeleteAt < cutoff {
+			t.delete(id)
+			count++
+		}
+	}
+
+	if count == 0 {
+		return 0, nil
+	}
+
+	if err := t.storeTrash(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// empty permanently deletes all bookmarks in the trash and returns the number
+// of deleted bookmarks
+func (t *Trash) empty() (int, error) {
+	count := len(t.ByID)
+	t.ByID = make(map[string]*Bookmark)
+	if err := t.storeTrash(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ByDeleteAt returns an array of the removed bookmarks, most recently removed
+// first
+func (t *Trash) ByDeleteAt() []*Bookmark {
+	bookmarks := make([]*Bookmark, 0, len(t.ByID))
+	for _, bmark := range t.ByID {
+		bookmarks = append(bookmarks, bmark)
+	}
+	sort.Slice(bookmarks, func(i, j int) bool {
+		return bookmarks[i].DeleteAt > bookmarks[j].DeleteAt
+	})
+	return bookmarks
+}
+
+// restoreBookmarks adds removed bookmarks back to the users bookmarks and
+// takes them out of the trash.  Labels deleted since the bookmarks were
+// removed are not restored on the bookmark
+func (p *Plugin) restoreBookmarks(userID string, restore []*Bookmark) (string, error) {
+	bmarks, err := NewBookmarksWithUser(p.API, userID).getBookmarksIndex()
+	if err != nil {
+		return "", err
+	}
+
+	labels, err := NewLabelsWithUser(p.API, userID).getLabels()
+	if err != nil {
+		return "", err
+	}
+
+	trash, err := NewTrashWithUser(p.API, userID).getTrash()
+	if err != nil {
+		return "", err
+	}
+
+	var ids []string
+	for _, bmark := range restore {
+		ids = append(ids, bmark.PostID)
+	}
+	if err = p.checkBookmarksLimit(bmarks, ids); err != nil {
+		return "", err
+	}
+
+	text := "Restored bookmark: "
+	if len(restore) > 1 {
+		text = "Restored bookmarks: \n"
+	}
+
+	var restoredIDs []string
+	for _, bmark := range restore {
+		// bookmark was added again since it was removed
+		if _, ok := bmarks.exists(bmark.PostID); ok {
+			continue
+		}
+
+		labelNames, labelIDs := labels.getNamesFromIDs(bmark.getLabelIDs())
+		bmark.recordEvent(&BookmarkEvent{Type: eventRestored})
+		bmark.updateLabelIDs(labelIDs)
+		bmark.DeleteAt = 0
+		bmarks.ByID[bmark.PostID] = bmark
+		restoredIDs = append(restoredIDs, bmark.PostID)
+
+		newText, err := p.getBmarkTextOneLine(bmark, labelNames)
+		if err != nil {
+			return "", err
+		}
+		text += newText
+	}
+
+	if err = bmarks.storeBookmarks(); err != nil {
+		return "", err
+	}
+
+	if _, err = trash.removeBookmarks(restoredIDs); err != nil {
+		return "", err
+	}
+
+	return text, nil
+}
+
+// purgeExpiredTrash permanently deletes expired bookmarks from the trash of
+// all users
+func (p *Plugin) purgeExpiredTrash() {
+	retention := p.getConfiguration().getTrashRetention()
+	userIDs, err := p.listUserIDsWithPrefix(StoreTrashKey + "_")
+	if err != nil {
+		p.API.LogError("Unable to list keys to purge trash", "error", err.Error())
+		return
+	}
+
+	for _, userID := range userIDs {
+		trash, err := NewTrashWithUser(p.API, userID).getTrash()
+		if err != nil {
+			p.API.LogError("Unable to get trash", "error", err.Error())
+			continue
+		}
+		if _, err = trash.purgeExpired(retention); err != nil {
+			p.API.LogError("Unable to purge trash", "error", err.Error())
+		}
+	}
+}
+
+func getTrashKey(userID string) string {
+	return fmt.Sprintf("%s_%s", StoreTrashKey, userID)
+}
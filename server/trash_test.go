@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestTrash() *Trash {
+	api := makeAPIMock()
+	api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+	trash := NewTrashWithUser(api, UserID)
+
+	now := model.GetMillis()
+	day := int64(24 * time.Hour / time.Millisecond)
+	_ = trash.add(&Bookmark{PostID: "ID5xxxxxxxxxxxxxxxxxxxxxxx", Title: "Removed today", DeleteAt: now})
+	_ = trash.add(&Bookmark{PostID: "ID6xxxxxxxxxxxxxxxxxxxxxxx", Title: "Removed 10 days ago", DeleteAt: now - 10*day})
+	_ = trash.add(&Bookmark{PostID: "ID7xxxxxxxxxxxxxxxxxxxxxxx", Title: "Removed 40 days ago", DeleteAt: now - 40*day})
+
+	return trash
+}
+
+func TestTrash_purgeExpired(t *testing.T) {
+	trash := getTestTrash()
+
+	count, err := trash.purgeExpired(30 * 24 * time.Hour)
+	require.Nil(t, err)
+	assert.Equal(t, 1, count)
+	assert.Nil(t, trash.get("ID7xxxxxxxxxxxxxxxxxxxxxxx"))
+	assert.Len(t, trash.ByID, 2)
+
+	count, err = trash.purgeExpired(5 * 24 * time.Hour)
+	require.Nil(t, err)
+	assert.Equal(t, 1, count)
+	assert.NotNil(t, trash.get("ID5xxxxxxxxxxxxxxxxxxxxxxx"))
+}
+
+func TestTrash_removeBookmarks(t *testing.T) {
+	trash := getTestTrash()
+
+	removed, err := trash.removeBookmarks([]string{"ID5xxxxxxxxxxxxxxxxxxxxxxx", "ID1xxxxxxxxxxxxxxxxxxxxxxx"})
+	require.Nil(t, err)
+	require.Len(t, removed, 1)
+	assert.Equal(t, "ID5xxxxxxxxxxxxxxxxxxxxxxx", removed[0].PostID)
+	assert.Equal(t, int64(0), removed[0].DeleteAt)
+	assert.Len(t, trash.ByID, 2)
+}
+
+func TestTrash_ByDeleteAt(t *testing.T) {
+	var ids []string
+	for _, bmark := range getTestTrash().ByDeleteAt() {
+		ids = append(ids, bmark.PostID)
+	}
+	assert.Equal(t, []string{"ID5xxxxxxxxxxxxxxxxxxxxxxx", "ID6xxxxxxxxxxxxxxxxxxxxxxx", "ID7xxxxxxxxxxxxxxxxxxxxxxx"}, ids)
+}
+
+func TestDeleteBookmarkMovesToTrash(t *testing.T) {
+	api := makeAPIMock()
+	mockBookmarksStore(api, UserID)
+	api.On("KVGet", getTrashKey(UserID)).Return(nil, nil)
+
+	var stored *Trash
+	api.On("KVSet", getTrashKey(UserID), mock.Anything).Run(func(args mock.Arguments) {
+		stored = NewTrashWithUser(api, UserID)
+		require.Nil(t, json.Unmarshal(args.Get(1).([]byte), stored))
+	}).Return(nil)
+
+	bmarks := NewBookmarksWithUser(api, UserID)
+	require.Nil(t, bmarks.add(&Bookmark{PostID: "ID1xxxxxxxxxxxxxxxxxxxxxxx", Title: "Title1"}))
+
+	_, err := bmarks.deleteBookmark("ID1xxxxxxxxxxxxxxxxxxxxxxx")
+	require.Nil(t, err)
+	assert.Empty(t, bmarks.ByID)
+	require.NotNil(t, stored)
+	require.NotNil(t, stored.get("ID1xxxxxxxxxxxxxxxxxxxxxxx"))
+	assert.NotZero(t, stored.get("ID1xxxxxxxxxxxxxxxxxxxxxxx").DeleteAt)
+}
+
+func TestDeleteBookmarkKeepsBookmarkWhenTrashFails(t *testing.T) {
+	api := makeAPIMock()
+	store := mockBookmarksStore(api, UserID)
+	api.On("KVGet", getTrashKey(UserID)).Return(nil, nil)
+	api.On("KVSet", getTrashKey(UserID), mock.Anything).Return(&model.AppError{Message: "failed"})
+
+	bmarks := NewBookmarksWithUser(api, UserID)
+	require.Nil(t, bmarks.add(&Bookmark{PostID: "ID1xxxxxxxxxxxxxxxxxxxxxxx", Title: "Title1"}))
+
+	_, err := bmarks.deleteBookmark("ID1xxxxxxxxxxxxxxxxxxxxxxx")
+	require.NotNil(t, err)
+	assert.Contains(t, store.getBookmarks().ByID, "ID1xxxxxxxxxxxxxxxxxxxxxxx")
+	api.AssertNotCalled(t, "KVDelete", mock.Anything)
+}
+
+func TestAddBookmarksKeepsTrashWhenStoreFails(t *testing.T) {
+	api := makeAPIMock()
+	jsonTrash, err := json.Marshal(getTestTrash())
+	require.Nil(t, err)
+	api.On("KVGet", getTrashKey(UserID)).Return(jsonTrash, nil)
+	api.On("KVGet", getBookmarksKey(UserID)).Return(nil, nil)
+	api.On("KVSet", mock.Anything, mock.Anything).Return(&model.AppError{Message: "failed"})
+
+	bmarks := NewBookmarksWithUser(api, UserID)
+	require.NotNil(t, bmarks.addBookmark(&Bookmark{PostID: "ID5xxxxxxxxxxxxxxxxxxxxxxx"}))
+
+	// the bookmark stays in the trash
+	api.AssertNotCalled(t, "KVSet", getTrashKey(UserID), mock.Anything)
+}
+
+func TestPurgeExpiredTrash(t *testing.T) {
+	api := makeAPIMock()
+	p := makePlugin(api)
+
+	jsonTrash, err := json.Marshal(getTestTrash())
+	require.Nil(t, err)
+
+	api.On("KVList", 0, kvListPerPage).Return([]string{getBookmarksKey(UserID), getTrashKey(UserID)}, nil)
+	api.On("KVGet", getTrashKey(UserID)).Return(jsonTrash, nil)
+	api.On("KVSet", getTrashKey(UserID), mock.Anything).Return(nil).Once()
+
+	p.purgeExpiredTrash()
+	api.AssertExpectations(t)
+}
@@ -2,12 +2,37 @@ package main
 
 import (
 	"fmt"
-	"regexp"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/mattermost/mattermost-server/v5/model"
 )
 
+// listUserIDsWithPrefix returns the user IDs of the keys starting with
+// prefix, e.g. the users with bookmarks for "bookmarks_".  All pages are
+// listed before the caller writes anything, keys written while paging would
+// shift the later pages of the list
+func (p *Plugin) listUserIDsWithPrefix(prefix string) ([]string, error) {
+	var userIDs []string
+	for page := 0; ; page++ {
+		keys, appErr := p.API.KVList(page, kvListPerPage)
+		if appErr != nil {
+			return nil, appErr
+		}
+
+		for _, key := range keys {
+			if strings.HasPrefix(key, prefix) {
+				userIDs = append(userIDs, strings.TrimPrefix(key, prefix))
+			}
+		}
+
+		if len(keys) < kvListPerPage {
+			return userIDs, nil
+		}
+	}
+}
+
 // getCodeBlockedLabels returns a list of individually codeblocked names
 func getCodeBlockedLabels(names []string) string {
 	labels := ""
@@ -23,13 +48,20 @@ func (p *Plugin) getPermaLink(postID string) string {
 	return fmt.Sprintf("%v/_redirect/pl/%v", p.GetSiteURL(), postID)
 }
 
-// getPostIDFromLink extracts a PostID from a link
+// getPluginActionURL returns the URL of a plugin endpoint used by interactive
+// message buttons
+func getPluginActionURL(path string) string {
+	return fmt.Sprintf("/plugins/%s%s", manifest.Id, path)
+}
+
+// getPostIDFromLink extracts a PostID from a link.  Anything that is not a
+// permalink to this server is returned as it is
 func (p *Plugin) getPostIDFromLink(s string) string {
-	r := regexp.MustCompile(`http:.*\/\w+\/\w+\/(\w+)`)
-	if len(r.FindStringSubmatch(s)) == 2 {
-		return r.FindStringSubmatch(s)[1]
+	permalink, err := p.parsePostLink(s)
+	if err != nil {
+		return s
 	}
-	return s
+	return permalink.PostID
 }
 
 // getIconLink returns a markdown link to a postID including a :link: icon
@@ -40,19 +72,11 @@ func (p *Plugin) getIconLink(postID string) string {
 
 // getTitleFromPost returns a title generated from a Post.Message
 func (p *Plugin) getTitleFromPost(postID string) (string, error) {
-	// MaxTitleCharacters is the maximum length of characters displayed in a
-	// bookmark title
-	// MaxTitleCharacters = 30
-
-	// TODO: set limit to number of character from post.Message
-	// numChars := math.Min(float64(len(post.Message)), MaxTitleCharacters)
-	// bookmark.Title = post.Message[0:int(numChars)]
 	post, appErr := p.API.GetPost(postID)
 	if appErr != nil {
 		return "", appErr
 	}
-	title := post.Message
-	return title, nil
+	return p.generatePostTitle(post), nil
 }
 
 const titleFromPostLabel = "**`TFP`**"
@@ -60,6 +84,11 @@ const titleFromPostLabel = "**`TFP`**"
 func getLegendText() string {
 	text := "#### Legend\n"
 	text += ":link: - Jump to the bookmarked post \n\n"
+	text += ":speech_balloon: thread, :hash: channel, :globe_with_meridians: link, :paperclip: file - Jump to other bookmarked things\n\n"
+	text += ":small_red_triangle: high, :small_red_triangle_down: low - Priority of the bookmark\n\n"
+	text += ":pencil2: edited, :ghost: deleted - The bookmarked post changed since it was bookmarked\n\n"
+	text += ":warning: overdue, :alarm_clock: due today, :calendar: due later - Due date of the bookmark\n\n"
+	text += ":hourglass_flowing_sand: in progress, :white_check_mark: done, :file_cabinet: archived - Reading status of the bookmark\n\n"
 	text += titleFromPostLabel + " (**T**ext**F**rom**P**ost) - Autogenerated label representing bookmarks without a user provided title.  Display text is generated from the bookmarked post message\n"
 	text += "`label` - **_Italicized & Bolded text signifies the bookmark has a saved title_**\n\n"
 	text += "***\n"
@@ -67,30 +96,61 @@ func getLegendText() string {
 }
 
 // getBmarksEphemeralText returns a the text for posting all bookmarks in an
-// ephemeral message
-func (p *Plugin) getBmarksEphemeralText(userID string, filters *BookmarksFilters) (string, error) {
+// ephemeral message sorted by sortBy, and "Mark done" buttons for the listed
+// bookmarks.  Bookmarks that are done or archived are hidden unless the
+// filters select statuses
+func (p *Plugin) getBmarksEphemeralText(userID string, filters *BookmarksFilters, sortBy string) (string, []*model.SlackAttachment, error) {
 	b, err := NewBookmarksWithUser(p.API, userID).getBookmarks()
 	if err != nil {
-		return "", err
+		return "", nil, err
+	}
+
+	// bookmarks is nil if user has never added a bookmark.
+	// bookmarks.ByID will be empty if user created a bookmark and then deleted
+	// it and now has 0 bookmarks
+	if b == nil || len(b.ByID) == 0 {
+		return "You do not have any saved bookmarks", nil, nil
+	}
+
+	// the users timezone is only needed for due dates
+	var now time.Time
+	if filters != nil && !filters.Now.IsZero() {
+		now = filters.Now
+	} else if (filters != nil && filters.Due != "") || b.hasDueDates() {
+		now = p.getNow(userID)
 	}
 
+	var statusFilters BookmarksFilters
 	if filters != nil {
-		b, err = b.applyFilters(filters)
+		statusFilters.Statuses = filters.Statuses
+		otherFilters := *filters
+		otherFilters.Statuses = nil
+		otherFilters.Now = now
+		b, err = b.applyFilters(&otherFilters)
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
 	}
+	hidden := 0
+	if len(statusFilters.Statuses) == 0 {
+		statusFilters.Statuses = activeStatuses
+		hidden = len(b.ByID)
+	}
+	b, err = b.applyFilters(&statusFilters)
+	if err != nil {
+		return "", nil, err
+	}
+	if hidden != 0 {
+		hidden -= len(b.ByID)
+	}
 
-	// bookmarks is nil if user has never added a bookmark.
-	// bookmarks.ByID will be empty if user created a bookmark and then deleted
-	// it and now has 0 bookmarks
-	if b == nil || len(b.ByID) == 0 {
-		return "You do not have any saved bookmarks", nil
+	if len(b.ByID) == 0 && hidden == 0 {
+		return "You do not have any saved bookmarks", nil, nil
 	}
 
-	bmarksSorted, err := b.ByPostCreateAt()
+	bmarksSorted, err := b.sortBookmarks(sortBy)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	text := getLegendText()
@@ -98,20 +158,23 @@ func (p *Plugin) getBmarksEphemeralText(userID string, filters *BookmarksFilters
 	for _, bmark := range bmarksSorted {
 		labelNames, err := b.getBmarkLabelNames(bmark)
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
 		nextText, err := p.getBmarkTextOneLine(bmark, labelNames)
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
-		text += nextText
+		text += strings.TrimSuffix(nextText, "\n") + getDueText(bmark, now) + "\n"
 	}
-	return text, nil
+	if hidden != 0 {
+		text += fmt.Sprintf("\n_%v bookmarks with other statuses are hidden. Use `--status all` to show them_\n", hidden)
+	}
+	return text, p.getDoneAttachments(bmarksSorted), nil
 }
 
 // getBmarkTextOneLine returns a single line bookmark text used for an ephemeral post
 func (p *Plugin) getBmarkTextOneLine(bmark *Bookmark, labelNames []string) (string, error) {
-	postMessage, err := p.getTitleFromPost(bmark.PostID)
+	generatedTitle, err := p.getBmarkGeneratedTitle(bmark)
 	if err != nil {
 		return "", err
 	}
@@ -122,20 +185,37 @@ func (p *Plugin) getBmarkTextOneLine(bmark *Bookmark, labelNames []string) (stri
 	title := "**_" + bmark.getTitle() + "_**"
 
 	if !bmark.hasUserTitle() {
-		// display the first portion of the post message in place of a title
-		title = postMessage
-		// prepend the title from post label before other labels
-		codeBlockedNames = " " + titleFromPostLabel + codeBlockedNames
+		// display the first portion of the post message, or the channel name,
+		// URL or file name in place of a title
+		title = generatedTitle
+		if bmark.isPost() {
+			// prepend the title from post label before other labels
+			codeBlockedNames = " " + titleFromPostLabel + codeBlockedNames
+		}
 	}
 
-	text := fmt.Sprintf("%s%s %s\n", p.getIconLink(bmark.PostID), codeBlockedNames, title)
+	iconLink := p.getBmarkIconLink(bmark)
+	if icon := getStatusIcon(bmark.getStatus()); icon != "" {
+		iconLink += " " + icon
+	}
+	if icon := getPriorityIcon(bmark.getPriority()); icon != "" {
+		iconLink += " " + icon
+	}
+
+	text := fmt.Sprintf("%s%s %s", iconLink, codeBlockedNames, title)
+	text += p.getSnapshotIndicator(bmark)
+	if count := p.getNewThreadReplies(bmark); count != 0 {
+		text += " " + getNewRepliesText(count)
+	}
+	text += "\n"
 
 	return text, nil
 }
 
-// getBmarkTextDetailed returns detailed, multi-line bookmark text used for an ephemeral post
-func (p *Plugin) getBmarkTextDetailed(bmark *Bookmark, labelNames []string, args *model.CommandArgs) (string, error) {
-	title, err := p.getTitleFromPost(bmark.PostID)
+// getBmarkTextDetailed returns detailed, multi-line bookmark text used for an
+// ephemeral post, optionally followed by the bookmark history
+func (p *Plugin) getBmarkTextDetailed(bmark *Bookmark, labelNames []string, args *model.CommandArgs, history bool) (string, error) {
+	title, err := p.getBmarkGeneratedTitle(bmark)
 	if err != nil {
 		return "", err
 	}
@@ -145,17 +225,79 @@ func (p *Plugin) getBmarkTextDetailed(bmark *Bookmark, labelNames []string, args
 	}
 
 	codeBlockedNames := getCodeBlockedLabels(labelNames)
-	post, appErr := p.API.GetPost(bmark.PostID)
-	if appErr != nil {
-		return "", appErr
-	}
+	iconLink := p.getBmarkIconLink(bmark)
 
-	iconLink := p.getIconLink(bmark.PostID)
+	// times are shown in the users timezone
+	location := func() *time.Location {
+		if args == nil {
+			return time.UTC
+		}
+		return p.getUserLocation(args.UserId)
+	}
 
 	text := fmt.Sprintf("%s\n#### Bookmark Title %s\n", codeBlockedNames, iconLink)
 	text += fmt.Sprintf("**%s**\n", title)
-	text += "##### Post Message \n"
-	text += post.Message
+	text += fmt.Sprintf("Status: `%s`\n", bmark.getStatus())
+	text += fmt.Sprintf("Priority: `%s`\n", bmark.getPriority())
+	if bmark.Due != "" {
+		text += fmt.Sprintf("Due: %s\n", formatDueDate(bmark.Due))
+	}
+
+	switch bmark.getKind() {
+	case bookmarkKindPost, bookmarkKindThread:
+		post, appErr := p.API.GetPost(bmark.getTarget())
+		switch {
+		case appErr != nil && bmark.Snapshot == nil:
+			return "", appErr
+		case appErr != nil:
+			text += "##### Post Message :ghost: \n"
+			text += "_The post was deleted. This is the copy saved with the bookmark_\n"
+			text += getSnapshotText(bmark.Snapshot, location())
+		case bmark.Snapshot != nil && bmark.Snapshot.isEdited(post):
+			text += "##### Post Message :pencil2: \n"
+			text += post.Message
+			text += "\n##### Saved Message \n"
+			text += "_The post was edited since it was bookmarked. This is the copy saved with the bookmark_\n"
+			text += getSnapshotText(bmark.Snapshot, location())
+		default:
+			text += "##### Post Message \n"
+			text += post.Message
+		}
+		if bmark.getKind() == bookmarkKindThread {
+			text += "\n##### Thread \n"
+			count, err := p.getThreadReplyCount(bmark.getTarget())
+			if err != nil {
+				return "", err
+			}
+			text += fmt.Sprintf("%v replies", count)
+			if count := p.getNewThreadReplies(bmark); count != 0 {
+				text += " " + getNewRepliesText(count)
+			}
+			if bmark.ThreadNotify {
+				text += ", notifications on"
+			}
+		}
+	case bookmarkKindChannel:
+		generated, _ := p.getBmarkGeneratedTitle(bmark)
+		text += "##### Channel \n"
+		text += generated
+	case bookmarkKindURL:
+		text += "##### URL \n"
+		text += bmark.getTarget()
+	case bookmarkKindFile:
+		generated, _ := p.getBmarkGeneratedTitle(bmark)
+		text += "##### File \n"
+		text += generated
+	}
+
+	// the history names the labels of the user running the command
+	if history && args != nil {
+		labels, err := NewLabelsWithUser(p.API, args.UserId).getLabels()
+		if err != nil {
+			return "", err
+		}
+		text += "\n" + getBmarkHistoryText(bmark, labels, location())
+	}
 
 	return text, nil
 }
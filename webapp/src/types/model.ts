@@ -1,15 +1,46 @@
+export type BookmarkKind = 'post' | 'thread' | 'channel' | 'url' | 'file';
+
+export type BookmarkStatus = 'unread' | 'in-progress' | 'done' | 'archived';
+
+export type BookmarkPriority = 'high' | 'normal' | 'low';
+
+export type BookmarkSort = 'time' | 'created' | 'priority' | 'manual';
+
 export type Bookmark = {
     postID: string;
+    kind?: BookmarkKind;
+    target?: string;
     title: string;
     create_at: number;
     update_at: number;
     label_ids: string[];
+    status?: BookmarkStatus;
+    priority?: BookmarkPriority;
+    position?: number;
+    due?: string;
+    thread_reply_count?: number;
+    thread_seen_at?: number;
+    thread_notify?: boolean;
+    thread_notified_at?: number;
+    snapshot?: BookmarkSnapshot;
+};
+
+export type BookmarkSnapshot = {
+    message: string;
+    user_id?: string;
+    username?: string;
+    channel_id?: string;
+    channel_name?: string;
+    create_at: number;
+    file_names?: string[];
+    taken_at: number;
 };
 
 export type Label = {
     name: string;
     color: string;
-    description: number;
+    description: string;
+    parent_id?: string;
 };
 
 export type Labels = {